@@ -0,0 +1,215 @@
+package yaml
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// canonicalWriter writes node trees in canonical form: every node is written in flow style with its tag,
+// every scalar is double-quoted in a single notation, and mapping entries are sorted, e.g.,
+//
+//	!!map {
+//	  !!str "a": !!seq [
+//	    !!int "1",
+//	  ],
+//	}
+type canonicalWriter struct {
+	// sorted caches the pairs of mappings, sorted by their canonical key
+	sorted map[*MappingNode][]MappingPair
+
+	// recursive holds the nodes an alias within themselves refers to, which are written with an anchor
+	// as they can not be expanded
+	recursive map[Node]bool
+	anchors   map[Node]string
+	defined   int
+	enclosing map[Node]bool
+}
+
+// Hash returns the SHA-256 of the canonical form of n, see Emitter.SetCanonical.
+// Semantically equal nodes have the same Hash, whatever their key order, styles, comments or aliases
+func Hash(n Node) ([sha256.Size]byte, error) {
+	var b strings.Builder
+	emitter := NewEmitter(&b)
+	emitter.SetCanonical(true)
+	if err := emitter.Emit(n); err != nil {
+		return [sha256.Size]byte{}, err
+	}
+	return sha256.Sum256([]byte(b.String())), nil
+}
+
+func newCanonicalWriter(root Node) *canonicalWriter {
+	c := &canonicalWriter{
+		sorted:    make(map[*MappingNode][]MappingPair),
+		recursive: make(map[Node]bool),
+		anchors:   make(map[Node]string),
+		enclosing: make(map[Node]bool),
+	}
+	c.findRecursive(root)
+	return c
+}
+
+// findRecursive walks n as it is written, expanding aliases, to find the nodes referred to by an alias within themselves
+func (c *canonicalWriter) findRecursive(n Node) {
+	n = resolveAlias(n)
+	if n == nil {
+		return
+	}
+	if c.enclosing[n] {
+		c.recursive[n] = true
+		return
+	}
+
+	c.enclosing[n] = true
+	switch n := n.(type) {
+	case *MappingNode:
+		for _, pair := range c.pairs(n) {
+			c.findRecursive(pair.Key)
+			c.findRecursive(pair.Value)
+		}
+	case *SequenceNode:
+		for _, item := range n.items {
+			c.findRecursive(item)
+		}
+	}
+	delete(c.enclosing, n)
+}
+
+// write writes n at indent. Collections are written on several lines, unless inline is true
+func (c *canonicalWriter) write(b *strings.Builder, n Node, indent int, inline bool) error {
+	target := resolveAlias(n)
+	if target == nil {
+		b.WriteString(`!!null ""`)
+		return nil
+	}
+	if c.enclosing[target] {
+		b.WriteString("*" + c.anchors[target])
+		return nil
+	}
+	if c.recursive[target] {
+		c.defined++
+		c.anchors[target] = "a" + strconv.Itoa(c.defined)
+		b.WriteString("&" + c.anchors[target] + " ")
+	}
+
+	c.enclosing[target] = true
+	defer delete(c.enclosing, target)
+
+	switch target := target.(type) {
+	case *ScalarNode:
+		value := canonicalScalar(target)
+		if target.Tag() == TagNull {
+			value = ""
+		}
+		b.WriteString(target.Tag() + " " + doubleQuoted(value))
+		return nil
+
+	case *SequenceNode:
+		b.WriteString(target.Tag() + " [")
+		for i, item := range target.items {
+			if err := c.entry(b, i, indent, inline, func() error {
+				return c.write(b, item, indent+2, inline)
+			}); err != nil {
+				return err
+			}
+		}
+		c.close(b, "]", len(target.items), indent, inline)
+		return nil
+
+	case *MappingNode:
+		b.WriteString(target.Tag() + " {")
+		pairs := c.pairs(target)
+		for i, pair := range pairs {
+			if err := c.entry(b, i, indent, inline, func() error {
+				if err := c.write(b, pair.Key, indent+2, true); err != nil {
+					return err
+				}
+				b.WriteString(": ")
+				return c.write(b, pair.Value, indent+2, inline)
+			}); err != nil {
+				return err
+			}
+		}
+		c.close(b, "}", len(pairs), indent, inline)
+		return nil
+	}
+	return fmt.Errorf("can not emit node of type %T", n)
+}
+
+// entry writes the i-th entry of a collection at indent, using write
+func (c *canonicalWriter) entry(b *strings.Builder, i int, indent int, inline bool, write func() error) error {
+	switch {
+	case !inline:
+		b.WriteString("\n" + strings.Repeat(" ", indent+2))
+	case i > 0:
+		b.WriteString(", ")
+	}
+	if err := write(); err != nil {
+		return err
+	}
+	if !inline {
+		b.WriteByte(',')
+	}
+	return nil
+}
+
+// close writes the closing bracket of a collection of length entries at indent
+func (c *canonicalWriter) close(b *strings.Builder, bracket string, length int, indent int, inline bool) {
+	if !inline && length > 0 {
+		b.WriteString("\n" + strings.Repeat(" ", indent))
+	}
+	b.WriteString(bracket)
+}
+
+// pairs returns the pairs of n sorted by their canonical key
+func (c *canonicalWriter) pairs(n *MappingNode) []MappingPair {
+	if pairs, ok := c.sorted[n]; ok {
+		return pairs
+	}
+
+	type keyed struct {
+		key  string
+		pair MappingPair
+	}
+	entries := make([]keyed, len(n.pairs))
+	for i, pair := range n.pairs {
+		var b strings.Builder
+		// keys are rendered without anchors, which only depend on the order being computed
+		key := &canonicalWriter{sorted: c.sorted, anchors: map[Node]string{}, enclosing: map[Node]bool{}}
+		if err := key.write(&b, pair.Key, 0, true); err != nil {
+			b.WriteString(err.Error())
+		}
+		entries[i] = keyed{key: b.String(), pair: pair}
+	}
+	slices.SortStableFunc(entries, func(a, b keyed) int {
+		return cmp.Compare(a.key, b.key)
+	})
+
+	pairs := make([]MappingPair, len(entries))
+	for i, entry := range entries {
+		pairs[i] = entry.pair
+	}
+	c.sorted[n] = pairs
+	return pairs
+}
+
+// emitCanonical writes the document whose root is root in canonical form
+func (e *Emitter) emitCanonical(root Node) error {
+	var b strings.Builder
+	if e.documents == 0 {
+		b.WriteString("%YAML 1.2\n")
+	}
+	b.WriteString("---\n")
+	if err := newCanonicalWriter(root).write(&b, root, 0, false); err != nil {
+		return err
+	}
+	b.WriteByte('\n')
+	e.documents++
+
+	_, err := io.WriteString(e.w, b.String())
+	return err
+}
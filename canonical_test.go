@@ -0,0 +1,95 @@
+package yaml_test
+
+import (
+	"github.com/ercross/yaml"
+	"strings"
+	"testing"
+)
+
+func canonical(t *testing.T, n yaml.Node) string {
+	t.Helper()
+	var b strings.Builder
+	emitter := yaml.NewEmitter(&b)
+	emitter.SetCanonical(true)
+	if err := emitter.Emit(n); err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
+
+func TestEmitter_Canonical(t *testing.T) {
+	document := parseDocument(t, "b: [1, 0x2, 'three', 4.50]\na: &x {k: ~, on: True}\nc: *x\n")
+	expected := `%YAML 1.2
+---
+!!map {
+  !!str "a": !!map {
+    !!str "k": !!null "",
+    !!str "on": !!bool "true",
+  },
+  !!str "b": !!seq [
+    !!int "1",
+    !!int "2",
+    !!str "three",
+    !!float "4.5",
+  ],
+  !!str "c": !!map {
+    !!str "k": !!null "",
+    !!str "on": !!bool "true",
+  },
+}
+`
+	actual := canonical(t, document)
+	if actual != expected {
+		t.Fatalf("expected %q, got %q", expected, actual)
+	}
+
+	if reparsed := canonical(t, parseDocument(t, actual)); reparsed != expected {
+		t.Errorf("expected the canonical form to read back unchanged, got %q", reparsed)
+	}
+}
+
+func TestEmitter_CanonicalRecursiveAlias(t *testing.T) {
+	sequence := yaml.NewSequenceNode()
+	sequence.SetAnchor("self")
+	sequence.Append(yaml.NewScalarNode("x"), yaml.NewAliasNode("self", sequence))
+
+	expected := "%YAML 1.2\n---\n&a1 !!seq [\n  !!str \"x\",\n  *a1,\n]\n"
+	if actual := canonical(t, sequence); actual != expected {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+}
+
+func TestHash(t *testing.T) {
+	hash := func(source string) [32]byte {
+		t.Helper()
+		h, err := yaml.Hash(parseDocument(t, source))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return h
+	}
+
+	base := hash("name: web\nports: [80, 443]\nlabels: {app: web}\n")
+	equal := []string{
+		"# reordered\nlabels:\n  app: \"web\"\nports:\n  - 0x50\n  - 443\nname: 'web'\n",
+		"name: &name web\nports: [80, 443]\nlabels: {app: *name}\n",
+	}
+	for _, source := range equal {
+		if hash(source) != base {
+			t.Errorf("expected %q to have the same hash", source)
+		}
+	}
+	if hash("name: web\nports: [443, 80]\nlabels: {app: web}\n") == base {
+		t.Error("expected a different item order to change the hash")
+	}
+	if hash("name: web\nports: ['80', 443]\nlabels: {app: web}\n") == base {
+		t.Error("expected a different tag to change the hash")
+	}
+
+	document := parseDocument(t, "a: 1\n")
+	fromDocument, _ := yaml.Hash(document)
+	fromRoot, _ := yaml.Hash(document.Root())
+	if fromDocument != fromRoot {
+		t.Error("expected a document and its root to have the same hash")
+	}
+}
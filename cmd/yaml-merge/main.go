@@ -0,0 +1,100 @@
+// Command yaml-merge is a git merge driver merging YAML files structurally, see yaml.Merge.
+//
+// Register it in the git configuration:
+//
+//	git config merge.yaml.name "structural YAML merge"
+//	git config merge.yaml.driver "yaml-merge %O %A %B"
+//
+// and assign it to YAML files in .gitattributes:
+//
+//	*.yaml merge=yaml
+//	*.yml merge=yaml
+//
+// yaml-merge writes the merged file over %A. It exits with status 1 when some nodes conflict,
+// each of them preceded by a comment showing both versions, and with status 2 when the files can not be merged
+// structurally (e.g., a file is not valid YAML), leaving %A unchanged
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/ercross/yaml"
+	"github.com/ercross/yaml/parser"
+	"os"
+)
+
+var errDocumentCount = errors.New("ours and theirs have a different number of documents")
+
+func main() {
+	if len(os.Args) != 4 {
+		fmt.Fprintln(os.Stderr, "usage: yaml-merge <base> <ours> <theirs>")
+		os.Exit(2)
+	}
+	os.Exit(run(os.Args[1], os.Args[2], os.Args[3]))
+}
+
+// run merges the files and returns the exit status
+func run(basePath, oursPath, theirsPath string) int {
+	var sources [3][]byte
+	for i, path := range []string{basePath, oursPath, theirsPath} {
+		source, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "yaml-merge:", err)
+			return 2
+		}
+		sources[i] = source
+	}
+
+	merged, conflicts, err := merge(sources[0], sources[1], sources[2])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "yaml-merge: %s: %v\n", oursPath, err)
+		return 2
+	}
+	if err = os.WriteFile(oursPath, merged, 0o644); err != nil {
+		fmt.Fprintln(os.Stderr, "yaml-merge:", err)
+		return 2
+	}
+
+	for _, conflict := range conflicts {
+		fmt.Fprintf(os.Stderr, "yaml-merge: %s: conflict at %s\n", oursPath, conflict.Path)
+	}
+	if len(conflicts) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// merge merges the documents of the streams base, ours and theirs pairwise, and returns the merged stream
+func merge(base, ours, theirs []byte) ([]byte, []yaml.Conflict, error) {
+	var documents [3][]*yaml.DocumentNode
+	for i, source := range [][]byte{base, ours, theirs} {
+		ast, err := parser.Parse(bytes.NewReader(source))
+		if err != nil {
+			return nil, nil, err
+		}
+		documents[i] = ast.Documents()
+	}
+	if len(documents[1]) != len(documents[2]) {
+		return nil, nil, fmt.Errorf("%w: %d and %d", errDocumentCount, len(documents[1]), len(documents[2]))
+	}
+
+	var b bytes.Buffer
+	emitter := yaml.NewEmitter(&b)
+	var conflicts []yaml.Conflict
+	for i := range documents[1] {
+		// documents are matched by position, so a base holding another number of documents is ignored,
+		// as if both sides had added every document
+		var base *yaml.DocumentNode
+		if i < len(documents[0]) && len(documents[0]) == len(documents[1]) {
+			base = documents[0][i]
+		}
+
+		merged, documentConflicts := yaml.Merge(base, documents[1][i], documents[2][i])
+		conflicts = append(conflicts, documentConflicts...)
+		if err := emitter.Emit(merged); err != nil {
+			return nil, nil, err
+		}
+	}
+	return b.Bytes(), conflicts, nil
+}
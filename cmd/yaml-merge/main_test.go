@@ -0,0 +1,61 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMerge_Documents(t *testing.T) {
+	merged, conflicts, err := merge(
+		[]byte("a: 1\n---\nb: 1\n"),
+		[]byte("a: 2\n---\nb: 1\n"),
+		[]byte("a: 1\n---\nb: 2\n"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "a: 2\n---\nb: 2\n"; string(merged) != expected {
+		t.Errorf("expected %q, got %q", expected, merged)
+	}
+	if len(conflicts) != 0 {
+		t.Errorf("expected no conflict, got %v", conflicts)
+	}
+
+	_, _, err = merge([]byte("a: 1\n"), []byte("a: 1\n"), []byte("a: 1\n---\nb: 1\n"))
+	if !errors.Is(err, errDocumentCount) {
+		t.Errorf("expected %v, got %v", errDocumentCount, err)
+	}
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	base := write("base.yaml", "a: 1\nb: 1\n")
+	ours := write("ours.yaml", "a: 2\nb: 1\n")
+	theirs := write("theirs.yaml", "a: 3\nb: 2\n")
+
+	if status := run(base, ours, theirs); status != 1 {
+		t.Errorf("expected exit status 1 on conflicts, got %d", status)
+	}
+	merged, err := os.ReadFile(ours)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "# <<<<<<< ours\n# a: 2\n# =======\n# a: 3\n# >>>>>>> theirs\na: 2\nb: 2\n"
+	if string(merged) != expected {
+		t.Errorf("expected %q to be written over ours, got %q", expected, merged)
+	}
+
+	invalid := write("invalid.yaml", "a: [\n")
+	if status := run(base, invalid, theirs); status != 2 {
+		t.Errorf("expected exit status 2 on invalid yaml, got %d", status)
+	}
+}
@@ -0,0 +1,169 @@
+// Package cst builds lossless concrete syntax trees of YAML streams.
+//
+// A Tree keeps every byte of its source as a sequence of tokens: the tokens produced by the tokenizer
+// followed by the trivia (whitespace and line breaks) the tokenizer skips, so that Tree.Bytes reproduces the source exactly.
+// Each yaml.Node of the semantic tree is linked to the span of source bytes it was parsed from,
+// which allows rewriting a single node while leaving the rest of the source untouched
+package cst
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ercross/yaml"
+	"github.com/ercross/yaml/parser"
+	"github.com/ercross/yaml/token"
+	"github.com/ercross/yaml/tokenizer"
+	"strings"
+)
+
+var (
+	// ErrNoSpan is returned when a node was not parsed from the source of a Tree, e.g., a node created programmatically
+	ErrNoSpan = errors.New("node has no source span")
+
+	// ErrInvalidEdit is returned when replacing the source of a node does not produce a valid YAML stream
+	ErrInvalidEdit = errors.New("edit produces invalid yaml")
+
+	// ErrUnexpectedChange is returned when the edited source reads back with changes other than the edited node,
+	// e.g., a replacing value whose text leaks into the surrounding flow collection
+	ErrUnexpectedChange = errors.New("edit changes other nodes")
+)
+
+type (
+	// Tree is a lossless concrete syntax tree, linking the nodes of a YAML stream to their source
+	Tree struct {
+		source    []byte
+		tokens    []Token
+		documents []*yaml.DocumentNode
+		spans     map[yaml.Node]Span
+	}
+
+	// Token is a piece of the source of a Tree
+	Token struct {
+		// Type is the type of the tokenizer token, or token.TypeUnknown for trivia
+		Type token.Type
+
+		// Offset is the byte offset of Text within the source
+		Offset int
+		Text   string
+	}
+
+	// Span is the range of source bytes [Start, End) a node was parsed from.
+	// The span of a node excludes its properties and comments
+	Span struct {
+		Start int
+		End   int
+	}
+)
+
+// Parse parses src into a Tree
+func Parse(src []byte) (*Tree, error) {
+	lines := splitLines(src)
+	t := tokenizer.New()
+	builder := parser.NewAstBuilder()
+	var scanned []token.Token
+
+	for i, line := range lines {
+		tokens, err := t.Tokenize(line.normalized(src), i+1)
+		if err != nil {
+			return nil, err
+		}
+
+		// a line within a multi-line quoted scalar may not complete any token
+		if len(tokens) > 0 {
+			if err = builder.Build(tokens); err != nil {
+				return nil, err
+			}
+		}
+		scanned = append(scanned, tokens...)
+	}
+
+	if err := t.Finish(); err != nil {
+		return nil, err
+	}
+	if err := builder.Finish(); err != nil {
+		return nil, err
+	}
+
+	tree := &Tree{
+		source:    src,
+		tokens:    newScanner(src, lines).scan(scanned),
+		documents: builder.AbstractSyntaxTree().Documents(),
+		spans:     make(map[yaml.Node]Span),
+	}
+	newSpanner(tree).link()
+	return tree, nil
+}
+
+// Bytes returns the source of the Tree, rebuilt from its tokens
+func (t *Tree) Bytes() []byte {
+	var b strings.Builder
+	b.Grow(len(t.source))
+	for _, tok := range t.tokens {
+		b.WriteString(tok.Text)
+	}
+	return []byte(b.String())
+}
+
+// Tokens returns every token of the Tree, including trivia, in source order
+func (t *Tree) Tokens() []Token {
+	return t.tokens
+}
+
+// Documents returns the semantic trees of the documents of the Tree
+func (t *Tree) Documents() []*yaml.DocumentNode {
+	return t.documents
+}
+
+// Span returns the span of source bytes n was parsed from.
+// An empty (null) value has an empty span following its mapping key or sequence entry indicator
+func (t *Tree) Span(n yaml.Node) (Span, bool) {
+	span, ok := t.spans[n]
+	return span, ok
+}
+
+// Source returns the source text n was parsed from
+func (t *Tree) Source(n yaml.Node) (string, error) {
+	span, ok := t.spans[n]
+	if !ok {
+		return "", ErrNoSpan
+	}
+	return string(t.source[span.Start:span.End]), nil
+}
+
+// Replace returns a new Tree whose source is the source of t with the span of n replaced by text.
+// Every byte outside the span of n is kept as is
+func (t *Tree) Replace(n yaml.Node, text string) (*Tree, error) {
+	span, ok := t.spans[n]
+	if !ok {
+		return nil, ErrNoSpan
+	}
+	return t.replaceSpan(span, text)
+}
+
+func (t *Tree) replaceSpan(span Span, text string) (*Tree, error) {
+	src := make([]byte, 0, len(t.source)-span.Len()+len(text))
+	src = append(src, t.source[:span.Start]...)
+	src = append(src, text...)
+	src = append(src, t.source[span.End:]...)
+
+	tree, err := Parse(src)
+	if err != nil {
+		return nil, fmt.Errorf("replacing %q at offset %d with %q: %w: %w",
+			t.source[span.Start:span.End], span.Start, text, ErrInvalidEdit, err)
+	}
+	return tree, nil
+}
+
+// IsTrivia checks that t holds whitespace or line breaks the tokenizer does not produce a token for
+func (t Token) IsTrivia() bool {
+	return t.Type == token.TypeUnknown
+}
+
+// End is the byte offset following Text within the source
+func (t Token) End() int {
+	return t.Offset + len(t.Text)
+}
+
+func (s Span) Len() int {
+	return s.End - s.Start
+}
@@ -0,0 +1,94 @@
+package cst
+
+import (
+	"errors"
+	"github.com/ercross/yaml"
+	"testing"
+)
+
+func mustParse(t *testing.T, src string) *Tree {
+	t.Helper()
+	tree, err := Parse([]byte(src))
+	if err != nil {
+		t.Fatalf("failed to parse %q: %v", src, err)
+	}
+	return tree
+}
+
+func TestParse_Lossless(t *testing.T) {
+	sources := []string{
+		"a: 1\n",
+		"a: 1",
+		"a: 1\r\nb:\r\n  - x\r\n",
+		"\n\n# only comments\n\n",
+		"key:    value    \n\t\n",
+		"- a\n-   b\n- [c,   {d: e}]\n",
+		"text: |+\n  kept\n\n\nnext: >-\n  folded\n  lines\n...\n",
+		"multi: \"line one\n  line two\"\nafter: x\n",
+		"a: &anchor value\nb: *anchor\nc: !!str 12\n",
+		"unicode: héllo wörld # ça va\n",
+	}
+	for _, src := range sources {
+		tree := mustParse(t, src)
+		if actual := string(tree.Bytes()); actual != src {
+			t.Errorf("expected %q, got %q", src, actual)
+		}
+	}
+}
+
+func TestTree_Source(t *testing.T) {
+	tree := mustParse(t, "name:   web   # comment\nimage: &x \"nginx:1.25\"\nports: [80,  443]\nempty:\nlist:\n  - |\n    echo\n\n  -\nnested:\n  a: *x\n")
+	root := tree.Documents()[0].Root().(*yaml.MappingNode)
+
+	expected := map[string]string{
+		"name":   "web",
+		"image":  `"nginx:1.25"`,
+		"ports":  "[80,  443]",
+		"empty":  "",
+		"list":   "- |\n    echo\n\n  -",
+		"nested": "a: *x",
+	}
+	for key, text := range expected {
+		value, _ := root.Get(key)
+		actual, err := tree.Source(value)
+		if err != nil {
+			t.Errorf("%s: %v", key, err)
+			continue
+		}
+		if actual != text {
+			t.Errorf("%s: expected %q, got %q", key, text, actual)
+		}
+	}
+}
+
+func TestTree_Replace(t *testing.T) {
+	src := "# keep me\nname:   web   # comment\nempty:\nlist:\n  - a\n  -\n"
+	tree := mustParse(t, src)
+	root := tree.Documents()[0].Root().(*yaml.MappingNode)
+
+	name, _ := root.Get("name")
+	edited, err := tree.Replace(name, "api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	empty, _ := edited.Documents()[0].Root().(*yaml.MappingNode).Get("empty")
+	if edited, err = edited.Replace(empty, " 'set'"); err != nil {
+		t.Fatal(err)
+	}
+	list, _ := edited.Documents()[0].Root().(*yaml.MappingNode).Get("list")
+	if edited, err = edited.Replace(list.(*yaml.SequenceNode).Items()[1], " b"); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "# keep me\nname:   api   # comment\nempty: 'set'\nlist:\n  - a\n  - b\n"
+	if actual := string(edited.Bytes()); actual != expected {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+
+	if _, err = tree.Replace(name, "[unclosed"); !errors.Is(err, ErrInvalidEdit) {
+		t.Errorf("expected ErrInvalidEdit, got %v", err)
+	}
+	if _, err = tree.Replace(yaml.NewScalarNode("x"), "y"); !errors.Is(err, ErrNoSpan) {
+		t.Errorf("expected ErrNoSpan, got %v", err)
+	}
+}
@@ -0,0 +1,343 @@
+package cst
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ercross/yaml"
+	"github.com/ercross/yaml/query"
+	"github.com/ercross/yaml/token"
+	"slices"
+	"strings"
+)
+
+var (
+	ErrPathNotFound  = errors.New("path not found")
+	ErrAmbiguousPath = errors.New("path matches several nodes")
+)
+
+// EditBytes replaces the node at path within src with newValue, and returns the edited source.
+//
+// path is either a JSON Pointer (e.g., /spec/replicas) or a query path expression (e.g., $.spec.containers[0].image),
+// and must match exactly one node across the documents of src. newValue is converted with yaml.NewNode.
+// Only the source span of the matched node is rewritten: a scalar keeps the quoting of the value it replaces
+// when the new value allows it, and a collection is indented to fit the surrounding lines.
+// The edited source is parsed again, and EditBytes fails with ErrUnexpectedChange unless the matched node
+// is the only one that changed, and now reads as newValue.
+//
+// EditBytes lives in package cst rather than in package yaml: editing needs the parser,
+// which imports package yaml, so package yaml can not import the parser without an import cycle
+func EditBytes(src []byte, path string, newValue any) ([]byte, error) {
+	tree, err := Parse(src)
+	if err != nil {
+		return nil, err
+	}
+
+	target, err := tree.find(path)
+	if err != nil {
+		return nil, err
+	}
+	value, err := yaml.NewNode(newValue)
+	if err != nil {
+		return nil, err
+	}
+
+	span, text, err := tree.render(target, value)
+	if err != nil {
+		return nil, err
+	}
+	edited, err := tree.replaceSpan(span, text)
+	if err != nil {
+		return nil, err
+	}
+	if err := tree.checkEdit(edited, target, value); err != nil {
+		return nil, err
+	}
+	return edited.Bytes(), nil
+}
+
+// checkEdit checks that the documents of edited only differ from those of t by the node target, replaced by value
+func (t *Tree) checkEdit(edited *Tree, target, value yaml.Node) error {
+	if len(edited.documents) != len(t.documents) {
+		return fmt.Errorf("%w: %d documents instead of %d", ErrUnexpectedChange, len(edited.documents), len(t.documents))
+	}
+
+	for i, document := range t.documents {
+		targetPath, found := pathOf(document, target)
+		for _, change := range yaml.Diff(document, edited.documents[i]) {
+			if !found || !hasPrefix(change.Path, targetPath) {
+				return fmt.Errorf("%w: %s", ErrUnexpectedChange, change.Path)
+			}
+		}
+		if !found {
+			continue
+		}
+
+		expected, actual := yaml.NewDocumentNode(), yaml.NewDocumentNode()
+		expected.SetRoot(value)
+		if replaced, ok := nodeAt(edited.documents[i], targetPath); ok {
+			actual.SetRoot(replaced)
+		}
+		if changes := yaml.Diff(expected, actual); len(changes) > 0 {
+			return fmt.Errorf("%w: %s does not read as the new value", ErrUnexpectedChange, targetPath)
+		}
+	}
+	return nil
+}
+
+// find returns the single node path matches in the documents of t
+func (t *Tree) find(path string) (yaml.Node, error) {
+	var matches []yaml.Node
+	if path == "" || strings.HasPrefix(path, "/") {
+		for _, document := range t.documents {
+			if n, err := yaml.Pointer(path).Get(document); err == nil {
+				matches = append(matches, n)
+			} else if errors.Is(err, yaml.ErrInvalidPointer) {
+				return nil, err
+			}
+		}
+	} else {
+		q, err := query.Compile(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, document := range t.documents {
+			for _, match := range q.Evaluate(document) {
+				matches = append(matches, match.Node)
+			}
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("%q: %w", path, ErrPathNotFound)
+	case 1:
+		return matches[0], nil
+	default:
+		return nil, fmt.Errorf("%q matches %d nodes: %w", path, len(matches), ErrAmbiguousPath)
+	}
+}
+
+// render renders value as the replacement of the source of old, and returns the span it replaces
+func (t *Tree) render(old yaml.Node, value yaml.Node) (Span, string, error) {
+	span, ok := t.spans[old]
+	if !ok {
+		return Span{}, "", ErrNoSpan
+	}
+
+	if scalar, isScalar := value.(*yaml.ScalarNode); isScalar {
+		value = fitQuoting(old, scalar)
+	}
+
+	// separate indicates that the span starts right after a key or entry indicator
+	separate := span.Len() == 0
+	if isBlockCollection(old) {
+		if isBlockCollection(value) {
+			text, err := emitIndented(value, t.column(span.Start))
+			return span, text, err
+		}
+
+		// a block collection starts on the line following its key or entry indicator,
+		// where the replacing node is written instead
+		if indicator, found := t.indicatorBefore(span.Start); found {
+			span.Start = indicator
+			separate = true
+		}
+	}
+
+	var text string
+	var err error
+	switch {
+	case separate && isBlockCollection(value):
+		// the span ends the line of its indicator, so a block collection can start on the next one
+		indentation := t.ownerColumn(span) + 2
+		text, err = emitIndented(value, indentation)
+		return span, "\n" + strings.Repeat(" ", indentation) + text, err
+
+	case isCollection(value):
+		text, err = emitFlow(value)
+
+	case t.inFlow(span.Start):
+		text, err = emitFlowScalar(value)
+
+	default:
+		text, err = emitIndented(value, max(t.ownerColumn(span), 0))
+	}
+	if separate {
+		text = " " + text
+	}
+	return span, text, err
+}
+
+// indicatorBefore returns the offset following the mapping value (:) or sequence entry (-) indicator
+// last found before offset
+func (t *Tree) indicatorBefore(offset int) (int, bool) {
+	for i := len(t.tokens) - 1; i >= 0; i-- {
+		tok := t.tokens[i]
+		if tok.Offset >= offset {
+			continue
+		}
+		switch {
+		case tok.Type == token.TypeColon || tok.Type == token.TypeDash:
+			return tok.End(), true
+		case !tok.IsTrivia() && tok.Type != token.TypeNewline && tok.Type != token.TypeIndentation:
+			// properties and comments following the indicator are kept
+			return 0, false
+		}
+	}
+	return 0, false
+}
+
+// inFlow checks that offset is within a flow collection
+func (t *Tree) inFlow(offset int) bool {
+	depth := 0
+	for _, tok := range t.tokens {
+		if tok.Offset >= offset {
+			break
+		}
+		switch tok.Type {
+		case token.TypeOpeningSquareBracket, token.TypeOpeningCurlyBrace:
+			depth++
+		case token.TypeClosingSquareBracket, token.TypeClosingCurlyBrace:
+			depth--
+		}
+	}
+	return depth > 0
+}
+
+// ownerColumn returns the 0-based column of the mapping key or sequence entry indicator
+// preceding the span on its line, or -1 for a root node
+func (t *Tree) ownerColumn(span Span) int {
+	owner := -1
+	lineStart := t.lineStart(span.Start)
+	for _, tok := range t.tokens {
+		if tok.Offset >= span.Start || tok.Offset < lineStart {
+			continue
+		}
+		switch tok.Type {
+		case token.TypeDash:
+			owner = tok.Offset
+		case token.TypeData:
+			owner = tok.Offset
+		}
+	}
+	if owner == -1 {
+		return -1
+	}
+	return t.column(owner)
+}
+
+// column returns the 0-based column of offset, counting bytes
+func (t *Tree) column(offset int) int {
+	return offset - t.lineStart(offset)
+}
+
+func (t *Tree) lineStart(offset int) int {
+	start := offset
+	for start > 0 && t.source[start-1] != '\n' {
+		start--
+	}
+	return start
+}
+
+// fitQuoting gives value the quoting style of old, if old is a quoted scalar
+func fitQuoting(old yaml.Node, value *yaml.ScalarNode) *yaml.ScalarNode {
+	oldScalar, ok := old.(*yaml.ScalarNode)
+	if !ok || value.Tag() != yaml.TagString {
+		return value
+	}
+
+	switch style := oldScalar.Style(); {
+	case style == yaml.StyleSingleQuoted || style == yaml.StyleDoubleQuoted:
+		value.SetStyle(style)
+	case (style == yaml.StyleLiteral || style == yaml.StyleFolded) && strings.Contains(value.Value(), "\n"):
+		value.SetStyle(style)
+	}
+	return value
+}
+
+// emitIndented renders n in block style, indenting every line but the first one by indentation spaces
+func emitIndented(n yaml.Node, indentation int) (string, error) {
+	out, err := yaml.Emit(n)
+	if err != nil {
+		return "", err
+	}
+
+	lines := strings.Split(strings.TrimSuffix(string(out), "\n"), "\n")
+	for i := 1; i < len(lines); i++ {
+		if lines[i] != "" {
+			lines[i] = strings.Repeat(" ", indentation) + lines[i]
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// emitFlow renders the collection n on a single line
+func emitFlow(n yaml.Node) (string, error) {
+	if styled, ok := n.(interface{ SetStyle(yaml.Style) }); ok {
+		styled.SetStyle(yaml.StyleFlow)
+	}
+	out, err := yaml.Emit(n)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(string(out), "\n"), nil
+}
+
+// emitFlowScalar renders the scalar or alias n as an item of a flow collection,
+// quoting the values holding flow indicators, e.g., commas or brackets
+func emitFlowScalar(n yaml.Node) (string, error) {
+	sequence := yaml.NewSequenceNode()
+	sequence.Append(n)
+	text, err := emitFlow(sequence)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(text, "["), "]"), nil
+}
+
+// pathOf returns the path of n from the root of document
+func pathOf(document *yaml.DocumentNode, n yaml.Node) (yaml.Path, bool) {
+	for path, node := range yaml.All(document.Root()) {
+		if node == n {
+			return path, true
+		}
+	}
+	return nil, false
+}
+
+// nodeAt returns the node at path from the root of document
+func nodeAt(document *yaml.DocumentNode, path yaml.Path) (yaml.Node, bool) {
+	for nodePath, node := range yaml.All(document.Root()) {
+		if slices.EqualFunc(nodePath, path, sameSegment) {
+			return node, true
+		}
+	}
+	return nil, false
+}
+
+// hasPrefix checks that path starts with prefix
+func hasPrefix(path, prefix yaml.Path) bool {
+	return len(path) >= len(prefix) && slices.EqualFunc(path[:len(prefix)], prefix, sameSegment)
+}
+
+// sameSegment checks that a and b lead to the same child of trees of the same shape
+func sameSegment(a, b yaml.PathSegment) bool {
+	return a.Index == b.Index && a.IsKey == b.IsKey && (a.Key == nil) == (b.Key == nil)
+}
+
+func isCollection(n yaml.Node) bool {
+	switch n.(type) {
+	case *yaml.MappingNode, *yaml.SequenceNode:
+		return true
+	default:
+		return false
+	}
+}
+
+func isEmptyCollection(n yaml.Node) bool {
+	return isCollection(n) && len(n.Children()) == 0
+}
+
+func isBlockCollection(n yaml.Node) bool {
+	return isCollection(n) && n.Style() != yaml.StyleFlow && !isEmptyCollection(n)
+}
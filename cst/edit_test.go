@@ -0,0 +1,146 @@
+package cst
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const manifest = `# deployment
+apiVersion: apps/v1
+kind: Deployment
+spec:
+  replicas: 3   # scaled by hand
+  template:
+    containers:
+      - name: web
+        image: "nginx:1.25"
+        args: [--port, "80"]
+        env:
+          - name: MODE
+            value: 'prod'
+      - name: sidecar
+        image: envoy:1.0
+  selector:
+`
+
+func TestEditBytes(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		value    any
+		expected map[string]string
+	}{
+		{
+			name:     "version bump keeps double quotes",
+			path:     "$.spec.template.containers[0].image",
+			value:    "nginx:1.26",
+			expected: map[string]string{`        image: "nginx:1.25"`: `        image: "nginx:1.26"`},
+		},
+		{
+			name:     "plain scalar keeps its line comment",
+			path:     "/spec/replicas",
+			value:    5,
+			expected: map[string]string{"  replicas: 3   # scaled by hand": "  replicas: 5   # scaled by hand"},
+		},
+		{
+			name:     "single quotes are kept",
+			path:     "$..env[0].value",
+			value:    "staging",
+			expected: map[string]string{"            value: 'prod'": "            value: 'staging'"},
+		},
+		{
+			name:     "flow collection stays on its line",
+			path:     "/spec/template/containers/0/args",
+			value:    []string{"--port", "8080"},
+			expected: map[string]string{`        args: [--port, "80"]`: `        args: [--port, "8080"]`},
+		},
+		{
+			name:  "block collection is indented to fit",
+			path:  "/spec/template/containers/0/env",
+			value: []map[string]string{{"name": "MODE", "value": "dev"}, {"name": "DEBUG", "value": "true"}},
+			expected: map[string]string{
+				"          - name: MODE\n            value: 'prod'\n": "          - name: MODE\n            value: dev\n          - name: DEBUG\n            value: \"true\"\n",
+			},
+		},
+		{
+			name:     "empty value becomes a block collection",
+			path:     "/spec/selector",
+			value:    map[string]string{"app": "web"},
+			expected: map[string]string{"  selector:\n": "  selector:\n    app: web\n"},
+		},
+		{
+			name:     "block collection replaced by a scalar",
+			path:     "/spec/template/containers/0/env",
+			value:    nil,
+			expected: map[string]string{"        env:\n          - name: MODE\n            value: 'prod'\n": "        env: null\n"},
+		},
+	}
+
+	for _, test := range tests {
+		out, err := EditBytes([]byte(manifest), test.path, test.value)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+
+		expected := manifest
+		for old, replacement := range test.expected {
+			expected = replaceOnce(t, expected, old, replacement)
+		}
+		if string(out) != expected {
+			t.Errorf("%s: expected:\n%s\ngot:\n%s", test.name, expected, out)
+		}
+	}
+}
+
+func TestEditBytes_FlowCollection(t *testing.T) {
+	tests := []struct {
+		src      string
+		path     string
+		value    any
+		expected string
+	}{
+		{src: "a: {b: 1, c: 2}\n", path: "/a/b", value: "x, y", expected: "a: {b: 'x, y', c: 2}\n"},
+		{src: "a: [1, 2]\n", path: "/a/0", value: "x]", expected: "a: ['x]', 2]\n"},
+		{src: "a: {b: 1, c: 2}\n", path: "/a/c", value: "}", expected: "a: {b: 1, c: '}'}\n"},
+		{src: "a: [[1], {b: 2}]\n", path: "/a/1/b", value: "{z", expected: "a: [[1], {b: '{z'}]\n"},
+		{src: "a: {b: 1}\n", path: "/a/b", value: "plain", expected: "a: {b: plain}\n"},
+	}
+
+	for _, test := range tests {
+		out, err := EditBytes([]byte(test.src), test.path, test.value)
+		if err != nil {
+			t.Errorf("%s in %q: %v", test.path, test.src, err)
+			continue
+		}
+		if string(out) != test.expected {
+			t.Errorf("%s in %q: expected %q, got %q", test.path, test.src, test.expected, out)
+		}
+	}
+}
+
+func TestEditBytes_Errors(t *testing.T) {
+	if _, err := EditBytes([]byte(manifest), "/spec/missing", 1); !errors.Is(err, ErrPathNotFound) {
+		t.Errorf("expected ErrPathNotFound, got %v", err)
+	}
+	if _, err := EditBytes([]byte(manifest), "$..image", "x"); !errors.Is(err, ErrAmbiguousPath) {
+		t.Errorf("expected ErrAmbiguousPath, got %v", err)
+	}
+	if _, err := EditBytes([]byte(manifest), "/spec/replicas", make(chan int)); err == nil {
+		t.Error("expected an unsupported value to fail")
+	}
+	// the alias of the edited node would change along with it
+	if _, err := EditBytes([]byte("a: &x 1\nb: *x\n"), "/a", 2); !errors.Is(err, ErrUnexpectedChange) {
+		t.Errorf("expected ErrUnexpectedChange, got %v", err)
+	}
+}
+
+func replaceOnce(t *testing.T, s, old, replacement string) string {
+	t.Helper()
+	i := strings.Index(s, old)
+	if i == -1 {
+		t.Fatalf("%q not found in test source", old)
+	}
+	return s[:i] + replacement + s[i+len(old):]
+}
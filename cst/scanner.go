@@ -0,0 +1,139 @@
+package cst
+
+import (
+	"github.com/ercross/yaml/token"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// line is a line of source, content excludes the line break
+type line struct {
+	start int
+	end   int
+}
+
+// scanner locates the tokens produced by the tokenizer within the source,
+// and fills the gaps between them with trivia
+type scanner struct {
+	source []byte
+	lines  []line
+}
+
+// splitLines splits src into lines, ending each line before its line break (\n or \r\n)
+func splitLines(src []byte) []line {
+	var lines []line
+	start := 0
+	for start < len(src) {
+		end := start
+		for end < len(src) && src[end] != '\n' {
+			end++
+		}
+		next := end + 1
+		if end > start && src[end-1] == '\r' && end < len(src) {
+			end--
+		}
+		lines = append(lines, line{start: start, end: end})
+		start = next
+	}
+	return lines
+}
+
+// normalized returns the line as given to the tokenizer: without carriage return, and always ending with \n
+func (l line) normalized(src []byte) string {
+	return strings.TrimSuffix(string(src[l.start:l.end]), "\r") + "\n"
+}
+
+func newScanner(source []byte, lines []line) *scanner {
+	return &scanner{source: source, lines: lines}
+}
+
+// scan turns the tokenizer tokens of the source into lossless tokens
+func (s *scanner) scan(scanned []token.Token) []Token {
+	starts := make([]int, len(scanned))
+	for i, t := range scanned {
+		starts[i] = s.offset(t.Position)
+	}
+
+	// a multi-line quoted scalar is produced after the tokens of the lines it spans,
+	// but located at its opening quote
+	order := make([]int, len(scanned))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		return starts[a] - starts[b]
+	})
+
+	var tokens []Token
+	position := 0
+	for i, index := range order {
+		start := starts[index]
+		next := len(s.source)
+		if i+1 < len(order) {
+			next = starts[order[i+1]]
+		}
+
+		end := s.end(scanned[index], start, next)
+		if end <= start {
+			// e.g., the line break the tokenizer is given for a source not ending with one
+			continue
+		}
+		if start > position {
+			tokens = append(tokens, Token{Type: token.TypeUnknown, Offset: position, Text: string(s.source[position:start])})
+		}
+		tokens = append(tokens, Token{Type: scanned[index].Type, Offset: start, Text: string(s.source[start:end])})
+		position = end
+	}
+	if position < len(s.source) {
+		tokens = append(tokens, Token{Type: token.TypeUnknown, Offset: position, Text: string(s.source[position:])})
+	}
+	return tokens
+}
+
+// offset converts a location, whose column counts runes, into a byte offset within the source
+func (s *scanner) offset(location token.Location) int {
+	if location.Line() < 1 || location.Line() > len(s.lines) {
+		return len(s.source)
+	}
+	l := s.lines[location.Line()-1]
+	offset := l.start
+	for column := 1; column < location.Column() && offset < l.end; column++ {
+		_, size := utf8.DecodeRune(s.source[offset:l.end])
+		offset += size
+	}
+	return offset
+}
+
+// end finds the byte offset following the source text of t, which starts at start and is followed by a token at next
+func (s *scanner) end(t token.Token, start int, next int) int {
+	switch t.Type {
+	case token.TypeIndentation, token.TypeBlockScalarLine, token.TypeDirective, token.TypeDocumentStart, token.TypeDocumentEnd:
+		return min(start+len(t.Value), next)
+
+	case token.TypeComment:
+		return min(start+1+len(t.Value), next)
+
+	case token.TypeNewline:
+		// the line break, which may be \r\n
+		end := start
+		for end < next && (s.source[end] == '\r' || s.source[end] == '\n') {
+			end++
+			if s.source[end-1] == '\n' {
+				break
+			}
+		}
+		return end
+
+	default:
+		end := next
+		for end > start && isTrivia(s.source[end-1]) {
+			end--
+		}
+		return end
+	}
+}
+
+func isTrivia(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\r' || b == '\n'
+}
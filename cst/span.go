@@ -0,0 +1,199 @@
+package cst
+
+import (
+	"github.com/ercross/yaml"
+	"github.com/ercross/yaml/token"
+	"strings"
+)
+
+// spanner links the nodes of a Tree to the spans of their tokens
+type spanner struct {
+	tree *Tree
+
+	// byOffset indexes the tokens of the Tree by the byte offset they start at
+	byOffset map[int]int
+	scanner  *scanner
+	lines    []line
+}
+
+func newSpanner(tree *Tree) *spanner {
+	lines := splitLines(tree.source)
+	s := &spanner{
+		tree:     tree,
+		byOffset: make(map[int]int, len(tree.tokens)),
+		scanner:  newScanner(tree.source, lines),
+		lines:    lines,
+	}
+	for i, t := range tree.tokens {
+		if !t.IsTrivia() {
+			s.byOffset[t.Offset] = i
+		}
+	}
+	return s
+}
+
+func (s *spanner) link() {
+	for _, document := range s.tree.documents {
+		s.span(document)
+	}
+}
+
+// span computes the span of n and of its descendants
+func (s *spanner) span(n yaml.Node) (Span, bool) {
+	span, ok := s.compute(n)
+	if ok {
+		s.tree.spans[n] = span
+	}
+	return span, ok
+}
+
+func (s *spanner) compute(n yaml.Node) (Span, bool) {
+	switch n := n.(type) {
+	case *yaml.DocumentNode:
+		if n.Root() == nil {
+			return Span{}, false
+		}
+		root, ok := s.span(n.Root())
+		if !ok {
+			return Span{}, false
+		}
+		if i, found := s.tokenAt(n.Position()); found && s.tree.tokens[i].Type == token.TypeDocumentStart {
+			root.Start = s.tree.tokens[i].Offset
+		}
+		return root, true
+
+	case *yaml.ScalarNode:
+		i, ok := s.tokenAt(n.Position())
+		if !ok {
+			return Span{}, false
+		}
+		t := s.tree.tokens[i]
+		switch {
+		case isEmptyPlain(n) && t.Type == token.TypeDash:
+			return Span{Start: t.End(), End: t.End()}, true
+		case isEmptyPlain(n):
+			// the empty value of a mapping entry is located by the mapping
+			return Span{}, false
+		case t.Type == token.TypePipe || t.Type == token.TypeGreaterThan:
+			return Span{Start: t.Offset, End: s.blockScalarEnd(i)}, true
+		case t.Type == token.TypeData:
+			return Span{Start: t.Offset, End: t.End()}, true
+		}
+		return Span{}, false
+
+	case *yaml.AliasNode:
+		i, ok := s.tokenAt(n.Position())
+		if !ok || s.tree.tokens[i].Type != token.TypeAsterisk {
+			return Span{}, false
+		}
+		return Span{Start: s.tree.tokens[i].Offset, End: s.tree.tokens[i].End()}, true
+
+	case *yaml.SequenceNode:
+		span, ok := s.collectionStart(n)
+		for _, item := range n.Items() {
+			if child, found := s.span(item); found {
+				span.End = max(span.End, child.End)
+			}
+		}
+		return span, ok
+
+	case *yaml.MappingNode:
+		span, ok := s.collectionStart(n)
+		for _, pair := range n.Pairs() {
+			key, found := s.span(pair.Key)
+			if !found {
+				continue
+			}
+			span.End = max(span.End, key.End)
+
+			value, found := s.span(pair.Value)
+			if !found && isEmptyPlain(pair.Value) {
+				value, found = s.emptyValue(key)
+				if found {
+					s.tree.spans[pair.Value] = value
+				}
+			}
+			if found {
+				span.End = max(span.End, value.End)
+			}
+		}
+		return span, ok
+	}
+	return Span{}, false
+}
+
+// collectionStart returns the span of the opening bracket of a flow collection, which extends to its closing bracket,
+// or the empty span starting a block collection
+func (s *spanner) collectionStart(n yaml.Node) (Span, bool) {
+	i, ok := s.tokenAt(n.Position())
+	if !ok {
+		return Span{}, false
+	}
+	t := s.tree.tokens[i]
+	if t.Type != token.TypeOpeningSquareBracket && t.Type != token.TypeOpeningCurlyBrace {
+		return Span{Start: t.Offset, End: t.Offset}, true
+	}
+
+	depth := 0
+	for _, t = range s.tree.tokens[i:] {
+		switch t.Type {
+		case token.TypeOpeningSquareBracket, token.TypeOpeningCurlyBrace:
+			depth++
+		case token.TypeClosingSquareBracket, token.TypeClosingCurlyBrace:
+			depth--
+			if depth == 0 {
+				return Span{Start: s.tree.tokens[i].Offset, End: t.End()}, true
+			}
+		}
+	}
+	return Span{}, false
+}
+
+// emptyValue returns the empty span following the mapping value indicator (:) of the key at key
+func (s *spanner) emptyValue(key Span) (Span, bool) {
+	i, ok := s.byOffset[key.Start]
+	if !ok {
+		return Span{}, false
+	}
+	for _, t := range s.tree.tokens[i+1:] {
+		switch {
+		case t.Type == token.TypeColon:
+			return Span{Start: t.End(), End: t.End()}, true
+		case !t.IsTrivia():
+			return Span{}, false
+		}
+	}
+	return Span{}, false
+}
+
+// blockScalarEnd finds the end of the last non-blank content line of the block scalar whose header is at header
+func (s *spanner) blockScalarEnd(header int) int {
+	end := s.tree.tokens[header].End()
+	for _, t := range s.tree.tokens[header+1:] {
+		switch {
+		case t.Type == token.TypeBlockScalarLine:
+			if strings.TrimSpace(t.Text) != "" {
+				end = t.End()
+			}
+		case t.IsTrivia() || t.Type == token.TypeNewline || t.Type == token.TypeComment:
+		default:
+			return end
+		}
+	}
+	return end
+}
+
+// tokenAt returns the index of the token located at location
+func (s *spanner) tokenAt(location token.Location) (int, bool) {
+	if location.Line() < 1 || location.Line() > len(s.lines) {
+		return 0, false
+	}
+	i, ok := s.byOffset[s.scanner.offset(location)]
+	return i, ok
+}
+
+// isEmptyPlain checks that n is an empty plain scalar, i.e., a null value written as nothing
+func isEmptyPlain(n yaml.Node) bool {
+	scalar, ok := n.(*yaml.ScalarNode)
+	return ok && scalar.Value() == "" && (scalar.Style() == yaml.StylePlain || scalar.Style() == yaml.StyleDefault)
+}
@@ -0,0 +1,379 @@
+package yaml
+
+import (
+	"encoding"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ercross/yaml/token"
+)
+
+// ErrTypeMismatch is returned when a node can not be decoded into the Go type of its destination,
+// e.g., a mapping into a slice, or 300 into an uint8
+var ErrTypeMismatch = errors.New("type mismatch")
+
+type (
+	// DecodeError reports the node that could not be decoded, where it was found in the YAML source,
+	// and the Go type it was decoded into
+	DecodeError struct {
+		Position token.Location
+		Path     Path
+		Type     reflect.Type
+		Err      error
+	}
+
+	// DecodeOptions control the decoding of YAML nodes into Go values
+	DecodeOptions struct {
+		// MaxAliasExpansion is the number of nodes the aliases of the decoded tree may expand to:
+		// every time an alias is decoded, the nodes of the tree it refers to count, its own aliases included.
+		// Decoding fails with a *LimitError beyond it. 0 is DefaultMaxAliasExpansion, and a negative value is no limit
+		MaxAliasExpansion int
+	}
+
+	// valueDecoder decodes node trees into Go values
+	valueDecoder struct {
+		// converter expands the merge keys of mappings, tracks the collections being decoded,
+		// and counts the nodes aliases expand to
+		converter *jsonConverter
+	}
+)
+
+var (
+	nodeType            = reflect.TypeFor[Node]()
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+	durationType        = reflect.TypeFor[time.Duration]()
+)
+
+// Decode stores the value of the tree rooted at n into the Go value v points to, the reverse of NewNode.
+// If n is a DocumentNode, its root is decoded, and an empty document is null.
+//
+// Scalars are decoded by their tag (explicit, or resolved with the core schema), null setting the zero value.
+// Any scalar but null can be decoded into a string, and a time.Duration is decoded from a string such as 1m30s,
+// or from an integer number of nanoseconds. Mappings are decoded into maps and into structs,
+// whose fields are named as by NewNode, keys matching no field being ignored.
+// Into an empty interface, mappings become map[string]any, sequences become []any, and integers become int,
+// or uint64 and then float64 when out of range. Node fields receive the nodes themselves.
+// Aliases are expanded, up to DefaultMaxAliasExpansion nodes, and merge keys (<<) are replaced with the pairs
+// of the mappings they merge
+func Decode(n Node, v any) error {
+	return DecodeWithOptions(n, v, DecodeOptions{})
+}
+
+// DecodeWithOptions is like Decode, with opts
+func DecodeWithOptions(n Node, v any, opts DecodeOptions) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("can not decode into %T, not a non-nil pointer: %w", v, ErrUnsupportedValue)
+	}
+	d := &valueDecoder{converter: newJSONConverter(JSONOptions{StringifyKeys: true, MaxAliasExpansion: opts.MaxAliasExpansion})}
+	return d.decode(nil, documentRoot(n), rv.Elem())
+}
+
+func (d *valueDecoder) decode(path Path, n Node, v reflect.Value) error {
+	target := resolveAlias(n)
+	if _, unresolved := target.(*AliasNode); unresolved || d.converter.enclosing[target] {
+		return d.fail(path, n, v.Type(), ErrRecursiveAlias)
+	}
+	if target != nil && v.Type().Implements(nodeType) {
+		if !reflect.TypeOf(target).AssignableTo(v.Type()) {
+			return d.fail(path, n, v.Type(), ErrTypeMismatch)
+		}
+		v.Set(reflect.ValueOf(target))
+		return nil
+	}
+
+	scalar, isScalar := target.(*ScalarNode)
+	if target == nil || isScalar && scalar.Tag() == TagNull {
+		v.SetZero()
+		return nil
+	}
+
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return d.decode(path, n, v.Elem())
+	}
+	if err := d.converter.expansion.expand(n); err != nil {
+		return d.fail(path, n, v.Type(), err)
+	}
+	if v.CanAddr() && v.Addr().Type().Implements(textUnmarshalerType) {
+		if !isScalar {
+			return d.fail(path, n, v.Type(), ErrTypeMismatch)
+		}
+		if err := v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(scalar.value)); err != nil {
+			return d.fail(path, n, v.Type(), err)
+		}
+		return nil
+	}
+	if v.Kind() == reflect.Interface && v.NumMethod() == 0 {
+		value, err := d.natural(path, target)
+		if err != nil {
+			return err
+		}
+		if value == nil {
+			v.SetZero()
+		} else {
+			v.Set(reflect.ValueOf(value))
+		}
+		return nil
+	}
+
+	switch target := target.(type) {
+	case *ScalarNode:
+		return d.scalar(path, target, v)
+
+	case *SequenceNode:
+		d.converter.enclosing[target] = true
+		defer delete(d.converter.enclosing, target)
+		return d.sequence(path, target, v)
+
+	case *MappingNode:
+		d.converter.enclosing[target] = true
+		defer delete(d.converter.enclosing, target)
+
+		switch v.Kind() {
+		case reflect.Map:
+			return d.mapping(path, target, v)
+		case reflect.Struct:
+			return d.structure(path, target, v)
+		}
+	}
+	return d.fail(path, n, v.Type(), ErrTypeMismatch)
+}
+
+// scalar decodes n, which is not null, into a boolean, number or string
+func (d *valueDecoder) scalar(path Path, n *ScalarNode, v reflect.Value) error {
+	tag := n.Tag()
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(n.value)
+		return nil
+
+	case reflect.Bool:
+		if tag == TagBool {
+			v.SetBool(strings.EqualFold(n.value, "true"))
+			return nil
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Type() == durationType && tag == TagString {
+			duration, err := time.ParseDuration(n.value)
+			if err != nil {
+				return d.fail(path, n, v.Type(), err)
+			}
+			v.SetInt(int64(duration))
+			return nil
+		}
+		if i, ok := parseInt(n.value); ok && tag == TagInt {
+			if !i.IsInt64() || v.OverflowInt(i.Int64()) {
+				return d.fail(path, n, v.Type(), fmt.Errorf("%s overflows %s: %w", n.value, v.Type(), ErrTypeMismatch))
+			}
+			v.SetInt(i.Int64())
+			return nil
+		}
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if i, ok := parseInt(n.value); ok && tag == TagInt {
+			if !i.IsUint64() || v.OverflowUint(i.Uint64()) {
+				return d.fail(path, n, v.Type(), fmt.Errorf("%s overflows %s: %w", n.value, v.Type(), ErrTypeMismatch))
+			}
+			v.SetUint(i.Uint64())
+			return nil
+		}
+
+	case reflect.Float32, reflect.Float64:
+		if f, ok := parseFloat(n.value, tag); ok {
+			v.SetFloat(f)
+			return nil
+		}
+
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 && tag == TagBinary {
+			data, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(n.value), ""))
+			if err != nil {
+				return d.fail(path, n, v.Type(), err)
+			}
+			v.SetBytes(data)
+			return nil
+		}
+	}
+	return d.fail(path, n, v.Type(), ErrTypeMismatch)
+}
+
+// sequence decodes the items of n into a slice, or into an array whose remaining elements are set to their zero value
+func (d *valueDecoder) sequence(path Path, n *SequenceNode, v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Slice:
+		v.Set(reflect.MakeSlice(v.Type(), len(n.items), len(n.items)))
+	case reflect.Array:
+		if len(n.items) > v.Len() {
+			return d.fail(path, n, v.Type(), fmt.Errorf("%d items overflow %s: %w", len(n.items), v.Type(), ErrTypeMismatch))
+		}
+		v.SetZero()
+	default:
+		return d.fail(path, n, v.Type(), ErrTypeMismatch)
+	}
+
+	for i, item := range n.items {
+		if err := d.decode(path.append(PathSegment{Index: i}), item, v.Index(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mapping decodes the pairs of n, merge keys expanded, into a map, creating the map if it is nil
+func (d *valueDecoder) mapping(path Path, n *MappingNode, v reflect.Value) error {
+	members, err := d.converter.members(path, n)
+	if err != nil {
+		return d.fail(path, n, v.Type(), err)
+	}
+	if v.IsNil() {
+		v.Set(reflect.MakeMapWithSize(v.Type(), len(members)))
+	}
+
+	for i, member := range members {
+		key := reflect.New(v.Type().Key()).Elem()
+		if key.Kind() == reflect.String {
+			key.SetString(member.name)
+		} else if err = d.decode(path.append(PathSegment{Key: member.key, Index: i, IsKey: true}), member.key, key); err != nil {
+			return err
+		}
+
+		value := reflect.New(v.Type().Elem()).Elem()
+		if err = d.decode(path.append(PathSegment{Key: member.key, Index: i}), member.value, value); err != nil {
+			return err
+		}
+		v.SetMapIndex(key, value)
+	}
+	return nil
+}
+
+// structure decodes the pairs of n, merge keys expanded, into the exported fields of a struct
+func (d *valueDecoder) structure(path Path, n *MappingNode, v reflect.Value) error {
+	fields := make(map[string]int, v.NumField())
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = i
+	}
+
+	members, err := d.converter.members(path, n)
+	if err != nil {
+		return d.fail(path, n, v.Type(), err)
+	}
+	for i, member := range members {
+		field, ok := fields[member.name]
+		if !ok {
+			continue
+		}
+		if err = d.decode(path.append(PathSegment{Key: member.key, Index: i}), member.value, v.Field(field)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// natural decodes n into the Go value an empty interface holds
+func (d *valueDecoder) natural(path Path, n Node) (any, error) {
+	switch n := n.(type) {
+	case *ScalarNode:
+		tag := n.Tag()
+		switch tag {
+		case TagNull:
+			return nil, nil
+		case TagBool:
+			return strings.EqualFold(n.value, "true"), nil
+		case TagInt:
+			i, ok := parseInt(n.value)
+			switch {
+			case ok && i.IsInt64() && i.Int64() == int64(int(i.Int64())):
+				return int(i.Int64()), nil
+			case ok && i.IsUint64():
+				return i.Uint64(), nil
+			}
+			if f, ok := parseFloat(n.value, tag); ok {
+				return f, nil
+			}
+		case TagFloat:
+			if f, ok := parseFloat(n.value, tag); ok {
+				return f, nil
+			}
+		}
+		return n.value, nil
+
+	case *SequenceNode:
+		var items []any
+		err := d.decode(path, n, reflect.ValueOf(&items).Elem())
+		return items, err
+
+	case *MappingNode:
+		var members map[string]any
+		err := d.decode(path, n, reflect.ValueOf(&members).Elem())
+		return members, err
+	}
+	return nil, d.fail(path, n, reflect.TypeFor[any](), ErrTypeMismatch)
+}
+
+func (d *valueDecoder) fail(path Path, n Node, t reflect.Type, err error) error {
+	var decodeErr *DecodeError
+	if errors.As(err, &decodeErr) {
+		return err
+	}
+	var conversionErr *ConversionError
+	if errors.As(err, &conversionErr) {
+		return &DecodeError{Position: conversionErr.Position, Path: conversionErr.Path, Type: t, Err: conversionErr.Err}
+	}
+	return &DecodeError{Position: n.Position(), Path: path, Type: t, Err: err}
+}
+
+// parseFloat parses the value of a scalar tagged !!float or !!int as a float64
+func parseFloat(value, tag string) (float64, bool) {
+	switch tag {
+	case TagInt:
+		i, ok := parseInt(value)
+		if !ok {
+			return 0, false
+		}
+		f, _ := new(big.Float).SetInt(i).Float64()
+		return f, true
+
+	case TagFloat:
+		switch strings.ToLower(strings.TrimPrefix(value, "+")) {
+		case ".inf":
+			return math.Inf(1), true
+		case "-.inf":
+			return math.Inf(-1), true
+		case ".nan":
+			return math.NaN(), true
+		}
+		f, err := strconv.ParseFloat(value, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("can not decode node at %s (%s) into %s: %v", e.Position, e.Path, e.Type, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,113 @@
+package yaml_test
+
+import (
+	"errors"
+	"github.com/ercross/yaml"
+	"math"
+	"net/netip"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDecode(t *testing.T) {
+	type port struct {
+		Number   uint16 `yaml:"number"`
+		Protocol string `yaml:"protocol"`
+	}
+	type service struct {
+		Name    string            `yaml:"name"`
+		Ports   []port            `yaml:"ports"`
+		Labels  map[string]string `yaml:"labels"`
+		Ratio   float64
+		Enabled *bool         `yaml:"enabled"`
+		Timeout time.Duration `yaml:"timeout"`
+		Address netip.Addr    `yaml:"address"`
+		Spec    yaml.Node     `yaml:"spec"`
+		Ignored string        `yaml:"-"`
+	}
+
+	source := "defaults: &d\n  protocol: tcp\nname: web\nports:\n  - <<: *d\n    number: 80\n  - {number: 0x1BB, protocol: udp}\n" +
+		"labels: {tier: 1, stage: prod}\nRatio: 1\nenabled: true\ntimeout: 1m30s\naddress: 10.0.0.1\nspec: [a]\nIgnored: x\nunknown: y\n"
+	var actual service
+	if err := yaml.Decode(parseDocument(t, source), &actual); err != nil {
+		t.Fatal(err)
+	}
+
+	enabled := true
+	expected := service{
+		Name:    "web",
+		Ports:   []port{{Number: 80, Protocol: "tcp"}, {Number: 443, Protocol: "udp"}},
+		Labels:  map[string]string{"tier": "1", "stage": "prod"},
+		Ratio:   1,
+		Enabled: &enabled,
+		Timeout: 90 * time.Second,
+		Address: netip.MustParseAddr("10.0.0.1"),
+	}
+	spec, ok := actual.Spec.(*yaml.SequenceNode)
+	if !ok || spec.Len() != 1 {
+		t.Fatalf("expected the spec node, got %#v", actual.Spec)
+	}
+	actual.Spec = nil
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %+v, got %+v", expected, actual)
+	}
+}
+
+func TestDecode_Any(t *testing.T) {
+	source := "a: [1, -2, 1.5, .inf, true, ~, text, '3']\nb: 18446744073709551615\n1: {c: d}\n"
+	var actual any
+	if err := yaml.Decode(parseDocument(t, source), &actual); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]any{
+		"a": []any{1, -2, 1.5, math.Inf(1), true, nil, "text", "3"},
+		"b": uint64(math.MaxUint64),
+		"1": map[string]any{"c": "d"},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestDecode_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		target   any
+		expected error
+		path     string
+	}{
+		{name: "overflow", source: "a: [1, 300]\n", target: &map[string][]uint8{}, expected: yaml.ErrTypeMismatch, path: "$.a[1]"},
+		{name: "mapping into a slice", source: "a: {b: c}\n", target: &map[string][]string{}, expected: yaml.ErrTypeMismatch, path: "$.a"},
+		{name: "string into an int", source: "- x\n", target: &[]int{}, expected: yaml.ErrTypeMismatch, path: "$[0]"},
+		{name: "invalid merge", source: "a:\n  <<: [x]\n", target: new(any), expected: yaml.ErrInvalidMerge, path: `$.a["<<"]`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := yaml.Decode(parseDocument(t, test.source), test.target)
+			if !errors.Is(err, test.expected) {
+				t.Fatalf("expected %v, got %v", test.expected, err)
+			}
+			var decodeErr *yaml.DecodeError
+			if !errors.As(err, &decodeErr) {
+				t.Fatalf("expected a DecodeError, got %T", err)
+			}
+			if path := decodeErr.Path.String(); path != test.path {
+				t.Errorf("expected the error at %s, got %s", test.path, path)
+			}
+		})
+	}
+
+	recursive := yaml.NewSequenceNode()
+	recursive.SetAnchor("a")
+	recursive.Append(yaml.NewAliasNode("a", recursive))
+	if err := yaml.Decode(recursive, new(any)); !errors.Is(err, yaml.ErrRecursiveAlias) {
+		t.Errorf("expected ErrRecursiveAlias, got %v", err)
+	}
+
+	var value int
+	if err := yaml.Decode(yaml.NewScalarNode("1"), value); !errors.Is(err, yaml.ErrUnsupportedValue) {
+		t.Errorf("expected ErrUnsupportedValue decoding into a non-pointer, got %v", err)
+	}
+}
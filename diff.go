@@ -0,0 +1,319 @@
+package yaml
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// ChangeKind is the kind of difference a Change reports
+type ChangeKind int8
+
+const (
+	// ChangeAdded is a mapping entry or sequence item only found in the new tree
+	ChangeAdded ChangeKind = iota + 1
+
+	// ChangeRemoved is a mapping entry or sequence item only found in the old tree
+	ChangeRemoved
+
+	// ChangeModified is a node whose value differs between both trees
+	ChangeModified
+)
+
+// Change is a single difference between two trees, as reported by Diff
+type Change struct {
+	Kind ChangeKind
+
+	// Path locates the changed node. Mapping segments hold the keys of the new tree,
+	// except for removed entries, whose path is made of the keys of the old tree
+	Path Path
+
+	// Old is the node of the old tree, or nil if the node was added
+	Old Node
+
+	// New is the node of the new tree, or nil if the node was removed
+	New Node
+}
+
+// differ compares two trees, collecting their differences
+type differ struct {
+	changes []Change
+
+	// compared holds the pairs of collections already compared, so that recursive aliases are compared once
+	compared map[[2]Node]bool
+}
+
+// Diff compares the documents a and b semantically, and returns their differences in document order.
+//
+// Mapping keys are matched regardless of their order, and sequence items are compared by index.
+// Scalars are compared by their tag and value, so quoting styles and equivalent notations
+// (e.g., 0x10 and 16, or ~ and null) are not reported. Aliases are compared as the nodes they refer to
+func Diff(a, b *DocumentNode) []Change {
+	d := &differ{compared: make(map[[2]Node]bool)}
+	d.diff(nil, rootOf(a), rootOf(b))
+	return d.changes
+}
+
+// rootOf returns the root of document, or nil if there is no document
+func rootOf(document *DocumentNode) Node {
+	if document == nil {
+		return nil
+	}
+	return document.root
+}
+
+func (d *differ) diff(path Path, a, b Node) {
+	a, b = resolveAlias(a), resolveAlias(b)
+	switch {
+	case a == nil && b == nil:
+		return
+	case a == nil:
+		d.changes = append(d.changes, Change{Kind: ChangeAdded, Path: path, New: b})
+		return
+	case b == nil:
+		d.changes = append(d.changes, Change{Kind: ChangeRemoved, Path: path, Old: a})
+		return
+	}
+
+	if d.compared[[2]Node{a, b}] {
+		return
+	}
+
+	switch a := a.(type) {
+	case *MappingNode:
+		if b, ok := b.(*MappingNode); ok && a.Tag() == b.Tag() {
+			d.compared[[2]Node{a, b}] = true
+			d.diffMappings(path, a, b)
+			return
+		}
+
+	case *SequenceNode:
+		if b, ok := b.(*SequenceNode); ok && a.Tag() == b.Tag() {
+			d.compared[[2]Node{a, b}] = true
+			d.diffSequences(path, a, b)
+			return
+		}
+
+	case *ScalarNode:
+		if b, ok := b.(*ScalarNode); ok && equalScalars(a, b) {
+			return
+		}
+	}
+	d.changes = append(d.changes, Change{Kind: ChangeModified, Path: path, Old: a, New: b})
+}
+
+func (d *differ) diffMappings(path Path, a, b *MappingNode) {
+	bIndex := make(map[string]int, len(b.pairs))
+	for i, pair := range b.pairs {
+		if _, found := bIndex[keyOf(pair.Key)]; !found {
+			bIndex[keyOf(pair.Key)] = i
+		}
+	}
+
+	matched := make(map[int]bool, len(a.pairs))
+	for i, pair := range a.pairs {
+		j, found := bIndex[keyOf(pair.Key)]
+		if !found || matched[j] {
+			d.changes = append(d.changes, Change{
+				Kind: ChangeRemoved,
+				Path: path.append(PathSegment{Key: pair.Key, Index: i}),
+				Old:  pair.Value,
+			})
+			continue
+		}
+		matched[j] = true
+		d.diff(path.append(PathSegment{Key: b.pairs[j].Key, Index: j}), pair.Value, b.pairs[j].Value)
+	}
+
+	for j, pair := range b.pairs {
+		if !matched[j] {
+			d.changes = append(d.changes, Change{
+				Kind: ChangeAdded,
+				Path: path.append(PathSegment{Key: pair.Key, Index: j}),
+				New:  pair.Value,
+			})
+		}
+	}
+}
+
+func (d *differ) diffSequences(path Path, a, b *SequenceNode) {
+	for i := 0; i < max(len(a.items), len(b.items)); i++ {
+		segment := path.append(PathSegment{Index: i})
+		switch {
+		case i >= len(b.items):
+			d.changes = append(d.changes, Change{Kind: ChangeRemoved, Path: segment, Old: a.items[i]})
+		case i >= len(a.items):
+			d.changes = append(d.changes, Change{Kind: ChangeAdded, Path: segment, New: b.items[i]})
+		default:
+			d.diff(segment, a.items[i], b.items[i])
+		}
+	}
+}
+
+// keyOf identifies a mapping key, so that keys can be matched across trees
+func keyOf(n Node) string {
+	n = resolveAlias(n)
+	if scalar, ok := n.(*ScalarNode); ok {
+		return scalar.Tag() + " " + canonicalScalar(scalar)
+	}
+
+	// collections used as keys are rare, and identified by their flow form
+	text, err := NewEmitter(io.Discard).inline(n, true)
+	if err != nil {
+		return fmt.Sprintf("%p", n)
+	}
+	return n.Tag() + " " + text
+}
+
+// equalScalars checks that a and b have the same tag and the same value
+func equalScalars(a, b *ScalarNode) bool {
+	return a.Tag() == b.Tag() && canonicalScalar(a) == canonicalScalar(b)
+}
+
+// canonicalScalar returns the value of n in a single notation for each tag of the core schema,
+// e.g., 16 for 0x10, or null for ~
+func canonicalScalar(n *ScalarNode) string {
+	switch n.Tag() {
+	case TagNull:
+		return "null"
+
+	case TagBool:
+		return strings.ToLower(n.value)
+
+	case TagInt:
+		if i, ok := parseInt(n.value); ok {
+			return i.String()
+		}
+
+	case TagFloat:
+		switch strings.ToLower(strings.TrimPrefix(n.value, "+")) {
+		case ".inf":
+			return ".inf"
+		case "-.inf":
+			return "-.inf"
+		case ".nan":
+			return ".nan"
+		}
+		if f, err := strconv.ParseFloat(n.value, 64); err == nil && !math.IsInf(f, 0) {
+			return strconv.FormatFloat(f, 'g', -1, 64)
+		}
+	}
+	return n.value
+}
+
+// parseInt parses an integer of the core schema: decimal, octal (0o) or hexadecimal (0x)
+func parseInt(value string) (*big.Int, bool) {
+	base := 10
+	digits := value
+	switch {
+	case strings.HasPrefix(value, "0o"):
+		base, digits = 8, value[2:]
+	case strings.HasPrefix(value, "0x"):
+		base, digits = 16, value[2:]
+	}
+	return new(big.Int).SetString(strings.TrimPrefix(digits, "+"), base)
+}
+
+func (k ChangeKind) String() string {
+	switch k {
+	case ChangeAdded:
+		return "added"
+	case ChangeRemoved:
+		return "removed"
+	case ChangeModified:
+		return "modified"
+	default:
+		return "unknown"
+	}
+}
+
+// WriteDiff renders changes in a unified diff-like text format. Each change starts with a header line
+// holding its kind, path and the positions of its nodes, followed by the old value prefixed with -
+// and the new value prefixed with +, e.g.,
+//
+//	@@ modified $.spec.replicas old 3:13 new 3:13 @@
+//	-3
+//	+5
+func WriteDiff(w io.Writer, changes []Change) error {
+	var b strings.Builder
+	for _, c := range changes {
+		header := []string{"@@", c.Kind.String(), c.Path.String()}
+		if c.Old != nil {
+			header = append(header, "old", positionText(c.Old))
+		}
+		if c.New != nil {
+			header = append(header, "new", positionText(c.New))
+		}
+		b.WriteString(strings.Join(append(header, "@@"), " ") + "\n")
+
+		for _, side := range []struct {
+			prefix string
+			n      Node
+		}{{"-", c.Old}, {"+", c.New}} {
+			if side.n == nil {
+				continue
+			}
+			for _, line := range strings.Split(diffText(side.n), "\n") {
+				b.WriteString(side.prefix + line + "\n")
+			}
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteDiffJSON renders changes as a JSON array, e.g.,
+//
+//	[{"kind":"modified","path":"$.spec.replicas","old":{"value":"3","line":3,"column":13},"new":{"value":"5","line":3,"column":13}}]
+func WriteDiffJSON(w io.Writer, changes []Change) error {
+	if changes == nil {
+		changes = []Change{}
+	}
+	return json.NewEncoder(w).Encode(changes)
+}
+
+func (c Change) MarshalJSON() ([]byte, error) {
+	type side struct {
+		Value  string `json:"value"`
+		Line   int    `json:"line"`
+		Column int    `json:"column"`
+	}
+	newSide := func(n Node) *side {
+		if n == nil {
+			return nil
+		}
+		return &side{Value: diffText(n), Line: n.Position().Line(), Column: n.Position().Column()}
+	}
+
+	return json.Marshal(struct {
+		Kind string `json:"kind"`
+		Path string `json:"path"`
+		Old  *side  `json:"old,omitempty"`
+		New  *side  `json:"new,omitempty"`
+	}{
+		Kind: c.Kind.String(),
+		Path: c.Path.String(),
+		Old:  newSide(c.Old),
+		New:  newSide(c.New),
+	})
+}
+
+// diffText renders n as YAML text, without its trailing line break
+func diffText(n Node) string {
+	if isEmptyScalar(n) {
+		return "null"
+	}
+	out, err := Emit(n)
+	if err != nil {
+		return fmt.Sprintf("<%v>", err)
+	}
+	return strings.TrimSuffix(string(out), "\n")
+}
+
+func positionText(n Node) string {
+	return fmt.Sprintf("%d:%d", n.Position().Line(), n.Position().Column())
+}
@@ -0,0 +1,151 @@
+package yaml_test
+
+import (
+	"encoding/json"
+	"github.com/ercross/yaml"
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		a, b     string
+		expected []string
+	}{
+		{
+			name: "equal",
+			a:    "a: 1\nb: [x, y]\n",
+			b:    "b:\n  - x\n  - 'y'\na: 0x1\n",
+		},
+		{
+			name: "equivalent scalars",
+			a:    "a: ~\nb: True\nc: 1.50\nd: 0o17\ne: .Inf\n",
+			b:    "a: null\nb: true\nc: 1.5\nd: 15\ne: .inf\n",
+		},
+		{
+			name: "equivalent integers beyond int64",
+			a:    "a: 18446744073709551616\nb: 0o2000000000000000000000\n",
+			b:    "a: 0x10000000000000000\nb: +18446744073709551616\n",
+		},
+		{
+			name:     "modified integer beyond int64",
+			a:        "a: 9223372036854775808\n",
+			b:        "a: 0x8000000000000001\n",
+			expected: []string{"modified $.a"},
+		},
+		{
+			name:     "modified scalar",
+			a:        "spec:\n  replicas: 3\n",
+			b:        "spec:\n  replicas: 5\n",
+			expected: []string{"modified $.spec.replicas"},
+		},
+		{
+			name:     "quoted number is a string",
+			a:        "a: 1\n",
+			b:        "a: '1'\n",
+			expected: []string{"modified $.a"},
+		},
+		{
+			name:     "added and removed keys",
+			a:        "a: 1\nb: 2\n",
+			b:        "b: 2\nc: 3\n",
+			expected: []string{"removed $.a", "added $.c"},
+		},
+		{
+			name:     "sequence items",
+			a:        "- a\n- b\n- c\n",
+			b:        "- a\n- x\n",
+			expected: []string{"modified $[1]", "removed $[2]"},
+		},
+		{
+			name:     "type mismatch",
+			a:        "a: [x]\n",
+			b:        "a: {x: 1}\n",
+			expected: []string{"modified $.a"},
+		},
+		{
+			name: "aliases are resolved",
+			a:    "base: &b\n  x: 1\nuse: *b\n",
+			b:    "base:\n  x: 1\nuse:\n  x: 1\n",
+		},
+		{
+			name:     "change behind an alias",
+			a:        "base: &b\n  x: 1\nuse: *b\n",
+			b:        "base:\n  x: 1\nuse:\n  x: 2\n",
+			expected: []string{"modified $.use.x"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			changes := yaml.Diff(parseDocument(t, test.a), parseDocument(t, test.b))
+			var actual []string
+			for _, c := range changes {
+				actual = append(actual, c.Kind.String()+" "+c.Path.String())
+			}
+			if strings.Join(actual, "\n") != strings.Join(test.expected, "\n") {
+				t.Errorf("expected changes %q, got %q", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestDiff_Positions(t *testing.T) {
+	changes := yaml.Diff(
+		parseDocument(t, "spec:\n  replicas: 3\n"),
+		parseDocument(t, "# scaled\nspec:\n  replicas: 5\n"),
+	)
+	if len(changes) != 1 {
+		t.Fatalf("expected 1 change, got %d", len(changes))
+	}
+	if line := changes[0].Old.Position().Line(); line != 2 {
+		t.Errorf("expected the old value on line 2, got %d", line)
+	}
+	if line := changes[0].New.Position().Line(); line != 3 {
+		t.Errorf("expected the new value on line 3, got %d", line)
+	}
+}
+
+func TestWriteDiff(t *testing.T) {
+	changes := yaml.Diff(
+		parseDocument(t, "a: 1\nb:\n  x: 1\n"),
+		parseDocument(t, "a: 2\n"),
+	)
+
+	var b strings.Builder
+	if err := yaml.WriteDiff(&b, changes); err != nil {
+		t.Fatal(err)
+	}
+	expected := "@@ modified $.a old 1:4 new 1:4 @@\n-1\n+2\n" +
+		"@@ removed $.b old 3:3 @@\n-x: 1\n"
+	if b.String() != expected {
+		t.Errorf("expected %q, got %q", expected, b.String())
+	}
+}
+
+func TestWriteDiffJSON(t *testing.T) {
+	changes := yaml.Diff(parseDocument(t, "a: 1\n"), parseDocument(t, "a: 1\nb: [x]\n"))
+
+	var b strings.Builder
+	if err := yaml.WriteDiffJSON(&b, changes); err != nil {
+		t.Fatal(err)
+	}
+	var actual []map[string]any
+	if err := json.Unmarshal([]byte(b.String()), &actual); err != nil {
+		t.Fatalf("expected valid json, got %q: %v", b.String(), err)
+	}
+	if len(actual) != 1 || actual[0]["kind"] != "added" || actual[0]["path"] != "$.b" || actual[0]["old"] != nil {
+		t.Fatalf("unexpected changes %v", actual)
+	}
+	if value := actual[0]["new"].(map[string]any)["value"]; value != "[x]" {
+		t.Errorf("expected the new value [x], got %v", value)
+	}
+
+	b.Reset()
+	if err := yaml.WriteDiffJSON(&b, nil); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != "[]\n" {
+		t.Errorf("expected an empty array, got %q", b.String())
+	}
+}
@@ -0,0 +1,146 @@
+package yaml
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/ercross/yaml/token"
+)
+
+type (
+	// DuplicateKeyPolicy decides what a Composer does with a key equal to a key preceding it in the same mapping.
+	// Keys are equal if they are scalars, or aliases of scalars, with the same tag and the same value,
+	// e.g., 16 and 0x10, or a and 'a'. Merge keys (<<) and keys that are not scalars are never duplicates
+	DuplicateKeyPolicy int
+
+	// DuplicateKeyError reports a key of a mapping equal to a key preceding it, see DuplicateKeyPolicy
+	DuplicateKeyError struct {
+		Key string
+
+		// First is the position of the preceding key, and Duplicate the position of the key equal to it
+		First     token.Location
+		Duplicate token.Location
+	}
+
+	// mappingKey is a key of an open mapping, see Composer.keys
+	mappingKey struct {
+		// pair is the index of the pair kept for the key, and first the position of the first key equal to it,
+		// which is not the key of that pair once a later pair replaced it
+		pair  int
+		first token.Location
+	}
+)
+
+const (
+	// DuplicateKeyReject fails composing the mapping with a *DuplicateKeyError, as YAML 1.2 requires keys to be unique
+	DuplicateKeyReject DuplicateKeyPolicy = iota
+
+	// DuplicateKeyWarn keeps the last pair like DuplicateKeyKeepLast, and records a *DuplicateKeyError
+	// in the warnings of the Composer, see Composer.Warnings
+	DuplicateKeyWarn
+
+	// DuplicateKeyKeepFirst keeps the first pair, discarding the pairs of the keys equal to its key
+	DuplicateKeyKeepFirst
+
+	// DuplicateKeyKeepLast keeps the last pair, at the position of its key, discarding the pairs preceding it
+	DuplicateKeyKeepLast
+)
+
+var duplicateKeyPolicyNames = map[DuplicateKeyPolicy]string{
+	DuplicateKeyReject:    "reject",
+	DuplicateKeyWarn:      "warn",
+	DuplicateKeyKeepFirst: "keep-first",
+	DuplicateKeyKeepLast:  "keep-last",
+}
+
+// SetDuplicateKeyPolicy sets what c does with duplicate keys, DuplicateKeyReject by default
+func (c *Composer) SetDuplicateKeyPolicy(policy DuplicateKeyPolicy) {
+	c.duplicateKeys = policy
+}
+
+// Warnings returns the problems found by c that did not stop composing, e.g., duplicate keys with DuplicateKeyWarn
+func (c *Composer) Warnings() []error {
+	return c.warnings
+}
+
+// addPairNode adds the complete node n, either a key or a value, to the open mapping m, applying the DuplicateKeyPolicy
+func (c *Composer) addPairNode(m *MappingNode, n Node) error {
+	if m.pendingKey != nil {
+		m.AddChild(n)
+		return nil
+	}
+	if c.discarding[m] {
+		// the value of a key discarded by DuplicateKeyKeepFirst
+		delete(c.discarding, m)
+		return nil
+	}
+
+	id, ok := keyID(n)
+	if !ok {
+		m.AddChild(n)
+		return nil
+	}
+	keys := c.keys[m]
+	if keys == nil {
+		keys = make(map[string]mappingKey)
+		c.keys[m] = keys
+	}
+	k, duplicate := keys[id]
+	if !duplicate {
+		keys[id] = mappingKey{pair: len(m.pairs), first: n.Position()}
+		m.AddChild(n)
+		return nil
+	}
+
+	err := &DuplicateKeyError{Key: resolveAlias(n).(*ScalarNode).value, First: k.first, Duplicate: n.Position()}
+	switch c.duplicateKeys {
+	case DuplicateKeyReject:
+		return err
+	case DuplicateKeyKeepFirst:
+		c.discarding[m] = true
+		return nil
+	case DuplicateKeyWarn:
+		c.warnings = append(c.warnings, err)
+	}
+
+	m.pairs = slices.Delete(m.pairs, k.pair, k.pair+1)
+	for other, key := range keys {
+		if key.pair > k.pair {
+			key.pair--
+			keys[other] = key
+		}
+	}
+	keys[id] = mappingKey{pair: len(m.pairs), first: k.first}
+	m.AddChild(n)
+	return nil
+}
+
+// keyID identifies the key n among the keys equal to it, or fails if n can not be a duplicate key
+func keyID(n Node) (string, bool) {
+	key, ok := resolveAlias(n).(*ScalarNode)
+	if !ok || key.Tag() == TagMerge {
+		return "", false
+	}
+	return key.Tag() + " " + canonicalScalar(key), true
+}
+
+// endMapping forgets the keys of the mapping m once it is complete
+func (c *Composer) endMapping(m *MappingNode) {
+	delete(c.keys, m)
+	delete(c.discarding, m)
+}
+
+func (p DuplicateKeyPolicy) String() string {
+	if name, ok := duplicateKeyPolicyNames[p]; ok {
+		return name
+	}
+	return fmt.Sprintf("DuplicateKeyPolicy(%d)", int(p))
+}
+
+func (e *DuplicateKeyError) Error() string {
+	return fmt.Sprintf("key %q at %s, first defined at %s: %v", e.Key, e.Duplicate, e.First, ErrDuplicateKey)
+}
+
+func (e *DuplicateKeyError) Unwrap() error {
+	return ErrDuplicateKey
+}
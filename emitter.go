@@ -0,0 +1,784 @@
+package yaml
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const defaultIndent = 2
+
+// Emitter writes node trees as YAML text.
+//
+// Emitter keeps the style of every node where the value allows it (e.g., a quoted scalar stays quoted),
+// and picks a style for nodes with StyleDefault, or whose style can not represent their value
+type Emitter struct {
+	w         io.Writer
+	indent    int
+	documents int
+
+	// lineWidth is the width long strings are folded at, or 0 to never fold them
+	lineWidth int
+
+	// flowWidth is the width under which collections with StyleDefault are written in flow style, or 0 to never do so
+	flowWidth int
+
+	canonical bool
+
+	// quote is the style of the scalars the Emitter has to quote, StyleSingleQuoted unless set to StyleDoubleQuoted
+	quote Style
+
+	// indentlessSequences indicates that block sequences nested in a mapping are written at the indentation of their key
+	indentlessSequences bool
+
+	// blankLines is the number of blank lines written between the entries of a root block mapping
+	blankLines int
+
+	// events writes the events given to EmitEvent
+	events *eventWriter
+}
+
+// NewEmitter creates an Emitter writing to w, indenting nested block collections by 2 spaces
+func NewEmitter(w io.Writer) *Emitter {
+	return &Emitter{w: w, indent: defaultIndent}
+}
+
+// Emit writes n to a new byte slice
+func Emit(n Node) ([]byte, error) {
+	var b strings.Builder
+	if err := NewEmitter(&b).Emit(n); err != nil {
+		return nil, err
+	}
+	return []byte(b.String()), nil
+}
+
+// SetIndent sets the number of spaces nested block collections are indented by, between 1 and 9
+func (e *Emitter) SetIndent(spaces int) {
+	e.indent = min(max(spaces, 1), 9)
+}
+
+// SetLineWidth sets the width, in characters, long strings are folded at. The lines of folded block scalars
+// are wrapped at spaces to fit within width, and single-line strings with StyleDefault longer than width
+// are written as folded block scalars. A width of 0, the default, disables folding
+func (e *Emitter) SetLineWidth(width int) {
+	e.lineWidth = max(width, 0)
+}
+
+// SetFlowWidth sets the width, in characters, under which collections with StyleDefault are written in flow style,
+// e.g., [a, b], rather than in block style. A collection holding comments, block scalars or collections with StyleBlock
+// is always written in block style. A width of 0, the default, writes every collection with StyleDefault in block style
+func (e *Emitter) SetFlowWidth(width int) {
+	e.flowWidth = max(width, 0)
+}
+
+// SetQuoteStyle sets the style of the scalars that can not be written plain and have no quoted style of their own,
+// either StyleSingleQuoted, the default, or StyleDoubleQuoted. Values single quotes can not represent are always double-quoted
+func (e *Emitter) SetQuoteStyle(style Style) {
+	if style == StyleSingleQuoted || style == StyleDoubleQuoted {
+		e.quote = style
+	}
+}
+
+// SetIndentlessSequences sets whether block sequences nested in a mapping are written at the indentation of their key,
+// e.g., "key:\n- item", rather than indented, e.g., "key:\n  - item"
+func (e *Emitter) SetIndentlessSequences(indentless bool) {
+	e.indentlessSequences = indentless
+}
+
+// SetBlankLines sets the number of blank lines written between the entries of a root block mapping, 0 by default
+func (e *Emitter) SetBlankLines(lines int) {
+	e.blankLines = max(lines, 0)
+}
+
+// SetCanonical enables or disables the canonical form, a deterministic serialization for hashing and signing.
+//
+// In canonical form, every node is written in flow style with its tag (explicit or resolved), mapping entries
+// are sorted, scalars are double-quoted with a single notation for each value of the core schema (e.g., 16 for 0x10),
+// and aliases are expanded. Comments, styles and the other options of the Emitter are ignored.
+// The stream starts with a %YAML 1.2 directive, and every document with a document start marker (---)
+func (e *Emitter) SetCanonical(canonical bool) {
+	e.canonical = canonical
+}
+
+// Emit writes n as a document. Every document but the first one is preceded by a document start marker (---)
+func (e *Emitter) Emit(n Node) error {
+	document, ok := n.(*DocumentNode)
+	if !ok {
+		document = &DocumentNode{root: n}
+	}
+	root := document.root
+	if e.canonical {
+		return e.emitCanonical(root)
+	}
+
+	marker := []string{"---", lineComment(document)}
+	if e.isBlock(root) {
+		// the properties of a root block collection can only be written on the document start marker line
+		marker = []string{"---", properties(root), join(lineComment(root), lineComment(document))}
+	}
+	header := join(marker...)
+
+	empty := root == nil || isEmptyScalar(root)
+	var b strings.Builder
+	writeComment(&b, document.HeadComment(), 0)
+	if e.documents > 0 || header != "---" || document.HeadComment() != "" || (empty && document.FootComment() == "") {
+		b.WriteString(header + "\n")
+	}
+	if !empty {
+		if err := e.root(&b, root); err != nil {
+			return err
+		}
+	}
+	writeComment(&b, document.FootComment(), 0)
+	e.documents++
+
+	_, err := io.WriteString(e.w, b.String())
+	return err
+}
+
+// EmitEvent writes the yaml stream made of a sequence of events, see Event, as the events arrive.
+//
+// Block collections are written entry by entry. The other nodes are written once complete: scalars, aliases,
+// flow collections and mapping keys. A collection with StyleDefault is held until it can no longer be written
+// in flow style, see SetFlowWidth. The output is the output of Emit for the documents the events make up,
+// except in canonical form, where every document is written once complete
+func (e *Emitter) EmitEvent(event Event) error {
+	if e.events == nil {
+		e.events = &eventWriter{emitter: e}
+	}
+	return e.events.add(event)
+}
+
+// root writes the root node of a document
+func (e *Emitter) root(b *strings.Builder, n Node) error {
+	writeComment(b, n.HeadComment(), 0)
+
+	props := properties(n)
+	switch n := n.(type) {
+	case *MappingNode:
+		if e.isBlock(n) {
+			if err := e.blockMapping(b, n, 0); err != nil {
+				return err
+			}
+			writeComment(b, n.FootComment(), 0)
+			return nil
+		}
+	case *SequenceNode:
+		if e.isBlock(n) {
+			if err := e.blockSequence(b, n, 0); err != nil {
+				return err
+			}
+			writeComment(b, n.FootComment(), 0)
+			return nil
+		}
+	case *ScalarNode:
+		if e.isBlockScalar(n) {
+			b.WriteString(join(props, e.blockScalarHeader(n), lineComment(n)))
+			e.blockScalarContent(b, n, e.indent)
+			writeComment(b, n.FootComment(), 0)
+			return nil
+		}
+	}
+
+	text, err := e.inline(n, false)
+	if err != nil {
+		return err
+	}
+	b.WriteString(join(props, text, lineComment(n)) + "\n")
+	writeComment(b, n.FootComment(), 0)
+	return nil
+}
+
+// blockMapping writes the pairs of n at indent. The first pair is written at the current position
+func (e *Emitter) blockMapping(b *strings.Builder, n *MappingNode, indent int) error {
+	for i, pair := range n.pairs {
+		if i > 0 && indent == 0 {
+			// only the root mapping is written at indentation 0
+			b.WriteString(strings.Repeat("\n", e.blankLines))
+		}
+		if i > 0 {
+			b.WriteString(strings.Repeat(" ", indent))
+		}
+		writeHeadComment(b, pair.Key.HeadComment(), indent)
+
+		key, err := e.key(pair.Key)
+		if err != nil {
+			return err
+		}
+		b.WriteString(key + ":")
+		if err = e.blockValue(b, pair.Value, indent, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// blockSequence writes the items of n at indent. The first item is written at the current position
+func (e *Emitter) blockSequence(b *strings.Builder, n *SequenceNode, indent int) error {
+	for i, item := range n.items {
+		if i > 0 {
+			b.WriteString(strings.Repeat(" ", indent))
+		}
+		writeHeadComment(b, item.HeadComment(), indent)
+
+		b.WriteByte('-')
+		if err := e.blockValue(b, item, indent, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// blockValue writes n following a mapping key or a sequence entry indicator written at indent
+func (e *Emitter) blockValue(b *strings.Builder, n Node, indent int, inSequence bool) error {
+	// a collection nested in a sequence entry starts on the entry line, e.g., "- key: value"
+	nested := indent + e.indent
+	if inSequence {
+		nested = indent + 2
+	}
+
+	// the foot comment of an indentless sequence is written under its last item,
+	// so that it is not read as the head comment of the next key
+	content, foot := nested, nested
+	if !inSequence && e.indentlessSequences && isSequence(n) && e.isBlock(n) {
+		content, foot = indent, indent+2
+	}
+
+	if err := e.blockValueContent(b, n, indent, content, inSequence); err != nil {
+		return err
+	}
+	writeComment(b, n.FootComment(), foot)
+	return nil
+}
+
+func (e *Emitter) blockValueContent(b *strings.Builder, n Node, indent int, nested int, inSequence bool) error {
+	props := properties(n)
+
+	// compact is the "- key: value" form of a collection nested in a sequence entry,
+	// which leaves no room for properties or comments on the entry line
+	compact := inSequence && props == "" && n.LineComment() == "" && firstHeadComment(n) == ""
+
+	switch n := n.(type) {
+	case *MappingNode:
+		if e.isBlock(n) {
+			if compact {
+				b.WriteByte(' ')
+			} else {
+				b.WriteString(following(join(props, lineComment(n))) + "\n" + strings.Repeat(" ", nested))
+			}
+			return e.blockMapping(b, n, nested)
+		}
+
+	case *SequenceNode:
+		if e.isBlock(n) {
+			if compact {
+				b.WriteByte(' ')
+			} else {
+				b.WriteString(following(join(props, lineComment(n))) + "\n" + strings.Repeat(" ", nested))
+			}
+			return e.blockSequence(b, n, nested)
+		}
+
+	case *ScalarNode:
+		if e.isBlockScalar(n) {
+			b.WriteString(following(join(props, e.blockScalarHeader(n), lineComment(n))))
+			e.blockScalarContent(b, n, indent+e.indent)
+			return nil
+		}
+	}
+
+	text, err := e.inline(n, false)
+	if err != nil {
+		return err
+	}
+	b.WriteString(following(join(props, text, lineComment(n))) + "\n")
+	return nil
+}
+
+func isSequence(n Node) bool {
+	_, ok := n.(*SequenceNode)
+	return ok
+}
+
+// key renders the key of a mapping pair, which must fit on a single line
+func (e *Emitter) key(n Node) (string, error) {
+	props := properties(n)
+	if scalar, ok := n.(*ScalarNode); ok && scalar.value == "" && isPlain(scalar) {
+		return join(props, "~"), nil
+	}
+
+	text, err := e.inline(n, true)
+	if err != nil {
+		return "", err
+	}
+	return join(props, text), nil
+}
+
+// inline renders n on a single line, using flow style for collections.
+// flow indicates that n is part of a flow collection or a mapping key
+func (e *Emitter) inline(n Node, flow bool) (string, error) {
+	switch n := n.(type) {
+	case *ScalarNode:
+		return e.scalarText(n, flow), nil
+
+	case *AliasNode:
+		return "*" + n.name, nil
+
+	case *SequenceNode:
+		items := make([]string, 0, len(n.items))
+		for _, item := range n.items {
+			text, err := e.inline(item, true)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, join(properties(item), text))
+		}
+		return "[" + strings.Join(items, ", ") + "]", nil
+
+	case *MappingNode:
+		pairs := make([]string, 0, len(n.pairs))
+		for _, pair := range n.pairs {
+			key, err := e.key(pair.Key)
+			if err != nil {
+				return "", err
+			}
+			value, err := e.inline(pair.Value, true)
+			if err != nil {
+				return "", err
+			}
+			pairs = append(pairs, key+": "+join(properties(pair.Value), value))
+		}
+		return "{" + strings.Join(pairs, ", ") + "}", nil
+
+	case *DocumentNode:
+		return "", fmt.Errorf("can not emit a document nested in another node")
+
+	default:
+		return "", fmt.Errorf("can not emit node of type %T", n)
+	}
+}
+
+// isBlockScalar checks that n is written as a literal or folded block scalar
+func (e *Emitter) isBlockScalar(n *ScalarNode) bool {
+	if !canBeBlockScalar(n.value) {
+		return false
+	}
+	switch n.style {
+	case StyleLiteral, StyleFolded:
+		return true
+	case StyleDefault, StylePlain:
+		return strings.Contains(n.value, "\n") || e.isLong(n)
+	default:
+		return false
+	}
+}
+
+// isFolded checks that the block scalar n is written in folded style
+func (e *Emitter) isFolded(n *ScalarNode) bool {
+	return (n.style == StyleFolded || e.isLong(n)) && canBeFolded(n.value)
+}
+
+// isLong checks that n is a single-line string with StyleDefault longer than the line width, which is written folded
+func (e *Emitter) isLong(n *ScalarNode) bool {
+	return e.lineWidth > 0 && n.style == StyleDefault && n.Tag() == TagString &&
+		utf8.RuneCountInString(n.value) > e.lineWidth && breakAt(n.value, e.lineWidth) != -1 &&
+		!strings.Contains(n.value, "\n") && canBeBlockScalar(n.value)
+}
+
+// isBlock checks that the collection n is written in block style
+func (e *Emitter) isBlock(n Node) bool {
+	if !isBlockCollection(n) {
+		return false
+	}
+	if n.Style() != StyleDefault || e.flowWidth == 0 || !canBeFlow(n) {
+		return true
+	}
+	text, err := e.inline(n, false)
+	return err != nil || utf8.RuneCountInString(text) > e.flowWidth
+}
+
+// blockScalarHeader renders the block scalar indicator of n, followed by its indentation and chomping indicators
+func (e *Emitter) blockScalarHeader(n *ScalarNode) string {
+	header := "|"
+	if e.isFolded(n) {
+		header = ">"
+	}
+
+	content := strings.TrimLeft(n.value, "\n")
+	if strings.HasPrefix(content, " ") {
+		header += strconv.Itoa(e.indent)
+	}
+
+	switch {
+	case !strings.HasSuffix(n.value, "\n"):
+		header += "-"
+	case n.value == "\n" || strings.HasSuffix(n.value, "\n\n"):
+		header += "+"
+	}
+	return header
+}
+
+// blockScalarContent writes the lines of a block scalar following its header, at indent
+func (e *Emitter) blockScalarContent(b *strings.Builder, n *ScalarNode, indent int) {
+	b.WriteByte('\n')
+
+	body := strings.TrimRight(n.value, "\n")
+	trailingLineBreaks := len(n.value) - len(body)
+	if body == "" {
+		// every line break of a value made of line breaks is an empty line of the scalar
+		b.WriteString(strings.Repeat("\n", trailingLineBreaks))
+		return
+	}
+
+	lines := strings.Split(body, "\n")
+	if e.isFolded(n) {
+		lines = foldedLines(lines)
+		if e.lineWidth > 0 {
+			lines = wrappedLines(lines, max(e.lineWidth-indent, 1))
+		}
+	}
+	for _, line := range lines {
+		if line != "" {
+			b.WriteString(strings.Repeat(" ", indent) + line)
+		}
+		b.WriteByte('\n')
+	}
+	b.WriteString(strings.Repeat("\n", max(trailingLineBreaks-1, 0)))
+}
+
+// wrappedLines wraps the lines of a folded block scalar longer than width at single spaces, which folding reads back as spaces
+func wrappedLines(lines []string, width int) []string {
+	wrapped := make([]string, 0, len(lines))
+	for _, line := range lines {
+		for utf8.RuneCountInString(line) > width {
+			i := breakAt(line, width)
+			if i == -1 {
+				break
+			}
+			wrapped = append(wrapped, line[:i])
+			line = line[i+1:]
+		}
+		wrapped = append(wrapped, line)
+	}
+	return wrapped
+}
+
+// breakAt returns the byte index of the last single space of line within width characters,
+// or of the first one past width if there is none, or -1 if line has no single space to break at
+func breakAt(line string, width int) int {
+	at := -1
+	column := 0
+	for i, r := range line {
+		column++
+		if r != ' ' || i == 0 || i == len(line)-1 || line[i-1] == ' ' || line[i+1] == ' ' {
+			continue
+		}
+		if column > width+1 && at != -1 {
+			break
+		}
+		at = i
+		if column > width+1 {
+			break
+		}
+	}
+	return at
+}
+
+// canBeFlow checks that no node within the collection n has comments, StyleBlock or a block scalar style,
+// which flow style would lose
+func canBeFlow(n Node) bool {
+	for _, child := range n.Children() {
+		switch {
+		case child.HeadComment() != "" || child.LineComment() != "" || child.FootComment() != "":
+			return false
+		case child.Style() == StyleBlock || child.Style() == StyleLiteral || child.Style() == StyleFolded:
+			return false
+		case !canBeFlow(child):
+			return false
+		}
+	}
+	return true
+}
+
+// foldedLines turns the lines of a value into the lines of a folded block scalar,
+// where every line break is written as an empty line
+func foldedLines(lines []string) []string {
+	folded := make([]string, 0, 2*len(lines))
+	for i, line := range lines {
+		if i > 0 && line != "" && lines[i-1] != "" {
+			folded = append(folded, "")
+		}
+		if i > 0 && line == "" && lines[i-1] != "" {
+			folded = append(folded, "")
+		}
+		folded = append(folded, line)
+	}
+	return folded
+}
+
+// canBeBlockScalar checks that value can be written as a block scalar without losing characters
+func canBeBlockScalar(value string) bool {
+	for _, line := range strings.Split(strings.TrimRight(value, "\n"), "\n") {
+		if line != "" && strings.TrimSpace(line) == "" {
+			return false
+		}
+	}
+	for _, r := range value {
+		if r != '\n' && r != '\t' && !unicode.IsPrint(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// canBeFolded checks that no line of value is more indented than the others, which folding would not preserve
+func canBeFolded(value string) bool {
+	for _, line := range strings.Split(value, "\n") {
+		if strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+			return false
+		}
+	}
+	return true
+}
+
+// scalarText renders a scalar on a single line. flow indicates that n is part of a flow collection or a mapping key
+func (e *Emitter) scalarText(n *ScalarNode, flow bool) string {
+	if n.style == StyleDefault && n.tag == TagString && resolveTag(n.value) != TagString {
+		// quoting keeps the value a string, making its tag implicit, see explicitTag
+		return e.quoted(n.value)
+	}
+
+	switch n.style {
+	case StyleSingleQuoted:
+		if !strings.Contains(n.value, "\n") && isPrintable(n.value) {
+			return singleQuoted(n.value)
+		}
+		return doubleQuoted(n.value)
+
+	case StyleDoubleQuoted, StyleLiteral, StyleFolded:
+		return doubleQuoted(n.value)
+	}
+
+	if n.value == "" {
+		if flow {
+			return "~"
+		}
+		return ""
+	}
+	if n.style == StyleDefault && resolveTag(n.value) == TagString && IsYAML11Scalar(n.value) {
+		// a string YAML 1.1 reads as a boolean or a number, e.g., yes or 1_000, is quoted for YAML 1.1 readers,
+		// unless it was written plain
+		return e.quoted(n.value)
+	}
+	if isPlainSafe(n.value, flow) {
+		return n.value
+	}
+	return e.quoted(n.value)
+}
+
+// quoted quotes value in the quote style of the Emitter, or in double quotes if single quotes can not represent value
+func (e *Emitter) quoted(value string) string {
+	if e.quote != StyleDoubleQuoted && isPrintable(value) {
+		return singleQuoted(value)
+	}
+	return doubleQuoted(value)
+}
+
+// isPlainSafe checks that value can be written as a plain scalar, and read back unchanged
+func isPlainSafe(value string, flow bool) bool {
+	if value == "" || value != strings.TrimSpace(value) || !isPrintable(value) {
+		return false
+	}
+
+	switch value[0] {
+	case '-', '?', ':':
+		if len(value) == 1 || value[1] == ' ' {
+			return false
+		}
+	case ',', '[', ']', '{', '}', '#', '&', '*', '!', '|', '>', '\'', '"', '%', '@', '`':
+		return false
+	}
+
+	if strings.HasPrefix(value, "---") || strings.HasPrefix(value, "...") ||
+		strings.Contains(value, ": ") || strings.Contains(value, " #") || strings.HasSuffix(value, ":") {
+		return false
+	}
+	return !flow || !strings.ContainsAny(value, ",[]{}")
+}
+
+// isPrintable checks that value holds no line break, tab or other control character
+func isPrintable(value string) bool {
+	for _, r := range value {
+		if !unicode.IsPrint(r) && r != ' ' {
+			return false
+		}
+	}
+	return utf8.ValidString(value)
+}
+
+func singleQuoted(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+func doubleQuoted(value string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range value {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\r':
+			b.WriteString(`\r`)
+		case 0:
+			b.WriteString(`\0`)
+		case '\u0085':
+			b.WriteString(`\N`)
+		case '\u2028':
+			b.WriteString(`\L`)
+		case '\u2029':
+			b.WriteString(`\P`)
+		default:
+			switch {
+			case r < 0x20 || r == 0x7f:
+				fmt.Fprintf(&b, `\x%02x`, r)
+			case !unicode.IsPrint(r) && r <= 0xffff:
+				fmt.Fprintf(&b, `\u%04x`, r)
+			case !unicode.IsPrint(r):
+				fmt.Fprintf(&b, `\U%08x`, r)
+			default:
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// properties renders the explicit tag and the anchor of n
+func properties(n Node) string {
+	var props []string
+	if tag := explicitTag(n); tag != "" {
+		props = append(props, tag)
+	}
+	if anchor := n.Anchor(); anchor != "" {
+		props = append(props, "&"+anchor)
+	}
+	return strings.Join(props, " ")
+}
+
+// explicitTag is the tag set on n, rather than resolved from its value
+func explicitTag(n Node) string {
+	switch n := n.(type) {
+	case *ScalarNode:
+		if n.style == StyleDefault && n.tag == TagString {
+			// a string is written quoted when it would otherwise be read back as another type
+			return ""
+		}
+		return n.tag
+	case *MappingNode:
+		return n.tag
+	case *SequenceNode:
+		return n.tag
+	default:
+		return ""
+	}
+}
+
+func isPlain(n *ScalarNode) bool {
+	return n.style == StyleDefault || n.style == StylePlain
+}
+
+// isEmptyScalar checks that n is an untagged plain scalar without value, i.e., null
+func isEmptyScalar(n Node) bool {
+	scalar, ok := n.(*ScalarNode)
+	return ok && scalar.value == "" && isPlain(scalar) && scalar.tag == "" && scalar.anchor == ""
+}
+
+// join joins the non-empty parts with a space
+func join(parts ...string) string {
+	nonEmpty := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part != "" {
+			nonEmpty = append(nonEmpty, part)
+		}
+	}
+	return strings.Join(nonEmpty, " ")
+}
+
+// following prefixes text with the space separating it from an indicator such as "key:" or "-", unless text is empty
+func following(text string) string {
+	if text == "" {
+		return ""
+	}
+	return " " + text
+}
+
+// isBlockCollection checks that n is a non-empty mapping or sequence written in block style
+func isBlockCollection(n Node) bool {
+	switch n := n.(type) {
+	case *MappingNode:
+		return n.style != StyleFlow && len(n.pairs) > 0
+	case *SequenceNode:
+		return n.style != StyleFlow && len(n.items) > 0
+	default:
+		return false
+	}
+}
+
+// firstHeadComment is the head comment of the first entry of a collection
+func firstHeadComment(n Node) string {
+	switch n := n.(type) {
+	case *MappingNode:
+		if len(n.pairs) > 0 {
+			return n.pairs[0].Key.HeadComment()
+		}
+	case *SequenceNode:
+		if len(n.items) > 0 {
+			return n.items[0].HeadComment()
+		}
+	}
+	return ""
+}
+
+// writeComment writes the lines of comment at indent, starting at the beginning of a line
+func writeComment(b *strings.Builder, comment string, indent int) {
+	for _, line := range commentLines(comment) {
+		b.WriteString(strings.Repeat(" ", indent) + line + "\n")
+	}
+}
+
+// writeHeadComment writes the lines of comment at the current position, which is at indent,
+// and indents the line following them
+func writeHeadComment(b *strings.Builder, comment string, indent int) {
+	for _, line := range commentLines(comment) {
+		b.WriteString(line + "\n" + strings.Repeat(" ", indent))
+	}
+}
+
+// lineComment renders the line comment of n, which is written after n on the same line
+func lineComment(n Node) string {
+	return strings.Join(commentLines(n.LineComment()), " ")
+}
+
+// commentLines splits comment into lines, adding the # indicator to the lines missing it
+func commentLines(comment string) []string {
+	if comment == "" {
+		return nil
+	}
+	lines := strings.Split(comment, "\n")
+	for i, line := range lines {
+		if !strings.HasPrefix(line, "#") {
+			lines[i] = "# " + line
+		}
+	}
+	return lines
+}
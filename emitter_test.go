@@ -0,0 +1,237 @@
+package yaml_test
+
+import (
+	"github.com/ercross/yaml"
+	"github.com/ercross/yaml/parser"
+	"strings"
+	"testing"
+)
+
+func emit(t *testing.T, n yaml.Node) string {
+	t.Helper()
+	out, err := yaml.Emit(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out)
+}
+
+func TestEmit_RoundTrip(t *testing.T) {
+	tests := []string{
+		"a: 1\nb: two\n",
+		"a:\n  b:\n    c: d\n",
+		"- a\n- b\n",
+		"- name: web\n  image: nginx\n- name: sidecar\n",
+		"- - a\n  - b\n- c\n",
+		"a: [x, y]\nb: {k: v}\n",
+		"a: 'quoted'\nb: \"double\\tquoted\"\n",
+		"a: ''\nb:\n",
+		"a: &base\n  x: 1\nb: *base\n",
+		"a: !!str 1\nb: !custom [x]\n",
+		"a: |\n  line one\n  line two\nb: >-\n  folded text\n",
+		"- &item\n  x: 1\n- *item\n",
+		"--- &root\na: 1\n",
+		"a: {}\nb: []\n",
+		"plain\n",
+		"key with spaces: 'a: b'\n",
+		"# header\na: 1 # one\n# about b\nb:\n  - x\n  # end of b\n# trailing\n",
+		"items:\n  # first\n  - k: v\n  - |- # literal\n    text\n",
+		"# only a comment\n",
+	}
+	for _, source := range tests {
+		document := parseDocument(t, source)
+		if actual := emit(t, document); actual != source {
+			t.Errorf("expected %q to be emitted unchanged, got %q", source, actual)
+		}
+	}
+}
+
+func TestEmit_Scalars(t *testing.T) {
+	tests := []struct {
+		value    string
+		style    yaml.Style
+		expected string
+	}{
+		{value: "plain", expected: "plain\n"},
+		{value: "- dash", expected: "'- dash'\n"},
+		{value: "a: b", expected: "'a: b'\n"},
+		{value: "it's", style: yaml.StyleSingleQuoted, expected: "'it''s'\n"},
+		{value: " padded", expected: "' padded'\n"},
+		{value: "tab\there", expected: "\"tab\\there\"\n"},
+		{value: "one\ntwo\n", expected: "|\n  one\n  two\n"},
+		{value: "one\ntwo", style: yaml.StyleFolded, expected: ">-\n  one\n\n  two\n"},
+		{value: "  indented\n", style: yaml.StyleLiteral, expected: "|2\n    indented\n"},
+		{value: "x", style: yaml.StyleDoubleQuoted, expected: "\"x\"\n"},
+		{value: "", expected: "---\n"},
+	}
+	for _, test := range tests {
+		n := yaml.NewScalarNode(test.value)
+		n.SetStyle(test.style)
+		if actual := emit(t, n); actual != test.expected {
+			t.Errorf("%q: expected %q, got %q", test.value, test.expected, actual)
+		}
+	}
+}
+
+func TestEmit_ScalarsRoundTrip(t *testing.T) {
+	values := []string{"- dash", "a: b", "it's", "tab\there", "one\ntwo\n", "ends with colon:", "#comment", "multi\n\nparagraph",
+		"\n", "\n\n", "a\n\n"}
+	for _, value := range values {
+		// the line breaks ending the value must not be lost before the next key
+		mapping := yaml.NewMappingNode()
+		mapping.Set("key", yaml.NewScalarNode(value))
+		mapping.Set("next", yaml.NewScalarNode("x"))
+
+		out := emit(t, mapping)
+		document := parseDocument(t, out)
+		n, ok := document.Root().(*yaml.MappingNode).Get("key")
+		if !ok {
+			t.Fatalf("%q: key missing from %q", value, out)
+		}
+		if actual := scalarValue(t, n); actual != value {
+			t.Errorf("expected %q to read back from %q, got %q", value, out, actual)
+		}
+	}
+}
+
+func TestEmitter_Documents(t *testing.T) {
+	ast, err := parser.Parse(strings.NewReader("a: 1\n---\n- b\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b strings.Builder
+	emitter := yaml.NewEmitter(&b)
+	emitter.SetIndent(4)
+	for _, document := range ast.Documents() {
+		if err = emitter.Emit(document); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mapping := yaml.NewMappingNode()
+	nested := yaml.NewMappingNode()
+	nested.Set("c", yaml.NewScalarNode("d"))
+	mapping.Set("n", nested)
+	if err = emitter.Emit(mapping); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := "a: 1\n---\n- b\n---\n'n':\n    c: d\n"; b.String() != expected {
+		t.Errorf("expected %q, got %q", expected, b.String())
+	}
+}
+
+func TestEmit_CommentsAfterMutation(t *testing.T) {
+	document := parseDocument(t, "# replicas\nreplicas: 3 # scaled by hand\nname: web\n")
+	root := document.Root().(*yaml.MappingNode)
+
+	if err := root.Rename("replicas", "count"); err != nil {
+		t.Fatal(err)
+	}
+	value := yaml.NewScalarNode("5")
+	value.SetLineComment("scaled by hand")
+	root.Set("count", value)
+	if err := root.Move(0, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected, actual := "name: web\n# replicas\ncount: 5 # scaled by hand\n", emit(t, document); actual != expected {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+}
+
+func TestEmitter_FlowWidth(t *testing.T) {
+	n, err := yaml.NewNode(map[string]any{
+		"ports":  []int{80, 443},
+		"labels": map[string]string{"app": "web", "tier": "frontend", "team": "platform"},
+		"empty":  []string{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed := parseDocument(t, "block:\n  - a\n")
+	n.(*yaml.MappingNode).Set("parsed", parsed.Root().(*yaml.MappingNode).Pairs()[0].Value)
+
+	var b strings.Builder
+	emitter := yaml.NewEmitter(&b)
+	emitter.SetFlowWidth(20)
+	if err = emitter.Emit(n); err != nil {
+		t.Fatal(err)
+	}
+	expected := "empty: []\nlabels:\n  app: web\n  team: platform\n  tier: frontend\nports: [80, 443]\nparsed:\n  - a\n"
+	if b.String() != expected {
+		t.Errorf("expected %q, got %q", expected, b.String())
+	}
+}
+
+func TestEmitter_LineWidth(t *testing.T) {
+	long := "the quick brown fox jumps over the lazy dog and keeps on running"
+	mapping := yaml.NewMappingNode()
+	mapping.Set("description", yaml.NewScalarNode(long))
+	mapping.Set("short", yaml.NewScalarNode("fits on the line"))
+	folded := yaml.NewScalarNode("first paragraph is long enough to wrap\nsecond\n")
+	folded.SetStyle(yaml.StyleFolded)
+	mapping.Set("folded", folded)
+
+	var b strings.Builder
+	emitter := yaml.NewEmitter(&b)
+	emitter.SetLineWidth(24)
+	if err := emitter.Emit(mapping); err != nil {
+		t.Fatal(err)
+	}
+	expected := "description: >-\n  the quick brown fox\n  jumps over the lazy\n  dog and keeps on\n  running\n" +
+		"short: fits on the line\n" +
+		"folded: >\n  first paragraph is\n  long enough to wrap\n\n  second\n"
+	if b.String() != expected {
+		t.Fatalf("expected %q, got %q", expected, b.String())
+	}
+
+	root := parseDocument(t, b.String()).Root().(*yaml.MappingNode)
+	for key, value := range map[string]string{"description": long, "folded": folded.Value()} {
+		if actual, _ := root.Get(key); actual.(*yaml.ScalarNode).Value() != value {
+			t.Errorf("expected %s to read back as %q, got %q", key, value, actual.(*yaml.ScalarNode).Value())
+		}
+	}
+}
+
+func TestEmit_StringTag(t *testing.T) {
+	tests := map[string]string{
+		"true":  "'true'\n",
+		"1.5":   "'1.5'\n",
+		"":      "''\n",
+		"plain": "plain\n",
+		"a: b":  "'a: b'\n",
+	}
+	for value, expected := range tests {
+		n := yaml.NewScalarNode(value)
+		n.SetTag(yaml.TagString)
+		if actual := emit(t, n); actual != expected {
+			t.Errorf("expected %q to be emitted as %q, got %q", value, expected, actual)
+		}
+	}
+
+	parsed := parseDocument(t, "!!str 1\n")
+	if actual := emit(t, parsed); actual != "!!str 1\n" {
+		t.Errorf("expected a parsed tag to be kept, got %q", actual)
+	}
+}
+
+func TestEmitter_QuoteStyle(t *testing.T) {
+	mapping := yaml.NewMappingNode()
+	mapping.Set("indicator", yaml.NewScalarNode("a: b"))
+	tagged := yaml.NewScalarNode("true")
+	tagged.SetTag(yaml.TagString)
+	mapping.Set("tagged", tagged)
+	mapping.Set("plain", yaml.NewScalarNode("value"))
+
+	var b strings.Builder
+	emitter := yaml.NewEmitter(&b)
+	emitter.SetQuoteStyle(yaml.StyleDoubleQuoted)
+	if err := emitter.Emit(mapping); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "indicator: \"a: b\"\ntagged: \"true\"\nplain: value\n"; b.String() != expected {
+		t.Errorf("expected %q, got %q", expected, b.String())
+	}
+}
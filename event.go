@@ -0,0 +1,269 @@
+package yaml
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ercross/yaml/token"
+)
+
+// ErrUnknownAnchor is returned when an alias refers to an anchor not defined by a node preceding it
+var ErrUnknownAnchor = errors.New("unknown anchor")
+
+type (
+	// EventType is the kind of an Event
+	EventType int8
+
+	// Event is a step of the serialization of a YAML stream, as produced by parsing it and as consumed by
+	// a Composer or Emitter.EmitEvent. A stream is made of the events
+	//
+	//	StreamStart (DocumentStart node DocumentEnd)* StreamEnd
+	//
+	// where a node is a Scalar, an Alias, MappingStart (node node)* MappingEnd, or SequenceStart node* SequenceEnd
+	Event struct {
+		Type EventType
+
+		// Anchor and Tag are the properties of the node a Scalar, MappingStart or SequenceStart event starts.
+		// Tag is the explicit tag, as written, or empty
+		Anchor string
+		Tag    string
+
+		// Value is the value of a Scalar, or the name of the anchor an Alias refers to
+		Value string
+
+		// Style is the style of the node a Scalar, MappingStart or SequenceStart event starts
+		Style Style
+
+		// Implicit indicates that a DocumentStart or DocumentEnd event has no document marker (--- or ...) in the source
+		Implicit bool
+
+		// Start and End delimit the source of the event, End being the location following its last character.
+		// The events of block collections and implicit documents span no character at all
+		Start token.Location
+		End   token.Location
+
+		// HeadComment and LineComment are the comments of the node or document the event starts,
+		// and FootComment is the foot comment of the node or document the event completes
+		HeadComment string
+		LineComment string
+		FootComment string
+	}
+
+	// nodeSetter sets the properties of the nodes a Composer creates
+	nodeSetter interface {
+		SetAnchor(anchor string)
+		SetTag(tag string)
+		SetStyle(style Style)
+		SetPosition(position token.Location)
+		SetHeadComment(comment string)
+		SetLineComment(comment string)
+		SetFootComment(comment string)
+	}
+
+	// Composer composes the events of a stream into documents, see Composer.Add
+	Composer struct {
+		document *DocumentNode
+
+		// node is the last node completed by AddNode outside of a document
+		node Node
+
+		// open are the collections started and not yet ended, innermost last
+		open    []NodeBuilder
+		anchors map[string]Node
+
+		duplicateKeys DuplicateKeyPolicy
+		warnings      []error
+
+		// keys index the pairs of the open mappings by the tag and canonical value of their scalar key, see keyID,
+		// and discarding are the open mappings whose next value is discarded along with its duplicate key
+		keys       map[*MappingNode]map[string]mappingKey
+		discarding map[*MappingNode]bool
+	}
+)
+
+const (
+	EventStreamStart EventType = iota + 1
+	EventStreamEnd
+	EventDocumentStart
+	EventDocumentEnd
+	EventMappingStart
+	EventMappingEnd
+	EventSequenceStart
+	EventSequenceEnd
+	EventScalar
+	EventAlias
+)
+
+var eventTypeNames = map[EventType]string{
+	EventStreamStart:   "StreamStart",
+	EventStreamEnd:     "StreamEnd",
+	EventDocumentStart: "DocumentStart",
+	EventDocumentEnd:   "DocumentEnd",
+	EventMappingStart:  "MappingStart",
+	EventMappingEnd:    "MappingEnd",
+	EventSequenceStart: "SequenceStart",
+	EventSequenceEnd:   "SequenceEnd",
+	EventScalar:        "Scalar",
+	EventAlias:         "Alias",
+}
+
+// NewComposer creates a Composer expecting a stream or a document to start
+func NewComposer() *Composer {
+	return &Composer{
+		anchors:    make(map[string]Node),
+		keys:       make(map[*MappingNode]map[string]mappingKey),
+		discarding: make(map[*MappingNode]bool),
+	}
+}
+
+// Add adds event to the document being composed, and returns the document once event completes it.
+//
+// An Alias refers to the last node defined with its anchor before it, which must be complete,
+// so that a collection can not hold an alias of itself. Duplicate keys are handled as SetDuplicateKeyPolicy sets
+func (c *Composer) Add(event Event) (*DocumentNode, error) {
+	switch event.Type {
+	case EventStreamStart, EventStreamEnd:
+		if c.document != nil {
+			return nil, fmt.Errorf("%s event at %s within a document", event.Type, event.Start)
+		}
+		return nil, nil
+
+	case EventDocumentStart:
+		if c.document != nil {
+			return nil, fmt.Errorf("document starting at %s within a document", event.Start)
+		}
+		c.document = NewDocumentNode()
+		c.document.SetPosition(event.Start)
+		c.document.SetHeadComment(event.HeadComment)
+		c.document.SetLineComment(event.LineComment)
+		clear(c.anchors)
+		return nil, nil
+
+	case EventDocumentEnd:
+		if c.document == nil || len(c.open) > 0 {
+			return nil, fmt.Errorf("document end at %s does not end a document", event.Start)
+		}
+		document := c.document
+		document.SetFootComment(event.FootComment)
+		c.document = nil
+		return document, nil
+
+	case EventMappingStart, EventMappingEnd, EventSequenceStart, EventSequenceEnd, EventScalar, EventAlias:
+		if c.document == nil {
+			return nil, fmt.Errorf("%s event at %s outside of a document", event.Type, event.Start)
+		}
+		return nil, c.add(event)
+	}
+	return nil, fmt.Errorf("unknown event type %d", event.Type)
+}
+
+// AddNode adds event to the node being composed outside of any document, and returns the node once event completes it.
+// AddNode composes the nodes of a document one at a time, e.g., the items of a sequence, the preceding events
+// having been consumed otherwise. Anchors remain defined for later nodes, until a DocumentStart is added with Add
+func (c *Composer) AddNode(event Event) (Node, error) {
+	switch event.Type {
+	case EventStreamStart, EventStreamEnd, EventDocumentStart, EventDocumentEnd:
+		return nil, fmt.Errorf("%s event at %s is not part of a node", event.Type, event.Start)
+	}
+	if c.document != nil {
+		return nil, fmt.Errorf("%s event at %s within a document", event.Type, event.Start)
+	}
+	if err := c.add(event); err != nil {
+		return nil, err
+	}
+	n := c.node
+	c.node = nil
+	return n, nil
+}
+
+// add adds the event of a node to the collection being composed
+func (c *Composer) add(event Event) error {
+	switch event.Type {
+	case EventMappingStart, EventSequenceStart:
+		var n NodeBuilder = NewSequenceNode()
+		if event.Type == EventMappingStart {
+			n = NewMappingNode()
+		}
+		c.start(event, n.(nodeSetter))
+		c.open = append(c.open, n)
+		return nil
+
+	case EventMappingEnd, EventSequenceEnd:
+		if len(c.open) == 0 {
+			return fmt.Errorf("%s event at %s does not end a collection", event.Type, event.Start)
+		}
+		n := c.open[len(c.open)-1]
+		if _, isMapping := n.(*MappingNode); isMapping != (event.Type == EventMappingEnd) {
+			return fmt.Errorf("%s event at %s does not end a collection of its kind", event.Type, event.Start)
+		}
+		c.open = c.open[:len(c.open)-1]
+		if mapping, ok := n.(*MappingNode); ok {
+			c.endMapping(mapping)
+		}
+		n.(nodeSetter).SetFootComment(event.FootComment)
+		return c.complete(n.ToNode())
+
+	case EventScalar:
+		n := NewScalarNode(event.Value)
+		c.start(event, n)
+		n.SetFootComment(event.FootComment)
+		return c.complete(n)
+
+	case EventAlias:
+		target, ok := c.anchors[event.Value]
+		if !ok {
+			return fmt.Errorf("alias %q at %s: %w", event.Value, event.Start, ErrUnknownAnchor)
+		}
+		n := NewAliasNode(event.Value, target)
+		n.SetPosition(event.Start)
+		n.SetHeadComment(event.HeadComment)
+		n.SetLineComment(event.LineComment)
+		n.SetFootComment(event.FootComment)
+		return c.complete(n)
+	}
+	return fmt.Errorf("unknown event type %d", event.Type)
+}
+
+// start gives n the properties, style, position and comments of the event starting it
+func (c *Composer) start(event Event, n nodeSetter) {
+	n.SetAnchor(event.Anchor)
+	if event.Tag != "" {
+		n.SetTag(event.Tag)
+	}
+	n.SetStyle(event.Style)
+	n.SetPosition(event.Start)
+	n.SetHeadComment(event.HeadComment)
+	n.SetLineComment(event.LineComment)
+}
+
+// complete adds the complete node n to the collection it is nested in, or makes it the root of the document,
+// or the node composed by AddNode outside of a document
+func (c *Composer) complete(n Node) error {
+	if n.Anchor() != "" {
+		c.anchors[n.Anchor()] = n
+	}
+
+	if len(c.open) > 0 {
+		if mapping, ok := c.open[len(c.open)-1].(*MappingNode); ok {
+			return c.addPairNode(mapping, n)
+		}
+		c.open[len(c.open)-1].AddChild(n)
+		return nil
+	}
+	if c.document == nil {
+		c.node = n
+		return nil
+	}
+	if c.document.root != nil {
+		return fmt.Errorf("node at %s follows the root of the document", n.Position())
+	}
+	c.document.root = n
+	return nil
+}
+
+func (t EventType) String() string {
+	if name, ok := eventTypeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("EventType(%d)", t)
+}
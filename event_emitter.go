@@ -0,0 +1,340 @@
+package yaml
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ercross/yaml/token"
+)
+
+type (
+	// eventWriter writes the documents of the events given to Emitter.EmitEvent as the events arrive.
+	//
+	// Block collections are written entry by entry, so that a document is never held whole. The other nodes are
+	// composed and written once complete: scalars, aliases, flow collections and the keys of block mappings.
+	// A collection with StyleDefault is composed until it can no longer be written in flow style, see Emitter.isBlock
+	eventWriter struct {
+		emitter *Emitter
+		b       strings.Builder
+
+		// document is the document being written. Its root is only set once composed whole,
+		// and streamed indicates that its root is a block collection written entry by entry
+		document *DocumentNode
+		streamed bool
+
+		// composer composes the nodes written whole, and holds the anchors the document defined so far
+		composer *Composer
+
+		// open are the block collections being written, innermost last
+		open []*openCollection
+
+		// pending are the events of the collection whose style is not decided yet, starting with its start event,
+		// and depth is the number of collections they leave open
+		pending []Event
+		depth   int
+	}
+
+	// openCollection is a block collection being written by an eventWriter
+	openCollection struct {
+		node Node
+
+		// indent is the indentation of the entries of the collection, and foot the indentation of its foot comment
+		indent  int
+		foot    int
+		entries int
+
+		// key is the key of the mapping pair whose value is being written, or nil,
+		// and keys the positions of the keys written, see keyID
+		key  Node
+		keys map[string]token.Location
+	}
+)
+
+// add writes event, then flushes what it wrote
+func (w *eventWriter) add(event Event) error {
+	err := w.handle(event)
+	if w.b.Len() > 0 {
+		if _, writeErr := io.WriteString(w.emitter.w, w.b.String()); err == nil {
+			err = writeErr
+		}
+		w.b.Reset()
+	}
+	return err
+}
+
+func (w *eventWriter) handle(event Event) error {
+	switch event.Type {
+	case EventStreamStart, EventStreamEnd:
+		if w.document != nil {
+			return fmt.Errorf("%s event at %s within a document", event.Type, event.Start)
+		}
+		return nil
+
+	case EventDocumentStart:
+		if w.document != nil {
+			return fmt.Errorf("document starting at %s within a document", event.Start)
+		}
+		w.document = NewDocumentNode()
+		w.document.SetPosition(event.Start)
+		w.document.SetHeadComment(event.HeadComment)
+		w.document.SetLineComment(event.LineComment)
+		w.streamed = false
+		w.composer = NewComposer()
+		return nil
+
+	case EventDocumentEnd:
+		if w.document == nil || len(w.open) > 0 || w.pending != nil {
+			return fmt.Errorf("document end at %s does not end a document", event.Start)
+		}
+		document := w.document
+		w.document = nil
+		document.SetFootComment(event.FootComment)
+		if !w.streamed {
+			return w.emitter.Emit(document)
+		}
+		writeComment(&w.b, document.FootComment(), 0)
+		w.emitter.documents++
+		return nil
+
+	case EventMappingStart, EventMappingEnd, EventSequenceStart, EventSequenceEnd, EventScalar, EventAlias:
+		if w.document == nil {
+			return fmt.Errorf("%s event at %s outside of a document", event.Type, event.Start)
+		}
+		return w.node(event)
+	}
+	return fmt.Errorf("unknown event type %d", event.Type)
+}
+
+// node writes the event of a node, or holds it until the style of the pending collection is decided
+func (w *eventWriter) node(event Event) error {
+	if w.pending != nil {
+		w.pending = append(w.pending, event)
+		switch event.Type {
+		case EventMappingStart, EventSequenceStart:
+			w.depth++
+		case EventMappingEnd, EventSequenceEnd:
+			w.depth--
+		}
+		return w.decide()
+	}
+
+	switch event.Type {
+	case EventMappingStart, EventSequenceStart:
+		w.pending, w.depth = []Event{event}, 1
+		return nil
+	case EventMappingEnd, EventSequenceEnd:
+		return w.end(event)
+	}
+
+	n, err := w.composer.AddNode(event)
+	if err != nil {
+		return err
+	}
+	return w.write(n)
+}
+
+// decide writes the pending collection whole once it is complete, or starts writing it in block style
+// as soon as its events show that it is a block collection, writing the events held so far
+func (w *eventWriter) decide() error {
+	events := w.pending
+	if w.depth == 0 {
+		w.pending = nil
+		var n Node
+		for _, event := range events {
+			var err error
+			if n, err = w.composer.AddNode(event); err != nil {
+				return err
+			}
+		}
+		return w.write(n)
+	}
+
+	if !w.isBlock() {
+		return nil
+	}
+	w.pending = nil
+	if err := w.start(events[0], events[1]); err != nil {
+		return err
+	}
+	for _, event := range events[1:] {
+		if err := w.node(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isBlock checks that the pending collection, holding at least one entry, is written in block style,
+// as Emitter.isBlock decides once the collection is complete
+func (w *eventWriter) isBlock() bool {
+	e := w.emitter
+	start := w.pending[0]
+	if e.canonical || start.Style == StyleFlow || w.isKey() {
+		return false
+	}
+	if start.Style != StyleDefault || e.flowWidth == 0 {
+		return true
+	}
+
+	// see canBeFlow. The flow style of a collection takes its brackets, and at least a character per node
+	nodes := 0
+	for _, event := range w.pending[1:] {
+		switch {
+		case event.HeadComment != "" || event.LineComment != "" || event.FootComment != "":
+			return true
+		case event.Style == StyleBlock || event.Style == StyleLiteral || event.Style == StyleFolded:
+			return true
+		case event.Type != EventMappingEnd && event.Type != EventSequenceEnd:
+			nodes++
+		}
+	}
+	return nodes+2 > e.flowWidth
+}
+
+// isKey checks that the next node is the key of a pair of a block mapping, which is always written on a single line
+func (w *eventWriter) isKey() bool {
+	if len(w.open) == 0 {
+		return false
+	}
+	c := w.open[len(w.open)-1]
+	return !isSequence(c.node) && c.key == nil
+}
+
+// start writes the start of the block collection event starts, first being the event starting its first entry.
+// The entries are written as blockMapping and blockSequence do
+func (w *eventWriter) start(event Event, first Event) error {
+	var builder NodeBuilder = NewSequenceNode()
+	if event.Type == EventMappingStart {
+		builder = NewMappingNode()
+	}
+	w.composer.start(event, builder.(nodeSetter))
+	n := builder.ToNode()
+	props, comment := properties(n), lineComment(n)
+
+	if len(w.open) == 0 {
+		if w.streamed || w.document.root != nil {
+			return fmt.Errorf("node at %s follows the root of the document", n.Position())
+		}
+		// see Emit and root
+		w.streamed = true
+		writeComment(&w.b, w.document.HeadComment(), 0)
+		header := join("---", props, join(comment, lineComment(w.document)))
+		if w.emitter.documents > 0 || header != "---" || w.document.HeadComment() != "" {
+			w.b.WriteString(header + "\n")
+		}
+		writeComment(&w.b, n.HeadComment(), 0)
+		w.open = append(w.open, &openCollection{node: n})
+		return nil
+	}
+
+	// see blockValue and blockValueContent
+	parent := w.open[len(w.open)-1]
+	if isSequence(parent.node) {
+		w.item(parent, n)
+		nested := parent.indent + 2
+		if props == "" && comment == "" && first.HeadComment == "" {
+			w.b.WriteByte(' ')
+		} else {
+			w.b.WriteString(following(join(props, comment)) + "\n" + strings.Repeat(" ", nested))
+		}
+		w.open = append(w.open, &openCollection{node: n, indent: nested, foot: nested})
+		return nil
+	}
+
+	nested := parent.indent + w.emitter.indent
+	content, foot := nested, nested
+	if w.emitter.indentlessSequences && isSequence(n) {
+		content, foot = parent.indent, parent.indent+2
+	}
+	w.b.WriteString(following(join(props, comment)) + "\n" + strings.Repeat(" ", content))
+	w.open = append(w.open, &openCollection{node: n, indent: content, foot: foot})
+	return nil
+}
+
+// end writes the end of the innermost block collection, i.e., its foot comment
+func (w *eventWriter) end(event Event) error {
+	if len(w.open) == 0 {
+		return fmt.Errorf("%s event at %s does not end a collection", event.Type, event.Start)
+	}
+	c := w.open[len(w.open)-1]
+	if isSequence(c.node) != (event.Type == EventSequenceEnd) {
+		return fmt.Errorf("%s event at %s does not end a collection of its kind", event.Type, event.Start)
+	}
+	if c.key != nil {
+		return fmt.Errorf("%s event at %s follows a key without value", event.Type, event.Start)
+	}
+	w.open = w.open[:len(w.open)-1]
+	writeComment(&w.b, event.FootComment, c.foot)
+
+	// an alias of the collection is written as such, regardless of its content
+	if c.node.Anchor() != "" {
+		w.composer.anchors[c.node.Anchor()] = c.node
+	}
+	if len(w.open) > 0 {
+		parent := w.open[len(w.open)-1]
+		parent.entries++
+		parent.key = nil
+	}
+	return nil
+}
+
+// write writes the complete node n as the root of the document, or as the next key, value or item
+// of the innermost block collection, as blockMapping and blockSequence do
+func (w *eventWriter) write(n Node) error {
+	if len(w.open) == 0 {
+		if w.streamed || w.document.root != nil {
+			return fmt.Errorf("node at %s follows the root of the document", n.Position())
+		}
+		// a root written whole is written by Emit, once the foot comment of the document is known
+		w.document.root = n
+		return nil
+	}
+
+	e := w.emitter
+	c := w.open[len(w.open)-1]
+	if isSequence(c.node) {
+		w.item(c, n)
+		c.entries++
+		return e.blockValue(&w.b, n, c.indent, true)
+	}
+
+	if c.key != nil {
+		c.key = nil
+		c.entries++
+		return e.blockValue(&w.b, n, c.indent, false)
+	}
+	// the pairs are written already, so that a duplicate key is always rejected, see DuplicateKeyReject
+	if id, ok := keyID(n); ok {
+		if first, duplicate := c.keys[id]; duplicate {
+			return &DuplicateKeyError{Key: resolveAlias(n).(*ScalarNode).value, First: first, Duplicate: n.Position()}
+		}
+		if c.keys == nil {
+			c.keys = make(map[string]token.Location)
+		}
+		c.keys[id] = n.Position()
+	}
+	if c.entries > 0 && c.indent == 0 {
+		w.b.WriteString(strings.Repeat("\n", e.blankLines))
+	}
+	if c.entries > 0 {
+		w.b.WriteString(strings.Repeat(" ", c.indent))
+	}
+	writeHeadComment(&w.b, n.HeadComment(), c.indent)
+	key, err := e.key(n)
+	if err != nil {
+		return err
+	}
+	w.b.WriteString(key + ":")
+	c.key = n
+	return nil
+}
+
+// item writes the indicator of a new item of the block sequence c, preceded by the head comment of the item
+func (w *eventWriter) item(c *openCollection, item Node) {
+	if c.entries > 0 {
+		w.b.WriteString(strings.Repeat(" ", c.indent))
+	}
+	writeHeadComment(&w.b, item.HeadComment(), c.indent)
+	w.b.WriteByte('-')
+}
@@ -0,0 +1,228 @@
+package yaml_test
+
+import (
+	"errors"
+	"github.com/ercross/yaml"
+	"github.com/ercross/yaml/parser"
+	"github.com/ercross/yaml/token"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestEmitter_EmitEvent(t *testing.T) {
+	sources := []string{
+		"a: &x 1\nb:\n  - *x\n  - [c, 'd']\n",
+		"# head\nkey: |\n  text\n---\n- !!str 2\n- {a: b}\n",
+		"--- !!map &m # line\n# key\na:\n  # item\n  - b: c\n    d: [e, f]\n  - - g\n    - h\n  # foot\nb: &s\n  - 1\n  - 2\nc: *s\n# document\n",
+		"- - a\n  - b\n- !!seq\n  - c\n- &n\n  d: e\n- *n\n",
+		"a:\n  b:\n    c: >\n      folded\n    d: [x, {y: z}]\n  e: 1\nf: 2\n",
+		"[a, b]\n",
+		"",
+	}
+	setups := map[string]func(*yaml.Emitter){
+		"default":     func(*yaml.Emitter) {},
+		"no flow":     func(e *yaml.Emitter) { e.SetFlowWidth(0) },
+		"narrow flow": func(e *yaml.Emitter) { e.SetFlowWidth(8) },
+		"indentless":  func(e *yaml.Emitter) { e.SetIndentlessSequences(true) },
+		"indent":      func(e *yaml.Emitter) { e.SetIndent(4) },
+		"blank lines": func(e *yaml.Emitter) { e.SetBlankLines(1) },
+		"canonical":   func(e *yaml.Emitter) { e.SetCanonical(true) },
+	}
+	for name, setup := range setups {
+		t.Run(name, func(t *testing.T) {
+			for _, source := range sources {
+				var b strings.Builder
+				emitter := yaml.NewEmitter(&b)
+				setup(emitter)
+				if err := parser.ParseEvents(strings.NewReader(source), emitter.EmitEvent); err != nil {
+					t.Fatal(err)
+				}
+
+				var expected strings.Builder
+				ast, err := parser.Parse(strings.NewReader(source))
+				if err != nil {
+					t.Fatal(err)
+				}
+				expectedEmitter := yaml.NewEmitter(&expected)
+				setup(expectedEmitter)
+				for _, document := range ast.Documents() {
+					if err = expectedEmitter.Emit(document); err != nil {
+						t.Fatal(err)
+					}
+				}
+				if b.String() != expected.String() {
+					t.Errorf("%q: expected %q, got %q", source, expected.String(), b.String())
+				}
+			}
+		})
+	}
+}
+
+func TestEmitter_EmitEvent_Streaming(t *testing.T) {
+	steps := []struct {
+		event    yaml.Event
+		expected string
+	}{
+		{event: yaml.Event{Type: yaml.EventStreamStart}},
+		{event: yaml.Event{Type: yaml.EventDocumentStart}},
+		{event: yaml.Event{Type: yaml.EventMappingStart, Style: yaml.StyleBlock}},
+		{event: yaml.Event{Type: yaml.EventScalar, Value: "a"}, expected: "a:"},
+		{event: yaml.Event{Type: yaml.EventScalar, Value: "1"}, expected: "a: 1\n"},
+		{event: yaml.Event{Type: yaml.EventScalar, Value: "b"}, expected: "a: 1\nb:"},
+		{event: yaml.Event{Type: yaml.EventSequenceStart}, expected: "a: 1\nb:"},
+		{event: yaml.Event{Type: yaml.EventScalar, Value: "c", LineComment: "# c"}, expected: "a: 1\nb:\n  - c # c\n"},
+		{event: yaml.Event{Type: yaml.EventSequenceEnd}, expected: "a: 1\nb:\n  - c # c\n"},
+		{event: yaml.Event{Type: yaml.EventMappingEnd}, expected: "a: 1\nb:\n  - c # c\n"},
+		{event: yaml.Event{Type: yaml.EventDocumentEnd, FootComment: "# end"}, expected: "a: 1\nb:\n  - c # c\n# end\n"},
+		{event: yaml.Event{Type: yaml.EventStreamEnd}, expected: "a: 1\nb:\n  - c # c\n# end\n"},
+	}
+
+	var b strings.Builder
+	emitter := yaml.NewEmitter(&b)
+	for _, step := range steps {
+		if err := emitter.EmitEvent(step.event); err != nil {
+			t.Fatal(err)
+		}
+		if b.String() != step.expected {
+			t.Fatalf("after %s: expected %q, got %q", step.event.Type, step.expected, b.String())
+		}
+	}
+}
+
+func TestEmitter_EmitEvent_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		events []yaml.Event
+	}{
+		{name: "node outside of a document", events: []yaml.Event{{Type: yaml.EventScalar}}},
+		{name: "unmatched end", events: []yaml.Event{
+			{Type: yaml.EventDocumentStart},
+			{Type: yaml.EventMappingStart, Style: yaml.StyleBlock},
+			{Type: yaml.EventSequenceEnd},
+		}},
+		{name: "second root", events: []yaml.Event{
+			{Type: yaml.EventDocumentStart},
+			{Type: yaml.EventSequenceStart, Style: yaml.StyleBlock},
+			{Type: yaml.EventScalar},
+			{Type: yaml.EventSequenceEnd},
+			{Type: yaml.EventScalar},
+		}},
+		{name: "key without value", events: []yaml.Event{
+			{Type: yaml.EventDocumentStart},
+			{Type: yaml.EventMappingStart, Style: yaml.StyleBlock},
+			{Type: yaml.EventScalar, Value: "a"},
+			{Type: yaml.EventMappingEnd},
+		}},
+		{name: "unterminated collection", events: []yaml.Event{
+			{Type: yaml.EventDocumentStart},
+			{Type: yaml.EventSequenceStart, Style: yaml.StyleBlock},
+			{Type: yaml.EventScalar},
+			{Type: yaml.EventDocumentEnd},
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			emitter := yaml.NewEmitter(io.Discard)
+			var err error
+			for _, event := range test.events {
+				if err = emitter.EmitEvent(event); err != nil {
+					break
+				}
+			}
+			if err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestEmitter_EmitEvent_DuplicateKey(t *testing.T) {
+	emitter := yaml.NewEmitter(io.Discard)
+	err := parser.ParseEvents(strings.NewReader("a: # comment\n  b: 1\n  0x10: 2\n  16: 3\n"), emitter.EmitEvent)
+	var duplicate *yaml.DuplicateKeyError
+	if !errors.As(err, &duplicate) {
+		t.Fatalf("expected a duplicate key error, got %v", err)
+	}
+	if duplicate.First.Line() != 3 || duplicate.Duplicate.Line() != 4 {
+		t.Errorf("expected lines 3 and 4, got %s and %s", duplicate.First, duplicate.Duplicate)
+	}
+}
+
+func TestComposer_Add(t *testing.T) {
+	events := []yaml.Event{
+		{Type: yaml.EventStreamStart},
+		{Type: yaml.EventDocumentStart, Implicit: true},
+		{Type: yaml.EventSequenceStart, Style: yaml.StyleFlow},
+		{Type: yaml.EventScalar, Anchor: "a", Value: "x"},
+		{Type: yaml.EventAlias, Value: "a"},
+		{Type: yaml.EventSequenceEnd},
+		{Type: yaml.EventDocumentEnd, Implicit: true},
+		{Type: yaml.EventStreamEnd},
+	}
+
+	composer := yaml.NewComposer()
+	var documents []*yaml.DocumentNode
+	for _, event := range events {
+		document, err := composer.Add(event)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if document != nil {
+			documents = append(documents, document)
+		}
+	}
+	if len(documents) != 1 {
+		t.Fatalf("expected 1 document, got %d", len(documents))
+	}
+	if actual := emit(t, documents[0]); actual != "[&a x, *a]\n" {
+		t.Errorf("expected %q, got %q", "[&a x, *a]\n", actual)
+	}
+}
+
+func TestComposer_Add_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		events []yaml.Event
+	}{
+		{name: "node outside of a document", events: []yaml.Event{{Type: yaml.EventScalar}}},
+		{name: "unmatched end", events: []yaml.Event{
+			{Type: yaml.EventDocumentStart},
+			{Type: yaml.EventMappingStart},
+			{Type: yaml.EventSequenceEnd},
+		}},
+		{name: "second root", events: []yaml.Event{
+			{Type: yaml.EventDocumentStart},
+			{Type: yaml.EventScalar},
+			{Type: yaml.EventScalar},
+		}},
+		{name: "unterminated collection", events: []yaml.Event{
+			{Type: yaml.EventDocumentStart},
+			{Type: yaml.EventSequenceStart},
+			{Type: yaml.EventDocumentEnd},
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			composer := yaml.NewComposer()
+			var err error
+			for _, event := range test.events {
+				if _, err = composer.Add(event); err != nil {
+					break
+				}
+			}
+			if err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestComposer_Add_UnknownAnchor(t *testing.T) {
+	composer := yaml.NewComposer()
+	_, _ = composer.Add(yaml.Event{Type: yaml.EventDocumentStart})
+	_, err := composer.Add(yaml.Event{Type: yaml.EventAlias, Value: "missing", Start: token.NewLocation(2, 4)})
+	if !errors.Is(err, yaml.ErrUnknownAnchor) {
+		t.Fatalf("expected %v, got %v", yaml.ErrUnknownAnchor, err)
+	}
+}
@@ -0,0 +1,99 @@
+// Package format rewrites YAML streams in a consistent style, keeping their comments.
+//
+// Formatting is idempotent: formatting the output of Source again leaves it unchanged
+package format
+
+import (
+	"bytes"
+	"github.com/ercross/yaml"
+	"github.com/ercross/yaml/parser"
+	"strings"
+)
+
+// defaultIndent is the indentation used when Options.Indent is 0 and the indentation of a document can not be inferred
+const defaultIndent = 2
+
+// Options are the style rules Source formats a stream with
+type Options struct {
+	// Indent is the number of spaces nested block collections are indented by, between 1 and 9.
+	// 0 keeps the indentation unit each document was written with, see parser.AbstractSyntaxTree.IndentationUnit
+	Indent int
+
+	// IndentlessSequences writes the block sequences nested in a mapping at the indentation of their key,
+	// e.g., "key:\n- item", rather than indented, e.g., "key:\n  - item"
+	IndentlessSequences bool
+
+	// Quote is the quoting style of strings, either yaml.StyleSingleQuoted or yaml.StyleDoubleQuoted.
+	// Quoted scalars are rewritten in this style, unless it can not represent their value.
+	// yaml.StyleDefault keeps quoted scalars as written, and single-quotes the scalars that need quoting
+	Quote yaml.Style
+
+	// Unquote writes quoted strings as plain scalars, where a plain scalar reads back as the same string,
+	// YAML 1.1 readers included, e.g., 'yes' stays quoted
+	Unquote bool
+
+	// BlankLines is the number of blank lines written between the top-level keys of a document
+	BlankLines int
+}
+
+// Source formats the YAML stream src with opts.
+//
+// Every document is rewritten by the yaml.Emitter, which normalizes the spacing around indicators (e.g., "key: value"),
+// and keeps comments, anchors, tags and the order of keys. src must be a valid YAML stream
+func Source(src []byte, opts Options) ([]byte, error) {
+	ast, err := parser.Parse(bytes.NewReader(src))
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	emitter := yaml.NewEmitter(&b)
+	emitter.SetIndentlessSequences(opts.IndentlessSequences)
+	emitter.SetQuoteStyle(opts.Quote)
+	emitter.SetBlankLines(opts.BlankLines)
+
+	for _, document := range ast.Documents() {
+		emitter.SetIndent(indentOf(ast, document, opts))
+		normalizeQuoting(document, opts)
+		if err = emitter.Emit(document); err != nil {
+			return nil, err
+		}
+	}
+	return b.Bytes(), nil
+}
+
+// indentOf returns the indentation document is formatted with
+func indentOf(ast *parser.AbstractSyntaxTree, document *yaml.DocumentNode, opts Options) int {
+	switch {
+	case opts.Indent > 0:
+		return opts.Indent
+	case ast.IndentationUnit(document) > 0:
+		return ast.IndentationUnit(document)
+	default:
+		return defaultIndent
+	}
+}
+
+// normalizeQuoting applies the quoting options to the quoted scalars of document
+func normalizeQuoting(document *yaml.DocumentNode, opts Options) {
+	for _, n := range yaml.All(document) {
+		scalar, ok := n.(*yaml.ScalarNode)
+		if !ok || (scalar.Style() != yaml.StyleSingleQuoted && scalar.Style() != yaml.StyleDoubleQuoted) {
+			continue
+		}
+
+		switch {
+		case opts.Unquote && isPlainString(scalar):
+			scalar.SetStyle(yaml.StylePlain)
+		case opts.Quote == yaml.StyleSingleQuoted || opts.Quote == yaml.StyleDoubleQuoted:
+			scalar.SetStyle(opts.Quote)
+		}
+	}
+}
+
+// isPlainString checks that the quoted scalar is a single-line string, which reads back as a string when written plain,
+// for YAML 1.1 readers as well
+func isPlainString(scalar *yaml.ScalarNode) bool {
+	return scalar.Tag() == yaml.TagString && !strings.Contains(scalar.Value(), "\n") &&
+		yaml.NewScalarNode(scalar.Value()).Tag() == yaml.TagString && !yaml.IsYAML11Scalar(scalar.Value())
+}
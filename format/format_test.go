@@ -0,0 +1,113 @@
+package format
+
+import (
+	"github.com/ercross/yaml"
+	"testing"
+)
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		opts     Options
+		expected string
+	}{
+		{
+			name:     "reindent",
+			source:   "# config\nspec:\n    replicas:   3 # scaled\n    ports:\n        - 80\n",
+			opts:     Options{Indent: 2},
+			expected: "# config\nspec:\n  replicas: 3 # scaled\n  ports:\n    - 80\n",
+		},
+		{
+			name:     "inferred indentation",
+			source:   "spec:\n    replicas:   3\n",
+			expected: "spec:\n    replicas: 3\n",
+		},
+		{
+			name:     "indentless sequences",
+			source:   "items:\n  - a\n  - b\nnested:\n  list:\n    - c\n",
+			opts:     Options{Indent: 2, IndentlessSequences: true},
+			expected: "items:\n- a\n- b\nnested:\n  list:\n  - c\n",
+		},
+		{
+			name:     "indented sequences",
+			source:   "items:\n- a\n- b\n",
+			opts:     Options{Indent: 2},
+			expected: "items:\n  - a\n  - b\n",
+		},
+		{
+			name:     "double quotes",
+			source:   "a: 'x'\nb: \"y\"\nc: 'it''s'\nd: plain\n",
+			opts:     Options{Quote: yaml.StyleDoubleQuoted},
+			expected: "a: \"x\"\nb: \"y\"\nc: \"it's\"\nd: plain\n",
+		},
+		{
+			name:     "unquote",
+			source:   "a: 'web'\nb: \"1.0\"\nc: 'a: b'\nd: \"\"\n",
+			opts:     Options{Unquote: true, Quote: yaml.StyleDoubleQuoted},
+			expected: "a: web\nb: \"1.0\"\nc: \"a: b\"\nd: \"\"\n",
+		},
+		{
+			name:     "unquote keeps the quotes of yaml 1.1 booleans and numbers",
+			source:   "a: 'yes'\nb: 'on'\nc: '0755'\nd: '1_000'\ne: 'N'\nf: 'yesterday'\n",
+			opts:     Options{Unquote: true},
+			expected: "a: 'yes'\nb: 'on'\nc: '0755'\nd: '1_000'\ne: 'N'\nf: yesterday\n",
+		},
+		{
+			name:     "blank lines between top-level keys",
+			source:   "a: 1\n# about b\nb:\n  c: 2\n  d: 3\n",
+			opts:     Options{BlankLines: 1},
+			expected: "a: 1\n\n# about b\nb:\n  c: 2\n  d: 3\n",
+		},
+		{
+			name:     "documents",
+			source:   "a:   1\n---\n-   x\n",
+			expected: "a: 1\n---\n- x\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual, err := Source([]byte(test.source), test.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(actual) != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestSource_Idempotent(t *testing.T) {
+	sources := []string{
+		"# head\nkey: value # line\nlist:\n    # first item\n    - a\n    - b: 1\n      c: [x, 'y']\n    # end of list\nblock: |\n    text\n# foot\n",
+		"- &anchor\n  name: web\n- *anchor\n- !!str 1\n",
+		"a:\n- x\n- y\nb: \"quoted\"\n",
+	}
+	options := []Options{
+		{},
+		{Indent: 4, IndentlessSequences: true, BlankLines: 1},
+		{Indent: 2, Quote: yaml.StyleDoubleQuoted, Unquote: true},
+	}
+	for _, source := range sources {
+		for _, opts := range options {
+			once, err := Source([]byte(source), opts)
+			if err != nil {
+				t.Fatalf("formatting %q with %+v: %v", source, opts, err)
+			}
+			twice, err := Source(once, opts)
+			if err != nil {
+				t.Fatalf("formatting %q with %+v: %v", once, opts, err)
+			}
+			if string(once) != string(twice) {
+				t.Errorf("expected formatting %q with %+v to be idempotent, got %q then %q", source, opts, once, twice)
+			}
+		}
+	}
+}
+
+func TestSource_InvalidYAML(t *testing.T) {
+	if _, err := Source([]byte("a: [\n"), Options{}); err == nil {
+		t.Error("expected an error for invalid yaml")
+	}
+}
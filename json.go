@@ -0,0 +1,489 @@
+package yaml
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+
+	"github.com/ercross/yaml/token"
+)
+
+var (
+	// ErrNonStringKey is returned when converting a mapping whose key is not a string to JSON,
+	// unless JSONOptions.StringifyKeys is set
+	ErrNonStringKey = errors.New("non-string mapping key")
+
+	// ErrNonFinite is returned when converting .inf, -.inf or .nan to JSON with the NonFiniteError policy
+	ErrNonFinite = errors.New("non-finite float")
+
+	// ErrRecursiveAlias is returned when converting an alias that refers to a node enclosing it, which can not be expanded
+	ErrRecursiveAlias = errors.New("recursive alias")
+
+	// ErrInvalidMerge is returned when the value of a merge key (<<) is neither a mapping nor a sequence of mappings
+	ErrInvalidMerge = errors.New("invalid merge key value")
+)
+
+type (
+	// JSONStream is the way StreamToJSON writes the documents of a stream
+	JSONStream int8
+
+	// NonFinitePolicy is the way .inf, -.inf and .nan, which JSON can not represent, are converted to JSON
+	NonFinitePolicy int8
+
+	// JSONOptions control the conversion of YAML nodes to JSON
+	JSONOptions struct {
+		Stream    JSONStream
+		NonFinite NonFinitePolicy
+
+		// StringifyKeys converts the mapping keys that are not strings to their YAML text, e.g., 1 to "1",
+		// rather than failing with ErrNonStringKey
+		StringifyKeys bool
+
+		// Indent indents the JSON text of each document with this string, e.g., "  ". Ignored by JSONLines
+		Indent string
+
+		// MaxAliasExpansion is the number of nodes the aliases of the converted tree may expand to, see DecodeOptions
+		MaxAliasExpansion int
+	}
+
+	// ConversionError reports the node that could not be converted, and where it was found in the YAML source
+	ConversionError struct {
+		Position token.Location
+		Path     Path
+		Err      error
+	}
+
+	// jsonConverter writes node trees as JSON
+	jsonConverter struct {
+		opts JSONOptions
+
+		// enclosing are the collections being converted, which an alias can not refer to
+		enclosing map[Node]bool
+		expansion *aliasExpansion
+	}
+
+	// jsonMember is a member of a JSON object, converted from a mapping pair
+	jsonMember struct {
+		name  string
+		key   Node
+		value Node
+	}
+)
+
+const (
+	// JSONArray writes a stream as a single JSON array holding every document
+	JSONArray JSONStream = iota
+
+	// JSONLines writes a stream as JSON Lines, one document per line
+	JSONLines
+)
+
+const (
+	// NonFiniteError fails the conversion with ErrNonFinite
+	NonFiniteError NonFinitePolicy = iota
+
+	// NonFiniteNull converts non-finite floats to null
+	NonFiniteNull
+
+	// NonFiniteString converts non-finite floats to the strings "Infinity", "-Infinity" and "NaN"
+	NonFiniteString
+)
+
+// ToJSON converts the tree rooted at n to JSON. If n is a DocumentNode, its root is converted, and an empty document is null.
+//
+// Scalars are converted by their tag (explicit, or resolved with the core schema): null, booleans, integers
+// and finite floats become JSON literals and numbers, in decimal notation, and every other scalar becomes a string.
+// Aliases are expanded, up to opts.MaxAliasExpansion nodes, and merge keys (<<) are replaced with the pairs
+// of the mappings they merge, unless the mapping defines the same keys itself
+func ToJSON(n Node, opts JSONOptions) ([]byte, error) {
+	var b bytes.Buffer
+	if err := newJSONConverter(opts).write(&b, nil, documentRoot(n)); err != nil {
+		return nil, err
+	}
+	if opts.Indent == "" {
+		return b.Bytes(), nil
+	}
+
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, b.Bytes(), "", opts.Indent); err != nil {
+		return nil, err
+	}
+	return indented.Bytes(), nil
+}
+
+// StreamToJSON converts the documents of a stream to JSON, as a JSON array or as JSON Lines depending on opts.Stream.
+// A JSONLines stream ends with a line break, and holds nothing if there are no documents
+func StreamToJSON(documents []*DocumentNode, opts JSONOptions) ([]byte, error) {
+	var b bytes.Buffer
+	if opts.Stream == JSONLines {
+		opts.Indent = ""
+		for i, document := range documents {
+			converted, err := ToJSON(document, opts)
+			if err != nil {
+				return nil, fmt.Errorf("document %d: %w", i, err)
+			}
+			b.Write(converted)
+			b.WriteByte('\n')
+		}
+		return b.Bytes(), nil
+	}
+
+	array := NewSequenceNode()
+	for _, document := range documents {
+		array.items = append(array.items, documentRoot(document))
+	}
+	return ToJSON(array, opts)
+}
+
+// FromJSON reads the stream of JSON values from r, e.g., a single JSON text or JSON Lines, as documents.
+//
+// Values are decoded token by token, keeping the order of object members, and numbers keep their exact notation.
+// Of the members of an object sharing a name, only the last one is kept, at its own position, as with encoding/json.
+// Strings that would be resolved to another type as plain scalars, e.g., "true" or "123",
+// or that YAML 1.1 would read as a boolean or a number, e.g., "yes" or "1_000", are double-quoted
+func FromJSON(r io.Reader) ([]*DocumentNode, error) {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+
+	var documents []*DocumentNode
+	for decoder.More() {
+		n, err := decodeJSONValue(decoder)
+		if err != nil {
+			return nil, fmt.Errorf("invalid json value %d at offset %d: %w", len(documents), decoder.InputOffset(), err)
+		}
+		documents = append(documents, &DocumentNode{root: n})
+	}
+	if t, err := decoder.Token(); err != io.EOF {
+		if err == nil {
+			err = fmt.Errorf("unexpected %v", t)
+		}
+		return nil, fmt.Errorf("invalid json at offset %d: %w", decoder.InputOffset(), err)
+	}
+	return documents, nil
+}
+
+// JSONToYAML converts the stream of JSON values read from r to YAML documents written to w in block style
+func JSONToYAML(w io.Writer, r io.Reader) error {
+	documents, err := FromJSON(r)
+	if err != nil {
+		return err
+	}
+	emitter := NewEmitter(w)
+	for _, document := range documents {
+		if err = emitter.Emit(document); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newJSONConverter(opts JSONOptions) *jsonConverter {
+	return &jsonConverter{opts: opts, enclosing: make(map[Node]bool), expansion: newAliasExpansion(opts.MaxAliasExpansion)}
+}
+
+func (c *jsonConverter) write(w io.Writer, path Path, n Node) error {
+	target := resolveAlias(n)
+	if target == nil {
+		_, err := io.WriteString(w, "null")
+		return err
+	}
+	if _, unresolved := target.(*AliasNode); unresolved || c.enclosing[target] {
+		return c.fail(path, n, ErrRecursiveAlias)
+	}
+	if err := c.expansion.expand(n); err != nil {
+		return c.fail(path, n, err)
+	}
+
+	switch target := target.(type) {
+	case *ScalarNode:
+		text, err := c.scalar(target)
+		if err != nil {
+			return c.fail(path, target, err)
+		}
+		_, err = io.WriteString(w, text)
+		return err
+
+	case *SequenceNode:
+		c.enclosing[target] = true
+		defer delete(c.enclosing, target)
+
+		io.WriteString(w, "[")
+		for i, item := range target.items {
+			if i > 0 {
+				io.WriteString(w, ",")
+			}
+			if err := c.write(w, path.append(PathSegment{Index: i}), item); err != nil {
+				return err
+			}
+		}
+		_, err := io.WriteString(w, "]")
+		return err
+
+	case *MappingNode:
+		c.enclosing[target] = true
+		defer delete(c.enclosing, target)
+
+		members, err := c.members(path, target)
+		if err != nil {
+			return err
+		}
+		io.WriteString(w, "{")
+		for i, member := range members {
+			if i > 0 {
+				io.WriteString(w, ",")
+			}
+			name, _ := json.Marshal(member.name)
+			w.Write(append(name, ':'))
+			if err = c.write(w, path.append(PathSegment{Key: member.key, Index: i}), member.value); err != nil {
+				return err
+			}
+		}
+		_, err = io.WriteString(w, "}")
+		return err
+	}
+	return c.fail(path, n, fmt.Errorf("can not convert node of type %T", n))
+}
+
+// scalar converts n to a JSON literal, number or string
+func (c *jsonConverter) scalar(n *ScalarNode) (string, error) {
+	switch n.Tag() {
+	case TagNull:
+		return "null", nil
+
+	case TagBool:
+		return canonicalScalar(n), nil
+
+	case TagInt:
+		if _, ok := parseInt(n.value); ok {
+			return canonicalScalar(n), nil
+		}
+
+	case TagFloat:
+		value := canonicalScalar(n)
+		switch value {
+		case ".inf", "-.inf", ".nan":
+			return c.nonFinite(value)
+		}
+		if json.Valid([]byte(value)) {
+			return value, nil
+		}
+	}
+
+	text, err := json.Marshal(n.value)
+	return string(text), err
+}
+
+func (c *jsonConverter) nonFinite(value string) (string, error) {
+	switch c.opts.NonFinite {
+	case NonFiniteNull:
+		return "null", nil
+	case NonFiniteString:
+		return map[string]string{".inf": `"Infinity"`, "-.inf": `"-Infinity"`, ".nan": `"NaN"`}[value], nil
+	default:
+		return "", fmt.Errorf("%s: %w", value, ErrNonFinite)
+	}
+}
+
+// members returns the members of the JSON object converted from n, expanding its merge keys
+func (c *jsonConverter) members(path Path, n *MappingNode) ([]jsonMember, error) {
+	defined := make(map[string]bool, len(n.pairs))
+	var members []jsonMember
+	add := func(member jsonMember) {
+		if !defined[member.name] {
+			defined[member.name] = true
+			members = append(members, member)
+		}
+	}
+
+	// keys defined by the mapping itself override the merged ones, wherever they are
+	for i, pair := range n.pairs {
+		if isMergeKey(pair.Key) {
+			continue
+		}
+		name, err := c.key(path.append(PathSegment{Key: pair.Key, Index: i, IsKey: true}), pair.Key)
+		if err != nil {
+			return nil, err
+		}
+		defined[name] = false
+	}
+
+	for i, pair := range n.pairs {
+		if !isMergeKey(pair.Key) {
+			name, _ := c.key(nil, pair.Key)
+			add(jsonMember{name: name, key: pair.Key, value: pair.Value})
+			continue
+		}
+
+		merged, err := c.merged(path.append(PathSegment{Key: pair.Key, Index: i}), pair.Value)
+		if err != nil {
+			return nil, err
+		}
+		for _, member := range merged {
+			if _, explicit := defined[member.name]; !explicit {
+				add(member)
+			}
+		}
+	}
+	return members, nil
+}
+
+// merged returns the members a merge key merges: those of a mapping, or those of a sequence of mappings,
+// where the first mappings take precedence
+func (c *jsonConverter) merged(path Path, value Node) ([]jsonMember, error) {
+	if err := c.expansion.expand(value); err != nil {
+		return nil, c.fail(path, value, err)
+	}
+	sources := []Node{value}
+	if sequence, ok := resolveAlias(value).(*SequenceNode); ok {
+		sources = sequence.items
+	}
+
+	var members []jsonMember
+	seen := make(map[string]bool)
+	for _, source := range sources {
+		mapping, ok := resolveAlias(source).(*MappingNode)
+		if !ok {
+			return nil, c.fail(path, source, ErrInvalidMerge)
+		}
+		if c.enclosing[mapping] {
+			return nil, c.fail(path, source, ErrRecursiveAlias)
+		}
+		if source != value {
+			if err := c.expansion.expand(source); err != nil {
+				return nil, c.fail(path, source, err)
+			}
+		}
+
+		c.enclosing[mapping] = true
+		sourceMembers, err := c.members(path, mapping)
+		delete(c.enclosing, mapping)
+		if err != nil {
+			return nil, err
+		}
+		for _, member := range sourceMembers {
+			if !seen[member.name] {
+				seen[member.name] = true
+				members = append(members, member)
+			}
+		}
+	}
+	return members, nil
+}
+
+// key converts a mapping key to the name of a JSON object member
+func (c *jsonConverter) key(path Path, n Node) (string, error) {
+	if scalar, ok := resolveAlias(n).(*ScalarNode); ok && scalar.Tag() == TagString {
+		return scalar.value, nil
+	}
+	if !c.opts.StringifyKeys {
+		return "", c.fail(path, n, ErrNonStringKey)
+	}
+
+	switch key := resolveAlias(n).(type) {
+	case *ScalarNode:
+		return canonicalScalar(key), nil
+	default:
+		text, err := NewEmitter(io.Discard).inline(key, true)
+		if err != nil {
+			return "", c.fail(path, n, err)
+		}
+		return text, nil
+	}
+}
+
+func (c *jsonConverter) fail(path Path, n Node, err error) error {
+	var conversionErr *ConversionError
+	if errors.As(err, &conversionErr) {
+		return err
+	}
+	return &ConversionError{Position: n.Position(), Path: path, Err: err}
+}
+
+// isMergeKey checks that n is the merge key <<
+func isMergeKey(n Node) bool {
+	scalar, ok := resolveAlias(n).(*ScalarNode)
+	return ok && scalar.Tag() == TagMerge
+}
+
+// decodeJSON decodes the JSON value data into a node tree, keeping the order of object members
+func decodeJSON(data []byte) (Node, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	n, err := decodeJSONValue(decoder)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = decoder.Token(); err != io.EOF {
+		return nil, fmt.Errorf("unexpected data after json value at offset %d", decoder.InputOffset())
+	}
+	return n, nil
+}
+
+func decodeJSONValue(decoder *json.Decoder) (Node, error) {
+	t, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := t.(type) {
+	case json.Delim:
+		if t == '[' {
+			sequence := NewSequenceNode()
+			for decoder.More() {
+				item, err := decodeJSONValue(decoder)
+				if err != nil {
+					return nil, err
+				}
+				sequence.items = append(sequence.items, item)
+			}
+			_, err = decoder.Token()
+			return sequence, err
+		}
+
+		mapping := NewMappingNode()
+		index := make(map[string]int)
+		for decoder.More() {
+			key, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeJSONValue(decoder)
+			if err != nil {
+				return nil, err
+			}
+			name := key.(string)
+			if i, duplicate := index[name]; duplicate {
+				// the last of duplicate members wins, as with encoding/json
+				mapping.pairs[i].Key = nil
+			}
+			index[name] = len(mapping.pairs)
+			mapping.pairs = append(mapping.pairs, MappingPair{Key: newStringNode(name), Value: value})
+		}
+		if len(index) < len(mapping.pairs) {
+			mapping.pairs = slices.DeleteFunc(mapping.pairs, func(pair MappingPair) bool { return pair.Key == nil })
+		}
+		_, err = decoder.Token()
+		return mapping, err
+
+	case string:
+		return newStringNode(t), nil
+	case json.Number:
+		return NewScalarNode(t.String()), nil
+	case bool:
+		return NewScalarNode(strconv.FormatBool(t)), nil
+	default:
+		return NewScalarNode("null"), nil
+	}
+}
+
+func (e *ConversionError) Error() string {
+	return fmt.Sprintf("can not convert node at %s (%s) to json: %v", e.Position, e.Path, e.Err)
+}
+
+func (e *ConversionError) Unwrap() error {
+	return e.Err
+}
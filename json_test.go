@@ -0,0 +1,228 @@
+package yaml_test
+
+import (
+	"errors"
+	"github.com/ercross/yaml"
+	"github.com/ercross/yaml/parser"
+	"strings"
+	"testing"
+)
+
+func TestToJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		opts     yaml.JSONOptions
+		expected string
+	}{
+		{
+			name:     "scalar types",
+			source:   "a: ~\nb: True\nc: 0x1F\nd: 0o17\ne: 017\nf: 1.50\ng: '1'\nh: text\ni: !!str 2\n",
+			expected: `{"a":null,"b":true,"c":31,"d":15,"e":17,"f":1.5,"g":"1","h":"text","i":"2"}`,
+		},
+		{
+			name:     "large integers keep their digits",
+			source:   "- 123456789012345678901234567890\n- 1e3\n",
+			expected: `[123456789012345678901234567890,1000]`,
+		},
+		{
+			name:     "non-finite floats to null",
+			source:   "[.inf, -.inf, .nan]",
+			opts:     yaml.JSONOptions{NonFinite: yaml.NonFiniteNull},
+			expected: `[null,null,null]`,
+		},
+		{
+			name:     "non-finite floats to strings",
+			source:   "[.inf, -.Inf, .NaN]",
+			opts:     yaml.JSONOptions{NonFinite: yaml.NonFiniteString},
+			expected: `["Infinity","-Infinity","NaN"]`,
+		},
+		{
+			name:     "aliases are expanded",
+			source:   "base: &b [1, 2]\nuse: *b\n",
+			expected: `{"base":[1,2],"use":[1,2]}`,
+		},
+		{
+			name:     "merge keys",
+			source:   "defaults: &d\n  a: 1\n  b: 2\nother: &o\n  b: 3\n  c: 4\nuse:\n  b: 0\n  <<: [*d, *o]\n  e: 5\n",
+			expected: `{"defaults":{"a":1,"b":2},"other":{"b":3,"c":4},"use":{"b":0,"a":1,"c":4,"e":5}}`,
+		},
+		{
+			name:     "stringified keys",
+			source:   "1: a\ntrue: b\n~: c\n",
+			opts:     yaml.JSONOptions{StringifyKeys: true},
+			expected: `{"1":"a","true":"b","null":"c"}`,
+		},
+		{
+			name:     "indent",
+			source:   "a: [1]\n",
+			opts:     yaml.JSONOptions{Indent: "  "},
+			expected: "{\n  \"a\": [\n    1\n  ]\n}",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual, err := yaml.ToJSON(parseDocument(t, test.source), test.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(actual) != test.expected {
+				t.Errorf("expected %s, got %s", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestToJSON_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected error
+		line     int
+	}{
+		{name: "non-finite float", source: "a:\n  b: .inf\n", expected: yaml.ErrNonFinite, line: 2},
+		{name: "non-string key", source: "a: 1\n2: b\n", expected: yaml.ErrNonStringKey, line: 2},
+		{name: "invalid merge", source: "a: 1\n<<: [x]\n", expected: yaml.ErrInvalidMerge, line: 2},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := yaml.ToJSON(parseDocument(t, test.source), yaml.JSONOptions{})
+			if !errors.Is(err, test.expected) {
+				t.Fatalf("expected %v, got %v", test.expected, err)
+			}
+			var conversionErr *yaml.ConversionError
+			if !errors.As(err, &conversionErr) {
+				t.Fatalf("expected a ConversionError, got %T", err)
+			}
+			if line := conversionErr.Position.Line(); line != test.line {
+				t.Errorf("expected the error on line %d, got %d", test.line, line)
+			}
+		})
+	}
+}
+
+func TestToJSON_AliasExpansion(t *testing.T) {
+	// merging *a into b expands a, its key and its value, and every *b expands b and its pair, then a again: 3 + 2 * 6
+	document := parseDocument(t, "a: &a {x: 1}\nb: &b {<<: *a}\nc: [*b, *b]\n")
+	tests := []struct {
+		limit    int
+		expected error
+	}{
+		{limit: 14, expected: yaml.ErrMaxAliasExpansion},
+		{limit: 15},
+		{limit: -1},
+	}
+	for _, test := range tests {
+		_, err := yaml.ToJSON(document, yaml.JSONOptions{MaxAliasExpansion: test.limit})
+		var limitErr *yaml.LimitError
+		if !errors.Is(err, test.expected) || test.expected != nil && !errors.As(err, &limitErr) {
+			t.Errorf("limit %d: expected %v, got %v", test.limit, test.expected, err)
+		}
+	}
+}
+
+func TestStreamToJSON(t *testing.T) {
+	ast, err := parser.Parse(strings.NewReader("a: 1\n---\n- x\n---\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	documents := ast.Documents()
+
+	array, err := yaml.StreamToJSON(documents, yaml.JSONOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `[{"a":1},["x"],null]`; string(array) != expected {
+		t.Errorf("expected %s, got %s", expected, array)
+	}
+
+	lines, err := yaml.StreamToJSON(documents, yaml.JSONOptions{Stream: yaml.JSONLines})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "{\"a\":1}\n[\"x\"]\nnull\n"; string(lines) != expected {
+		t.Errorf("expected %q, got %q", expected, lines)
+	}
+}
+
+func TestJSONToYAML(t *testing.T) {
+	source := `{"name":"app","replicas":3,"ratio":1.0e5,"spec":{"ports":[80,"443"],"labels":{}},` +
+		`"flags":["true","null","","0x1F",null],"notes":"line\nbreak"}` + "\n" + `["second"]`
+	expected := "name: app\nreplicas: 3\nratio: 1.0e5\nspec:\n  ports:\n    - 80\n    - \"443\"\n  labels: {}\n" +
+		"flags:\n  - \"true\"\n  - \"null\"\n  - \"\"\n  - \"0x1F\"\n  - null\nnotes: |-\n  line\n  break\n---\n- second\n"
+
+	var b strings.Builder
+	if err := yaml.JSONToYAML(&b, strings.NewReader(source)); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != expected {
+		t.Errorf("expected %q, got %q", expected, b.String())
+	}
+}
+
+func TestJSONToYAML_YAML11Scalars(t *testing.T) {
+	source := `["yes","On","n","OFF","1_000","12:30","0b101","1.5_0","yesterday","10:70"]`
+	expected := "- \"yes\"\n- \"On\"\n- \"n\"\n- \"OFF\"\n- \"1_000\"\n- \"12:30\"\n- \"0b101\"\n- \"1.5_0\"\n- yesterday\n- 10:70\n"
+
+	var b strings.Builder
+	if err := yaml.JSONToYAML(&b, strings.NewReader(source)); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != expected {
+		t.Errorf("expected %q, got %q", expected, b.String())
+	}
+
+	mapping := yaml.NewMappingNode()
+	mapping.Set("on", yaml.NewScalarNode("push"))
+	if actual := emit(t, mapping); actual != "'on': push\n" {
+		t.Errorf("expected a created key to be quoted, got %q", actual)
+	}
+	if actual := emit(t, parseDocument(t, "on: push\n")); actual != "on: push\n" {
+		t.Errorf("expected a plain key to be kept, got %q", actual)
+	}
+}
+
+func TestFromJSON_DuplicateMembers(t *testing.T) {
+	var b strings.Builder
+	if err := yaml.JSONToYAML(&b, strings.NewReader(`{"a":1,"b":{"c":1,"c":2},"a":3}`)); err != nil {
+		t.Fatal(err)
+	}
+	expected := "b:\n  c: 2\na: 3\n"
+	if b.String() != expected {
+		t.Errorf("expected %q, got %q", expected, b.String())
+	}
+	if _, err := parser.Parse(strings.NewReader(b.String())); err != nil {
+		t.Errorf("expected the output to parse, got %v", err)
+	}
+}
+
+func TestFromJSON_RoundTrip(t *testing.T) {
+	source := `{"z":1,"a":["1",true,"false",-0.5,{"b":null}],"m":""}`
+	documents, err := yaml.FromJSON(strings.NewReader(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(documents) != 1 {
+		t.Fatalf("expected 1 document, got %d", len(documents))
+	}
+
+	var b strings.Builder
+	if err = yaml.NewEmitter(&b).Emit(documents[0]); err != nil {
+		t.Fatal(err)
+	}
+	actual, err := yaml.ToJSON(parseDocument(t, b.String()), yaml.JSONOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(actual) != source {
+		t.Errorf("expected %s, got %s from %q", source, actual, b.String())
+	}
+}
+
+func TestFromJSON_Invalid(t *testing.T) {
+	for _, source := range []string{`{"a":}`, `[1, 2`, `]`} {
+		if _, err := yaml.FromJSON(strings.NewReader(source)); err == nil {
+			t.Errorf("expected an error for %q", source)
+		}
+	}
+}
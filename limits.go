@@ -0,0 +1,68 @@
+package yaml
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ercross/yaml/token"
+)
+
+// ErrMaxAliasExpansion is returned when the aliases of a tree expand to more nodes than allowed, see DecodeOptions
+var ErrMaxAliasExpansion = errors.New("maximum alias expansion exceeded")
+
+// DefaultMaxAliasExpansion is the number of nodes the aliases of a tree may expand to by default,
+// when it is decoded, converted to JSON or validated against a schema. It stops a few nested aliases
+// from expanding to billions of nodes, e.g., in a "billion laughs" document
+const DefaultMaxAliasExpansion = 1_000_000
+
+type (
+	// LimitError reports a limit exceeded while reading a stream or expanding the aliases of a tree, and where.
+	// Err is ErrMaxAliasExpansion, or one of the errors of the limits of the parser, e.g., parser.ErrMaxDepth
+	LimitError struct {
+		Err      error
+		Max      int
+		Position token.Location
+	}
+
+	// aliasExpansion counts the nodes the aliases of a tree expand to as the tree is walked, every time an alias
+	// is expanded, the nodes of the tree it refers to counting once, the aliases within that tree included
+	aliasExpansion struct {
+		max   int
+		nodes int
+	}
+)
+
+// newAliasExpansion limits the nodes aliases expand to to max: 0 is DefaultMaxAliasExpansion,
+// and a negative max is no limit
+func newAliasExpansion(max int) *aliasExpansion {
+	if max == 0 {
+		max = DefaultMaxAliasExpansion
+	}
+	return &aliasExpansion{max: max}
+}
+
+// expand counts the nodes of the tree the alias n refers to, as n is expanded, and fails once the limit is exceeded.
+// n is ignored unless it is an alias
+func (e *aliasExpansion) expand(n Node) error {
+	if _, alias := n.(*AliasNode); !alias || e.max < 0 {
+		return nil
+	}
+	for range All(resolveAlias(n)) {
+		e.nodes++
+		if e.nodes > e.max {
+			return &LimitError{Err: ErrMaxAliasExpansion, Max: e.max, Position: n.Position()}
+		}
+	}
+	return nil
+}
+
+func (e *LimitError) Error() string {
+	if e.Position.Line() == 0 {
+		return fmt.Sprintf("%v (limit %d)", e.Err, e.Max)
+	}
+	return fmt.Sprintf("%v at %s (limit %d)", e.Err, e.Position, e.Max)
+}
+
+func (e *LimitError) Unwrap() error {
+	return e.Err
+}
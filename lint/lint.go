@@ -0,0 +1,206 @@
+// Package lint checks YAML files against configurable rules, in the spirit of yamllint, reporting the position
+// and severity of every problem found.
+//
+// Every rule is a struct holding its Severity and options, e.g., LineLength{Severity: SeverityWarning, Max: 120}.
+// DefaultRules returns every rule with its default options:
+//
+//	line-length      lines longer than Max characters
+//	trailing-spaces  spaces or tabs at the end of lines
+//	indentation      block collections not indented by one indentation unit within their parent, or block
+//	                 sequences within mappings indented unlike the first of them
+//	truthy           plain scalars such as yes or on, read as booleans by YAML 1.1 and as strings by YAML 1.2
+//	key-duplicates   keys defined twice in the same mapping
+//	document-start   documents without the --- marker
+//	comments         comments not starting with a space, or too close to the content preceding them
+//	empty-values     mapping keys without a value
+//	key-ordering     mapping keys not sorted
+//	octal-values     integers such as 0755, octal in YAML 1.1 and decimal in YAML 1.2
+//
+// A file that can not be parsed is reported as a single problem of the syntax rule, along with the problems found
+// by the other rules. The rules reading events read the tokens of the file instead, finding its collections
+// from their indentation and brackets, except key-duplicates and document-start, which do not check it.
+//
+// Problems are written as text with WriteText, as JSON with WriteJSON, and as a SARIF log with WriteSARIF
+package lint
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"github.com/ercross/yaml"
+	"github.com/ercross/yaml/parser"
+	"github.com/ercross/yaml/token"
+	"github.com/ercross/yaml/tokenizer"
+	"slices"
+	"strings"
+)
+
+// syntaxRule is the name of the problems reporting files that can not be parsed
+const syntaxRule = "syntax"
+
+type (
+	// Severity is the severity of the problems a Rule reports
+	Severity int
+
+	// Problem is a violation of a Rule found in a file
+	Problem struct {
+		File     string
+		Rule     string
+		Severity Severity
+		Position token.Location
+		Message  string
+	}
+
+	// Rule checks a file, see the rules of DefaultRules
+	Rule interface {
+		// Name identifies the rule in problems, e.g., line-length
+		Name() string
+
+		// Description describes what the rule checks
+		Description() string
+
+		severity() Severity
+		check(s *source, report reporter)
+	}
+
+	// Linter checks files against its Rules
+	Linter struct {
+		Rules []Rule
+	}
+
+	// reporter reports a problem found by a rule at position
+	reporter func(position token.Location, format string, args ...any)
+
+	// source is a file being linted, as lines, tokens, events and nodes
+	source struct {
+		// lines are the lines of the file, without their line break
+		lines  []string
+		tokens []token.Token
+
+		// events and tree are nil if the file can not be parsed. The tree only keeps the last pair
+		// of the keys defined several times in a mapping, while the events hold every pair
+		events []yaml.Event
+		tree   *parser.AbstractSyntaxTree
+	}
+)
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+)
+
+var severityNames = map[Severity]string{
+	SeverityError:   "error",
+	SeverityWarning: "warning",
+}
+
+// DefaultRules returns every rule, with its default options
+func DefaultRules() []Rule {
+	return []Rule{
+		LineLength{Severity: SeverityError, Max: 80, AllowNonBreakableWords: true},
+		TrailingSpaces{Severity: SeverityError},
+		Indentation{Severity: SeverityError},
+		Truthy{Severity: SeverityWarning, Allowed: []string{"true", "false"}},
+		KeyDuplicates{Severity: SeverityError},
+		DocumentStart{Severity: SeverityWarning},
+		Comments{Severity: SeverityWarning, RequireStartingSpace: true, MinSpacesFromContent: 2},
+		EmptyValues{Severity: SeverityWarning},
+		KeyOrdering{Severity: SeverityWarning},
+		OctalValues{Severity: SeverityError, ForbidImplicit: true},
+	}
+}
+
+// Lint checks the file name, holding src, against DefaultRules
+func Lint(name string, src []byte) []Problem {
+	return (&Linter{Rules: DefaultRules()}).Lint(name, src)
+}
+
+// Lint checks the file name, holding src, against the rules of l, and returns the problems found in source order
+func (l *Linter) Lint(name string, src []byte) []Problem {
+	s, err := newSource(src)
+
+	var problems []Problem
+	if err != nil {
+		line := 1
+		var parseErr *parser.ParseError
+		if errors.As(err, &parseErr) {
+			line = max(1, min(parseErr.Line, len(s.lines)))
+		}
+		problems = append(problems, Problem{
+			File:     name,
+			Rule:     syntaxRule,
+			Severity: SeverityError,
+			Position: token.NewLocation(line, 1),
+			Message:  err.Error(),
+		})
+	}
+
+	for _, rule := range l.Rules {
+		rule.check(s, func(position token.Location, format string, args ...any) {
+			problems = append(problems, Problem{
+				File:     name,
+				Rule:     rule.Name(),
+				Severity: rule.severity(),
+				Position: position,
+				Message:  fmt.Sprintf(format, args...),
+			})
+		})
+	}
+
+	slices.SortStableFunc(problems, func(a, b Problem) int {
+		return cmp.Or(cmp.Compare(a.Position.Line(), b.Position.Line()), cmp.Compare(a.Position.Column(), b.Position.Column()))
+	})
+	return problems
+}
+
+// newSource splits src into lines and tokens, and parses it. If src can not be parsed, newSource returns
+// the source holding its lines and the tokens preceding the error, along with the error
+func newSource(src []byte) (*source, error) {
+	text := strings.ReplaceAll(string(src), "\r\n", "\n")
+	s := &source{lines: strings.Split(strings.TrimSuffix(text, "\n"), "\n")}
+	if text == "" {
+		s.lines = nil
+	}
+
+	t := tokenizer.New()
+	for i, line := range s.lines {
+		tokens, err := t.Tokenize(line+"\n", i+1)
+		if err != nil {
+			break
+		}
+		s.tokens = append(s.tokens, tokens...)
+	}
+
+	// duplicate keys are reported by the key-duplicates rule rather than as syntax errors,
+	// and the tree is composed from the events kept as they are parsed
+	var events []yaml.Event
+	tree, err := parser.ParseWithOptions(bytes.NewReader(src), parser.Options{
+		DuplicateKeys: yaml.DuplicateKeyWarn,
+		Events: func(event yaml.Event) error {
+			events = append(events, event)
+			return nil
+		},
+	})
+	if err != nil {
+		return s, err
+	}
+	s.tree, s.events = tree, events
+	return s, nil
+}
+
+func (s Severity) String() string {
+	if name, ok := severityNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// String renders p as file:line:column: [severity] message (rule)
+func (p Problem) String() string {
+	return fmt.Sprintf("%s:%d:%d: [%s] %s (%s)", p.File, p.Position.Line(), p.Position.Column(), p.Severity, p.Message, p.Rule)
+}
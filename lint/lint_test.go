@@ -0,0 +1,336 @@
+package lint
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestRules(t *testing.T) {
+	tests := []struct {
+		name     string
+		rule     Rule
+		src      string
+		expected []string
+	}{
+		{
+			name: "line too long",
+			rule: LineLength{Max: 20},
+			src:  "key: a value longer than twenty characters\nshort: value\n",
+			expected: []string{
+				"test.yaml:1:21: [error] line too long (42 > 20 characters) (line-length)",
+			},
+		},
+		{
+			name: "non-breakable words",
+			rule: LineLength{Max: 20, AllowNonBreakableWords: true},
+			src:  "urls:\n- https://example.com/a/very/long/path\n# https://example.com/a/very/long/path\nkey: https://example.com/a/very/long/path\n",
+			expected: []string{
+				"test.yaml:4:21: [error] line too long (41 > 20 characters) (line-length)",
+			},
+		},
+		{
+			name: "trailing spaces",
+			rule: TrailingSpaces{Severity: SeverityWarning},
+			src:  "a: 1  \nb: |\n  text\t\n",
+			expected: []string{
+				"test.yaml:1:5: [warning] trailing spaces (trailing-spaces)",
+				"test.yaml:3:7: [warning] trailing spaces (trailing-spaces)",
+			},
+		},
+		{
+			name: "indentation of the document",
+			rule: Indentation{},
+			src:  "a:\n  b:\n      c: 1\n  d:\n  - 1\n  e:\n    - 1\n    -\n      f: 1\n",
+			expected: []string{
+				"test.yaml:3:7: [error] wrong indentation: expected 2 spaces, found 4 (indentation)",
+				"test.yaml:7:5: [error] wrong indentation: expected 0 spaces, found 2 (indentation)",
+			},
+		},
+		{
+			name: "indentation of sequences",
+			rule: Indentation{},
+			src:  "a:\n  - 1\nb:\n- 2\nc:\n  - 3\n",
+			expected: []string{
+				"test.yaml:4:1: [error] wrong indentation: expected 2 spaces, found 0 (indentation)",
+			},
+		},
+		{
+			name: "indentation widths",
+			rule: Indentation{},
+			src:  "a:\n    b:\n        c: 1\nd:\n   - e:\n      f: 1\n",
+			expected: []string{
+				"test.yaml:5:1: [error] failed to push frame on stack near line 5: inconsistent indentation level: " +
+					"indentation must be a multiple of indentationLevelModuloFactor (syntax)",
+				"test.yaml:5:4: [error] wrong indentation: expected 4 spaces, found 3 (indentation)",
+				"test.yaml:6:7: [error] wrong indentation: expected 4 spaces, found 1 (indentation)",
+			},
+		},
+		{
+			name: "indentation of spaces",
+			rule: Indentation{Spaces: 2, IndentSequences: true},
+			src:  "a:\n    b:\n        c: 1\n    d:\n    - 1\n",
+			expected: []string{
+				"test.yaml:2:5: [error] wrong indentation: expected 2 spaces, found 4 (indentation)",
+				"test.yaml:3:9: [error] wrong indentation: expected 2 spaces, found 4 (indentation)",
+				"test.yaml:5:5: [error] wrong indentation: expected 2 spaces, found 0 (indentation)",
+			},
+		},
+		{
+			name: "truthy values",
+			rule: Truthy{Allowed: []string{"true", "false"}},
+			src:  "enabled: yes\non: push\nquoted: 'no'\nvalid: true\n",
+			expected: []string{
+				`test.yaml:1:10: [error] truthy value "yes" should be one of [false, true] (truthy)`,
+				`test.yaml:2:1: [error] truthy value "on" should be one of [false, true] (truthy)`,
+			},
+		},
+		{
+			name: "duplicate keys",
+			rule: KeyDuplicates{},
+			src:  "base: &base {a: 1}\nb:\n  <<: *base\n  <<: {c: 1}\n  a: 1\n  a: 2\n  'a': 3\n",
+			expected: []string{
+				`test.yaml:6:3: [error] duplicate key "a", first defined at line(5): column(3) (key-duplicates)`,
+				`test.yaml:7:3: [error] duplicate key "a", first defined at line(5): column(3) (key-duplicates)`,
+			},
+		},
+		{
+			name: "document start",
+			rule: DocumentStart{},
+			src:  "a: 1\n---\nb: 1\n...\n# comment\n",
+			expected: []string{
+				`test.yaml:1:1: [error] missing document start "---" (document-start)`,
+			},
+		},
+		{
+			name: "comments",
+			rule: Comments{RequireStartingSpace: true, MinSpacesFromContent: 2},
+			src:  "#!/usr/bin/env yaml\n#comment\n###\na: 1 # close\nb: '#' # text\nc: 1  #far\n",
+			expected: []string{
+				"test.yaml:2:1: [error] missing starting space in comment (comments)",
+				"test.yaml:4:6: [error] too few spaces before comment: expected 2, found 1 (comments)",
+				"test.yaml:5:8: [error] too few spaces before comment: expected 2, found 1 (comments)",
+				"test.yaml:6:7: [error] missing starting space in comment (comments)",
+			},
+		},
+		{
+			name: "empty values",
+			rule: EmptyValues{},
+			src:  "a:\nb: null\nc: ~\nd: ''\ne: {f: }\n",
+			expected: []string{
+				`test.yaml:1:1: [error] empty value for key "a" (empty-values)`,
+			},
+		},
+		{
+			name: "empty values in flow mappings",
+			rule: EmptyValues{ForbidInFlowMappings: true},
+			src:  "e: {f: , g: 1}\n",
+			expected: []string{
+				`test.yaml:1:5: [error] empty value for key "f" (empty-values)`,
+			},
+		},
+		{
+			name: "key ordering",
+			rule: KeyOrdering{},
+			src:  "b: 1\na: 1\nc:\n  <<: {z: 1}\n  x: 1\n  y: 1\nd: [{b: 1, a: 1}]\n",
+			expected: []string{
+				`test.yaml:2:1: [error] wrong ordering of key "a", which should precede "b" (key-ordering)`,
+				`test.yaml:7:12: [error] wrong ordering of key "a", which should precede "b" (key-ordering)`,
+			},
+		},
+		{
+			name: "octal values",
+			rule: OctalValues{ForbidImplicit: true, ForbidExplicit: true},
+			src:  "mode: 0755\nexplicit: 0o644\nquoted: '0755'\ndecimal: 0\nnine: 0789\n",
+			expected: []string{
+				`test.yaml:1:7: [error] forbidden implicit octal value "0755" (octal-values)`,
+				`test.yaml:2:11: [error] forbidden explicit octal value "0o644" (octal-values)`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problems := (&Linter{Rules: []Rule{tt.rule}}).Lint("test.yaml", []byte(tt.src))
+			if actual := problemStrings(problems); !slices.Equal(actual, tt.expected) {
+				t.Errorf("expected problems:\n%s\ngot:\n%s", strings.Join(tt.expected, "\n"), strings.Join(actual, "\n"))
+			}
+		})
+	}
+}
+
+func TestLint(t *testing.T) {
+	src := "---\n# settings\nmode: 644\nname: service\nports:\n  - 80\n  - 443\n"
+	if problems := Lint("service.yaml", []byte(src)); len(problems) > 0 {
+		t.Errorf("expected no problem, got:\n%s", strings.Join(problemStrings(problems), "\n"))
+	}
+
+	problems := Lint("service.yaml", []byte("---\nenabled: on   \nname: service\n"))
+	expected := []string{
+		`service.yaml:2:10: [warning] truthy value "on" should be one of [false, true] (truthy)`,
+		"service.yaml:2:12: [error] trailing spaces (trailing-spaces)",
+	}
+	if actual := problemStrings(problems); !slices.Equal(actual, expected) {
+		t.Errorf("expected problems:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(actual, "\n"))
+	}
+}
+
+func TestLint_DuplicateKeys(t *testing.T) {
+	// the pairs of the duplicate keys are checked, although only the last one is kept
+	problems := Lint("duplicates.yaml", []byte("---\nz: yes\na: 0755\nz: 1\nb:\nb: 2\n"))
+	expected := []string{
+		`duplicates.yaml:2:4: [warning] truthy value "yes" should be one of [false, true] (truthy)`,
+		`duplicates.yaml:3:1: [warning] wrong ordering of key "a", which should precede "z" (key-ordering)`,
+		`duplicates.yaml:3:4: [error] forbidden implicit octal value "0755" (octal-values)`,
+		`duplicates.yaml:4:1: [error] duplicate key "z", first defined at line(2): column(1) (key-duplicates)`,
+		`duplicates.yaml:5:1: [warning] empty value for key "b" (empty-values)`,
+		`duplicates.yaml:5:1: [warning] wrong ordering of key "b", which should precede "z" (key-ordering)`,
+		`duplicates.yaml:6:1: [error] duplicate key "b", first defined at line(5): column(1) (key-duplicates)`,
+		`duplicates.yaml:6:1: [warning] wrong ordering of key "b", which should precede "z" (key-ordering)`,
+	}
+	if actual := problemStrings(problems); !slices.Equal(actual, expected) {
+		t.Errorf("expected problems:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(actual, "\n"))
+	}
+}
+
+func TestLint_SyntaxError(t *testing.T) {
+	problems := Lint("broken.yaml", []byte("---\na:\n  b: 1\n c: 2  \n"))
+	expected := []string{
+		"broken.yaml:4:1: [error] indentation of line 4 does not match any enclosing node: inconsistent indentation (syntax)",
+		"broken.yaml:4:6: [error] trailing spaces (trailing-spaces)",
+	}
+	if actual := problemStrings(problems); !slices.Equal(actual, expected) {
+		t.Errorf("expected problems:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(actual, "\n"))
+	}
+}
+
+func TestLint_SyntaxErrorTokens(t *testing.T) {
+	rules := DefaultRules()
+	for i, rule := range rules {
+		if _, ok := rule.(EmptyValues); ok {
+			rules[i] = EmptyValues{Severity: SeverityWarning, ForbidInFlowMappings: true}
+		}
+	}
+	src := "---\nb: yes\na:\n    x: 0755\n    w:\nc:\n   u: [1, {z: , v: 1}]\n"
+	problems := (&Linter{Rules: rules}).Lint("broken.yaml", []byte(src))
+	expected := []string{
+		"broken.yaml:2:4: [warning] truthy value \"yes\" should be one of [false, true] (truthy)",
+		`broken.yaml:3:1: [warning] wrong ordering of key "a", which should precede "b" (key-ordering)`,
+		`broken.yaml:4:8: [error] forbidden implicit octal value "0755" (octal-values)`,
+		`broken.yaml:5:5: [warning] empty value for key "w" (empty-values)`,
+		`broken.yaml:5:5: [warning] wrong ordering of key "w", which should precede "x" (key-ordering)`,
+		"broken.yaml:7:1: [error] failed to push frame on stack near line 7: inconsistent indentation level: " +
+			"indentation must be a multiple of indentationLevelModuloFactor (syntax)",
+		"broken.yaml:7:4: [error] wrong indentation: expected 4 spaces, found 3 (indentation)",
+		`broken.yaml:7:12: [warning] empty value for key "z" (empty-values)`,
+		`broken.yaml:7:17: [warning] wrong ordering of key "v", which should precede "z" (key-ordering)`,
+	}
+	if actual := problemStrings(problems); !slices.Equal(actual, expected) {
+		t.Errorf("expected problems:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(actual, "\n"))
+	}
+}
+
+func TestWriteText(t *testing.T) {
+	var b bytes.Buffer
+	if err := WriteText(&b, Lint("a.yaml", []byte("---\na: yes\n"))); err != nil {
+		t.Fatal(err)
+	}
+	expected := "a.yaml:2:4: [warning] truthy value \"yes\" should be one of [false, true] (truthy)\n"
+	if b.String() != expected {
+		t.Errorf("expected %q, got %q", expected, b.String())
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var b bytes.Buffer
+	if err := WriteJSON(&b, Lint("a.yaml", []byte("---\na: 0755\n"))); err != nil {
+		t.Fatal(err)
+	}
+	expected := `[{"file":"a.yaml","line":2,"column":4,"severity":"error","rule":"octal-values","message":"forbidden implicit octal value \"0755\""}]` + "\n"
+	if b.String() != expected {
+		t.Errorf("expected %s, got %s", expected, b.String())
+	}
+
+	b.Reset()
+	if err := WriteJSON(&b, nil); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != "[]\n" {
+		t.Errorf("expected an empty array, got %s", b.String())
+	}
+}
+
+func TestWriteSARIF(t *testing.T) {
+	rules := DefaultRules()
+	problems := append(Lint("config/a.yaml", []byte("---\na: yes\n")), Lint("config/b.yaml", []byte("a: [\n"))...)
+
+	var b bytes.Buffer
+	if err := WriteSARIF(&b, problems, rules); err != nil {
+		t.Fatal(err)
+	}
+
+	var log struct {
+		Version string `json:"version"`
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Rules []struct {
+						ID string `json:"id"`
+					} `json:"rules"`
+				} `json:"driver"`
+			} `json:"tool"`
+			Results []struct {
+				RuleID    string `json:"ruleId"`
+				Level     string `json:"level"`
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct {
+							URI string `json:"uri"`
+						} `json:"artifactLocation"`
+						Region struct {
+							StartLine   int `json:"startLine"`
+							StartColumn int `json:"startColumn"`
+						} `json:"region"`
+					} `json:"physicalLocation"`
+				} `json:"locations"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	if err := json.Unmarshal(b.Bytes(), &log); err != nil {
+		t.Fatal(err)
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 {
+		t.Fatalf("expected a SARIF 2.1.0 log of a single run, got %s", b.String())
+	}
+
+	run := log.Runs[0]
+	if len(run.Tool.Driver.Rules) != len(rules)+1 || run.Tool.Driver.Rules[len(rules)].ID != "syntax" {
+		t.Errorf("expected the default rules followed by the syntax rule, got %+v", run.Tool.Driver.Rules)
+	}
+
+	var results []string
+	for _, r := range run.Results {
+		location := r.Locations[0].PhysicalLocation
+		results = append(results, strings.Join([]string{
+			r.RuleID, r.Level, location.ArtifactLocation.URI, strconv.Itoa(location.Region.StartLine), strconv.Itoa(location.Region.StartColumn),
+		}, " "))
+	}
+	expected := []string{
+		"truthy warning config/a.yaml 2 4",
+		"syntax error config/b.yaml 1 1",
+	}
+	if !slices.Equal(results, expected) {
+		t.Errorf("expected results:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(results, "\n"))
+	}
+}
+
+func problemStrings(problems []Problem) []string {
+	var lines []string
+	for _, p := range problems {
+		lines = append(lines, p.String())
+	}
+	return lines
+}
@@ -0,0 +1,143 @@
+package lint
+
+import (
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// sarifVersion and sarifSchema identify the format of the logs WriteSARIF writes, and toolName the tool reporting problems
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	toolName     = "github.com/ercross/yaml/lint"
+)
+
+type (
+	sarifLog struct {
+		Version string     `json:"version"`
+		Schema  string     `json:"$schema"`
+		Runs    []sarifRun `json:"runs"`
+	}
+
+	sarifRun struct {
+		Tool    sarifTool     `json:"tool"`
+		Results []sarifResult `json:"results"`
+	}
+
+	sarifTool struct {
+		Driver sarifDriver `json:"driver"`
+	}
+
+	sarifDriver struct {
+		Name  string      `json:"name"`
+		Rules []sarifRule `json:"rules"`
+	}
+
+	sarifRule struct {
+		ID               string       `json:"id"`
+		ShortDescription sarifMessage `json:"shortDescription"`
+	}
+
+	sarifResult struct {
+		RuleID    string          `json:"ruleId"`
+		Level     string          `json:"level"`
+		Message   sarifMessage    `json:"message"`
+		Locations []sarifLocation `json:"locations"`
+	}
+
+	sarifMessage struct {
+		Text string `json:"text"`
+	}
+
+	sarifLocation struct {
+		PhysicalLocation struct {
+			ArtifactLocation struct {
+				URI string `json:"uri"`
+			} `json:"artifactLocation"`
+			Region struct {
+				StartLine   int `json:"startLine"`
+				StartColumn int `json:"startColumn"`
+			} `json:"region"`
+		} `json:"physicalLocation"`
+	}
+)
+
+// WriteText renders problems one per line, e.g.,
+//
+//	config.yaml:3:81: [error] line too long (95 > 80 characters) (line-length)
+func WriteText(w io.Writer, problems []Problem) error {
+	var b strings.Builder
+	for _, p := range problems {
+		b.WriteString(p.String() + "\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteJSON renders problems as a JSON array, e.g.,
+//
+//	[{"file":"config.yaml","line":3,"column":81,"severity":"error","rule":"line-length","message":"line too long (95 > 80 characters)"}]
+func WriteJSON(w io.Writer, problems []Problem) error {
+	if problems == nil {
+		problems = []Problem{}
+	}
+	return json.NewEncoder(w).Encode(problems)
+}
+
+func (p Problem) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		File     string   `json:"file"`
+		Line     int      `json:"line"`
+		Column   int      `json:"column"`
+		Severity Severity `json:"severity"`
+		Rule     string   `json:"rule"`
+		Message  string   `json:"message"`
+	}{
+		File:     p.File,
+		Line:     p.Position.Line(),
+		Column:   p.Position.Column(),
+		Severity: p.Severity,
+		Rule:     p.Rule,
+		Message:  p.Message,
+	})
+}
+
+// WriteSARIF renders problems as a SARIF 2.1.0 log, e.g., for code scanning annotations in CI.
+// The log describes rules, and the syntax rule if some file can not be parsed. File names are written as URIs
+// relative to the root of the repository being analyzed, with forward slashes
+func WriteSARIF(w io.Writer, problems []Problem, rules []Rule) error {
+	driver := sarifDriver{Name: toolName, Rules: []sarifRule{}}
+	for _, rule := range rules {
+		driver.Rules = append(driver.Rules, sarifRule{ID: rule.Name(), ShortDescription: sarifMessage{Text: rule.Description()}})
+	}
+	for _, p := range problems {
+		if p.Rule == syntaxRule {
+			driver.Rules = append(driver.Rules, sarifRule{ID: syntaxRule, ShortDescription: sarifMessage{Text: "Files must be valid YAML"}})
+			break
+		}
+	}
+
+	results := make([]sarifResult, len(problems))
+	for i, p := range problems {
+		var location sarifLocation
+		location.PhysicalLocation.ArtifactLocation.URI = filepath.ToSlash(p.File)
+		location.PhysicalLocation.Region.StartLine = p.Position.Line()
+		location.PhysicalLocation.Region.StartColumn = p.Position.Column()
+		results[i] = sarifResult{
+			RuleID:    p.Rule,
+			Level:     p.Severity.String(),
+			Message:   sarifMessage{Text: p.Message},
+			Locations: []sarifLocation{location},
+		}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(sarifLog{
+		Version: sarifVersion,
+		Schema:  sarifSchema,
+		Runs:    []sarifRun{{Tool: sarifTool{Driver: driver}, Results: results}},
+	})
+}
@@ -0,0 +1,455 @@
+package lint
+
+import (
+	"errors"
+	"github.com/ercross/yaml"
+	"github.com/ercross/yaml/token"
+	"iter"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+type (
+	// LineLength reports lines longer than Max characters. If AllowNonBreakableWords is set, lines holding a single
+	// word, e.g., a long URL, possibly after a sequence entry indicator or a comment indicator, are allowed
+	LineLength struct {
+		Severity               Severity
+		Max                    int
+		AllowNonBreakableWords bool
+	}
+
+	// TrailingSpaces reports spaces and tabs at the end of lines
+	TrailingSpaces struct {
+		Severity Severity
+	}
+
+	// Indentation reports block collections starting a line that are not indented by one indentation unit
+	// from the collection holding them. The unit is Spaces, or if Spaces is 0, the indentation unit of each document
+	// found by the parser: the parser requires every indentation to be a multiple of the unit, and Indentation
+	// also reports indentations of several units at once. In a file that can not be parsed, the unit is the first
+	// indentation of each document, and Indentation reports the indentations of other widths.
+	// Block sequences within mappings are indented if IndentSequences is set, and otherwise either indented or not,
+	// as the first of them in the document
+	Indentation struct {
+		Severity        Severity
+		Spaces          int
+		IndentSequences bool
+	}
+
+	// Truthy reports plain scalars, keys included, that YAML 1.1 reads as booleans and that are not Allowed,
+	// e.g., yes, No or ON, which YAML 1.2 reads as strings
+	Truthy struct {
+		Severity Severity
+		Allowed  []string
+	}
+
+	// KeyDuplicates reports keys equal to a key preceding them in the same mapping, see yaml.DuplicateKeyPolicy.
+	// Merge keys (<<) may be repeated
+	KeyDuplicates struct {
+		Severity Severity
+	}
+
+	// DocumentStart reports documents that do not start with the --- marker
+	DocumentStart struct {
+		Severity Severity
+	}
+
+	// Comments reports comments not starting with a space, e.g., #comment, if RequireStartingSpace is set,
+	// and comments following content separated from it by fewer than MinSpacesFromContent spaces.
+	// Comments made of # only, e.g., a ### separator, and a shebang on the first line, are allowed
+	Comments struct {
+		Severity             Severity
+		RequireStartingSpace bool
+		MinSpacesFromContent int
+	}
+
+	// EmptyValues reports keys of block mappings without any value, e.g., key: rather than key: null,
+	// and keys of flow mappings as well if ForbidInFlowMappings is set
+	EmptyValues struct {
+		Severity             Severity
+		ForbidInFlowMappings bool
+	}
+
+	// KeyOrdering reports scalar keys sorted before a preceding key of their mapping, keys being compared as strings.
+	// Merge keys (<<) are ignored
+	KeyOrdering struct {
+		Severity Severity
+	}
+
+	// OctalValues reports plain scalars read as octal integers by YAML 1.1 and as decimal integers by YAML 1.2,
+	// e.g., 0755, if ForbidImplicit is set, and the octal integers of YAML 1.2, e.g., 0o755, if ForbidExplicit is set
+	OctalValues struct {
+		Severity       Severity
+		ForbidImplicit bool
+		ForbidExplicit bool
+	}
+)
+
+// truthyValues are the plain scalars YAML 1.1 reads as booleans
+var truthyValues = []string{
+	"y", "Y", "yes", "Yes", "YES", "n", "N", "no", "No", "NO",
+	"true", "True", "TRUE", "false", "False", "FALSE",
+	"on", "On", "ON", "off", "Off", "OFF",
+}
+
+var (
+	implicitOctalPattern = regexp.MustCompile(`^0[0-7]+$`)
+	explicitOctalPattern = regexp.MustCompile(`^0o[0-7]+$`)
+)
+
+func (r LineLength) Name() string        { return "line-length" }
+func (r LineLength) Description() string { return "Lines must not be longer than the maximum length" }
+func (r LineLength) severity() Severity  { return r.Severity }
+
+func (r LineLength) check(s *source, report reporter) {
+	for i, line := range s.lines {
+		length := utf8.RuneCountInString(line)
+		if length <= r.Max || r.AllowNonBreakableWords && isNonBreakable(line) {
+			continue
+		}
+		report(token.NewLocation(i+1, r.Max+1), "line too long (%d > %d characters)", length, r.Max)
+	}
+}
+
+// isNonBreakable reports whether line holds a single word, after its indentation and any sequence entry
+// or comment indicator
+func isNonBreakable(line string) bool {
+	word := strings.TrimLeft(line, " ")
+	if strings.HasPrefix(word, "- ") {
+		word = word[2:]
+	} else if strings.HasPrefix(word, "#") {
+		word = strings.TrimLeft(strings.TrimLeft(word, "#"), " ")
+	}
+	return !strings.ContainsAny(word, " \t")
+}
+
+func (r TrailingSpaces) Name() string        { return "trailing-spaces" }
+func (r TrailingSpaces) Description() string { return "Lines must not end with spaces or tabs" }
+func (r TrailingSpaces) severity() Severity  { return r.Severity }
+
+func (r TrailingSpaces) check(s *source, report reporter) {
+	for i, line := range s.lines {
+		trimmed := strings.TrimRight(line, " \t")
+		if len(trimmed) < len(line) {
+			report(token.NewLocation(i+1, utf8.RuneCountInString(trimmed)+1), "trailing spaces")
+		}
+	}
+}
+
+func (r Indentation) Name() string { return "indentation" }
+func (r Indentation) Description() string {
+	return "Block collections must be indented by one indentation unit from their parent"
+}
+func (r Indentation) severity() Severity { return r.Severity }
+
+func (r Indentation) check(s *source, report reporter) {
+	if s.tree == nil {
+		r.checkTokens(s, report)
+		return
+	}
+
+	documents := s.tree.Documents()
+	document, check := -1, r.newCheck(report, 0)
+	// open are the start events of the collections holding the current event, innermost last
+	var open []yaml.Event
+	for _, event := range s.events {
+		switch event.Type {
+		case yaml.EventDocumentStart:
+			document++
+			unit := r.Spaces
+			if unit == 0 && document < len(documents) {
+				unit = s.tree.IndentationUnit(documents[document])
+			}
+			check = r.newCheck(report, unit)
+			check.learn = false
+
+		case yaml.EventMappingStart, yaml.EventSequenceStart:
+			if len(open) > 0 && event.Style == yaml.StyleBlock && s.startsLine(event.Start) {
+				parent := open[len(open)-1]
+				check.nested(event.Start, event.Start.Column()-parent.Start.Column(),
+					event.Type == yaml.EventSequenceStart && parent.Type == yaml.EventMappingStart)
+			}
+			open = append(open, event)
+
+		case yaml.EventMappingEnd, yaml.EventSequenceEnd:
+			open = open[:len(open)-1]
+		}
+	}
+}
+
+// indentationCheck checks the indentation of the block collections of a document, see Indentation
+type indentationCheck struct {
+	rule   Indentation
+	report reporter
+
+	// unit is the indentation unit of the document, or 0 if it is unknown. If learn is set,
+	// the first indentation found sets the unknown unit
+	unit  int
+	learn bool
+
+	// sequences is set once a block sequence within a mapping is found, indentless if it is not indented
+	sequences  bool
+	indentless bool
+}
+
+// newCheck creates the indentationCheck of a document indented by unit, learning the unit if it is 0
+func (r Indentation) newCheck(report reporter, unit int) *indentationCheck {
+	return &indentationCheck{rule: r, report: report, unit: unit, learn: unit == 0}
+}
+
+// nested checks the block collection starting a line at position, indented by found spaces from its parent.
+// inMapping is set for a block sequence within a mapping
+func (c *indentationCheck) nested(position token.Location, found int, inMapping bool) {
+	if c.unit == 0 && c.learn && found > 0 {
+		c.unit = found
+	}
+	if c.unit == 0 {
+		return
+	}
+
+	expected := c.unit
+	if inMapping && !c.rule.IndentSequences {
+		if !c.sequences {
+			c.sequences, c.indentless = true, found == 0
+		}
+		if c.indentless {
+			expected = 0
+		}
+	}
+	if found != expected {
+		c.report(position, "wrong indentation: expected %d spaces, found %d", expected, found)
+	}
+}
+
+func (r Truthy) Name() string { return "truthy" }
+func (r Truthy) Description() string {
+	return "Plain scalars read as booleans by YAML 1.1 must be one of the allowed values"
+}
+func (r Truthy) severity() Severity { return r.Severity }
+
+func (r Truthy) check(s *source, report reporter) {
+	allowed := slices.Sorted(slices.Values(r.Allowed))
+	for value, position := range s.plainScalars() {
+		if slices.Contains(truthyValues, value) && !slices.Contains(allowed, value) {
+			report(position, "truthy value %q should be one of [%s]", value, strings.Join(allowed, ", "))
+		}
+	}
+}
+
+func (r KeyDuplicates) Name() string        { return "key-duplicates" }
+func (r KeyDuplicates) Description() string { return "Keys must be defined once in a mapping" }
+func (r KeyDuplicates) severity() Severity  { return r.Severity }
+
+func (r KeyDuplicates) check(s *source, report reporter) {
+	if s.tree == nil {
+		return
+	}
+	for _, warning := range s.tree.Warnings() {
+		var duplicate *yaml.DuplicateKeyError
+		if errors.As(warning, &duplicate) {
+			report(duplicate.Duplicate, "duplicate key %q, first defined at %s", duplicate.Key, duplicate.First)
+		}
+	}
+}
+
+func (r DocumentStart) Name() string        { return "document-start" }
+func (r DocumentStart) Description() string { return `Documents must start with the "---" marker` }
+func (r DocumentStart) severity() Severity  { return r.Severity }
+
+func (r DocumentStart) check(s *source, report reporter) {
+	for i, event := range s.events {
+		if event.Type != yaml.EventDocumentStart || !event.Implicit {
+			continue
+		}
+		// an implicit document without a root, or whose root is an empty scalar, holds nothing but comments
+		root := s.events[i+1]
+		empty := root.Type == yaml.EventDocumentEnd ||
+			root.Type == yaml.EventScalar && root.Value == "" && root.Tag == "" && root.Anchor == ""
+		if !empty {
+			report(event.Start, `missing document start "---"`)
+		}
+	}
+}
+
+func (r Comments) Name() string { return "comments" }
+func (r Comments) Description() string {
+	return "Comments must start with a space, and be separated from the content preceding them"
+}
+func (r Comments) severity() Severity { return r.Severity }
+
+func (r Comments) check(s *source, report reporter) {
+	for _, t := range s.tokens {
+		line, column := t.Position.Line(), t.Position.Column()
+		if t.Type != token.TypeComment || line > len(s.lines) {
+			continue
+		}
+
+		text := strings.TrimLeft(t.Value, "#")
+		shebang := line == 1 && column == 1 && strings.HasPrefix(t.Value, "!")
+		if r.RequireStartingSpace && text != "" && !strings.HasPrefix(text, " ") && !shebang {
+			report(t.Position, "missing starting space in comment")
+		}
+
+		preceding := []rune(s.lines[line-1])
+		preceding = preceding[:min(column-1, len(preceding))]
+		content := strings.TrimRight(string(preceding), " \t")
+		if spaces := len(string(preceding)) - len(content); content != "" && spaces < r.MinSpacesFromContent {
+			report(t.Position, "too few spaces before comment: expected %d, found %d", r.MinSpacesFromContent, spaces)
+		}
+	}
+}
+
+func (r EmptyValues) Name() string        { return "empty-values" }
+func (r EmptyValues) Description() string { return "Mapping keys must have a value" }
+func (r EmptyValues) severity() Severity  { return r.Severity }
+
+func (r EmptyValues) check(s *source, report reporter) {
+	if s.tree == nil {
+		r.checkTokens(s, report)
+		return
+	}
+	for pair := range s.pairs() {
+		if pair.mapping.Style == yaml.StyleFlow && !r.ForbidInFlowMappings {
+			continue
+		}
+		value := pair.value
+		if value.Type != yaml.EventScalar || value.Style != yaml.StylePlain || value.Value != "" ||
+			scalarTag(value) != yaml.TagNull {
+			continue
+		}
+		if pair.key.Type == yaml.EventScalar {
+			report(pair.key.Start, "empty value for key %q", pair.key.Value)
+		} else {
+			report(pair.key.Start, "empty value in mapping")
+		}
+	}
+}
+
+func (r KeyOrdering) Name() string        { return "key-ordering" }
+func (r KeyOrdering) Description() string { return "Mapping keys must be sorted" }
+func (r KeyOrdering) severity() Severity  { return r.Severity }
+
+func (r KeyOrdering) check(s *source, report reporter) {
+	if s.tree == nil {
+		r.checkTokens(s, report)
+		return
+	}
+	// greatest is the greatest key of each mapping preceding its current key
+	greatest := make(map[int]string)
+	for pair := range s.pairs() {
+		key := pair.key
+		if key.Type != yaml.EventScalar || scalarTag(key) == yaml.TagMerge {
+			continue
+		}
+		if previous, ordered := greatest[pair.mappingIndex]; ordered && key.Value < previous {
+			report(key.Start, "wrong ordering of key %q, which should precede %q", key.Value, previous)
+			continue
+		}
+		greatest[pair.mappingIndex] = key.Value
+	}
+}
+
+func (r OctalValues) Name() string { return "octal-values" }
+func (r OctalValues) Description() string {
+	return "Integers must not be written as octal numbers, which YAML 1.1 and YAML 1.2 read differently"
+}
+func (r OctalValues) severity() Severity { return r.Severity }
+
+func (r OctalValues) check(s *source, report reporter) {
+	for value, position := range s.plainScalars() {
+		switch {
+		case r.ForbidImplicit && implicitOctalPattern.MatchString(value):
+			report(position, "forbidden implicit octal value %q", value)
+		case r.ForbidExplicit && explicitOctalPattern.MatchString(value):
+			report(position, "forbidden explicit octal value %q", value)
+		}
+	}
+}
+
+// eventPair is a pair of a mapping of a source, made of the events starting its key and its value
+type eventPair struct {
+	mapping yaml.Event
+
+	// mappingIndex is the index of the event starting the mapping, which identifies it
+	mappingIndex int
+	key          yaml.Event
+	value        yaml.Event
+}
+
+// pairs returns an iterator over the pairs of the mappings of s in source order, the pairs of duplicate keys included
+func (s *source) pairs() iter.Seq[eventPair] {
+	return func(yield func(eventPair) bool) {
+		// open are the collections holding the current event, innermost last, with the key awaiting its value
+		type collection struct {
+			index int
+			key   *yaml.Event
+		}
+		var open []collection
+		for i, event := range s.events {
+			switch event.Type {
+			case yaml.EventScalar, yaml.EventAlias, yaml.EventMappingStart, yaml.EventSequenceStart:
+				if len(open) == 0 || s.events[open[len(open)-1].index].Type != yaml.EventMappingStart {
+					break
+				}
+				c := &open[len(open)-1]
+				if c.key == nil {
+					c.key = &s.events[i]
+					break
+				}
+				pair := eventPair{mapping: s.events[c.index], mappingIndex: c.index, key: *c.key, value: event}
+				c.key = nil
+				if !yield(pair) {
+					return
+				}
+			}
+
+			switch event.Type {
+			case yaml.EventMappingStart, yaml.EventSequenceStart:
+				open = append(open, collection{index: i})
+			case yaml.EventMappingEnd, yaml.EventSequenceEnd:
+				open = open[:len(open)-1]
+			}
+		}
+	}
+}
+
+// scalarTag returns the tag of the scalar a Scalar event starts, explicit or resolved, see yaml.ScalarNode.Tag
+func scalarTag(event yaml.Event) string {
+	n := yaml.NewScalarNode(event.Value)
+	if event.Tag != "" {
+		n.SetTag(event.Tag)
+	}
+	n.SetStyle(event.Style)
+	return n.Tag()
+}
+
+// plainScalars returns an iterator over the values and positions of the plain scalars of s, keys included,
+// read from its events, or from its tokens if s can not be parsed
+func (s *source) plainScalars() iter.Seq2[string, token.Location] {
+	return func(yield func(string, token.Location) bool) {
+		if s.tree == nil {
+			for _, t := range s.tokens {
+				if t.Type == token.TypeData && t.Quote == 0 && !yield(t.Value, t.Position) {
+					return
+				}
+			}
+			return
+		}
+		for _, event := range s.events {
+			if event.Type == yaml.EventScalar && event.Style == yaml.StylePlain && !yield(event.Value, event.Start) {
+				return
+			}
+		}
+	}
+}
+
+// startsLine reports whether position is the first character of its line following the indentation
+func (s *source) startsLine(position token.Location) bool {
+	if position.Line() > len(s.lines) {
+		return false
+	}
+	line := s.lines[position.Line()-1]
+	return len(line)-len(strings.TrimLeft(line, " ")) == position.Column()-1
+}
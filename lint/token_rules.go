@@ -0,0 +1,258 @@
+package lint
+
+import (
+	"github.com/ercross/yaml/token"
+	"iter"
+)
+
+// The rules reading events read the tokens of a file that can not be parsed instead, finding the block collections
+// from the columns of their keys and entry indicators, and the flow collections from their brackets
+
+type (
+	// blockCollection is a block collection found in the tokens of a file, its entries starting at column,
+	// counted from 0
+	blockCollection struct {
+		column   int
+		sequence bool
+	}
+
+	// keyOrder is a collection found in the tokens of a file, see KeyOrdering.checkTokens.
+	// column is the column of the keys of a block mapping, or -1 for a flow collection
+	keyOrder struct {
+		column   int
+		sequence bool
+
+		// greatest is the greatest key preceding the current one
+		greatest string
+		ordered  bool
+	}
+)
+
+// checkTokens checks the indentation of the block collections of s from its tokens, as check does from its events.
+// The indentation unit of a document is Spaces, or if Spaces is 0, the first indentation found in the document
+func (r Indentation) checkTokens(s *source, report reporter) {
+	check := r.newCheck(report, r.Spaces)
+
+	// open are the block collections holding the current line, innermost last,
+	// and awaiting is set if the preceding line ends with a key or an entry indicator, its value following it
+	var open []blockCollection
+	awaiting, flow := false, 0
+	for line := range s.contentLines() {
+		first := line[0]
+		switch {
+		case first.Type == token.TypeDocumentStart || first.Type == token.TypeDocumentEnd || first.Type == token.TypeDirective:
+			check = r.newCheck(report, r.Spaces)
+			open, awaiting, flow = nil, false, 0
+			continue
+		case flow > 0 || first.Type == token.TypeBlockScalarLine:
+			flow += flowDepth(line)
+			continue
+		}
+
+		startsLine := true
+		for i := 0; i < len(line); {
+			j := skipProperties(line, i)
+			if j == len(line) {
+				break
+			}
+			sequence := line[j].Type == token.TypeDash
+			if !sequence && !isKey(line, j) {
+				flow += flowDepth(line[j:])
+				break
+			}
+
+			// a key closes the block sequence not indented within its mapping
+			entry := blockCollection{column: line[i].Position.Column() - 1, sequence: sequence}
+			for len(open) > 0 {
+				top := open[len(open)-1]
+				if top.column < entry.column || top.column == entry.column && (sequence || !top.sequence) {
+					break
+				}
+				open = open[:len(open)-1]
+			}
+
+			if len(open) == 0 || open[len(open)-1] != entry {
+				if len(open) > 0 && startsLine {
+					if !awaiting {
+						// the line is indented deeper than the preceding one, which holds its value already
+						break
+					}
+					parent := open[len(open)-1]
+					check.nested(line[i].Position, entry.column-parent.column, sequence && !parent.sequence)
+				}
+				open = append(open, entry)
+			}
+
+			startsLine = false
+			if !sequence {
+				flow += flowDepth(line[j+2:])
+				break
+			}
+			i = j + 1
+		}
+		awaiting = endsWithIndicator(line)
+	}
+}
+
+// checkTokens checks the keys of the tokens of s, as check does from its events
+func (r EmptyValues) checkTokens(s *source, report reporter) {
+	flow := 0
+	for i, t := range s.tokens {
+		switch t.Type {
+		case token.TypeOpeningSquareBracket, token.TypeOpeningCurlyBrace:
+			flow++
+		case token.TypeClosingSquareBracket, token.TypeClosingCurlyBrace:
+			flow = max(flow-1, 0)
+		}
+		if !isKey(s.tokens, i) {
+			continue
+		}
+
+		next, ok := nextToken(s.tokens, i+2)
+		var empty bool
+		if flow > 0 {
+			empty = r.ForbidInFlowMappings && ok && (next.Type == token.TypeComma ||
+				next.Type == token.TypeClosingCurlyBrace || next.Type == token.TypeClosingSquareBracket)
+		} else {
+			// the value of a block mapping key on a following line is indented deeper than the key,
+			// unless it is a block sequence
+			column := next.Position.Column()
+			empty = !ok || next.Position.Line() > t.Position.Line() &&
+				(column < t.Position.Column() || column == t.Position.Column() && next.Type != token.TypeDash)
+		}
+
+		switch {
+		case !empty:
+		case t.Type == token.TypeData:
+			report(t.Position, "empty value for key %q", t.Value)
+		default:
+			report(t.Position, "empty value in mapping")
+		}
+	}
+}
+
+// checkTokens checks the keys of the tokens of s, as check does from its events
+func (r KeyOrdering) checkTokens(s *source, report reporter) {
+	// open are the collections holding the current token, innermost last
+	var open []*keyOrder
+	closeBlock := func(column int) {
+		for len(open) > 0 && open[len(open)-1].column > column {
+			open = open[:len(open)-1]
+		}
+	}
+
+	for i, t := range s.tokens {
+		switch t.Type {
+		case token.TypeDocumentStart, token.TypeDocumentEnd:
+			open = nil
+			continue
+		case token.TypeOpeningSquareBracket, token.TypeOpeningCurlyBrace:
+			open = append(open, &keyOrder{column: -1, sequence: t.Type == token.TypeOpeningSquareBracket})
+			continue
+		case token.TypeClosingSquareBracket, token.TypeClosingCurlyBrace:
+			closeBlock(-1)
+			if len(open) > 0 {
+				open = open[:len(open)-1]
+			}
+			continue
+		case token.TypeDash:
+			closeBlock(t.Position.Column() - 1)
+			continue
+		}
+		if t.Type != token.TypeData || !isKey(s.tokens, i) {
+			continue
+		}
+
+		// a key of a flow collection belongs to it, and a key of a block mapping to the mapping at its column
+		column := t.Position.Column() - 1
+		if len(open) == 0 || open[len(open)-1].column >= 0 {
+			closeBlock(column)
+			if len(open) == 0 || open[len(open)-1].column != column {
+				open = append(open, &keyOrder{column: column})
+			}
+		}
+
+		// the pairs of a flow sequence are mappings of their own
+		mapping := open[len(open)-1]
+		if mapping.sequence || t.Value == "<<" && t.Quote == 0 {
+			continue
+		}
+		if mapping.ordered && t.Value < mapping.greatest {
+			report(t.Position, "wrong ordering of key %q, which should precede %q", t.Value, mapping.greatest)
+			continue
+		}
+		mapping.greatest, mapping.ordered = t.Value, true
+	}
+}
+
+// contentLines returns an iterator over the lines of tokens of s holding content, without their indentation,
+// comments and line breaks
+func (s *source) contentLines() iter.Seq[[]token.Token] {
+	return func(yield func([]token.Token) bool) {
+		var line []token.Token
+		for i, t := range s.tokens {
+			switch t.Type {
+			case token.TypeIndentation, token.TypeComment, token.TypeNewline:
+			default:
+				line = append(line, t)
+			}
+			if i+1 < len(s.tokens) && s.tokens[i+1].Position.Line() == t.Position.Line() {
+				continue
+			}
+			if len(line) > 0 && !yield(line) {
+				return
+			}
+			line = nil
+		}
+	}
+}
+
+// isKey reports whether tokens[i] is the implicit key of a mapping pair, i.e., a scalar or an alias followed by a colon
+func isKey(tokens []token.Token, i int) bool {
+	return (tokens[i].Type == token.TypeData || tokens[i].Type == token.TypeAsterisk) &&
+		i+1 < len(tokens) && tokens[i+1].Type == token.TypeColon
+}
+
+// nextToken returns the first token from tokens[i] that is not a comment, an indentation or a line break
+func nextToken(tokens []token.Token, i int) (token.Token, bool) {
+	for _, t := range tokens[min(i, len(tokens)):] {
+		switch t.Type {
+		case token.TypeIndentation, token.TypeComment, token.TypeNewline:
+		default:
+			return t, true
+		}
+	}
+	return token.Token{}, false
+}
+
+// skipProperties returns the index of the first token from line[i] that is not an anchor or a tag
+func skipProperties(line []token.Token, i int) int {
+	for i < len(line) && (line[i].Type == token.TypeAmpersand || line[i].Type == token.TypeExclamationMark) {
+		i++
+	}
+	return i
+}
+
+// endsWithIndicator reports whether line ends with a mapping value or a block sequence entry indicator,
+// possibly followed by properties, the value following it on the next lines
+func endsWithIndicator(line []token.Token) bool {
+	i := len(line) - 1
+	for i >= 0 && (line[i].Type == token.TypeAmpersand || line[i].Type == token.TypeExclamationMark) {
+		i--
+	}
+	return i >= 0 && (line[i].Type == token.TypeColon || line[i].Type == token.TypeDash)
+}
+
+// flowDepth is the number of flow collections tokens open and does not close
+func flowDepth(tokens []token.Token) int {
+	depth := 0
+	for _, t := range tokens {
+		switch t.Type {
+		case token.TypeOpeningSquareBracket, token.TypeOpeningCurlyBrace:
+			depth++
+		case token.TypeClosingSquareBracket, token.TypeClosingCurlyBrace:
+			depth--
+		}
+	}
+	return depth
+}
@@ -0,0 +1,264 @@
+package yaml
+
+import (
+	"io"
+	"slices"
+	"strings"
+)
+
+// Conflict is a node ours and theirs both changed, each in its own way, during a Merge
+type Conflict struct {
+	// Path locates the node, using the keys of ours where it has the node
+	Path Path
+
+	// Base, Ours and Theirs are the node in each tree, or nil where the tree does not have it
+	Base   Node
+	Ours   Node
+	Theirs Node
+}
+
+// merger merges the changes made by theirs into ours
+type merger struct {
+	conflicts []Conflict
+
+	// parents are the collections of ours enclosing the node being merged
+	parents []Node
+}
+
+// Merge merges three versions of a document: theirs and ours, both changed from their common ancestor base.
+//
+// Mapping entries are merged independently of each other, and sequences item by item when neither side
+// changed their length. A node both sides changed differently is a conflict: ours is kept
+// (or theirs, if ours deleted the node), and the node is preceded by a comment showing both versions, e.g.,
+//
+//	# <<<<<<< ours
+//	# replicas: 3
+//	# =======
+//	# replicas: 5
+//	# >>>>>>> theirs
+//	replicas: 3
+//
+// ours is modified in place and returned, which keeps its comments, styles and key order.
+// base is nil when the document has no common ancestor
+func Merge(base, ours, theirs *DocumentNode) (*DocumentNode, []Conflict) {
+	if ours == nil {
+		ours = NewDocumentNode()
+	}
+
+	m := &merger{}
+	root, conflict := m.merge(nil, rootOf(base), ours.root, rootOf(theirs))
+	ours.root = root
+	if conflict != nil {
+		m.mark(ours, conflict)
+	}
+	return ours, m.conflicts
+}
+
+// merge returns the merged version of a node, or nil if the node is deleted.
+// It returns the conflict the node is in, if any, for the caller to mark it
+func (m *merger) merge(path Path, base, ours, theirs Node) (Node, *Conflict) {
+	switch {
+	case equalNodes(ours, theirs):
+		return ours, nil
+	case equalNodes(base, ours):
+		return m.adopt(theirs), nil
+	case equalNodes(base, theirs):
+		return ours, nil
+	}
+
+	// an alias of ours is not merged into, as it would change every other alias of its anchor too
+	switch o := ours.(type) {
+	case *MappingNode:
+		if t, ok := resolveAlias(theirs).(*MappingNode); ok && o.Tag() == t.Tag() {
+			b, _ := resolveAlias(base).(*MappingNode)
+			m.mergeMappings(path, b, o, t)
+			return o, nil
+		}
+
+	case *SequenceNode:
+		t, isSequence := resolveAlias(theirs).(*SequenceNode)
+		b, _ := resolveAlias(base).(*SequenceNode)
+		if isSequence && b != nil && len(b.items) == len(o.items) && len(t.items) == len(o.items) {
+			m.mergeSequences(path, b, o, t)
+			return o, nil
+		}
+	}
+
+	conflict := Conflict{Path: path, Base: base, Ours: ours, Theirs: theirs}
+	m.conflicts = append(m.conflicts, conflict)
+	if ours != nil {
+		return ours, &conflict
+	}
+	return m.adopt(theirs), &conflict
+}
+
+func (m *merger) mergeMappings(path Path, base, ours, theirs *MappingNode) {
+	m.parents = append(m.parents, ours)
+	defer func() { m.parents = m.parents[:len(m.parents)-1] }()
+
+	baseValues := make(map[string]Node)
+	if base != nil {
+		for _, pair := range base.pairs {
+			baseValues[keyOf(pair.Key)] = pair.Value
+		}
+	}
+	theirsValues := make(map[string]Node, len(theirs.pairs))
+	for _, pair := range theirs.pairs {
+		theirsValues[keyOf(pair.Key)] = pair.Value
+	}
+
+	pairs := make([]MappingPair, 0, len(ours.pairs))
+	for _, pair := range ours.pairs {
+		key := keyOf(pair.Key)
+		value, conflict := m.merge(path.append(PathSegment{Key: pair.Key, Index: len(pairs)}),
+			baseValues[key], pair.Value, theirsValues[key])
+		if value == nil {
+			continue
+		}
+		if conflict != nil {
+			m.mark(pair.Key, conflict)
+		}
+		pairs = append(pairs, MappingPair{Key: pair.Key, Value: value})
+	}
+
+	// entries missing from ours are inserted after the entry preceding them in theirs
+	next := 0
+	for _, pair := range theirs.pairs {
+		key := keyOf(pair.Key)
+		if i := slices.IndexFunc(pairs, func(p MappingPair) bool { return keyOf(p.Key) == key }); i != -1 {
+			next = i + 1
+			continue
+		}
+		if containsKey(ours, key) {
+			// merged above, and deleted
+			continue
+		}
+
+		value, conflict := m.merge(path.append(PathSegment{Key: pair.Key, Index: next}), baseValues[key], nil, pair.Value)
+		if value == nil {
+			continue
+		}
+		if conflict != nil {
+			m.mark(pair.Key, conflict)
+		}
+		pairs = insertAt(pairs, next, MappingPair{Key: pair.Key, Value: value})
+		next++
+	}
+	ours.pairs = pairs
+}
+
+func (m *merger) mergeSequences(path Path, base, ours, theirs *SequenceNode) {
+	m.parents = append(m.parents, ours)
+	defer func() { m.parents = m.parents[:len(m.parents)-1] }()
+
+	for i := range ours.items {
+		item, conflict := m.merge(path.append(PathSegment{Index: i}), base.items[i], ours.items[i], theirs.items[i])
+		ours.items[i] = item
+		if conflict != nil {
+			m.mark(item, conflict)
+		}
+	}
+}
+
+// adopt prepares a node of theirs to become part of ours. Its aliases, which may refer to anchors ours does not define,
+// are replaced with the nodes they refer to, unless the anchor is defined earlier within n
+func (m *merger) adopt(n Node) Node {
+	n = resolveAlias(n)
+	visited := make(map[Node]bool)
+	var visit func(n Node)
+	adoptChild := func(child Node) Node {
+		if target := resolveAlias(child); !visited[target] {
+			visit(target)
+			return target
+		}
+		return child
+	}
+	visit = func(n Node) {
+		visited[n] = true
+		switch n := n.(type) {
+		case *MappingNode:
+			for i := range n.pairs {
+				n.pairs[i].Value = adoptChild(n.pairs[i].Value)
+			}
+		case *SequenceNode:
+			for i := range n.items {
+				n.items[i] = adoptChild(n.items[i])
+			}
+		}
+	}
+	if n != nil {
+		visit(n)
+	}
+	return n
+}
+
+// mark precedes n with a comment showing both versions of the conflict.
+// The collections enclosing n are written in block style, as comments can not be written within a flow collection
+func (m *merger) mark(n Node, conflict *Conflict) {
+	commented, ok := n.(interface{ SetHeadComment(comment string) })
+	if !ok {
+		return
+	}
+
+	lines := []string{"<<<<<<< ours"}
+	lines = append(lines, conflictLines(conflict.Path, conflict.Ours)...)
+	lines = append(lines, "=======")
+	lines = append(lines, conflictLines(conflict.Path, conflict.Theirs)...)
+	lines = append(lines, ">>>>>>> theirs")
+	for i := range lines {
+		lines[i] = strings.TrimSuffix("# "+lines[i], " ")
+	}
+
+	comment := strings.Join(lines, "\n")
+	if n.HeadComment() != "" {
+		comment = n.HeadComment() + "\n" + comment
+	}
+	commented.SetHeadComment(comment)
+
+	for _, parent := range m.parents {
+		if styled, ok := parent.(interface{ SetStyle(Style) }); ok && parent.Style() == StyleFlow {
+			styled.SetStyle(StyleBlock)
+		}
+	}
+}
+
+// conflictLines renders one version of a conflicting node, prefixed with its key if it is a mapping value
+func conflictLines(path Path, n Node) []string {
+	if n == nil {
+		return []string{"(deleted)"}
+	}
+	text := diffText(n)
+	if len(path) == 0 || path[len(path)-1].Key == nil {
+		return strings.Split(text, "\n")
+	}
+
+	key, err := NewEmitter(io.Discard).key(path[len(path)-1].Key)
+	if err != nil {
+		key = "?"
+	}
+	if isBlockCollection(resolveAlias(n)) {
+		return append([]string{key + ":"}, indentLines(strings.Split(text, "\n"))...)
+	}
+	return strings.Split(key+": "+text, "\n")
+}
+
+func indentLines(lines []string) []string {
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = "  " + line
+		}
+	}
+	return lines
+}
+
+// containsKey checks that a key of n is identified as key
+func containsKey(n *MappingNode, key string) bool {
+	return slices.ContainsFunc(n.pairs, func(p MappingPair) bool { return keyOf(p.Key) == key })
+}
+
+// equalNodes checks that a and b are semantically equal, as compared by Diff. A nil node is only equal to nil
+func equalNodes(a, b Node) bool {
+	d := &differ{compared: make(map[[2]Node]bool)}
+	d.diff(nil, a, b)
+	return len(d.changes) == 0
+}
@@ -0,0 +1,103 @@
+package yaml_test
+
+import (
+	"github.com/ercross/yaml"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name               string
+		base, ours, theirs string
+		expected           string
+		expectedConflicts  []string
+	}{
+		{
+			name:     "independent keys",
+			base:     "a: 1\nb: 2\n",
+			ours:     "# ours\na: 10 # changed\nb: 2\n",
+			theirs:   "b: 20\na: 1\n",
+			expected: "# ours\na: 10 # changed\nb: 20\n",
+		},
+		{
+			name:     "added and deleted keys",
+			base:     "a: 1\nb: 2\nc: 3\n",
+			ours:     "a: 1\nc: 3\nd: 4\n",
+			theirs:   "a: 1\nx: 0\nb: 2\n",
+			expected: "a: 1\nx: 0\nd: 4\n",
+		},
+		{
+			name:     "nested mappings",
+			base:     "spec:\n  replicas: 1\n  image: v1\n",
+			ours:     "spec:\n  replicas: 3\n  image: v1\n",
+			theirs:   "spec:\n  replicas: 1\n  image: v2\n",
+			expected: "spec:\n  replicas: 3\n  image: v2\n",
+		},
+		{
+			name:     "same change on both sides",
+			base:     "a: 1\n",
+			ours:     "a: 2\n",
+			theirs:   "a: 0x2\n",
+			expected: "a: 2\n",
+		},
+		{
+			name:     "sequence items",
+			base:     "- a\n- b\n",
+			ours:     "- x\n- b\n",
+			theirs:   "- a\n- y\n",
+			expected: "- x\n- y\n",
+		},
+		{
+			name:              "conflicting scalar",
+			base:              "a: 1\nb: 1\n",
+			ours:              "a: 2\nb: 1\n",
+			theirs:            "a: 3\nb: 2\n",
+			expected:          "# <<<<<<< ours\n# a: 2\n# =======\n# a: 3\n# >>>>>>> theirs\na: 2\nb: 2\n",
+			expectedConflicts: []string{"$.a"},
+		},
+		{
+			name:              "deleted and modified",
+			base:              "a: 1\nb: 1\n",
+			ours:              "b: 1\n",
+			theirs:            "a: 2\nb: 1\n",
+			expected:          "# <<<<<<< ours\n# (deleted)\n# =======\n# a: 2\n# >>>>>>> theirs\na: 2\nb: 1\n",
+			expectedConflicts: []string{"$.a"},
+		},
+		{
+			name:              "conflict within a flow mapping",
+			base:              "a: {x: 1}\n",
+			ours:              "a: {x: 2}\n",
+			theirs:            "a: {x: 3}\n",
+			expected:          "a:\n  # <<<<<<< ours\n  # x: 2\n  # =======\n  # x: 3\n  # >>>>>>> theirs\n  x: 2\n",
+			expectedConflicts: []string{"$.a.x"},
+		},
+		{
+			name:     "alias of theirs",
+			base:     "a: &x 1\nb: 1\n",
+			ours:     "a: &x 1\nb: 1\n",
+			theirs:   "a: &y 2\nb: *y\n",
+			expected: "a: &y 2\nb: *y\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			merged, conflicts := yaml.Merge(parseDocument(t, test.base), parseDocument(t, test.ours), parseDocument(t, test.theirs))
+			if actual := emit(t, merged); actual != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, actual)
+			}
+
+			var actual []string
+			for _, conflict := range conflicts {
+				actual = append(actual, conflict.Path.String())
+			}
+			if len(actual) != len(test.expectedConflicts) {
+				t.Fatalf("expected conflicts %q, got %q", test.expectedConflicts, actual)
+			}
+			for i := range actual {
+				if actual[i] != test.expectedConflicts[i] {
+					t.Errorf("expected conflicts %q, got %q", test.expectedConflicts, actual)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package yaml
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrIndexOutOfRange = errors.New("index out of range")
+	ErrKeyNotFound     = errors.New("key not found")
+	ErrDuplicateKey    = errors.New("duplicate key")
+)
+
+// SetRoot replaces the root node of the document
+func (n *DocumentNode) SetRoot(root Node) {
+	n.root = root
+}
+
+// Get returns the value of the first pair whose key is a scalar holding key
+func (n *MappingNode) Get(key string) (Node, bool) {
+	i := n.indexOf(key)
+	if i == -1 {
+		return nil, false
+	}
+	return n.pairs[i].Value, true
+}
+
+// Set replaces the value of key, keeping the pair at its position, or appends a new pair if key is missing
+func (n *MappingNode) Set(key string, value Node) {
+	if i := n.indexOf(key); i != -1 {
+		n.pairs[i].Value = value
+		return
+	}
+	n.pairs = append(n.pairs, MappingPair{Key: NewScalarNode(key), Value: value})
+}
+
+// Insert adds a new key/value pair at index, shifting the following pairs.
+// index can be Len to append the pair
+func (n *MappingNode) Insert(index int, key string, value Node) error {
+	if index < 0 || index > len(n.pairs) {
+		return fmt.Errorf("can not insert key %q at %d in mapping of %d pairs: %w", key, index, len(n.pairs), ErrIndexOutOfRange)
+	}
+	if n.indexOf(key) != -1 {
+		return fmt.Errorf("can not insert key %q: %w", key, ErrDuplicateKey)
+	}
+	n.pairs = insertAt(n.pairs, index, MappingPair{Key: NewScalarNode(key), Value: value})
+	return nil
+}
+
+// Delete removes the pair of key. It reports whether key was found
+func (n *MappingNode) Delete(key string) bool {
+	i := n.indexOf(key)
+	if i == -1 {
+		return false
+	}
+	n.pairs = append(n.pairs[:i], n.pairs[i+1:]...)
+	return true
+}
+
+// Move moves the pair at index from to index to, shifting the pairs in between
+func (n *MappingNode) Move(from, to int) error {
+	if err := checkMove(from, to, len(n.pairs)); err != nil {
+		return err
+	}
+	n.pairs = move(n.pairs, from, to)
+	return nil
+}
+
+// Rename replaces the key of a pair, keeping the pair at its position along with its value and the properties of its key
+func (n *MappingNode) Rename(oldKey, newKey string) error {
+	i := n.indexOf(oldKey)
+	if i == -1 {
+		return fmt.Errorf("can not rename key %q: %w", oldKey, ErrKeyNotFound)
+	}
+	if oldKey == newKey {
+		return nil
+	}
+	if n.indexOf(newKey) != -1 {
+		return fmt.Errorf("can not rename key %q to %q: %w", oldKey, newKey, ErrDuplicateKey)
+	}
+
+	renamed := NewScalarNode(newKey)
+	if key, ok := n.pairs[i].Key.(*ScalarNode); ok {
+		renamed.nodeProperties = key.nodeProperties
+	}
+	n.pairs[i].Key = renamed
+	return nil
+}
+
+// Set replaces the item at index
+func (n *SequenceNode) Set(index int, value Node) error {
+	if index < 0 || index >= len(n.items) {
+		return fmt.Errorf("can not set item %d of sequence of %d items: %w", index, len(n.items), ErrIndexOutOfRange)
+	}
+	n.items[index] = value
+	return nil
+}
+
+// Append adds values after the last item of the sequence
+func (n *SequenceNode) Append(values ...Node) {
+	n.items = append(n.items, values...)
+}
+
+// Insert adds value at index, shifting the following items. index can be Len to append value
+func (n *SequenceNode) Insert(index int, value Node) error {
+	if index < 0 || index > len(n.items) {
+		return fmt.Errorf("can not insert item at %d in sequence of %d items: %w", index, len(n.items), ErrIndexOutOfRange)
+	}
+	n.items = insertAt(n.items, index, value)
+	return nil
+}
+
+// Delete removes the item at index, shifting the following items
+func (n *SequenceNode) Delete(index int) error {
+	if index < 0 || index >= len(n.items) {
+		return fmt.Errorf("can not delete item %d of sequence of %d items: %w", index, len(n.items), ErrIndexOutOfRange)
+	}
+	n.items = append(n.items[:index], n.items[index+1:]...)
+	return nil
+}
+
+// Move moves the item at index from to index to, shifting the items in between
+func (n *SequenceNode) Move(from, to int) error {
+	if err := checkMove(from, to, len(n.items)); err != nil {
+		return err
+	}
+	n.items = move(n.items, from, to)
+	return nil
+}
+
+func checkMove(from, to, length int) error {
+	if from < 0 || from >= length || to < 0 || to >= length {
+		return fmt.Errorf("can not move %d to %d in collection of %d: %w", from, to, length, ErrIndexOutOfRange)
+	}
+	return nil
+}
+
+func insertAt[T any](s []T, index int, v T) []T {
+	var zero T
+	s = append(s, zero)
+	copy(s[index+1:], s[index:])
+	s[index] = v
+	return s
+}
+
+func move[T any](s []T, from, to int) []T {
+	v := s[from]
+	if from < to {
+		copy(s[from:to], s[from+1:to+1])
+	} else {
+		copy(s[to+1:from+1], s[to:from])
+	}
+	s[to] = v
+	return s
+}
@@ -0,0 +1,115 @@
+package yaml_test
+
+import (
+	"errors"
+	"github.com/ercross/yaml"
+	"testing"
+)
+
+const mutationSource = `name: web
+replicas: 3
+ports:
+  - 80
+  - 443
+labels:
+  app: web
+  tier: frontend
+`
+
+func TestMappingNode_Mutations(t *testing.T) {
+	document := parseDocument(t, mutationSource)
+	root := document.Root().(*yaml.MappingNode)
+
+	root.Set("replicas", yaml.NewScalarNode("5"))
+	root.Set("image", yaml.NewScalarNode("nginx"))
+	if err := root.Insert(1, "namespace", yaml.NewScalarNode("prod")); err != nil {
+		t.Fatal(err)
+	}
+	if err := root.Rename("labels", "metadata"); err != nil {
+		t.Fatal(err)
+	}
+	if err := root.Move(5, 0); err != nil {
+		t.Fatal(err)
+	}
+	if !root.Delete("name") {
+		t.Error("expected name to be deleted")
+	}
+
+	expected := `image: nginx
+namespace: prod
+replicas: 5
+ports:
+  - 80
+  - 443
+metadata:
+  app: web
+  tier: frontend
+`
+	if actual := emit(t, document); actual != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, actual)
+	}
+
+	value, ok := root.Get("namespace")
+	if !ok || scalarValue(t, value) != "prod" {
+		t.Errorf("expected namespace to be prod, got %v", value)
+	}
+	if root.Delete("missing") {
+		t.Error("expected deleting a missing key to report false")
+	}
+}
+
+func TestMappingNode_MutationErrors(t *testing.T) {
+	root := parseDocument(t, mutationSource).Root().(*yaml.MappingNode)
+
+	if err := root.Insert(0, "name", yaml.NewScalarNode("x")); !errors.Is(err, yaml.ErrDuplicateKey) {
+		t.Errorf("expected ErrDuplicateKey, got %v", err)
+	}
+	if err := root.Insert(9, "other", yaml.NewScalarNode("x")); !errors.Is(err, yaml.ErrIndexOutOfRange) {
+		t.Errorf("expected ErrIndexOutOfRange, got %v", err)
+	}
+	if err := root.Rename("missing", "other"); !errors.Is(err, yaml.ErrKeyNotFound) {
+		t.Errorf("expected ErrKeyNotFound, got %v", err)
+	}
+	if err := root.Rename("name", "replicas"); !errors.Is(err, yaml.ErrDuplicateKey) {
+		t.Errorf("expected ErrDuplicateKey, got %v", err)
+	}
+	if err := root.Move(0, 4); !errors.Is(err, yaml.ErrIndexOutOfRange) {
+		t.Errorf("expected ErrIndexOutOfRange, got %v", err)
+	}
+}
+
+func TestSequenceNode_Mutations(t *testing.T) {
+	document := parseDocument(t, mutationSource)
+	ports, _ := document.Root().(*yaml.MappingNode).Get("ports")
+	sequence := ports.(*yaml.SequenceNode)
+
+	sequence.Append(yaml.NewScalarNode("8080"))
+	if err := sequence.Insert(0, yaml.NewScalarNode("22")); err != nil {
+		t.Fatal(err)
+	}
+	if err := sequence.Set(1, yaml.NewScalarNode("8000")); err != nil {
+		t.Fatal(err)
+	}
+	if err := sequence.Move(3, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := sequence.Delete(0); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "name: web\nreplicas: 3\nports:\n  - 8080\n  - 8000\n  - 443\nlabels:\n  app: web\n  tier: frontend\n"
+	if actual := emit(t, document); actual != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, actual)
+	}
+
+	for _, err := range []error{
+		sequence.Set(3, yaml.NewScalarNode("x")),
+		sequence.Insert(4, yaml.NewScalarNode("x")),
+		sequence.Delete(-1),
+		sequence.Move(0, 3),
+	} {
+		if !errors.Is(err, yaml.ErrIndexOutOfRange) {
+			t.Errorf("expected ErrIndexOutOfRange, got %v", err)
+		}
+	}
+}
@@ -4,6 +4,9 @@ import "github.com/ercross/yaml/token"
 
 type (
 	NodeType int8
+
+	// Style is the presentation style of a Node in the YAML source
+	Style int8
 )
 
 const (
@@ -38,6 +41,9 @@ const (
 	// NodeTypeAnchor represents an anchor node, allowing values to be reused or referenced elsewhere in the document.
 	// Example:
 	//   base: &baseAnchor "Base Value"
+	//
+	// Deprecated: an anchor is a property of the node it names, read through Node.Anchor.
+	// No node reports this type; it keeps the values of the later constants unchanged.
 	NodeTypeAnchor
 
 	// NodeTypeSequenceFlowStyle represents a sequence in flow style, denoted by square brackets `[]`. This style is non-nestable.
@@ -66,40 +72,131 @@ const (
 	NodeTypeMappingBlockStyle
 
 	// NodeTypeAlias represents a reference to an anchor node, allowing the reuse of values defined by an anchor.
+	// Anchors themselves are not nodes but a property of the anchored node, see Node.Anchor.
 	// Example:
 	//   base: &baseAnchor "Base Value"
 	//   alias: *baseAnchor
 	NodeTypeAlias
 )
 
+const (
+	// StyleDefault leaves the presentation of a Node to whoever writes it out.
+	// Nodes created programmatically usually have StyleDefault
+	StyleDefault Style = iota
+
+	// StylePlain is an unquoted scalar, e.g., key: value
+	StylePlain
+
+	// StyleSingleQuoted is a scalar enclosed in single quotes, e.g., key: 'value'
+	StyleSingleQuoted
+
+	// StyleDoubleQuoted is a scalar enclosed in double quotes, e.g., key: "value"
+	StyleDoubleQuoted
+
+	// StyleLiteral is a block scalar preserving line breaks, introduced by `|`
+	StyleLiteral
+
+	// StyleFolded is a block scalar folding line breaks into spaces, introduced by `>`
+	StyleFolded
+
+	// StyleFlow is a sequence or mapping written within `[]` or `{}`
+	StyleFlow
+
+	// StyleBlock is a sequence or mapping written using indentation
+	StyleBlock
+)
+
 type (
 	Node interface {
-		Key() string
 		Type() NodeType
-		Value() interface{}
-		Children() any
+
+		// Tag is the explicit tag of the Node (e.g., !!str) or, if none was given, the tag resolved for it
+		Tag() string
+		Style() Style
+
+		// Anchor is the anchor name defined on the Node, without the `&` indicator
+		Anchor() string
+
+		// Position is the location of the first token of the Node
+		Position() token.Location
+
+		// HeadComment is the comment on the lines preceding the Node, e.g., "# note".
+		// Comments keep their # indicator, and the lines of a multi-line comment are separated by \n
+		HeadComment() string
+
+		// LineComment is the comment following the Node on the same line
+		LineComment() string
+
+		// FootComment is the comment on the lines following the Node, more indented than the node that follows
+		FootComment() string
+
+		// Children returns the nodes directly nested in the Node.
+		// For a MappingNode, keys and values alternate in source order.
+		// Scalar and alias nodes have no children
+		Children() []Node
 	}
 
 	NodeBuilder interface {
 		AddChild(Node)
-		SetValue(any)
 		ToNode() Node
-		SetKey(key string)
-		CurrentPosition() token.Location
-		SetCurrentPosition(position token.Location)
+		Position() token.Location
+		SetPosition(position token.Location)
 	}
 )
 
 type (
 	// DocumentNode is usually the root of an AbstractSyntaxTree
 	DocumentNode struct {
-		children []Node
+		nodeProperties
+		root Node
 	}
 
 	ScalarNode struct {
-		value           any
-		key             string
-		currentPosition token.Location
+		nodeProperties
+		value string
+	}
+
+	// MappingNode is an ordered collection of key/value pairs
+	MappingNode struct {
+		nodeProperties
+		pairs []MappingPair
+
+		// pendingKey is the key added by AddChild still waiting for its value
+		pendingKey Node
+	}
+
+	// MappingPair is a single key/value entry of a MappingNode
+	MappingPair struct {
+		Key   Node
+		Value Node
+	}
+
+	// SequenceNode is an ordered collection of nodes
+	SequenceNode struct {
+		nodeProperties
+		items []Node
+	}
+
+	// AliasNode refers to a node previously defined with an anchor
+	AliasNode struct {
+		nodeProperties
+		name   string
+		target Node
+	}
+
+	// nodeProperties are the properties shared by every Node
+	nodeProperties struct {
+		tag      string
+		style    Style
+		anchor   string
+		position token.Location
+		comments comments
+	}
+
+	comments struct {
+		head string
+		line string
+		foot string
 	}
 )
 
@@ -116,73 +213,273 @@ func (nt NodeType) IsNestable() bool {
 	}
 }
 
-func NewDocumentNode() *DocumentNode {
-	return &DocumentNode{}
+func (p *nodeProperties) Style() Style {
+	return p.style
 }
 
-func (n *DocumentNode) Key() string {
-	return ""
+func (p *nodeProperties) SetStyle(style Style) {
+	p.style = style
+}
+
+func (p *nodeProperties) Anchor() string {
+	return p.anchor
+}
+
+func (p *nodeProperties) SetAnchor(anchor string) {
+	p.anchor = anchor
+}
+
+// SetTag sets an explicit tag, given in any of its source forms (e.g., !!str, !<tag:yaml.org,2002:str> or !local)
+func (p *nodeProperties) SetTag(tag string) {
+	p.tag = normalizeTag(tag)
+}
+
+func (p *nodeProperties) Position() token.Location {
+	return p.position
+}
+
+func (p *nodeProperties) SetPosition(position token.Location) {
+	p.position = position
+}
+
+func (p *nodeProperties) HeadComment() string {
+	return p.comments.head
+}
+
+func (p *nodeProperties) SetHeadComment(comment string) {
+	p.comments.head = comment
+}
+
+func (p *nodeProperties) LineComment() string {
+	return p.comments.line
+}
+
+func (p *nodeProperties) SetLineComment(comment string) {
+	p.comments.line = comment
+}
+
+func (p *nodeProperties) FootComment() string {
+	return p.comments.foot
+}
+
+func (p *nodeProperties) SetFootComment(comment string) {
+	p.comments.foot = comment
+}
+
+func NewDocumentNode() *DocumentNode {
+	return &DocumentNode{}
 }
 
 func (n *DocumentNode) Type() NodeType {
 	return NodeTypeDocument
 }
 
-func (n *DocumentNode) Value() any {
-	return nil
+func (n *DocumentNode) Tag() string {
+	return ""
+}
+
+// Root is the single top-level node of the document, or nil if the document is empty
+func (n *DocumentNode) Root() Node {
+	return n.root
 }
 
 func (n *DocumentNode) Children() []Node {
-	return n.children
+	if n.root == nil {
+		return nil
+	}
+	return []Node{n.root}
 }
 
+// AddChild sets child as the document root. A document can only hold a single root
 func (n *DocumentNode) AddChild(child Node) {
-	n.children = append(n.children, child)
+	if n.root != nil {
+		panic("document already has a root node")
+	}
+	n.root = child
 }
 
-func (n *DocumentNode) SetValue(_ interface{}) {
-	return
+func (n *DocumentNode) ToNode() Node {
+	return n
 }
 
-func NewScalarNodeBuilder() *ScalarNode {
-	return &ScalarNode{}
+func NewScalarNode(value string) *ScalarNode {
+	return &ScalarNode{value: value}
 }
 
-func (n *ScalarNode) Key() string {
-	return n.key
+func (n *ScalarNode) Type() NodeType {
+	switch n.style {
+	case StyleLiteral:
+		return NodeTypeMultilineString
+	case StyleFolded:
+		return NodeTypeFoldedString
+	default:
+		return NodeTypeScalar
+	}
 }
 
-func (n *ScalarNode) Type() NodeType {
-	return NodeTypeScalar
+// Tag returns the explicit tag of the scalar, or the tag resolved from its value by the YAML 1.2 core schema.
+// Only plain scalars are resolved, every other style is a TagString
+func (n *ScalarNode) Tag() string {
+	if n.tag != "" && n.tag != TagNonSpecific {
+		return n.tag
+	}
+	if n.tag == TagNonSpecific || (n.style != StylePlain && n.style != StyleDefault) {
+		return TagString
+	}
+	return resolveTag(n.value)
 }
 
-func (n *ScalarNode) Value() any {
+// Value is the content of the scalar, with quotes, escape sequences and block indentation already processed
+func (n *ScalarNode) Value() string {
 	return n.value
 }
 
-func (n *ScalarNode) Children() any {
-	panic("can not get children of scalar node")
+func (n *ScalarNode) Children() []Node {
+	return nil
 }
 
 func (n *ScalarNode) AddChild(_ Node) {
 	panic("can not add child to scalar node")
 }
-func (n *ScalarNode) SetValue(v any) {
+
+func (n *ScalarNode) SetValue(v string) {
 	n.value = v
 }
 
-func (n *ScalarNode) SetKey(key string) {
-	n.key = key
+func (n *ScalarNode) ToNode() Node {
+	return n
 }
 
-func (n *ScalarNode) ToNode() Node {
+func NewMappingNode() *MappingNode {
+	return &MappingNode{}
+}
+
+func (n *MappingNode) Type() NodeType {
+	if n.style == StyleFlow {
+		return NodeTypeMappingFlowStyle
+	}
+	return NodeTypeMappingBlockStyle
+}
+
+func (n *MappingNode) Tag() string {
+	if n.tag != "" {
+		return n.tag
+	}
+	return TagMap
+}
+
+// Pairs returns the key/value pairs of the mapping in source order.
+// The returned slice must not be modified
+func (n *MappingNode) Pairs() []MappingPair {
+	return n.pairs
+}
+
+// Len is the number of key/value pairs in the mapping
+func (n *MappingNode) Len() int {
+	return len(n.pairs)
+}
+
+func (n *MappingNode) Children() []Node {
+	children := make([]Node, 0, 2*len(n.pairs))
+	for _, pair := range n.pairs {
+		children = append(children, pair.Key, pair.Value)
+	}
+	return children
+}
+
+// AddChild alternately adds a key and its value to the mapping
+func (n *MappingNode) AddChild(child Node) {
+	if n.pendingKey == nil {
+		n.pendingKey = child
+		return
+	}
+	n.pairs = append(n.pairs, MappingPair{Key: n.pendingKey, Value: child})
+	n.pendingKey = nil
+}
+
+// ToNode completes the mapping. A key still waiting for its value is given an empty (null) value
+func (n *MappingNode) ToNode() Node {
+	if n.pendingKey != nil {
+		n.AddChild(NewScalarNode(""))
+	}
 	return n
 }
 
-func (n *ScalarNode) SetCurrentPosition(position token.Location) {
-	n.currentPosition = position
+func NewSequenceNode() *SequenceNode {
+	return &SequenceNode{}
 }
 
-func (n *ScalarNode) CurrentPosition() token.Location {
-	return n.currentPosition
+func (n *SequenceNode) Type() NodeType {
+	if n.style == StyleFlow {
+		return NodeTypeSequenceFlowStyle
+	}
+	return NodeTypeSequenceBlockStyle
+}
+
+func (n *SequenceNode) Tag() string {
+	if n.tag != "" {
+		return n.tag
+	}
+	return TagSeq
+}
+
+// Items returns the entries of the sequence in source order.
+// The returned slice must not be modified
+func (n *SequenceNode) Items() []Node {
+	return n.items
+}
+
+// Len is the number of entries in the sequence
+func (n *SequenceNode) Len() int {
+	return len(n.items)
+}
+
+func (n *SequenceNode) Children() []Node {
+	return n.items
+}
+
+func (n *SequenceNode) AddChild(child Node) {
+	n.items = append(n.items, child)
+}
+
+func (n *SequenceNode) ToNode() Node {
+	return n
+}
+
+// NewAliasNode creates an alias named name, referring to target
+func NewAliasNode(name string, target Node) *AliasNode {
+	return &AliasNode{name: name, target: target}
+}
+
+func (n *AliasNode) Type() NodeType {
+	return NodeTypeAlias
+}
+
+// Tag is always empty: an alias can not carry properties of its own
+func (n *AliasNode) Tag() string {
+	return ""
+}
+
+// Name is the anchor name the alias refers to, without the `*` indicator
+func (n *AliasNode) Name() string {
+	return n.name
+}
+
+// Target is the anchored node the alias refers to
+func (n *AliasNode) Target() Node {
+	return n.target
+}
+
+// Children of an alias are always empty, so that tree walkers never loop through recursive aliases.
+// Use Target to follow the alias
+func (n *AliasNode) Children() []Node {
+	return nil
+}
+
+func (n *AliasNode) AddChild(_ Node) {
+	panic("can not add child to alias node")
+}
+
+func (n *AliasNode) ToNode() Node {
+	return n
 }
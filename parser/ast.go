@@ -4,22 +4,40 @@ import "github.com/ercross/yaml"
 
 type AbstractSyntaxTree struct {
 	documents []*yaml.DocumentNode
+
+	// indentationUnits are the indentation units inferred for each document, see IndentationUnit
+	indentationUnits map[*yaml.DocumentNode]int
+
+	warnings []error
 }
 
 func newAbstractSyntaxTree() *AbstractSyntaxTree {
-	return &AbstractSyntaxTree{
-		documents: []*yaml.DocumentNode{
-			yaml.NewDocumentNode(),
-		},
-	}
+	return &AbstractSyntaxTree{indentationUnits: make(map[*yaml.DocumentNode]int)}
+}
+
+// Documents returns the documents of the yaml stream in source order.
+// An empty stream has no documents
+func (ast *AbstractSyntaxTree) Documents() []*yaml.DocumentNode {
+	return ast.documents
+}
+
+// IndentationUnit returns the number of spaces document indents nested block nodes by, as inferred by the indentationManager
+// from the first nested node, e.g., 2 for a node following a "- " sequence entry indicator. It is 0 if document nests no node
+func (ast *AbstractSyntaxTree) IndentationUnit(document *yaml.DocumentNode) int {
+	return ast.indentationUnits[document]
 }
 
-// addChild adds n to the yaml document currently being built
-func (ast *AbstractSyntaxTree) addChild(n yaml.Node) {
-	currentlyParsedDocumentIndex := len(ast.documents) - 1
-	ast.documents[currentlyParsedDocumentIndex].AddChild(n)
+// Warnings returns the problems found while building the documents that did not stop parsing,
+// e.g., the *yaml.DuplicateKeyError of every duplicate key with yaml.DuplicateKeyWarn
+func (ast *AbstractSyntaxTree) Warnings() []error {
+	return ast.warnings
 }
 
-func (ast *AbstractSyntaxTree) startAnotherDocument() {
-	ast.documents = append(ast.documents, yaml.NewDocumentNode())
+// addDocument appends a completely built document to the AbstractSyntaxTree,
+// along with the indentation unit inferred for it, or 0 if none was
+func (ast *AbstractSyntaxTree) addDocument(document *yaml.DocumentNode, indentationUnit int) {
+	ast.documents = append(ast.documents, document)
+	if indentationUnit > 0 {
+		ast.indentationUnits[document] = indentationUnit
+	}
 }
@@ -2,6 +2,7 @@ package parser
 
 import (
 	testdata "github.com/ercross/yaml/test/data"
+	"sync"
 	"testing"
 )
 
@@ -15,3 +16,30 @@ func TestAstBuilder_ParseScalarTokenSequence(t *testing.T) {
 		}
 	}
 }
+
+// TestAstBuilder_ConcurrentBuild is meant to be run with the -race flag
+func TestAstBuilder_ConcurrentBuild(t *testing.T) {
+	const workers = 8
+
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			astBuilder := NewAstBuilder()
+			for _, tokens := range testdata.ScalarTokens {
+				if err := astBuilder.Build(tokens); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+}
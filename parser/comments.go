@@ -0,0 +1,59 @@
+package parser
+
+import (
+	"github.com/ercross/yaml"
+	"github.com/ercross/yaml/token"
+)
+
+// comments are the head, line and foot comments of a node, held until the node is complete
+type comments struct {
+	head string
+	line string
+	foot string
+}
+
+// commentLine is a line holding nothing but a comment
+type commentLine struct {
+	text        string
+	indentation int
+}
+
+func (c comments) isEmpty() bool {
+	return c.head == "" && c.line == "" && c.foot == ""
+}
+
+// applyTo gives the comments to the node made of events: the head and line comments to the event starting it,
+// and the foot comment to the event ending it
+func (c comments) applyTo(events []yaml.Event) {
+	if c.head != "" {
+		events[0].HeadComment = c.head
+	}
+	if c.line != "" {
+		events[0].LineComment = c.line
+	}
+	if c.foot != "" {
+		events[len(events)-1].FootComment = c.foot
+	}
+}
+
+// appendLine adds a comment line to comment, which may already hold several lines
+func appendLine(comment string, line string) string {
+	if comment == "" {
+		return line
+	}
+	return comment + "\n" + line
+}
+
+// lineComment returns the comment ending tokens, with its # indicator, or an empty string
+func lineComment(tokens []token.Token) string {
+	for i := len(tokens) - 1; i >= 0; i-- {
+		switch tokens[i].Type {
+		case token.TypeNewline:
+		case token.TypeComment:
+			return string(token.CharCommentStarter) + tokens[i].Value
+		default:
+			return ""
+		}
+	}
+	return ""
+}
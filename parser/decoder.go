@@ -0,0 +1,265 @@
+package parser
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/ercross/yaml"
+	"io"
+)
+
+// Decoder reads the yaml stream of a reader and decodes its nodes one at a time, analogous to json.Decoder.
+//
+// The stream is parsed line by line as events are requested, so that walking into a block collection with Token
+// and decoding its entries with Decode holds one entry at a time, rather than the whole document, e.g.:
+//
+//	decoder := parser.NewDecoder(r)
+//	for {
+//		event, err := decoder.Token()
+//		if err != nil {
+//			return err
+//		}
+//		if event.Type == yaml.EventSequenceStart {
+//			break
+//		}
+//	}
+//	for decoder.More() {
+//		var item Item
+//		if err := decoder.Decode(&item); err != nil {
+//			return err
+//		}
+//	}
+//
+// Flow collections, and the nodes held on a single line, are parsed whole
+type Decoder struct {
+	lines *lineReader
+
+	// events are the events parsed and not yet consumed
+	events []yaml.Event
+
+	// composer composes the nodes decoded within the current document, keeping their anchors
+	composer      *yaml.Composer
+	duplicateKeys yaml.DuplicateKeyPolicy
+
+	// warnings are the warnings of the composers of the previous documents
+	warnings []error
+
+	// err is the error that ended parsing, or io.EOF once the stream is parsed
+	err error
+
+	// ctx stops decoding once done, checked on every line read, as it is tokenized, and every contextCheckInterval events consumed
+	ctx      context.Context
+	consumed int
+}
+
+// NewDecoder creates a Decoder reading the yaml stream of r
+func NewDecoder(r io.Reader) *Decoder {
+	d := &Decoder{ctx: context.Background()}
+	d.resetComposer()
+	d.lines = newLineReader(r, NewEventParser(func(event yaml.Event) error {
+		d.events = append(d.events, event)
+		return nil
+	}))
+	return d
+}
+
+// SetDuplicateKeyPolicy sets what is done with duplicate mapping keys, yaml.DuplicateKeyReject by default.
+// Keys are checked within the nodes and documents composed by Decode, but not across the events returned by Token.
+// With yaml.DuplicateKeyWarn, duplicate keys are reported by Warnings
+func (d *Decoder) SetDuplicateKeyPolicy(policy yaml.DuplicateKeyPolicy) {
+	d.duplicateKeys = policy
+	d.composer.SetDuplicateKeyPolicy(policy)
+}
+
+// Warnings returns the problems found while decoding that did not stop decoding, see SetDuplicateKeyPolicy
+func (d *Decoder) Warnings() []error {
+	return append(d.warnings[:len(d.warnings):len(d.warnings)], d.composer.Warnings()...)
+}
+
+// Token returns the next event of the stream, see yaml.Event, and io.EOF once the StreamEnd event has been returned.
+// Anchored scalars returned by Token can be referred to by the aliases of the nodes decoded later on
+func (d *Decoder) Token() (yaml.Event, error) {
+	event, err := d.next()
+	if err != nil {
+		return event, err
+	}
+
+	switch {
+	case event.Type == yaml.EventDocumentStart:
+		d.resetComposer()
+	case event.Type == yaml.EventScalar && event.Anchor != "":
+		if _, err = d.composer.AddNode(event); err != nil {
+			return event, err
+		}
+	}
+	return event, nil
+}
+
+// More reports whether a node follows within the collection or document being read, or a document within the stream
+func (d *Decoder) More() bool {
+	event, err := d.peek(0)
+	if err == nil && event.Type == yaml.EventStreamStart {
+		event, err = d.peek(1)
+	}
+	if err != nil {
+		return false
+	}
+
+	switch event.Type {
+	case yaml.EventMappingEnd, yaml.EventSequenceEnd, yaml.EventDocumentEnd, yaml.EventStreamEnd:
+		return false
+	}
+	return true
+}
+
+// Decode reads the next node and stores its value into the Go value v points to, see yaml.Decode.
+// Between documents, the next document is read whole. Decode returns io.EOF at the end of the stream.
+//
+// A node failing to decode into v is still consumed, so that decoding can go on with the node following it
+func (d *Decoder) Decode(v any) error {
+	return d.DecodeContext(context.Background(), v)
+}
+
+// DecodeContext is like Decode, stopping once ctx is done: ctx is checked as the stream is read, on every line,
+// as long lines are tokenized, and as nodes are composed. The error of ctx is returned along with the position decoding stopped at.
+// Like a parsing error, it ends decoding: every later call returns it
+func (d *Decoder) DecodeContext(ctx context.Context, v any) error {
+	d.setContext(ctx)
+	defer d.setContext(context.Background())
+
+	event, err := d.peek(0)
+	if err == nil && event.Type == yaml.EventStreamStart {
+		d.events = d.events[1:]
+		event, err = d.peek(0)
+	}
+	if err != nil {
+		return err
+	}
+
+	switch event.Type {
+	case yaml.EventStreamEnd:
+		_, err = d.next()
+		if err == nil {
+			err = io.EOF
+		}
+		return err
+
+	case yaml.EventMappingEnd, yaml.EventSequenceEnd, yaml.EventDocumentEnd:
+		return fmt.Errorf("no node to decode before the %s event at %s", event.Type, event.Start)
+
+	case yaml.EventDocumentStart:
+		document, err := d.document()
+		if err != nil {
+			return err
+		}
+		return yaml.DecodeWithOptions(document, v, d.decodeOptions())
+	}
+
+	n, err := d.node()
+	if err != nil {
+		return err
+	}
+	return yaml.DecodeWithOptions(n, v, d.decodeOptions())
+}
+
+// Unmarshal decodes the first document of the yaml stream data into the Go value v points to, see yaml.Decode.
+// An empty stream leaves v unchanged, and the documents following the first one are not decoded.
+// Aliases expand to yaml.DefaultMaxAliasExpansion nodes at most, see Decoder.SetLimits to change it
+func Unmarshal(data []byte, v any) error {
+	return UnmarshalContext(context.Background(), data, v)
+}
+
+// UnmarshalContext is like Unmarshal, stopping once ctx is done, see Decoder.DecodeContext
+func UnmarshalContext(ctx context.Context, data []byte, v any) error {
+	err := NewDecoder(bytes.NewReader(data)).DecodeContext(ctx, v)
+	if err == io.EOF {
+		return nil
+	}
+	return err
+}
+
+// decodeOptions are the options of the nodes d decodes, enforcing its limits
+func (d *Decoder) decodeOptions() yaml.DecodeOptions {
+	return yaml.DecodeOptions{MaxAliasExpansion: d.lines.limits.MaxAliasExpansion}
+}
+
+// document composes the document starting with the next event
+func (d *Decoder) document() (*yaml.DocumentNode, error) {
+	d.resetComposer()
+	for {
+		event, err := d.next()
+		if err != nil {
+			return nil, err
+		}
+		document, err := d.composer.Add(event)
+		if err != nil {
+			d.err = err
+			return nil, err
+		}
+		if document != nil {
+			d.resetComposer()
+			return document, nil
+		}
+	}
+}
+
+// node composes the node starting with the next event
+func (d *Decoder) node() (yaml.Node, error) {
+	for {
+		event, err := d.next()
+		if err != nil {
+			return nil, err
+		}
+		n, err := d.composer.AddNode(event)
+		if err != nil {
+			d.err = err
+			return nil, err
+		}
+		if n != nil {
+			return n, nil
+		}
+	}
+}
+
+// resetComposer replaces the composer at the start of a document, keeping the warnings of the previous one
+func (d *Decoder) resetComposer() {
+	if d.composer != nil {
+		d.warnings = append(d.warnings, d.composer.Warnings()...)
+	}
+	d.composer = yaml.NewComposer()
+	d.composer.SetDuplicateKeyPolicy(d.duplicateKeys)
+}
+
+// setContext sets the context stopping d once done
+func (d *Decoder) setContext(ctx context.Context) {
+	d.ctx = ctx
+	d.lines.input.ctx = ctx
+}
+
+// next consumes the next event
+func (d *Decoder) next() (yaml.Event, error) {
+	event, err := d.peek(0)
+	if err != nil {
+		return event, err
+	}
+	d.consumed++
+	if d.consumed%contextCheckInterval == 0 {
+		if err = d.ctx.Err(); err != nil {
+			d.err = fmt.Errorf("decoding stopped at %s: %w", event.Start, err)
+			return event, d.err
+		}
+	}
+	d.events = d.events[1:]
+	return event, nil
+}
+
+// peek returns the event following the next i events, parsing lines until it is available
+func (d *Decoder) peek(i int) (yaml.Event, error) {
+	for len(d.events) <= i {
+		if d.err != nil {
+			return yaml.Event{}, d.err
+		}
+		d.err = d.lines.next()
+	}
+	return d.events[i], nil
+}
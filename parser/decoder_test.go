@@ -0,0 +1,237 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ercross/yaml"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// countingReader counts the bytes read from its reader
+type countingReader struct {
+	reader io.Reader
+	read   int
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.read += n
+	return n, err
+}
+
+func TestDecoder_Sequence(t *testing.T) {
+	type item struct {
+		Name string `yaml:"name"`
+		Size int    `yaml:"size"`
+	}
+
+	var b strings.Builder
+	b.WriteString("# export\nitems:\n")
+	for i := 0; i < 5000; i++ {
+		fmt.Fprintf(&b, "  - name: item%d\n    size: %d\n", i, i)
+	}
+	b.WriteString("total: 5000\n")
+	source := &countingReader{reader: strings.NewReader(b.String())}
+
+	decoder := NewDecoder(source)
+	for {
+		event, err := decoder.Token()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if event.Type == yaml.EventSequenceStart {
+			break
+		}
+	}
+
+	count := 0
+	for decoder.More() {
+		var actual item
+		if err := decoder.Decode(&actual); err != nil {
+			t.Fatal(err)
+		}
+		if expected := (item{Name: fmt.Sprintf("item%d", count), Size: count}); actual != expected {
+			t.Fatalf("expected %+v, got %+v", expected, actual)
+		}
+		if count == 0 && source.read == b.Len() {
+			t.Error("expected the first item to be decoded before the whole stream is read")
+		}
+		count++
+	}
+	if count != 5000 {
+		t.Errorf("expected 5000 items, got %d", count)
+	}
+
+	var types []yaml.EventType
+	for {
+		event, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		types = append(types, event.Type)
+	}
+	expected := []yaml.EventType{yaml.EventSequenceEnd, yaml.EventScalar, yaml.EventScalar,
+		yaml.EventMappingEnd, yaml.EventDocumentEnd, yaml.EventStreamEnd}
+	if !reflect.DeepEqual(types, expected) {
+		t.Errorf("expected events %v, got %v", expected, types)
+	}
+}
+
+func TestDecoder_Documents(t *testing.T) {
+	decoder := NewDecoder(strings.NewReader("a: 1\n---\n- x\n---\n"))
+	var documents []any
+	for decoder.More() {
+		var document any
+		if err := decoder.Decode(&document); err != nil {
+			t.Fatal(err)
+		}
+		documents = append(documents, document)
+	}
+	expected := []any{map[string]any{"a": 1}, []any{"x"}, nil}
+	if !reflect.DeepEqual(documents, expected) {
+		t.Errorf("expected %#v, got %#v", expected, documents)
+	}
+
+	var document any
+	if err := decoder.Decode(&document); err != io.EOF {
+		t.Errorf("expected io.EOF at the end of the stream, got %v", err)
+	}
+}
+
+func TestDecoder_Anchors(t *testing.T) {
+	decoder := NewDecoder(strings.NewReader("base: &b x\nitems:\n  - &i {a: *b}\n  - *i\n  - *missing\n"))
+	for {
+		event, err := decoder.Token()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if event.Type == yaml.EventSequenceStart {
+			break
+		}
+	}
+
+	var items []any
+	for range 2 {
+		var item any
+		if err := decoder.Decode(&item); err != nil {
+			t.Fatal(err)
+		}
+		items = append(items, item)
+	}
+	expected := []any{map[string]any{"a": "x"}, map[string]any{"a": "x"}}
+	if !reflect.DeepEqual(items, expected) {
+		t.Errorf("expected %#v, got %#v", expected, items)
+	}
+
+	var item any
+	if err := decoder.Decode(&item); !errors.Is(err, errUnknownAnchor) {
+		t.Errorf("expected %v, got %v", errUnknownAnchor, err)
+	}
+}
+
+func TestDecoder_TypeMismatch(t *testing.T) {
+	decoder := NewDecoder(strings.NewReader("- 1\n- x\n- 3\n"))
+	if _, err := decoder.Token(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := decoder.Token(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := decoder.Token(); err != nil {
+		t.Fatal(err)
+	}
+
+	var values []int
+	for decoder.More() {
+		var value int
+		if err := decoder.Decode(&value); err != nil {
+			if !errors.Is(err, yaml.ErrTypeMismatch) {
+				t.Fatalf("expected %v, got %v", yaml.ErrTypeMismatch, err)
+			}
+			continue
+		}
+		values = append(values, value)
+	}
+	if !reflect.DeepEqual(values, []int{1, 3}) {
+		t.Errorf("expected decoding to go on after a mismatch, got %v", values)
+	}
+}
+
+func TestDecoder_DuplicateKeys(t *testing.T) {
+	source := "a: 1\na: 2\n---\nb: 1\nb: 2\n"
+	var document map[string]int
+	if err := NewDecoder(strings.NewReader(source)).Decode(&document); !errors.Is(err, yaml.ErrDuplicateKey) {
+		t.Errorf("expected yaml.ErrDuplicateKey, got %v", err)
+	}
+
+	decoder := NewDecoder(strings.NewReader(source))
+	decoder.SetDuplicateKeyPolicy(yaml.DuplicateKeyWarn)
+	var documents []map[string]int
+	for decoder.More() {
+		if err := decoder.Decode(&document); err != nil {
+			t.Fatal(err)
+		}
+		documents = append(documents, document)
+		document = nil
+	}
+	expected := []map[string]int{{"a": 2}, {"b": 2}}
+	if !reflect.DeepEqual(documents, expected) {
+		t.Errorf("expected %v, got %v", expected, documents)
+	}
+	if warnings := decoder.Warnings(); len(warnings) != 2 {
+		t.Errorf("expected a warning per document, got %v", warnings)
+	}
+}
+
+func TestDecoder_DecodeContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	decoder := NewDecoder(strings.NewReader("- 1\n- 2\n"))
+	var values []int
+	if err := decoder.DecodeContext(ctx, &values); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(values, []int{1, 2}) {
+		t.Errorf("expected [1 2], got %v", values)
+	}
+
+	cancel()
+	decoder = NewDecoder(strings.NewReader("- 1\n- 2\n"))
+	err := decoder.DecodeContext(ctx, &values)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("expected the line decoding stopped at, got %v", err)
+	}
+	if err = decoder.Decode(&values); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected decoding to have ended, got %v", err)
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	var document map[string]int
+	if err := Unmarshal([]byte("a: 1\n---\nb: [\n"), &document); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(document, map[string]int{"a": 1}) {
+		t.Errorf("expected the first document, got %v", document)
+	}
+
+	document = nil
+	if err := Unmarshal(nil, &document); err != nil || document != nil {
+		t.Errorf("expected an empty stream to leave the value unchanged, got %v, %v", document, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := UnmarshalContext(ctx, []byte("a: 1\n"), &document); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
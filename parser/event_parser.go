@@ -0,0 +1,640 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ercross/yaml"
+	"github.com/ercross/yaml/token"
+)
+
+var (
+	errInconsistentIndentation = errors.New("inconsistent indentation")
+	errIncompleteNode          = errors.New("incomplete node")
+)
+
+// EventParser parses tokens into the events of a yaml stream, see yaml.Event, without building any node.
+//
+// Events are handed to the handler as soon as they are parsed: the events of a block collection are produced
+// entry by entry, while the events of a node held on a single line (or a flow collection spanning several lines)
+// are produced once the node is complete. Aliases are not resolved, so an alias of an unknown anchor
+// is only reported by the consumer resolving it, e.g., yaml.Composer
+type EventParser struct {
+	handler        func(event yaml.Event) error
+	stack          *stack
+	awaitingParse  []token.Token
+	nodeTypeFinder *nodeTypeFinder
+
+	// root is the node of the document currently being parsed
+	root *blockValue
+
+	// streamStarted and documentStarted indicate that the StreamStart and DocumentStart events have been produced
+	streamStarted   bool
+	documentStarted bool
+
+	// last is the location following the last character of the last event produced
+	last token.Location
+
+	// pendingComments are the comment lines not yet attached to a node
+	pendingComments []commentLine
+
+	// headComment is the head comment of the node built by the next call to buildEntry
+	headComment string
+
+	// frameComments are the comments of the node each Frame builds, applied once the Frame is popped
+	frameComments map[Frame]*comments
+
+	// documentComments are the comments of the document currently being parsed
+	documentComments comments
+
+	// limits are enforced on the events produced, counted by depth, nodes and documents
+	limits    Limits
+	depth     int
+	nodes     int
+	documents int
+}
+
+// NewEventParser creates an EventParser handing the events parsed from tokens to handler.
+// An error returned by handler stops parsing, and is returned by the Build or Finish call that produced the event.
+//
+// Distinct EventParser instances can be used concurrently,
+// but a single EventParser must not be used from multiple goroutines at once.
+func NewEventParser(handler func(event yaml.Event) error) *EventParser {
+	return &EventParser{
+		handler:        handler,
+		stack:          newStack(),
+		nodeTypeFinder: newNodeTypeFinder(grammar),
+		root:           &blockValue{},
+		frameComments:  make(map[Frame]*comments),
+	}
+}
+
+// Build parses tokens into events.
+//
+// Build maintains an internal state, which enables it to continuously parse over multiple invocations.
+// Tokens are expected one line at a time, as produced by tokenizer.Tokenizer
+func (p *EventParser) Build(tokens []token.Token) error {
+	if len(tokens) == 0 {
+		return errors.New("can not parse empty tokens")
+	}
+	if err := p.startStream(); err != nil {
+		return err
+	}
+
+	if tokens[0].Type == token.TypeBlockScalarLine {
+		if p.stack.isEmpty() {
+			return fmt.Errorf("block scalar content at %s has no header: %w", tokens[0].Position, errUnexpectedTokenType)
+		}
+		frame, ok := p.stack.peek().(*blockScalarFrame)
+		if !ok {
+			return fmt.Errorf("block scalar content at %s has no header: %w", tokens[0].Position, errUnexpectedTokenType)
+		}
+		return frame.Build(tokens)
+	}
+
+	if !p.stack.isEmpty() {
+		if frame, ok := p.stack.peek().(multilineFrame); ok && !frame.isComplete() {
+			if err := frame.Build(tokens); err != nil {
+				return fmt.Errorf("error building %d frame near line %d: %w", frame.NodeType(), tokens[0].Position.Line(), err)
+			}
+			return nil
+		}
+	}
+
+	if len(p.awaitingParse) == 0 {
+		if isBlankLine(tokens) {
+			p.addCommentLine(tokens)
+			return nil
+		}
+		if tokens[0].Type == token.TypeDocumentStart || tokens[0].Type == token.TypeDocumentEnd {
+			return p.handleDocumentMarker(tokens)
+		}
+	}
+
+	p.nodeTypeFinder.match(tokens)
+	if !p.nodeTypeFinder.done {
+		if tokens[len(tokens)-1].Type == token.TypeNewline {
+			return fmt.Errorf("can not determine node type on line %d: %w", tokens[0].Position.Line(), errUnexpectedTokenType)
+		}
+
+		p.awaitingParse = append(p.awaitingParse, tokens...)
+
+		// continue finding on getting the next set of tokens since nodeTypeFinder is not done
+		return nil
+	}
+
+	tokens = append(p.awaitingParse, tokens...)
+	nodeType := p.nodeTypeFinder.nodeType()
+	p.nodeTypeFinder.reset()
+	p.awaitingParse = nil
+
+	relationship, indentationLength := p.stack.indentationManager.findIndentation(tokens)
+	if relationship == indentationRelationshipUnknown && p.stack.isEmpty() && p.root.isEmpty() {
+		// the root node of a document may be indented, which sets the indentation of the whole document
+		p.stack.indentationManager.indentDocument(indentationLength)
+		relationship = indentationRelationSibling
+	}
+	if relationship == indentationRelationshipUnknown {
+		return fmt.Errorf("indentation of line %d does not match any enclosing node: %w",
+			tokens[0].Position.Line(), errInconsistentIndentation)
+	}
+
+	p.headComment = p.takeHeadComment(indentationLength)
+	return p.buildEntry(tokens, nodeType, indentationLength)
+}
+
+// Finish completes the document currently being parsed, and ends the stream.
+// Finish must be called once all tokens have been given to Build
+func (p *EventParser) Finish() error {
+	if len(p.awaitingParse) > 0 {
+		return fmt.Errorf("node starting at %s is not terminated: %w", p.awaitingParse[0].Position, errIncompleteNode)
+	}
+	if !p.stack.isEmpty() {
+		if frame, ok := p.stack.peek().(multilineFrame); ok && !frame.isComplete() {
+			return fmt.Errorf("flow collection is not closed: %w", errIncompleteNode)
+		}
+	}
+	if err := p.startStream(); err != nil {
+		return err
+	}
+	if err := p.endDocument(nil); err != nil {
+		return err
+	}
+	return p.emit(yaml.Event{Type: yaml.EventStreamEnd, Start: p.last, End: p.last})
+}
+
+// buildEntry builds the node of a line (or the rest of a line following a "- " indicator) into a new Frame
+func (p *EventParser) buildEntry(tokens []token.Token, nodeType yaml.NodeType, indentation int) error {
+	frame, err := p.createNewFrame(nodeType, tokens, indentation)
+	if err != nil {
+		return fmt.Errorf("failed to create new %d frame: %w", nodeType, err)
+	}
+
+	err = p.pushOnStack(frame)
+	if err != nil {
+		return fmt.Errorf("failed to push frame on stack near line %d: %w", tokens[0].Position.Line(), err)
+	}
+
+	if nodeType == yaml.NodeTypeSequenceBlockStyle {
+		p.attachHeadComment(frame)
+		return p.buildSequenceEntry(frame, tokens)
+	}
+
+	if blockScalar, ok := frame.(*blockScalarFrame); ok {
+		blockScalar.parentIndentation = p.blockScalarParentIndentation(tokens, indentation)
+	}
+
+	err = frame.Build(tokens)
+	if err != nil {
+		return fmt.Errorf("error building %d frame near line %d: %w", nodeType, tokens[0].Position.Line(), err)
+	}
+
+	p.attachHeadComment(frame)
+	if comment := lineComment(tokens); comment != "" {
+		p.commentsOf(frame).line = comment
+	}
+	return p.enter(frame, firstNodeToken(tokens).Position)
+}
+
+// buildSequenceEntry builds the dash of a block sequence entry into frame,
+// and the node following the dash on the same line into a Frame nested in frame
+func (p *EventParser) buildSequenceEntry(frame Frame, tokens []token.Token) error {
+	dash := 0
+	for dash < len(tokens) && tokens[dash].Type != token.TypeDash {
+		dash++
+	}
+	if dash == len(tokens) {
+		return fmt.Errorf("sequence entry near line %d is missing its dash: %w", tokens[0].Position.Line(), errUnexpectedTokenType)
+	}
+
+	if err := frame.Build(tokens[:dash+1]); err != nil {
+		return err
+	}
+
+	rest := tokens[dash+1:]
+	if comment := lineComment(rest); comment != "" && (isBlankLine(rest) || isProperty(rest[0])) {
+		p.commentsOf(frame).line = comment
+	}
+	if err := p.enter(frame, tokens[dash].Position); err != nil {
+		return err
+	}
+	if isBlankLine(rest) {
+		return nil
+	}
+	if props, ok, err := readProperties(rest); ok || err != nil {
+		frame.(*blockFrame).value.properties = props
+		return err
+	}
+
+	p.nodeTypeFinder.reset()
+	p.nodeTypeFinder.match(rest)
+	if !p.nodeTypeFinder.done {
+		return fmt.Errorf("can not determine node type of sequence entry at %s: %w", tokens[dash].Position, errUnexpectedTokenType)
+	}
+	nodeType := p.nodeTypeFinder.nodeType()
+	p.nodeTypeFinder.reset()
+
+	return p.buildEntry(rest, nodeType, rest[0].Position.Column()-1)
+}
+
+// blockScalarParentIndentation finds the indentation of the node owning a block scalar:
+// the indentation of its mapping key, or of its sequence entry indicator
+func (p *EventParser) blockScalarParentIndentation(tokens []token.Token, indentation int) int {
+	for _, t := range tokens {
+		if t.Type == token.TypeColon {
+			return indentation
+		}
+	}
+
+	// the block scalar frame itself is on top of the stack
+	if p.stack.size() > 1 {
+		return p.stack.elements[p.stack.size()-2].IndentationLevel()
+	}
+	return -1
+}
+
+func (p *EventParser) createNewFrame(nt yaml.NodeType, tokens []token.Token, indentation int) (Frame, error) {
+	var frame Frame
+	switch nt {
+	case yaml.NodeTypeScalar:
+		syntax := bareScalarNodeSyntax()
+		for _, t := range tokens {
+			if t.Type == token.TypeColon {
+				syntax = scalarNodeSyntax()
+				break
+			}
+		}
+		frame = newScalarFrame(indentation, newNodeSyntaxTraverser(syntax.head))
+
+	case yaml.NodeTypeMappingBlockStyle, yaml.NodeTypeSequenceBlockStyle:
+		frame = newBlockFrame(nt, indentation)
+
+	case yaml.NodeTypeMappingFlowStyle, yaml.NodeTypeSequenceFlowStyle:
+		frame = newFlowFrame(nt, indentation, p.limits.MaxDepth)
+
+	case yaml.NodeTypeMultilineString, yaml.NodeTypeFoldedString:
+		frame = newBlockScalarFrame(nt, indentation)
+
+	case yaml.NodeTypeAlias:
+		frame = newAliasFrame(indentation)
+
+	default:
+		return nil, fmt.Errorf("can not handle NodeType %d", nt)
+	}
+	return frame, nil
+}
+
+// enter starts the node frame builds within the value it is nested in. The value becomes a mapping or a sequence
+// on its first entry, whose start event is produced then, followed by the key of a mapping entry.
+// position is the location of the entry, used to report entries that do not fit in the value
+func (p *EventParser) enter(frame Frame, position token.Location) error {
+	value, owner, err := p.valueAt(p.stack.size() - 2)
+	if err != nil {
+		return err
+	}
+
+	var collection yaml.EventType
+	switch {
+	case frame.Key() != nil:
+		collection = yaml.EventMappingStart
+	case isSequenceEntry(frame):
+		collection = yaml.EventSequenceStart
+	}
+
+	switch {
+	case value.isEmpty() && collection == 0:
+		value.standalone = true
+		return nil
+
+	case value.isEmpty():
+		value.collection = collection
+		start := yaml.Event{Type: collection, Style: yaml.StyleBlock, Start: position, End: position}
+		value.properties.applyTo(&start)
+		if c, ok := p.frameComments[owner]; ok && owner != nil {
+			start.HeadComment, start.LineComment = c.head, c.line
+		}
+		if err = p.emit(start); err != nil {
+			return err
+		}
+
+	case value.collection != collection:
+		kind := map[yaml.EventType]string{yaml.EventMappingStart: "mapping entry", yaml.EventSequenceStart: "sequence entry"}[collection]
+		if kind == "" {
+			kind = "node"
+		}
+		return fmt.Errorf("%s at %s: %w", kind, position, errMixedBlockEntries)
+	}
+
+	if key := frame.Key(); key != nil {
+		return p.emit(*key)
+	}
+	return nil
+}
+
+// exit completes the node built by the popped frame, producing its events
+// unless it is the standalone node of its parent, which holds them until it completes itself
+func (p *EventParser) exit(frame Frame) error {
+	c := p.frameComments[frame]
+	delete(p.frameComments, frame)
+	if c == nil {
+		c = &comments{}
+	}
+
+	var events []yaml.Event
+	switch frame := frame.(type) {
+	case *blockFrame:
+		if frame.value.collection != 0 {
+			return p.emit(p.endOf(frame.value, c.foot))
+		}
+		events = frame.value.events()
+	case leafFrame:
+		events = frame.events()
+	}
+	c.applyTo(events)
+
+	value, _, err := p.valueAt(p.stack.size() - 1)
+	if err != nil {
+		return err
+	}
+	if value.standalone {
+		value.node = events
+		return nil
+	}
+	return p.emit(events...)
+}
+
+// valueAt returns the value of the frame at index i of stack, along with the frame, or the document root if i is negative
+func (p *EventParser) valueAt(i int) (*blockValue, Frame, error) {
+	if i < 0 {
+		return p.root, nil, nil
+	}
+	frame, ok := p.stack.elements[i].(*blockFrame)
+	if !ok {
+		return nil, nil, fmt.Errorf("%d node can not nest other nodes: %w", p.stack.elements[i].NodeType(), errChildNodeOnNonNestableNode)
+	}
+	return frame.value, frame, nil
+}
+
+// endOf returns the event ending the collection value became, following its last entry
+func (p *EventParser) endOf(value *blockValue, footComment string) yaml.Event {
+	end := yaml.Event{Type: yaml.EventSequenceEnd, Start: p.last, End: p.last, FootComment: footComment}
+	if value.collection == yaml.EventMappingStart {
+		end.Type = yaml.EventMappingEnd
+	}
+	return end
+}
+
+// emit hands events to the handler, preceded by the start of an implicit document if none is started
+func (p *EventParser) emit(events ...yaml.Event) error {
+	for _, event := range events {
+		if !p.documentStarted && event.Type != yaml.EventStreamStart && event.Type != yaml.EventStreamEnd && event.Type != yaml.EventDocumentStart {
+			p.documentStarted = true
+			if err := p.emit(yaml.Event{Type: yaml.EventDocumentStart, Implicit: true, Start: event.Start, End: event.Start}); err != nil {
+				return err
+			}
+		}
+		if event.Type == yaml.EventDocumentStart {
+			p.documentStarted = true
+		}
+
+		if err := p.count(event); err != nil {
+			return err
+		}
+		if err := p.handler(event); err != nil {
+			return err
+		}
+		p.last = event.End
+	}
+	return nil
+}
+
+// startStream produces the StreamStart event, unless it has already been produced
+func (p *EventParser) startStream() error {
+	if p.streamStarted {
+		return nil
+	}
+	p.streamStarted = true
+	start := token.NewLocation(1, 1)
+	return p.emit(yaml.Event{Type: yaml.EventStreamStart, Start: start, End: start})
+}
+
+// pushOnStack pops the frames frame can not be nested in, then pushes frame on stack
+func (p *EventParser) pushOnStack(frame Frame) error {
+	for {
+		relationship, _ := p.stack.indentationManager.determineRelationship(frame.IndentationLevel())
+		switch relationship {
+		case indentationRelationshipChild:
+			return p.stack.push(frame)
+
+		case indentationRelationSibling:
+			if p.stack.isEmpty() || p.isIndentlessSequenceEntry(frame) {
+				return p.stack.push(frame)
+			}
+
+		case indentationRelationshipParentLevel:
+
+		default:
+			return fmt.Errorf("indentation %d does not match any enclosing node: %w", frame.IndentationLevel(), errInconsistentIndentation)
+		}
+
+		if err := p.exit(p.stack.pop()); err != nil {
+			return err
+		}
+	}
+}
+
+// isIndentlessSequenceEntry checks that frame is a block sequence entry written at the same indentation
+// as the mapping entry on top of the stack, e.g.,
+//
+//	key:
+//	- item
+func (p *EventParser) isIndentlessSequenceEntry(frame Frame) bool {
+	if !isSequenceEntry(frame) {
+		return false
+	}
+	owner, ok := p.stack.peek().(*blockFrame)
+	if !ok || owner.nodeType != yaml.NodeTypeMappingBlockStyle {
+		return false
+	}
+	if !owner.value.isEmpty() && !owner.indentlessSequence {
+		return false
+	}
+	owner.indentlessSequence = true
+	return true
+}
+
+// handleDocumentMarker ends the document currently being parsed on a document start (---) or end (...) marker.
+// A node may follow a document start marker on the same line
+func (p *EventParser) handleDocumentMarker(tokens []token.Token) error {
+	marker := tokens[0]
+	if marker.Type == token.TypeDocumentEnd {
+		if err := expectLineEnd(tokens[1:]); err != nil {
+			return err
+		}
+		return p.endDocument(&marker)
+	}
+
+	if p.documentStarted || !p.stack.isEmpty() || !p.root.isEmpty() {
+		if err := p.endDocument(nil); err != nil {
+			return err
+		}
+	}
+	p.root.position = marker.Position
+
+	// comments preceding the document start marker of an empty document are the head comment of the document
+	p.documentComments.head = p.takeHeadComment(-1)
+
+	rest := tokens[1:]
+	if comment := lineComment(rest); comment != "" && (isBlankLine(rest) || isProperty(rest[0])) {
+		p.documentComments.line = comment
+	}
+	if err := p.emit(yaml.Event{
+		Type:        yaml.EventDocumentStart,
+		Start:       marker.Position,
+		End:         token.NewLocation(marker.Position.Line(), marker.Position.Column()+3),
+		HeadComment: p.documentComments.head,
+		LineComment: p.documentComments.line,
+	}); err != nil {
+		return err
+	}
+
+	if isBlankLine(rest) {
+		return nil
+	}
+	if props, ok, err := readProperties(rest); ok || err != nil {
+		p.root.properties = props
+		return err
+	}
+
+	p.nodeTypeFinder.match(rest)
+	if !p.nodeTypeFinder.done {
+		return fmt.Errorf("can not determine node type on line %d: %w", marker.Position.Line(), errUnexpectedTokenType)
+	}
+	nodeType := p.nodeTypeFinder.nodeType()
+	p.nodeTypeFinder.reset()
+
+	return p.buildEntry(rest, nodeType, 0)
+}
+
+// endDocument unwinds the stack into the document root, and completes the document unless it is empty.
+// marker is the document end marker (...) ending the document, or nil
+func (p *EventParser) endDocument(marker *token.Token) error {
+	p.documentComments.foot = p.takeHeadComment(0)
+	for !p.stack.isEmpty() {
+		if err := p.exit(p.stack.pop()); err != nil {
+			return err
+		}
+	}
+
+	if p.documentStarted || !p.root.isEmpty() || !p.documentComments.isEmpty() {
+		root := []yaml.Event{p.endOf(p.root, "")}
+		if p.root.collection == 0 {
+			root = p.root.events()
+		}
+		if err := p.emit(root...); err != nil {
+			return err
+		}
+
+		end := yaml.Event{Type: yaml.EventDocumentEnd, Implicit: true, Start: p.last, End: p.last, FootComment: p.documentComments.foot}
+		if marker != nil {
+			end.Implicit, end.Start, end.End = false, marker.Position, token.NewLocation(marker.Position.Line(), marker.Position.Column()+3)
+		}
+		if err := p.emit(end); err != nil {
+			return err
+		}
+	}
+
+	p.stack.clear()
+	p.root = &blockValue{}
+	p.documentStarted = false
+	p.documentComments = comments{}
+	clear(p.frameComments)
+	return nil
+}
+
+// addCommentLine holds the comment of a line holding no node until the node it belongs to is built
+func (p *EventParser) addCommentLine(tokens []token.Token) {
+	for _, t := range tokens {
+		if t.Type == token.TypeComment {
+			p.pendingComments = append(p.pendingComments, commentLine{
+				text:        string(token.CharCommentStarter) + t.Value,
+				indentation: t.Position.Column() - 1,
+			})
+		}
+	}
+}
+
+// takeHeadComment attaches the pending comment lines more indented than indentation
+// as foot comments of the block collections they are nested in,
+// and returns the other lines, which form the head comment of the node that follows them
+func (p *EventParser) takeHeadComment(indentation int) string {
+	var head string
+	for _, line := range p.pendingComments {
+		if line.indentation > indentation {
+			if owner := p.footCommentOwner(line.indentation); owner != nil {
+				c := p.commentsOf(owner)
+				c.foot = appendLine(c.foot, line.text)
+				continue
+			}
+		}
+		head = appendLine(head, line.text)
+	}
+	p.pendingComments = nil
+	return head
+}
+
+// footCommentOwner finds the innermost block mapping or sequence entry on stack
+// whose value holds a comment line at indentation
+func (p *EventParser) footCommentOwner(indentation int) Frame {
+	for i := p.stack.size() - 1; i >= 0; i-- {
+		frame := p.stack.elements[i]
+		if _, ok := frame.(*blockFrame); ok && frame.IndentationLevel() < indentation {
+			return frame
+		}
+	}
+	return nil
+}
+
+// attachHeadComment attaches the pending head comment to the key of the mapping entry frame builds,
+// or to the node frame builds if it has no key
+func (p *EventParser) attachHeadComment(frame Frame) {
+	if p.headComment == "" {
+		return
+	}
+	if key := frame.Key(); key != nil {
+		key.HeadComment = p.headComment
+	} else {
+		p.commentsOf(frame).head = p.headComment
+	}
+	p.headComment = ""
+}
+
+func (p *EventParser) commentsOf(frame Frame) *comments {
+	c, ok := p.frameComments[frame]
+	if !ok {
+		c = &comments{}
+		p.frameComments[frame] = c
+	}
+	return c
+}
+
+// isBlankLine checks that tokens hold no node
+func isBlankLine(tokens []token.Token) bool {
+	for _, t := range tokens {
+		switch t.Type {
+		case token.TypeIndentation, token.TypeNewline, token.TypeComment, token.TypeDirective:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// firstNodeToken returns the first token of tokens that is not indentation
+func firstNodeToken(tokens []token.Token) token.Token {
+	for _, t := range tokens {
+		if t.Type != token.TypeIndentation {
+			return t
+		}
+	}
+	return tokens[0]
+}
@@ -0,0 +1,139 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ercross/yaml"
+	"strings"
+	"testing"
+)
+
+// describe formats event as its type followed by its anchor, tag, value and span, when set
+func describe(event yaml.Event) string {
+	s := event.Type.String()
+	if event.Anchor != "" {
+		s += " &" + event.Anchor
+	}
+	if event.Tag != "" {
+		s += " " + event.Tag
+	}
+	if event.Type == yaml.EventScalar || event.Type == yaml.EventAlias {
+		s += fmt.Sprintf(" %q", event.Value)
+	}
+	return s + fmt.Sprintf(" %d:%d-%d:%d", event.Start.Line(), event.Start.Column(), event.End.Line(), event.End.Column())
+}
+
+func collectEvents(t *testing.T, source string) []yaml.Event {
+	t.Helper()
+	var events []yaml.Event
+	err := ParseEvents(strings.NewReader(source), func(event yaml.Event) error {
+		events = append(events, event)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to parse %q: %v", source, err)
+	}
+	return events
+}
+
+func TestParseEvents(t *testing.T) {
+	source := "a: &x !!str 1\nb:\n  - *x\n  - [c, 'd']\n"
+	expected := []string{
+		"StreamStart 1:1-1:1",
+		"DocumentStart 1:1-1:1",
+		"MappingStart 1:1-1:1",
+		`Scalar "a" 1:1-1:2`,
+		`Scalar &x !!str "1" 1:13-1:14`,
+		`Scalar "b" 2:1-2:2`,
+		"SequenceStart 3:3-3:3",
+		`Alias "x" 3:5-3:7`,
+		"SequenceStart 4:5-4:6",
+		`Scalar "c" 4:6-4:7`,
+		`Scalar "d" 4:9-4:12`,
+		"SequenceEnd 4:12-4:13",
+		"SequenceEnd 4:13-4:13",
+		"MappingEnd 4:13-4:13",
+		"DocumentEnd 4:13-4:13",
+		"StreamEnd 4:13-4:13",
+	}
+
+	events := collectEvents(t, source)
+	actual := make([]string, len(events))
+	for i, event := range events {
+		actual[i] = describe(event)
+	}
+	if strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected events\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(actual, "\n"))
+	}
+}
+
+func TestParseEvents_Documents(t *testing.T) {
+	events := collectEvents(t, "a\n---\nb\n...\n")
+
+	var documents []yaml.Event
+	for _, event := range events {
+		if event.Type == yaml.EventDocumentStart || event.Type == yaml.EventDocumentEnd {
+			documents = append(documents, event)
+		}
+	}
+	expected := []struct {
+		eventType yaml.EventType
+		implicit  bool
+	}{
+		{yaml.EventDocumentStart, true},
+		{yaml.EventDocumentEnd, true},
+		{yaml.EventDocumentStart, false},
+		{yaml.EventDocumentEnd, false},
+	}
+	if len(documents) != len(expected) {
+		t.Fatalf("expected %d document events, got %d", len(expected), len(documents))
+	}
+	for i, event := range documents {
+		if event.Type != expected[i].eventType || event.Implicit != expected[i].implicit {
+			t.Errorf("expected event %d to be %s with implicit %t, got %s with implicit %t",
+				i, expected[i].eventType, expected[i].implicit, event.Type, event.Implicit)
+		}
+	}
+}
+
+func TestParseEvents_HandlerError(t *testing.T) {
+	stop := errors.New("stop")
+	count := 0
+	err := ParseEvents(strings.NewReader("- a\n- b\n- c\n"), func(event yaml.Event) error {
+		count++
+		if event.Type == yaml.EventScalar {
+			return stop
+		}
+		return nil
+	})
+	if !errors.Is(err, stop) {
+		t.Fatalf("expected the handler error, got %v", err)
+	}
+	if count != 4 {
+		t.Errorf("expected parsing to stop after 4 events, got %d", count)
+	}
+}
+
+func TestEvents(t *testing.T) {
+	var types []yaml.EventType
+	for event, err := range Events(strings.NewReader("[a, b]\n")) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		types = append(types, event.Type)
+		if event.Type == yaml.EventScalar {
+			break
+		}
+	}
+	if len(types) != 4 {
+		t.Errorf("expected iteration to stop after 4 events, got %v", types)
+	}
+
+	var last error
+	for _, err := range Events(strings.NewReader("a: 'unterminated\n")) {
+		last = err
+	}
+	if last == nil {
+		t.Errorf("expected the iteration to end with an error")
+	}
+}
@@ -0,0 +1,209 @@
+package parser
+
+import (
+	"fmt"
+	"github.com/ercross/yaml"
+	"github.com/ercross/yaml/token"
+	"slices"
+)
+
+// flowParser parses a complete flow collection ([] or {}) into events using recursive descent
+type flowParser struct {
+	tokens   []token.Token
+	position int
+	events   []yaml.Event
+}
+
+func newFlowParser(tokens []token.Token) *flowParser {
+	return &flowParser{tokens: tokens}
+}
+
+// parse the flow collection, which must span all tokens held by flowParser
+func (p *flowParser) parse() ([]yaml.Event, error) {
+	t, ok := p.peek()
+	if !ok || (t.Type != token.TypeOpeningSquareBracket && t.Type != token.TypeOpeningCurlyBrace) {
+		return nil, fmt.Errorf("flow collection must start with [ or {: %w", errUnexpectedTokenType)
+	}
+
+	if err := p.parseNode(); err != nil {
+		return nil, err
+	}
+	if t, ok = p.peek(); ok {
+		return nil, fmt.Errorf("unexpected token %s after flow collection: %w", t, errUnexpectedTokenType)
+	}
+	return p.events, nil
+}
+
+// peek returns the next token that is part of the flow collection syntax, without consuming it
+func (p *flowParser) peek() (token.Token, bool) {
+	for p.position < len(p.tokens) {
+		switch t := p.tokens[p.position]; t.Type {
+		case token.TypeNewline, token.TypeIndentation, token.TypeComment:
+			p.position++
+		default:
+			return t, true
+		}
+	}
+	return token.Token{}, false
+}
+
+func (p *flowParser) next() token.Token {
+	t, _ := p.peek()
+	p.position++
+	return t
+}
+
+// parseNode parses the next node, which may be omitted, e.g. the value in {key: }
+func (p *flowParser) parseNode() error {
+	var props properties
+	for t, ok := p.peek(); ok && isProperty(t); t, ok = p.peek() {
+		if err := props.add(p.next()); err != nil {
+			return err
+		}
+	}
+
+	t, ok := p.peek()
+	if !ok {
+		return fmt.Errorf("unterminated flow collection: %w", errUnexpectedTokenType)
+	}
+
+	start := len(p.events)
+	switch t.Type {
+	case token.TypeOpeningSquareBracket:
+		if err := p.parseSequence(); err != nil {
+			return err
+		}
+
+	case token.TypeOpeningCurlyBrace:
+		if err := p.parseMapping(); err != nil {
+			return err
+		}
+
+	case token.TypeData:
+		event, err := newScalarEvent(p.next())
+		if err != nil {
+			return err
+		}
+		p.events = append(p.events, event)
+
+	case token.TypeAsterisk:
+		if !props.isEmpty() {
+			return fmt.Errorf("alias at %s can not have properties: %w", t.Position, errUnexpectedTokenType)
+		}
+		p.events = append(p.events, newAliasEvent(p.next()))
+
+	case token.TypeComma, token.TypeColon, token.TypeClosingSquareBracket, token.TypeClosingCurlyBrace:
+		p.events = append(p.events, newEmptyScalarEvent(t.Position))
+
+	default:
+		return fmt.Errorf("unexpected token %s in flow collection: %w", t, errUnexpectedTokenType)
+	}
+
+	props.applyTo(&p.events[start])
+	return nil
+}
+
+func (p *flowParser) parseSequence() error {
+	start := p.next()
+	p.events = append(p.events, yaml.Event{
+		Type:  yaml.EventSequenceStart,
+		Style: yaml.StyleFlow,
+		Start: start.Position,
+		End:   nextColumn(start),
+	})
+
+	for {
+		t, ok := p.peek()
+		if !ok {
+			return fmt.Errorf("unterminated flow sequence starting at %s: %w", start.Position, errUnexpectedTokenType)
+		}
+		if t.Type == token.TypeClosingSquareBracket {
+			end := p.next()
+			p.events = append(p.events, yaml.Event{Type: yaml.EventSequenceEnd, Start: end.Position, End: nextColumn(end)})
+			return nil
+		}
+
+		entry := len(p.events)
+		if err := p.parseNode(); err != nil {
+			return err
+		}
+
+		// a single key/value pair can be written directly in a flow sequence, e.g. [key: value]
+		if t, ok = p.peek(); ok && t.Type == token.TypeColon {
+			p.next()
+			position := p.events[entry].Start
+			p.events = slices.Insert(p.events, entry, yaml.Event{
+				Type:  yaml.EventMappingStart,
+				Style: yaml.StyleFlow,
+				Start: position,
+				End:   position,
+			})
+			if err := p.parseNode(); err != nil {
+				return err
+			}
+			end := p.events[len(p.events)-1].End
+			p.events = append(p.events, yaml.Event{Type: yaml.EventMappingEnd, Start: end, End: end})
+		}
+
+		if err := p.expectSeparator(token.TypeClosingSquareBracket); err != nil {
+			return err
+		}
+	}
+}
+
+func (p *flowParser) parseMapping() error {
+	start := p.next()
+	p.events = append(p.events, yaml.Event{
+		Type:  yaml.EventMappingStart,
+		Style: yaml.StyleFlow,
+		Start: start.Position,
+		End:   nextColumn(start),
+	})
+
+	for {
+		t, ok := p.peek()
+		if !ok {
+			return fmt.Errorf("unterminated flow mapping starting at %s: %w", start.Position, errUnexpectedTokenType)
+		}
+		if t.Type == token.TypeClosingCurlyBrace {
+			end := p.next()
+			p.events = append(p.events, yaml.Event{Type: yaml.EventMappingEnd, Start: end.Position, End: nextColumn(end)})
+			return nil
+		}
+
+		key := len(p.events)
+		if err := p.parseNode(); err != nil {
+			return err
+		}
+
+		if t, ok = p.peek(); ok && t.Type == token.TypeColon {
+			p.next()
+			if err := p.parseNode(); err != nil {
+				return err
+			}
+		} else {
+			p.events = append(p.events, newEmptyScalarEvent(p.events[key].Start))
+		}
+
+		if err := p.expectSeparator(token.TypeClosingCurlyBrace); err != nil {
+			return err
+		}
+	}
+}
+
+// expectSeparator consumes the comma following a flow collection entry,
+// unless the entry is the last one, followed by the closing token
+func (p *flowParser) expectSeparator(closing token.Type) error {
+	t, ok := p.peek()
+	switch {
+	case !ok:
+		return fmt.Errorf("unterminated flow collection: %w", errUnexpectedTokenType)
+	case t.Type == token.TypeComma:
+		p.next()
+		return nil
+	case t.Type == closing:
+		return nil
+	default:
+		return fmt.Errorf("expected , or closing bracket but got %s: %w", t, errUnexpectedTokenType)
+	}
+}
@@ -5,38 +5,125 @@ import (
 	"fmt"
 	"github.com/ercross/yaml"
 	"github.com/ercross/yaml/token"
+	"unicode/utf8"
 )
 
 var (
 	errUnexpectedTokenType = errors.New("unexpected token type")
+	errMixedBlockEntries   = errors.New("block mapping entries, sequence entries and standalone nodes can not be mixed")
+	errUnknownAnchor       = yaml.ErrUnknownAnchor
 )
 
-// Frame is a stateful yaml.Node building session
+// Frame is a stateful session parsing the node of a line, or of the lines nested under it
 type Frame interface {
 	NodeType() yaml.NodeType
 
 	// Build a yaml.NodeType from tokens argument
 	Build([]token.Token) error
 
-	// Builder building the underlying Node
-	Builder() yaml.NodeBuilder
-
 	// IndentationLevel is usually set by the indentation preceding the first token
 	// parsed into this Frame
 	IndentationLevel() int
+
+	// Key is the Scalar event of the key of the mapping entry built by this Frame,
+	// or nil if the Frame builds a sequence entry or a standalone node
+	Key() *yaml.Event
+}
+
+// leafFrame is a Frame whose node is parsed from its own tokens, rather than made of the frames nested in it
+type leafFrame interface {
+	Frame
+
+	// events returns the events of the node, once the Frame is complete
+	events() []yaml.Event
+}
+
+// multilineFrame is a Frame whose node may span several lines.
+// Until a multilineFrame is complete, EventParser hands every line to it
+type multilineFrame interface {
+	Frame
+	isComplete() bool
 }
 
 type (
 	scalarFrame struct {
 		sequenceIterator *nodeSyntaxTraverser
-		builder          *yaml.ScalarNode
+		value            yaml.Event
+		key              *yaml.Event
+		indentationLevel int
+	}
+
+	// blockFrame builds a block mapping entry (key:) or a block sequence entry (-)
+	// whose value is made of the lines nested under it
+	blockFrame struct {
+		nodeType         yaml.NodeType
+		key              *yaml.Event
+		value            *blockValue
+		indentationLevel int
+
+		// indentlessSequence indicates that the sequence entries nested in a mapping entry
+		// are written at the same indentation as its key
+		indentlessSequence bool
+	}
+
+	// flowFrame builds a flow sequence ([]) or flow mapping ({}), possibly spanning several lines
+	flowFrame struct {
+		nodeType   yaml.NodeType
+		key        *yaml.Event
+		properties properties
+		tokens     []token.Token
+		parsed     []yaml.Event
+
+		// depth is the number of brackets opened and not yet closed, up to maxDepth if it is not 0
+		depth            int
+		maxDepth         int
+		indentationLevel int
+	}
+
+	// blockScalarFrame builds a literal (|) or folded (>) block scalar from its header and content lines
+	blockScalarFrame struct {
+		nodeType   yaml.NodeType
+		key        *yaml.Event
+		properties properties
+		header     token.Token
+		lines      []string
+
+		// end is the location following the last character of the header or of the last content line
+		end token.Location
+
+		// contentIndentation is the indentation of the block scalar content, or 0 until it is known
+		contentIndentation int
+
+		// parentIndentation is the indentation level of the node owning the block scalar
+		parentIndentation int
+		indentationLevel  int
+	}
+
+	aliasFrame struct {
+		key              *yaml.Event
+		alias            yaml.Event
 		indentationLevel int
 	}
 )
 
+// blockValue is the node nested under a blockFrame, or at the root of a document.
+// It becomes a mapping or a sequence as soon as a mapping or sequence entry is nested in it,
+// or holds a single standalone node, whose events are held until the blockValue is complete
+type blockValue struct {
+	properties properties
+	position   token.Location
+
+	// collection is the type of the event starting the collection the blockValue became, if any
+	collection yaml.EventType
+
+	// standalone indicates that the blockValue holds a standalone node, made of node once complete
+	standalone bool
+	node       []yaml.Event
+}
+
 func newScalarFrame(indentationLevel int, iterator *nodeSyntaxTraverser) *scalarFrame {
 	return &scalarFrame{
-		builder:          yaml.NewScalarNodeBuilder(),
+		value:            yaml.Event{Type: yaml.EventScalar},
 		indentationLevel: indentationLevel,
 		sequenceIterator: iterator,
 	}
@@ -47,51 +134,401 @@ func (f *scalarFrame) NodeType() yaml.NodeType {
 }
 
 func (f *scalarFrame) Build(tokens []token.Token) error {
-	var hasVisitedAllowedIndentation bool
-	i := -1
-	for f.sequenceIterator.hasNext() && i < len(tokens) {
-		i++
-		f.builder.SetCurrentPosition(tokens[i].Position)
-		if tokens[i].Type == token.TypeIndentation {
-			if !hasVisitedAllowedIndentation && f.Builder().ToNode().Value() == nil {
-				hasVisitedAllowedIndentation = true
+	var props properties
+	for i, t := range tokens {
+		switch {
+		case t.Type == token.TypeIndentation:
+			if i == 0 {
 				continue
-			} else {
-				return fmt.Errorf("indentation at %s unsupported: %w", tokens[i].Position, errUnexpectedTokenType)
 			}
-		}
+			return fmt.Errorf("indentation at %s unsupported: %w", t.Position, errUnexpectedTokenType)
 
-		expected := f.sequenceIterator.next()
+		case t.Type == token.TypeComment:
+			continue
 
-		if expected.optional && tokens[i].Type != expected.tokenType {
+		case isProperty(t):
+			if err := props.add(t); err != nil {
+				return err
+			}
 			continue
 		}
 
-		if expected.tokenType != tokens[i].Type {
-			return fmt.Errorf("expected token type %d but got token type %d: %w", expected.tokenType, tokens[i].Type, errUnexpectedTokenType)
+		if !f.sequenceIterator.hasNext() {
+			return fmt.Errorf("unexpected token %s: %w", t, errUnexpectedTokenType)
+		}
+		expected := f.sequenceIterator.next()
+		for expected.optional && expected.tokenType != t.Type && f.sequenceIterator.hasNext() {
+			expected = f.sequenceIterator.next()
 		}
 
-		// newline token is the last token in a scalar frame syntax
-		if expected.tokenType == token.TypeNewline && f.Builder().ToNode().Value() != nil && f.Builder().ToNode().Key() != "" {
-			return nil
+		if expected.tokenType != t.Type {
+			return fmt.Errorf("expected token type %d but got token type %d: %w", expected.tokenType, t.Type, errUnexpectedTokenType)
 		}
 
-		if tokens[i].Type == token.TypeData {
-			if f.builder.Key() == "" {
-				f.Builder().SetKey(tokens[i].Value)
+		switch t.Type {
+		case token.TypeData:
+			event, err := newScalarEvent(t)
+			if err != nil {
+				return err
+			}
+			props.applyTo(&event)
+			props = properties{}
+
+			// data followed by a colon is the key of a mapping entry
+			if f.sequenceIterator.hasNext() && f.sequenceIterator.current.tokenType == token.TypeColon {
+				f.key = &event
 			} else {
-				f.Builder().SetValue(tokens[i].Value)
+				f.value = event
 			}
+
+		// newline token is the last token in a scalar frame syntax
+		case token.TypeNewline:
+			if !props.isEmpty() {
+				return fmt.Errorf("properties at %s are not followed by a node: %w", t.Position, errUnexpectedTokenType)
+			}
+			return nil
 		}
 	}
 
 	return nil
 }
 
-func (f *scalarFrame) Builder() yaml.NodeBuilder {
-	return f.builder
+func (f *scalarFrame) events() []yaml.Event {
+	return []yaml.Event{f.value}
 }
 
 func (f *scalarFrame) IndentationLevel() int {
 	return f.indentationLevel
 }
+
+func (f *scalarFrame) Key() *yaml.Event {
+	return f.key
+}
+
+func newBlockFrame(nodeType yaml.NodeType, indentationLevel int) *blockFrame {
+	return &blockFrame{
+		nodeType:         nodeType,
+		value:            &blockValue{},
+		indentationLevel: indentationLevel,
+	}
+}
+
+func (f *blockFrame) NodeType() yaml.NodeType {
+	return f.nodeType
+}
+
+// Build reads the dash of a sequence entry, or the key and value properties of a mapping entry
+func (f *blockFrame) Build(tokens []token.Token) error {
+	if f.nodeType == yaml.NodeTypeSequenceBlockStyle {
+		for _, t := range tokens {
+			if t.Type == token.TypeDash {
+				f.value.position = t.Position
+				return nil
+			}
+		}
+		return fmt.Errorf("sequence entry is missing its dash: %w", errUnexpectedTokenType)
+	}
+
+	key, props, rest, err := splitEntry(tokens)
+	if err != nil {
+		return err
+	}
+	if key == nil {
+		return fmt.Errorf("block mapping entry is missing its key: %w", errUnexpectedTokenType)
+	}
+	if err = expectLineEnd(rest); err != nil {
+		return err
+	}
+
+	f.key = key
+	f.value.properties = props
+	f.value.position = key.Start
+	return nil
+}
+
+func (f *blockFrame) IndentationLevel() int {
+	return f.indentationLevel
+}
+
+func (f *blockFrame) Key() *yaml.Event {
+	return f.key
+}
+
+// isSequenceEntry checks that f builds an entry of a block sequence
+func isSequenceEntry(f Frame) bool {
+	block, ok := f.(*blockFrame)
+	return ok && block.nodeType == yaml.NodeTypeSequenceBlockStyle
+}
+
+func (v *blockValue) isEmpty() bool {
+	return v.collection == 0 && !v.standalone
+}
+
+// events returns the events of a blockValue that is not a collection: its standalone node,
+// or an empty (null) scalar if it is empty
+func (v *blockValue) events() []yaml.Event {
+	events := v.node
+	if len(events) == 0 {
+		events = []yaml.Event{newEmptyScalarEvent(v.position)}
+	}
+	v.properties.applyTo(&events[0])
+	return events
+}
+
+func newFlowFrame(nodeType yaml.NodeType, indentationLevel int, maxDepth int) *flowFrame {
+	return &flowFrame{
+		nodeType:         nodeType,
+		indentationLevel: indentationLevel,
+		maxDepth:         maxDepth,
+	}
+}
+
+func (f *flowFrame) NodeType() yaml.NodeType {
+	return f.nodeType
+}
+
+// Build collects the tokens of the flow collection, and parses them once the collection is closed
+func (f *flowFrame) Build(tokens []token.Token) error {
+	if len(f.tokens) == 0 {
+		key, props, rest, err := splitEntry(tokens)
+		if err != nil {
+			return err
+		}
+		f.key, f.properties, tokens = key, props, rest
+	}
+
+	for _, t := range tokens {
+		if f.isComplete() || (f.depth == 0 && len(f.tokens) > 0) {
+			if t.Type != token.TypeNewline && t.Type != token.TypeComment {
+				return fmt.Errorf("unexpected token %s after flow collection: %w", t, errUnexpectedTokenType)
+			}
+			continue
+		}
+
+		f.tokens = append(f.tokens, t)
+		switch t.Type {
+		case token.TypeOpeningSquareBracket, token.TypeOpeningCurlyBrace:
+			f.depth++
+			// the collection is parsed recursively once closed, so its brackets are limited as they are read,
+			// the collections holding it being counted once its events are produced
+			if exceeds(f.depth, f.maxDepth) {
+				return &LimitError{Err: ErrMaxDepth, Max: f.maxDepth, Position: t.Position}
+			}
+		case token.TypeClosingSquareBracket, token.TypeClosingCurlyBrace:
+			f.depth--
+		}
+	}
+
+	if f.depth > 0 || f.isComplete() {
+		return nil
+	}
+
+	events, err := newFlowParser(f.tokens).parse()
+	if err != nil {
+		return err
+	}
+	f.properties.applyTo(&events[0])
+	f.parsed = events
+	return nil
+}
+
+func (f *flowFrame) isComplete() bool {
+	return f.parsed != nil
+}
+
+func (f *flowFrame) events() []yaml.Event {
+	return f.parsed
+}
+
+func (f *flowFrame) IndentationLevel() int {
+	return f.indentationLevel
+}
+
+func (f *flowFrame) Key() *yaml.Event {
+	return f.key
+}
+
+func newBlockScalarFrame(nodeType yaml.NodeType, indentationLevel int) *blockScalarFrame {
+	return &blockScalarFrame{
+		nodeType:          nodeType,
+		indentationLevel:  indentationLevel,
+		parentIndentation: -1,
+	}
+}
+
+func (f *blockScalarFrame) NodeType() yaml.NodeType {
+	return f.nodeType
+}
+
+// Build reads the block scalar header on the first call, then one content line per call
+func (f *blockScalarFrame) Build(tokens []token.Token) error {
+	if tokens[0].Type == token.TypeBlockScalarLine {
+		return f.addLine(tokens[0])
+	}
+
+	key, props, rest, err := splitEntry(tokens)
+	if err != nil {
+		return err
+	}
+	if len(rest) == 0 || (rest[0].Type != token.TypePipe && rest[0].Type != token.TypeGreaterThan) {
+		return fmt.Errorf("block scalar is missing its header: %w", errUnexpectedTokenType)
+	}
+	if err = expectLineEnd(rest[1:]); err != nil {
+		return err
+	}
+
+	if _, err = blockScalarValue(nil, false, rest[0].Value, f.parentIndentation); err != nil {
+		return fmt.Errorf("invalid block scalar header at %s: %w", rest[0].Position, err)
+	}
+	for _, indicator := range rest[0].Value {
+		if indicator >= '1' && indicator <= '9' {
+			f.contentIndentation = max(f.parentIndentation, 0) + int(indicator-'0')
+		}
+	}
+	f.key, f.properties, f.header = key, props, rest[0]
+	f.end = token.NewLocation(f.header.Position.Line(), f.header.Position.Column()+1+utf8.RuneCountInString(f.header.Value))
+	return nil
+}
+
+func (f *blockScalarFrame) addLine(line token.Token) error {
+	indentation := len(line.Value) - len(trimIndentation(line.Value))
+	if trimIndentation(line.Value) != "" {
+		if f.contentIndentation == 0 {
+			f.contentIndentation = indentation
+		}
+		if indentation < f.contentIndentation {
+			return fmt.Errorf("block scalar line at %s is less indented than its first line: %w",
+				line.Position, errUnexpectedTokenType)
+		}
+		f.end = token.NewLocation(line.Position.Line(), line.Position.Column()+utf8.RuneCountInString(line.Value))
+	}
+	f.lines = append(f.lines, line.Value)
+	return nil
+}
+
+func (f *blockScalarFrame) events() []yaml.Event {
+	// the header and lines have been validated as they were built
+	value, _ := blockScalarValue(f.lines, f.nodeType == yaml.NodeTypeFoldedString, f.header.Value, f.parentIndentation)
+	event := yaml.Event{Type: yaml.EventScalar, Value: value, Style: yaml.StyleLiteral, Start: f.header.Position, End: f.end}
+	if f.nodeType == yaml.NodeTypeFoldedString {
+		event.Style = yaml.StyleFolded
+	}
+	f.properties.applyTo(&event)
+	return []yaml.Event{event}
+}
+
+func (f *blockScalarFrame) IndentationLevel() int {
+	return f.indentationLevel
+}
+
+func (f *blockScalarFrame) Key() *yaml.Event {
+	return f.key
+}
+
+func newAliasFrame(indentationLevel int) *aliasFrame {
+	return &aliasFrame{indentationLevel: indentationLevel}
+}
+
+func (f *aliasFrame) NodeType() yaml.NodeType {
+	return yaml.NodeTypeAlias
+}
+
+func (f *aliasFrame) Build(tokens []token.Token) error {
+	key, props, rest, err := splitEntry(tokens)
+	if err != nil {
+		return err
+	}
+	if len(rest) == 0 || rest[0].Type != token.TypeAsterisk {
+		return fmt.Errorf("alias is missing: %w", errUnexpectedTokenType)
+	}
+	if !props.isEmpty() {
+		return fmt.Errorf("alias at %s can not have properties: %w", rest[0].Position, errUnexpectedTokenType)
+	}
+	if err = expectLineEnd(rest[1:]); err != nil {
+		return err
+	}
+
+	f.key, f.alias = key, newAliasEvent(rest[0])
+	return nil
+}
+
+func (f *aliasFrame) events() []yaml.Event {
+	return []yaml.Event{f.alias}
+}
+
+func (f *aliasFrame) IndentationLevel() int {
+	return f.indentationLevel
+}
+
+func (f *aliasFrame) Key() *yaml.Event {
+	return f.key
+}
+
+// newAliasEvent creates the Alias event represented by a token.TypeAsterisk token.
+// The anchor it refers to is only resolved once the event is composed into a node
+func newAliasEvent(t token.Token) yaml.Event {
+	return yaml.Event{
+		Type:  yaml.EventAlias,
+		Value: t.Value,
+		Start: t.Position,
+		End:   token.NewLocation(t.Position.Line(), t.Position.Column()+1+utf8.RuneCountInString(t.Value)),
+	}
+}
+
+// nextColumn returns the location following the single character token t, e.g., a bracket
+func nextColumn(t token.Token) token.Location {
+	return token.NewLocation(t.Position.Line(), t.Position.Column()+1)
+}
+
+// splitEntry splits the tokens of a line into the key of a mapping entry,
+// the properties of the entry value, and the tokens following them.
+// key is nil if tokens do not start with a mapping key
+func splitEntry(tokens []token.Token) (key *yaml.Event, valueProperties properties, rest []token.Token, err error) {
+	i := 0
+	for i < len(tokens) && tokens[i].Type == token.TypeIndentation {
+		i++
+	}
+
+	var props properties
+	for ; i < len(tokens) && isProperty(tokens[i]); i++ {
+		if err = props.add(tokens[i]); err != nil {
+			return nil, properties{}, nil, err
+		}
+	}
+
+	if i+1 < len(tokens) && tokens[i].Type == token.TypeData && tokens[i+1].Type == token.TypeColon {
+		event, err := newScalarEvent(tokens[i])
+		if err != nil {
+			return nil, properties{}, nil, err
+		}
+		props.applyTo(&event)
+		key, props = &event, properties{}
+
+		for i += 2; i < len(tokens) && isProperty(tokens[i]); i++ {
+			if err = props.add(tokens[i]); err != nil {
+				return nil, properties{}, nil, err
+			}
+		}
+	}
+
+	return key, props, tokens[i:], nil
+}
+
+// expectLineEnd checks that no node is left in tokens
+func expectLineEnd(tokens []token.Token) error {
+	for _, t := range tokens {
+		if t.Type != token.TypeNewline && t.Type != token.TypeComment {
+			return fmt.Errorf("unexpected token %s: %w", t, errUnexpectedTokenType)
+		}
+	}
+	return nil
+}
+
+func trimIndentation(line string) string {
+	for i := 0; i < len(line); i++ {
+		if line[i] != ' ' {
+			return line[i:]
+		}
+	}
+	return ""
+}
@@ -7,9 +7,8 @@ import (
 
 func TestScalarFrameParser(t *testing.T) {
 	defaultIndentationLevel := 0
-	nst := newNodeSyntaxTraverser(scalarNodeSyntax().head)
 	for _, scalarNodeTokens := range testdata.ScalarTokens {
-		frame := newScalarFrame(defaultIndentationLevel, nst)
+		frame := newScalarFrame(defaultIndentationLevel, newNodeSyntaxTraverser(scalarNodeSyntax().head))
 		if err := frame.Build(scalarNodeTokens); err != nil {
 			t.Error(err)
 		}
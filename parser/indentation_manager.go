@@ -77,6 +77,19 @@ func (m *indentationManager) pop() {
 	m.stack = m.stack[:len(m.stack)-1]
 }
 
+// indentDocument sets the indentation level of the document node, i.e., the indentation of its root node
+func (m *indentationManager) indentDocument(level int) {
+	m.stack[0].level = level
+}
+
+// indentationUnit returns the indentationLevelModuloFactor, or 0 if it has not been set
+func (m *indentationManager) indentationUnit() int {
+	if m.indentationLevelModuloFactor == nil {
+		return 0
+	}
+	return *m.indentationLevelModuloFactor
+}
+
 // peek returns the top indentation.level without removing it from indentationManager.stack
 func (m *indentationManager) peek() indentation {
 	// indentationManager has been initialized with a default indentation level
@@ -89,10 +102,19 @@ func (m *indentationManager) peek() indentation {
 //
 // push panics on attempt to push an unsupported indentation level onto stack.
 // Use indentationManager.determineRelationship to obtain the indentationRelationship
-// of incoming newIndentationLevel and ensure that it could be pushed onto the stack
+// of incoming newIndentationLevel and ensure that it could be pushed onto the stack,
+// or use indentationManager.tryPush to get an error instead.
 //
 // Check indentationManager.canPush for push rules
 func (m *indentationManager) push(newIndentationLevel int, nodeType yaml.NodeType) {
+	if err := m.tryPush(newIndentationLevel, nodeType); err != nil {
+		panic(fmt.Errorf("stack push error: %w", err))
+	}
+}
+
+// tryPush pushes a newIndentation onto indentationManager.stack,
+// or returns the reason newIndentationLevel can not be pushed
+func (m *indentationManager) tryPush(newIndentationLevel int, nodeType yaml.NodeType) error {
 
 	nin := newIndentation(newIndentationLevel, nodeType)
 
@@ -100,14 +122,15 @@ func (m *indentationManager) push(newIndentationLevel int, nodeType yaml.NodeTyp
 		moduloFactor := newIndentationLevel - m.peek().level
 		m.indentationLevelModuloFactor = &moduloFactor
 		m.stack = append(m.stack, nin)
-		return
+		return nil
 	}
 
 	if err := m.canPush(nin); err != nil {
-		panic(fmt.Errorf("stack push error: %w", err))
+		return err
 	}
 
 	m.stack = append(m.stack, nin)
+	return nil
 }
 
 // canPush check that newIndentationLevel can be pushed onto indentationManager
@@ -117,8 +140,11 @@ func (m *indentationManager) push(newIndentationLevel int, nodeType yaml.NodeTyp
 //   - can not push child node onto stack if top stack element is not nestable
 //   - can not push parent-level node onto stack:
 //     pop stack until top stack element.level == newIndentation.level, then try again
-//   - if indentationManager.indentationLevelModuloFactor has been set, the newIndentation.level
-//     must be a multiple of indentationManager.indentationLevelModuloFactor
+//   - a sibling can only be pushed on the document node, or be an indentless sequence entry
+//     pushed on the mapping entry owning the sequence (e.g., "key:" followed by "- item" at the same level)
+//   - if indentationManager.indentationLevelModuloFactor has been set, a child node must be indented
+//     by a multiple of indentationManager.indentationLevelModuloFactor relative to its parent.
+//     Nodes nested in a sequence entry are exempted, since their indentation follows the "- " indicator
 func (m *indentationManager) canPush(newIndentation indentation) error {
 
 	if newIndentation.level < 0 {
@@ -134,7 +160,7 @@ func (m *indentationManager) canPush(newIndentation indentation) error {
 		return errParentLevelIndentation
 	}
 
-	if m.peek().level == newIndentation.level && m.peek().nodeType != yaml.NodeTypeDocument {
+	if m.peek().level == newIndentation.level && m.peek().nodeType != yaml.NodeTypeDocument && !isIndentlessSequence(m.peek(), newIndentation) {
 		return errSiblingNodeOnNonDocumentNode
 	}
 
@@ -142,13 +168,20 @@ func (m *indentationManager) canPush(newIndentation indentation) error {
 		return errChildNodeOnNonNestableNode
 	}
 
-	if m.indentationLevelModuloFactor != nil && newIndentation.level%(*m.indentationLevelModuloFactor) != 0 {
+	if m.indentationLevelModuloFactor != nil && m.peek().nodeType != yaml.NodeTypeSequenceBlockStyle &&
+		(newIndentation.level-m.peek().level)%(*m.indentationLevelModuloFactor) != 0 {
 		return errModuloFactorIncompatibleIndentation
 	}
 
 	return nil
 }
 
+// isIndentlessSequence checks that entry is a block sequence entry written at the same indentation as
+// the mapping entry owning the sequence, which YAML allows
+func isIndentlessSequence(owner indentation, entry indentation) bool {
+	return owner.nodeType == yaml.NodeTypeMappingBlockStyle && entry.nodeType == yaml.NodeTypeSequenceBlockStyle
+}
+
 // determineRelationship finds the hierarchical relationship between newIndentationLevel and existing indentations.
 // If indentationRelationship is indentationRelationshipParentLevel, ancestorPathLength is the distance between
 // newIndentationLevel and its direct parent or parent sibling.
@@ -176,23 +209,25 @@ func (m *indentationManager) determineRelationship(newIndentationLevel int) (rel
 	return indentationRelationshipUnknown, -1
 }
 
+// findIndentation finds the indentation level of a line made of tokens,
+// and its indentationRelationship with existing indentations.
+//
+// The indentation level is the length of the leading indentation token, if any,
+// or else the column preceding the first token (e.g., a node following a "- " indicator)
 func (m *indentationManager) findIndentation(tokens []token.Token) (relationship indentationRelationship, indentationCount int) {
 
-	if m.peek().level == 0 && tokens[0].Type == token.TypeData {
-		return indentationRelationSibling, 0
-	}
-
 	for i := 0; i < len(tokens); i++ {
 
 		if tokens[i].Type == token.TypeNewline {
 			continue
 		}
 		if tokens[i].Type == token.TypeIndentation {
-			relationship, _ = m.determineRelationship(len(tokens[i].Value))
-			return relationship, len(tokens[i].Value)
+			indentationCount = len(tokens[i].Value)
+		} else {
+			indentationCount = max(tokens[i].Position.Column()-1, 0)
 		}
-
-		break
+		relationship, _ = m.determineRelationship(indentationCount)
+		return relationship, indentationCount
 	}
 
 	return indentationRelationshipUnknown, 0
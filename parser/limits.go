@@ -0,0 +1,126 @@
+package parser
+
+import (
+	"errors"
+	"github.com/ercross/yaml"
+	"github.com/ercross/yaml/token"
+)
+
+var (
+	ErrMaxDepth        = errors.New("maximum nesting depth exceeded")
+	ErrMaxNodes        = errors.New("maximum number of nodes exceeded")
+	ErrMaxScalarLength = errors.New("maximum scalar length exceeded")
+	ErrMaxBytes        = errors.New("maximum input size exceeded")
+	ErrMaxDocuments    = errors.New("maximum number of documents exceeded")
+
+	// ErrMaxAliasExpansion is returned when decoding a node whose aliases expand to more than MaxAliasExpansion nodes
+	ErrMaxAliasExpansion = yaml.ErrMaxAliasExpansion
+)
+
+type (
+	// Limits cap the resources parsing a stream takes, e.g., when parsing untrusted input.
+	// Limits are enforced as the stream is read and parsed, so that parsing stops as soon as one is exceeded,
+	// with a *LimitError. A limit of 0 is no limit
+	Limits struct {
+		// MaxDepth is the number of collections nested in each other, e.g., 2 for {a: [1]}
+		MaxDepth int
+
+		// MaxNodes is the number of nodes of the whole stream: scalars, aliases, sequences and mappings.
+		// An alias is a single node, as parsing does not expand aliases: see MaxAliasExpansion
+		MaxNodes int
+
+		// MaxScalarLength is the number of bytes of the value of a scalar, and of each line of a multi-line scalar
+		MaxScalarLength int
+
+		// MaxBytes is the number of bytes of the stream
+		MaxBytes int
+
+		// MaxDocuments is the number of documents of the stream
+		MaxDocuments int
+
+		// MaxAliasExpansion is the number of nodes the aliases of every node a Decoder decodes may expand to,
+		// see yaml.DecodeOptions. Unlike the other limits, 0 is yaml.DefaultMaxAliasExpansion,
+		// and a negative value is no limit
+		MaxAliasExpansion int
+	}
+
+	// LimitError reports the limit of Limits exceeded by a stream, and where. Err is one of ErrMaxDepth, ErrMaxNodes,
+	// ErrMaxScalarLength, ErrMaxBytes, ErrMaxDocuments and ErrMaxAliasExpansion
+	LimitError = yaml.LimitError
+)
+
+// setLimits enforces limits on the stream l reads, and on the events its parser produces
+func (l *lineReader) setLimits(limits Limits) {
+	l.limits = limits
+	l.input.max = limits.MaxBytes
+	l.parser.SetLimits(limits)
+}
+
+// checkTokens enforces MaxScalarLength on the scalars of a tokenized line
+func (l *lineReader) checkTokens(tokens []token.Token) error {
+	for _, t := range tokens {
+		if (t.Type == token.TypeData || t.Type == token.TypeBlockScalarLine) && exceeds(len(t.Value), l.limits.MaxScalarLength) {
+			return &LimitError{Err: ErrMaxScalarLength, Max: l.limits.MaxScalarLength, Position: t.Position}
+		}
+	}
+	return nil
+}
+
+// SetLimits enforces limits on the events p produces: MaxDepth, MaxNodes, MaxScalarLength and MaxDocuments
+func (p *EventParser) SetLimits(limits Limits) {
+	p.limits = limits
+}
+
+// count enforces the Limits of p on event, about to be handed to the handler
+func (p *EventParser) count(event yaml.Event) error {
+	switch event.Type {
+	case yaml.EventDocumentStart:
+		p.documents++
+		if exceeds(p.documents, p.limits.MaxDocuments) {
+			return &LimitError{Err: ErrMaxDocuments, Max: p.limits.MaxDocuments, Position: event.Start}
+		}
+		return nil
+
+	case yaml.EventMappingEnd, yaml.EventSequenceEnd:
+		p.depth--
+		return nil
+
+	case yaml.EventMappingStart, yaml.EventSequenceStart:
+		p.depth++
+		if exceeds(p.depth, p.limits.MaxDepth) {
+			return &LimitError{Err: ErrMaxDepth, Max: p.limits.MaxDepth, Position: event.Start}
+		}
+
+	case yaml.EventScalar:
+		if exceeds(len(event.Value), p.limits.MaxScalarLength) {
+			return &LimitError{Err: ErrMaxScalarLength, Max: p.limits.MaxScalarLength, Position: event.Start}
+		}
+
+	case yaml.EventAlias:
+
+	default:
+		return nil
+	}
+
+	p.nodes++
+	if exceeds(p.nodes, p.limits.MaxNodes) {
+		return &LimitError{Err: ErrMaxNodes, Max: p.limits.MaxNodes, Position: event.Start}
+	}
+	return nil
+}
+
+// SetLimits enforces limits on the events the AstBuilder builds nodes from, see EventParser.SetLimits
+func (builder *AstBuilder) SetLimits(limits Limits) {
+	builder.parser.SetLimits(limits)
+}
+
+// SetLimits enforces limits on the stream d reads, and MaxAliasExpansion on the nodes it decodes.
+// SetLimits must be called before reading the stream
+func (d *Decoder) SetLimits(limits Limits) {
+	d.lines.setLimits(limits)
+}
+
+// exceeds checks that value exceeds the limit max, unless max is 0
+func exceeds(value, max int) bool {
+	return max > 0 && value > max
+}
@@ -0,0 +1,189 @@
+package parser
+
+import (
+	"errors"
+	"github.com/ercross/yaml"
+	"io"
+	"strings"
+	"testing"
+)
+
+// endlessReader reads an endless stream of the same byte
+type endlessReader byte
+
+func (r endlessReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = byte(r)
+	}
+	return len(p), nil
+}
+
+func TestParseWithOptions_Limits(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		limits   Limits
+		expected error
+		position string
+	}{
+		{
+			name:     "block depth",
+			source:   "a:\n  b:\n    c:\n      d: 1\n",
+			limits:   Limits{MaxDepth: 3},
+			expected: ErrMaxDepth,
+			position: "line(4): column(7)",
+		},
+		{
+			name:     "flow depth",
+			source:   "[[[[1]]]]\n",
+			limits:   Limits{MaxDepth: 3},
+			expected: ErrMaxDepth,
+			position: "line(1): column(4)",
+		},
+		{
+			name:     "flow depth within a block collection",
+			source:   "- [[[1]]]\n",
+			limits:   Limits{MaxDepth: 3},
+			expected: ErrMaxDepth,
+			position: "line(1): column(5)",
+		},
+		{
+			name:     "nodes",
+			source:   "- 1\n- &a [2, 3]\n- *a\n",
+			limits:   Limits{MaxNodes: 5},
+			expected: ErrMaxNodes,
+			position: "line(3): column(3)",
+		},
+		{
+			name:     "scalar token",
+			source:   "a: 1\nb: 'a long value'\n",
+			limits:   Limits{MaxScalarLength: 8},
+			expected: ErrMaxScalarLength,
+			position: "line(2): column(4)",
+		},
+		{
+			name:     "block scalar",
+			source:   "a: |\n  line\n  line\n",
+			limits:   Limits{MaxScalarLength: 8},
+			expected: ErrMaxScalarLength,
+			position: "line(1): column(4)",
+		},
+		{
+			name:     "bytes",
+			source:   "a: 1\nb: 2\nc: 3\n",
+			limits:   Limits{MaxBytes: 10},
+			expected: ErrMaxBytes,
+			position: "line(3): column(1)",
+		},
+		{
+			name:     "documents",
+			source:   "a\n---\nb\n---\nc\n",
+			limits:   Limits{MaxDocuments: 2},
+			expected: ErrMaxDocuments,
+			position: "line(4): column(1)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseWithOptions(strings.NewReader(tt.source), Options{Limits: tt.limits})
+			var limitErr *LimitError
+			if !errors.Is(err, tt.expected) || !errors.As(err, &limitErr) {
+				t.Fatalf("expected a *LimitError of %v, got %v", tt.expected, err)
+			}
+			if limitErr.Position.String() != tt.position {
+				t.Errorf("expected the limit to be exceeded at %s, got %s", tt.position, limitErr.Position)
+			}
+
+			// the stream is parsed within limits it does not exceed
+			if _, err = ParseWithOptions(strings.NewReader(tt.source), Options{Limits: Limits{MaxDepth: 10, MaxNodes: 20, MaxScalarLength: 20, MaxBytes: 100, MaxDocuments: 3}}); err != nil {
+				t.Errorf("expected the stream to be parsed, got %v", err)
+			}
+		})
+	}
+}
+
+func TestParseWithOptions_LimitsStopReading(t *testing.T) {
+	// a line that never ends
+	_, err := ParseWithOptions(endlessReader('a'), Options{Limits: Limits{MaxBytes: 1 << 20}})
+	if !errors.Is(err, ErrMaxBytes) {
+		t.Errorf("expected ErrMaxBytes, got %v", err)
+	}
+
+	// brackets that are never closed
+	_, err = ParseWithOptions(io.LimitReader(endlessReader('['), 1<<20), Options{Limits: Limits{MaxDepth: 64}})
+	if !errors.Is(err, ErrMaxDepth) {
+		t.Errorf("expected ErrMaxDepth, got %v", err)
+	}
+}
+
+func TestParseEventsWithOptions_Limits(t *testing.T) {
+	var events int
+	err := ParseEventsWithOptions(strings.NewReader("[1, 2, 3]\n"), Options{Limits: Limits{MaxNodes: 3}}, func(yaml.Event) error {
+		events++
+		return nil
+	})
+	if !errors.Is(err, ErrMaxNodes) {
+		t.Errorf("expected ErrMaxNodes, got %v", err)
+	}
+	// StreamStart, DocumentStart, SequenceStart and two scalars
+	if events != 5 {
+		t.Errorf("expected the events preceding the limit to be handled, got %d", events)
+	}
+}
+
+func TestDecoder_Limits(t *testing.T) {
+	decoder := NewDecoder(strings.NewReader("- 1\n- 2\n- 3\n"))
+	decoder.SetLimits(Limits{MaxNodes: 3})
+	var items []int
+	if err := decoder.Decode(&items); !errors.Is(err, ErrMaxNodes) {
+		t.Errorf("expected ErrMaxNodes, got %v", err)
+	}
+}
+
+// billionLaughs is a document of a few hundred bytes whose aliases expand to a billion scalars
+const billionLaughs = `a: &a ["lol","lol","lol","lol","lol","lol","lol","lol","lol"]
+b: &b [*a,*a,*a,*a,*a,*a,*a,*a,*a]
+c: &c [*b,*b,*b,*b,*b,*b,*b,*b,*b]
+d: &d [*c,*c,*c,*c,*c,*c,*c,*c,*c]
+e: &e [*d,*d,*d,*d,*d,*d,*d,*d,*d]
+f: &f [*e,*e,*e,*e,*e,*e,*e,*e,*e]
+g: &g [*f,*f,*f,*f,*f,*f,*f,*f,*f]
+h: &h [*g,*g,*g,*g,*g,*g,*g,*g,*g]
+i: &i [*h,*h,*h,*h,*h,*h,*h,*h,*h]
+`
+
+func TestUnmarshal_BillionLaughs(t *testing.T) {
+	limits := Limits{MaxNodes: 1000, MaxDepth: 5, MaxBytes: 4096}
+	if _, err := ParseWithOptions(strings.NewReader(billionLaughs), Options{Limits: limits}); err != nil {
+		t.Fatalf("expected the document to be parsed within %+v, got %v", limits, err)
+	}
+
+	var limitErr *LimitError
+	if err := Unmarshal([]byte(billionLaughs), new(any)); !errors.Is(err, ErrMaxAliasExpansion) || !errors.As(err, &limitErr) {
+		t.Fatalf("expected a *LimitError of %v, got %v", ErrMaxAliasExpansion, err)
+	}
+	if limitErr.Max != yaml.DefaultMaxAliasExpansion {
+		t.Errorf("expected the default limit, got %d", limitErr.Max)
+	}
+
+	limits.MaxAliasExpansion = 1000
+	decoder := NewDecoder(strings.NewReader(billionLaughs))
+	decoder.SetLimits(limits)
+	if err := decoder.Decode(new(any)); !errors.As(err, &limitErr) || limitErr.Max != 1000 {
+		t.Fatalf("expected a *LimitError of %v, got %v", ErrMaxAliasExpansion, err)
+	}
+}
+
+func TestDecoder_AliasExpansion(t *testing.T) {
+	// every alias expands to the sequence and its 2 items
+	source := "a: &a [1, 2]\nb: *a\nc: *a\n"
+	for limit, expected := range map[int]error{5: ErrMaxAliasExpansion, 6: nil, -1: nil} {
+		decoder := NewDecoder(strings.NewReader(source))
+		decoder.SetLimits(Limits{MaxAliasExpansion: limit})
+		var value map[string][]int
+		if err := decoder.Decode(&value); !errors.Is(err, expected) {
+			t.Errorf("limit %d: expected %v, got %v", limit, expected, err)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+// Package parser parses YAML streams one line at a time, into events, abstract syntax trees and Go values.
+//
+// Some YAML 1.2 constructs are not supported yet, and fail to parse:
+//   - explicit mapping keys (? key), and so keys that are sequences or mappings
+//   - flow collections used as implicit keys, e.g., [a, b]: c
+//   - plain scalars spanning several lines, which must be quoted or written as block scalars
+package parser
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ercross/yaml"
+	"github.com/ercross/yaml/token"
+	"github.com/ercross/yaml/tokenizer"
+	"io"
+	"iter"
+	"strings"
+)
+
+// errStopped stops parsing once the consumer of Events stops iterating
+var errStopped = errors.New("stopped")
+
+// contextCheckInterval is the number of events built or decoded between two checks of a context,
+// which is also checked on every line read, and as the line is tokenized
+const contextCheckInterval = 1024
+
+// ParseError reports the line of the stream on which parsing failed. Errors found at the end of the stream,
+// e.g., an unterminated quoted scalar, are reported on the line following the last one
+type ParseError struct {
+	Line int
+	Err  error
+}
+
+// lineParser parses a yaml stream one line of tokens at a time, as AstBuilder and EventParser do
+type lineParser interface {
+	Build(tokens []token.Token) error
+	Finish() error
+	SetLimits(limits Limits)
+}
+
+// Options configure how Parse builds nodes
+type Options struct {
+	// DuplicateKeys decides what is done with duplicate mapping keys, rejecting them by default
+	DuplicateKeys yaml.DuplicateKeyPolicy
+
+	// Limits cap the resources parsing takes, none by default
+	Limits Limits
+
+	// Events, if set, is handed every event of the stream before it is composed into nodes, e.g., to keep the pairs
+	// of the duplicate keys the DuplicateKeys policy discards. An error it returns stops parsing.
+	// ParseEventsWithOptions hands the events to its own handler instead
+	Events func(event yaml.Event) error
+}
+
+// Parse reads a whole yaml stream from r and builds its AbstractSyntaxTree, with the default Options
+func Parse(r io.Reader) (*AbstractSyntaxTree, error) {
+	return ParseWithOptions(r, Options{})
+}
+
+// ParseWithOptions reads a whole yaml stream from r and builds its AbstractSyntaxTree with opts
+func ParseWithOptions(r io.Reader, opts Options) (*AbstractSyntaxTree, error) {
+	return ParseContext(context.Background(), r, opts)
+}
+
+// ParseContext is like ParseWithOptions, stopping once ctx is done: ctx is checked as the stream is read,
+// on every line, as long lines are tokenized, and as nodes are built. The error of ctx is returned
+// within a *ParseError, along with the position parsing stopped at
+func ParseContext(ctx context.Context, r io.Reader, opts Options) (*AbstractSyntaxTree, error) {
+	builder := NewAstBuilder()
+	builder.SetDuplicateKeyPolicy(opts.DuplicateKeys)
+	builder.ctx = ctx
+	builder.handler = opts.Events
+	if err := parse(ctx, r, builder, opts.Limits); err != nil {
+		return nil, err
+	}
+	return builder.AbstractSyntaxTree(), nil
+}
+
+// ParseEvents reads a yaml stream from r, handing its events to handler as they are parsed, see EventParser.
+// Parsing stops at the first error, including an error returned by handler
+func ParseEvents(r io.Reader, handler func(event yaml.Event) error) error {
+	return parse(context.Background(), r, NewEventParser(handler), Limits{})
+}
+
+// ParseEventsWithOptions is like ParseEvents, enforcing the Limits of opts. Events are not composed into nodes,
+// so that opts.DuplicateKeys does not apply
+func ParseEventsWithOptions(r io.Reader, opts Options, handler func(event yaml.Event) error) error {
+	return parse(context.Background(), r, NewEventParser(handler), opts.Limits)
+}
+
+// Events returns an iterator over the events of the yaml stream read from r, parsed as the iteration proceeds.
+// A parsing error is yielded with a zero Event, and ends the iteration
+func Events(r io.Reader) iter.Seq2[yaml.Event, error] {
+	return func(yield func(yaml.Event, error) bool) {
+		err := ParseEvents(r, func(event yaml.Event) error {
+			if !yield(event, nil) {
+				return errStopped
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, errStopped) {
+			yield(yaml.Event{}, err)
+		}
+	}
+}
+
+// parse tokenizes r line by line, handing the tokens of every line to p, within limits and until ctx is done
+func parse(ctx context.Context, r io.Reader, p lineParser, limits Limits) error {
+	lines := newLineReader(r, p)
+	lines.setLimits(limits)
+	lines.input.ctx = ctx
+	for {
+		if err := lines.next(); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// lineReader tokenizes a yaml stream one line at a time, handing the tokens of each line to a lineParser
+type lineReader struct {
+	input      *inputReader
+	reader     *bufio.Reader
+	tokenizer  *tokenizer.Tokenizer
+	parser     lineParser
+	lineNumber int
+	finished   bool
+	limits     Limits
+}
+
+// inputReader reads the stream of a lineReader, failing with the error of ctx once it is done,
+// and with a *LimitError past max bytes, unless max is 0
+type inputReader struct {
+	r    io.Reader
+	ctx  context.Context
+	max  int
+	read int
+}
+
+func newLineReader(r io.Reader, p lineParser) *lineReader {
+	input := &inputReader{r: r, ctx: context.Background()}
+	return &lineReader{input: input, reader: bufio.NewReader(input), tokenizer: tokenizer.New(), parser: p}
+}
+
+// next tokenizes and parses the next line, finishing the parser at the end of the stream.
+// next returns io.EOF once the parser is finished
+func (l *lineReader) next() error {
+	if l.finished {
+		return io.EOF
+	}
+
+	l.lineNumber++
+	if err := l.input.ctx.Err(); err != nil {
+		return l.stopped(err)
+	}
+	line, err := l.reader.ReadString('\n')
+	var limitErr *LimitError
+	if errors.As(err, &limitErr) {
+		limitErr.Position = token.NewLocation(l.lineNumber, 1)
+		return &ParseError{Line: l.lineNumber, Err: limitErr}
+	}
+	if err != nil && err != io.EOF {
+		if ctxErr := l.input.ctx.Err(); ctxErr != nil && errors.Is(err, ctxErr) {
+			return l.stopped(ctxErr)
+		}
+		return fmt.Errorf("failed to read line %d: %w", l.lineNumber, err)
+	}
+	if line != "" {
+		line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r") + "\n"
+		tokens, tokenizeErr := l.tokenizer.TokenizeContext(l.input.ctx, line, l.lineNumber)
+		if tokenizeErr == nil {
+			tokenizeErr = l.checkTokens(tokens)
+		}
+		if tokenizeErr != nil {
+			return &ParseError{Line: l.lineNumber, Err: tokenizeErr}
+		}
+
+		// a line within a multi-line quoted scalar may not complete any token
+		if len(tokens) > 0 {
+			if buildErr := l.parser.Build(tokens); buildErr != nil {
+				return &ParseError{Line: l.lineNumber, Err: buildErr}
+			}
+		}
+	}
+	if err != io.EOF {
+		return nil
+	}
+
+	l.finished = true
+	if err = l.tokenizer.Finish(); err != nil {
+		return &ParseError{Line: l.lineNumber, Err: err}
+	}
+	if err = l.parser.Finish(); err != nil {
+		return &ParseError{Line: l.lineNumber, Err: err}
+	}
+	return nil
+}
+
+// stopped reports that parsing stopped on the line being read, as its context is done with err
+func (l *lineReader) stopped(err error) error {
+	return &ParseError{Line: l.lineNumber, Err: fmt.Errorf("parsing stopped at line %d: %w", l.lineNumber, err)}
+}
+
+func (e *ParseError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+func (in *inputReader) Read(b []byte) (int, error) {
+	if err := in.ctx.Err(); err != nil {
+		return 0, err
+	}
+	if in.max <= 0 {
+		return in.r.Read(b)
+	}
+	if in.read > in.max {
+		return 0, &LimitError{Err: ErrMaxBytes, Max: in.max}
+	}
+
+	// reading one byte past max tells a stream of max bytes from a longer one
+	if len(b) > in.max-in.read+1 {
+		b = b[:in.max-in.read+1]
+	}
+	n, err := in.r.Read(b)
+	in.read += n
+	if in.read > in.max {
+		return n - (in.read - in.max), &LimitError{Err: ErrMaxBytes, Max: in.max}
+	}
+	return n, err
+}
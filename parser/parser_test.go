@@ -0,0 +1,411 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ercross/yaml"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// dump renders n in a compact flow-like notation, so that trees can be compared as strings
+func dump(n yaml.Node) string {
+	switch n := n.(type) {
+	case *yaml.DocumentNode:
+		return dump(n.Root())
+	case *yaml.ScalarNode:
+		return fmt.Sprintf("%q", n.Value())
+	case *yaml.AliasNode:
+		return "*" + n.Name()
+	case *yaml.SequenceNode:
+		items := make([]string, 0, n.Len())
+		for _, item := range n.Items() {
+			items = append(items, dump(item))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case *yaml.MappingNode:
+		pairs := make([]string, 0, n.Len())
+		for _, pair := range n.Pairs() {
+			pairs = append(pairs, dump(pair.Key)+": "+dump(pair.Value))
+		}
+		return "{" + strings.Join(pairs, ", ") + "}"
+	default:
+		return fmt.Sprintf("<%T>", n)
+	}
+}
+
+func mustParse(t *testing.T, source string) *AbstractSyntaxTree {
+	t.Helper()
+	ast, err := Parse(strings.NewReader(source))
+	if err != nil {
+		t.Fatalf("failed to parse %q: %v", source, err)
+	}
+	return ast
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{"flat mapping", "a: 1\nb: two\n", `{"a": "1", "b": "two"}`},
+		{"missing final newline", "a: 1", `{"a": "1"}`},
+		{"crlf line breaks", "a: 1\r\nb: 2\r\n", `{"a": "1", "b": "2"}`},
+		{"nested mapping", "person:\n  name: John\n  address:\n    city: Anytown\n  age: 30\nnext: x\n",
+			`{"person": {"name": "John", "address": {"city": "Anytown"}, "age": "30"}, "next": "x"}`},
+		{"empty value", "a:\nb: 1\n", `{"a": "", "b": "1"}`},
+		{"block sequence", "- a\n- b\n", `["a", "b"]`},
+		{"nested sequence", "list:\n  - a\n  - b\nother: c\n", `{"list": ["a", "b"], "other": "c"}`},
+		{"indentless sequence", "list:\n- a\n- b\nother: c\n", `{"list": ["a", "b"], "other": "c"}`},
+		{"sequence of mappings", "- name: a\n  size: 1\n- name: b\n", `[{"name": "a", "size": "1"}, {"name": "b"}]`},
+		{"sequence of sequences", "- - a\n  - b\n- - c\n", `[["a", "b"], ["c"]]`},
+		{"empty sequence entry", "-\n  a: 1\n- b\n", `[{"a": "1"}, "b"]`},
+		{"flow collections", "numbers: [1, 2, 3]\ninfo: {k: v, e: }\n", `{"numbers": ["1", "2", "3"], "info": {"k": "v", "e": ""}}`},
+		{"nested flow collections", "[a, [b, c], {d: [e]}]\n", `["a", ["b", "c"], {"d": ["e"]}]`},
+		{"multi-line flow collection", "list: [a,\n  b,\n  c]\nnext: d\n", `{"list": ["a", "b", "c"], "next": "d"}`},
+		{"flow pair in sequence", "[a: b, c]\n", `[{"a": "b"}, "c"]`},
+		{"literal block scalar", "text: |\n  line 1\n  line 2\nnext: x\n", `{"text": "line 1\nline 2\n", "next": "x"}`},
+		{"folded block scalar", "text: >-\n  folded\n  line\n\n  para\n", `{"text": "folded line\npara"}`},
+		{"keep chomping", "text: |+\n  a\n\n", `{"text": "a\n\n"}`},
+		{"block scalar in sequence", "- |\n  a\n- b\n", `["a\n", "b"]`},
+		{"quoted scalars", `a: "x\ty"` + "\nb: 'it''s'\n", `{"a": "x\ty", "b": "it's"}`},
+		{"multi-line quoted scalar", "a: \"first\n  second\"\n", `{"a": "first second"}`},
+		{"anchor and alias", "base: &b value\ncopy: *b\n", `{"base": "value", "copy": *b}`},
+		{"anchored mapping", "defaults: &d\n  user: guest\nuser1:\n  <<: *d\n  user: admin\n",
+			`{"defaults": {"user": "guest"}, "user1": {"<<": *d, "user": "admin"}}`},
+		{"root scalar", "hello\n", `"hello"`},
+		{"tagged values", "a: !!str 1\nb: !custom x\n", `{"a": "1", "b": "x"}`},
+		{"document start with content", "--- [a]\n", `["a"]`},
+		{"indented root mapping", "  a: 1\n  b:\n    c: 2\n", `{"a": "1", "b": {"c": "2"}}`},
+		{"indented root sequence", "---\n  - a\n  - b\n", `["a", "b"]`},
+		{"tab separating a value", "a:\tb\n", `{"a": "b"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			documents := mustParse(t, tt.source).Documents()
+			if len(documents) != 1 {
+				t.Fatalf("expected 1 document, got %d", len(documents))
+			}
+			if actual := dump(documents[0]); actual != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestParse_MultipleDocuments(t *testing.T) {
+	documents := mustParse(t, "a: 1\n---\nb: 2\n...\n---\n- c\n").Documents()
+
+	expected := []string{`{"a": "1"}`, `{"b": "2"}`, `["c"]`}
+	if len(documents) != len(expected) {
+		t.Fatalf("expected %d documents, got %d", len(expected), len(documents))
+	}
+	for i, document := range documents {
+		if actual := dump(document); actual != expected[i] {
+			t.Errorf("document %d: expected %s, got %s", i, expected[i], actual)
+		}
+	}
+}
+
+func TestParse_EmptyStream(t *testing.T) {
+	if documents := mustParse(t, "").Documents(); len(documents) != 0 {
+		t.Errorf("expected no documents, got %d", len(documents))
+	}
+
+	documents := mustParse(t, "---\n").Documents()
+	if len(documents) != 1 {
+		t.Fatalf("expected 1 document, got %d", len(documents))
+	}
+	if root := documents[0].Root(); root.Tag() != yaml.TagNull {
+		t.Errorf("expected empty document root to be %s, got %s", yaml.TagNull, root.Tag())
+	}
+}
+
+func TestParse_NodeProperties(t *testing.T) {
+	document := mustParse(t, "a: &x 1\nb: !!str 2\nc: 'q'\nd: [e]\nf: *x\n").Documents()[0]
+	pairs := document.Root().(*yaml.MappingNode).Pairs()
+
+	a := pairs[0].Value
+	if a.Anchor() != "x" || a.Tag() != yaml.TagInt || a.Style() != yaml.StylePlain {
+		t.Errorf("unexpected properties of a: anchor %q, tag %q, style %d", a.Anchor(), a.Tag(), a.Style())
+	}
+	if line, column := a.Position().Line(), a.Position().Column(); line != 1 || column != 7 {
+		t.Errorf("expected a at 1:7, got %d:%d", line, column)
+	}
+	if b := pairs[1].Value; b.Tag() != yaml.TagString {
+		t.Errorf("expected explicit tag %s, got %s", yaml.TagString, b.Tag())
+	}
+	if c := pairs[2].Value; c.Style() != yaml.StyleSingleQuoted {
+		t.Errorf("expected single-quoted style, got %d", c.Style())
+	}
+	if d := pairs[3].Value; d.Type() != yaml.NodeTypeSequenceFlowStyle {
+		t.Errorf("expected flow sequence, got %d", d.Type())
+	}
+
+	alias, ok := pairs[4].Value.(*yaml.AliasNode)
+	if !ok {
+		t.Fatalf("expected alias node, got %T", pairs[4].Value)
+	}
+	if alias.Target() != a {
+		t.Errorf("expected alias to target the node anchored as x")
+	}
+}
+
+func TestParse_PropertiesOnTheirOwnLine(t *testing.T) {
+	// the properties of a block collection may precede it on the line of its entry indicator or document marker
+	tests := []struct {
+		source string
+		node   func(root yaml.Node) yaml.Node
+		anchor string
+		tag    string
+	}{
+		{source: "- &a\n  b: 1\n- *a\n", node: func(root yaml.Node) yaml.Node { return root.Children()[0] }, anchor: "a", tag: yaml.TagMap},
+		{source: "- !custom\n  - 1\n", node: func(root yaml.Node) yaml.Node { return root.Children()[0] }, tag: "!custom"},
+		{source: "--- !!map &r\na: 1\n", node: func(root yaml.Node) yaml.Node { return root }, anchor: "r", tag: yaml.TagMap},
+		{source: "--- &r\n- 1\n", node: func(root yaml.Node) yaml.Node { return root }, anchor: "r", tag: yaml.TagSeq},
+	}
+	for _, test := range tests {
+		n := test.node(mustParse(t, test.source).Documents()[0].Root())
+		if n.Anchor() != test.anchor || n.Tag() != test.tag {
+			t.Errorf("%q: expected anchor %q and tag %q, got %q and %q", test.source, test.anchor, test.tag, n.Anchor(), n.Tag())
+		}
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected error
+	}{
+		{"unknown anchor", "a: *missing\n", errUnknownAnchor},
+		{"mixed entries", "a: 1\n- b\n", errMixedBlockEntries},
+		{"inconsistent indentation", "a:\n    b: 1\n  c: 2\n", errInconsistentIndentation},
+		{"unclosed flow collection", "a: [1, 2\n", errIncompleteNode},
+		{"trailing token after flow collection", "a: [1] b\n", errUnexpectedTokenType},
+		{"dedent below an indented root", "  a: 1\nb: 2\n", errInconsistentIndentation},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(strings.NewReader(tt.source))
+			if !errors.Is(err, tt.expected) {
+				t.Errorf("expected error %v, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestParse_TabIndentation(t *testing.T) {
+	if _, err := Parse(strings.NewReader("a:\n\tb: 1\n")); err == nil || !strings.Contains(err.Error(), "tab character") {
+		t.Errorf("expected tab indentation to be rejected, got %v", err)
+	}
+	mustParse(t, "a: 1\n\t# comment\n")
+}
+
+func TestParse_Comments(t *testing.T) {
+	source := `# header
+name: web # the name
+ports:
+  # http
+  - 80
+  - 443 # https
+  # end of ports
+labels: # labels
+  app: web
+# trailing
+`
+	document := mustParse(t, source).Documents()[0]
+	pairs := document.Root().(*yaml.MappingNode).Pairs()
+	ports := pairs[1].Value.(*yaml.SequenceNode)
+	labels := pairs[2].Value
+
+	tests := []struct {
+		name     string
+		actual   string
+		expected string
+	}{
+		{"head comment of the first key", pairs[0].Key.HeadComment(), "# header"},
+		{"line comment of a scalar value", pairs[0].Value.LineComment(), "# the name"},
+		{"head comment of a sequence item", ports.Items()[0].HeadComment(), "# http"},
+		{"line comment of a sequence item", ports.Items()[1].LineComment(), "# https"},
+		{"foot comment of a block sequence", ports.FootComment(), "# end of ports"},
+		{"line comment of a block mapping", labels.LineComment(), "# labels"},
+		{"foot comment of the document", document.FootComment(), "# trailing"},
+	}
+	for _, test := range tests {
+		if test.actual != test.expected {
+			t.Errorf("%s: expected %q, got %q", test.name, test.expected, test.actual)
+		}
+	}
+}
+
+func TestParse_CommentOnlyDocument(t *testing.T) {
+	documents := mustParse(t, "# first\n# second\n").Documents()
+	if len(documents) != 1 {
+		t.Fatalf("expected 1 document, got %d", len(documents))
+	}
+	if foot := documents[0].FootComment(); foot != "# first\n# second" {
+		t.Errorf("unexpected document comment %q", foot)
+	}
+}
+
+func TestAbstractSyntaxTree_IndentationUnit(t *testing.T) {
+	ast := mustParse(t, "a:\n    b: 1\n---\n- x\n---\nc:\n- y\n---\nd:\n   - z\n---\nscalar\n")
+	for i, expected := range []int{4, 2, 2, 3, 0} {
+		if actual := ast.IndentationUnit(ast.Documents()[i]); actual != expected {
+			t.Errorf("expected document %d to be indented by %d, got %d", i, expected, actual)
+		}
+	}
+}
+
+func TestParseWithOptions_DuplicateKeys(t *testing.T) {
+	source := "a: 1\nb: {c: 1, 'c': 2}\n0x10: x\na:\n  d: 3\n16: y\n<<: {e: 1}\n<<: {f: 1}\n"
+	tests := []struct {
+		policy   yaml.DuplicateKeyPolicy
+		expected string
+		warnings []string
+	}{
+		{
+			policy:   yaml.DuplicateKeyKeepFirst,
+			expected: `{"a": "1", "b": {"c": "1"}, "0x10": "x", "<<": {"e": "1"}, "<<": {"f": "1"}}`,
+		},
+		{
+			policy:   yaml.DuplicateKeyKeepLast,
+			expected: `{"b": {"c": "2"}, "a": {"d": "3"}, "16": "y", "<<": {"e": "1"}, "<<": {"f": "1"}}`,
+		},
+		{
+			policy:   yaml.DuplicateKeyWarn,
+			expected: `{"b": {"c": "2"}, "a": {"d": "3"}, "16": "y", "<<": {"e": "1"}, "<<": {"f": "1"}}`,
+			warnings: []string{
+				`key "c" at line(2): column(11), first defined at line(2): column(5): duplicate key`,
+				`key "a" at line(4): column(1), first defined at line(1): column(1): duplicate key`,
+				`key "16" at line(6): column(1), first defined at line(3): column(1): duplicate key`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy.String(), func(t *testing.T) {
+			ast, err := ParseWithOptions(strings.NewReader(source), Options{DuplicateKeys: tt.policy})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if actual := dump(ast.Documents()[0]); actual != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, actual)
+			}
+
+			var warnings []string
+			for _, warning := range ast.Warnings() {
+				warnings = append(warnings, warning.Error())
+			}
+			if strings.Join(warnings, "\n") != strings.Join(tt.warnings, "\n") {
+				t.Errorf("expected warnings:\n%s\ngot:\n%s", strings.Join(tt.warnings, "\n"), strings.Join(warnings, "\n"))
+			}
+		})
+	}
+}
+
+func TestParseWithOptions_Events(t *testing.T) {
+	// the events hold the pair the policy discards
+	var values []string
+	stop := errors.New("stop")
+	ast, err := ParseWithOptions(strings.NewReader("a: 1\na: 2\n"), Options{
+		DuplicateKeys: yaml.DuplicateKeyKeepLast,
+		Events: func(event yaml.Event) error {
+			if event.Type == yaml.EventScalar {
+				values = append(values, event.Value)
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"a", "1", "a", "2"}; !slices.Equal(values, expected) {
+		t.Errorf("expected scalars %q, got %q", expected, values)
+	}
+	if actual := dump(ast.Documents()[0]); actual != `{"a": "2"}` {
+		t.Errorf("expected the last pair to be kept, got %s", actual)
+	}
+
+	_, err = ParseWithOptions(strings.NewReader("a: 1\n"), Options{Events: func(yaml.Event) error { return stop }})
+	if !errors.Is(err, stop) {
+		t.Errorf("expected the error of the handler, got %v", err)
+	}
+}
+
+func TestParse_DuplicateKeys(t *testing.T) {
+	_, err := Parse(strings.NewReader("name: a\nport: 80\nname: b\n"))
+	var duplicate *yaml.DuplicateKeyError
+	if !errors.As(err, &duplicate) || !errors.Is(err, yaml.ErrDuplicateKey) {
+		t.Fatalf("expected a *yaml.DuplicateKeyError, got %v", err)
+	}
+	if duplicate.Key != "name" || duplicate.First.Line() != 1 || duplicate.Duplicate.Line() != 3 {
+		t.Errorf("expected name to be defined on lines 1 and 3, got %+v", duplicate)
+	}
+}
+
+// cancellingReader cancels its context once its reader is read whole
+type cancellingReader struct {
+	reader io.Reader
+	cancel context.CancelFunc
+}
+
+func (r *cancellingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if err == io.EOF {
+		r.cancel()
+	}
+	return n, err
+}
+
+func TestParseContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := ParseContext(ctx, strings.NewReader("a: 1\n"), Options{})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("expected the line parsing stopped at, got %v", err)
+	}
+
+	// a single line holding many nodes is stopped while its events are built
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	source := "[" + strings.Repeat("1, ", 2*contextCheckInterval) + "1]"
+	_, err = ParseContext(ctx, &cancellingReader{reader: strings.NewReader(source), cancel: cancel}, Options{})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "parsing stopped at line(1)") {
+		t.Errorf("expected the position parsing stopped at, got %v", err)
+	}
+
+	// a single line holding a long scalar is stopped while it is tokenized
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	source = "'" + strings.Repeat("a", 1<<20) + "'"
+	_, err = ParseContext(ctx, &cancellingReader{reader: strings.NewReader(source), cancel: cancel}, Options{})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "tokenizing stopped on 1:") {
+		t.Errorf("expected the position tokenizing stopped at, got %v", err)
+	}
+}
+
+func TestParseContext_EndlessLine(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := ParseContext(ctx, endlessReader('a'), Options{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+}
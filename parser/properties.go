@@ -0,0 +1,70 @@
+package parser
+
+import (
+	"fmt"
+	"github.com/ercross/yaml"
+	"github.com/ercross/yaml/token"
+)
+
+// properties are the anchor and tag that may precede a node
+type properties struct {
+	anchor string
+	tag    string
+}
+
+func isProperty(t token.Token) bool {
+	return t.Type == token.TypeAmpersand || t.Type == token.TypeExclamationMark
+}
+
+// add the anchor or tag held by t
+func (p *properties) add(t token.Token) error {
+	switch t.Type {
+	case token.TypeAmpersand:
+		if p.anchor != "" {
+			return fmt.Errorf("second anchor &%s at %s: a node can only have one anchor", t.Value, t.Position)
+		}
+		p.anchor = t.Value
+
+	case token.TypeExclamationMark:
+		if p.tag != "" {
+			return fmt.Errorf("second tag %s at %s: a node can only have one tag", t.Value, t.Position)
+		}
+		p.tag = t.Value
+
+	default:
+		return fmt.Errorf("token %s is not a node property: %w", t, errUnexpectedTokenType)
+	}
+	return nil
+}
+
+func (p properties) isEmpty() bool {
+	return p.anchor == "" && p.tag == ""
+}
+
+// applyTo gives the properties to the node started by event
+func (p properties) applyTo(event *yaml.Event) {
+	if p.anchor != "" {
+		event.Anchor = p.anchor
+	}
+	if p.tag != "" {
+		event.Tag = p.tag
+	}
+}
+
+// readProperties reads the properties of a line holding no node, e.g., "- &anchor" followed by a nested collection.
+// It reports false if tokens hold anything but properties
+func readProperties(tokens []token.Token) (properties, bool, error) {
+	var props properties
+	for _, t := range tokens {
+		switch {
+		case isProperty(t):
+			if err := props.add(t); err != nil {
+				return properties{}, false, err
+			}
+		case t.Type == token.TypeIndentation, t.Type == token.TypeNewline, t.Type == token.TypeComment:
+		default:
+			return properties{}, false, nil
+		}
+	}
+	return props, !props.isEmpty(), nil
+}
@@ -0,0 +1,264 @@
+package parser
+
+import (
+	"fmt"
+	"github.com/ercross/yaml"
+	"github.com/ercross/yaml/token"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+var doubleQuotedEscapes = map[byte]string{
+	'0':  "\x00",
+	'a':  "\a",
+	'b':  "\b",
+	't':  "\t",
+	'\t': "\t",
+	'n':  "\n",
+	'v':  "\v",
+	'f':  "\f",
+	'r':  "\r",
+	'e':  "\x1b",
+	' ':  " ",
+	'"':  `"`,
+	'/':  "/",
+	'\\': `\`,
+	'N':  "\u0085",
+	'_':  "\u00a0",
+	'L':  "\u2028",
+	'P':  "\u2029",
+}
+
+// hexEscapeWidths is the number of hexadecimal digits following the \x, \u and \U escape indicators
+var hexEscapeWidths = map[byte]int{
+	'x': 2,
+	'u': 4,
+	'U': 8,
+}
+
+// newScalarEvent creates the Scalar event represented by a token.TypeData token
+func newScalarEvent(t token.Token) (yaml.Event, error) {
+	event := yaml.Event{Type: yaml.EventScalar, Value: t.Value, Style: yaml.StylePlain, Start: t.Position, End: endOf(t)}
+
+	switch t.Quote {
+	case token.CharDoubleQuote:
+		value, err := unescapeDoubleQuoted(foldQuotedLines(t.Value, true))
+		if err != nil {
+			return yaml.Event{}, fmt.Errorf("invalid double-quoted scalar at %s: %w", t.Position, err)
+		}
+		event.Value, event.Style = value, yaml.StyleDoubleQuoted
+
+	case token.CharSingleQuote:
+		event.Value, event.Style = strings.ReplaceAll(foldQuotedLines(t.Value, false), "''", "'"), yaml.StyleSingleQuoted
+	}
+	return event, nil
+}
+
+// newEmptyScalarEvent creates the Scalar event of a node omitted at position, e.g., the value of "key:"
+func newEmptyScalarEvent(position token.Location) yaml.Event {
+	return yaml.Event{Type: yaml.EventScalar, Style: yaml.StylePlain, Start: position, End: position}
+}
+
+// endOf returns the location following the last character of the token t, including the quotes of a quoted scalar
+func endOf(t token.Token) token.Location {
+	text := t.Value
+	if t.Quote != 0 {
+		text = string(t.Quote) + text + string(t.Quote)
+	}
+	if i := strings.LastIndexByte(text, '\n'); i != -1 {
+		return token.NewLocation(t.Position.Line()+strings.Count(text, "\n"), utf8.RuneCountInString(text[i+1:])+1)
+	}
+	return token.NewLocation(t.Position.Line(), t.Position.Column()+utf8.RuneCountInString(text))
+}
+
+// foldQuotedLines folds the line breaks of a multi-line quoted scalar:
+// a single line break becomes a space, while each empty line becomes a line break.
+//
+// If escapable is true, a line ending with an escaping backslash is joined to the next line without a space
+func foldQuotedLines(value string, escapable bool) string {
+	lines := strings.Split(value, "\n")
+	if len(lines) == 1 {
+		return value
+	}
+
+	var b strings.Builder
+	emptyLines := 0
+	joinNext := false
+	for i, line := range lines {
+		last := i == len(lines)-1
+		if i > 0 {
+			line = strings.TrimLeft(line, " \t")
+		}
+		if !last {
+			line = strings.TrimRight(line, " \t")
+		}
+
+		if i > 0 && line == "" && !last {
+			emptyLines++
+			continue
+		}
+
+		if i > 0 && !joinNext {
+			if emptyLines > 0 {
+				b.WriteString(strings.Repeat("\n", emptyLines))
+			} else {
+				b.WriteByte(' ')
+			}
+		}
+		emptyLines = 0
+
+		joinNext = escapable && !last && endsWithEscape(line)
+		if joinNext {
+			line = line[:len(line)-1]
+		}
+		b.WriteString(line)
+	}
+	return b.String()
+}
+
+// endsWithEscape checks that s ends with a backslash that is not itself escaped
+func endsWithEscape(s string) bool {
+	backslashes := len(s) - len(strings.TrimRight(s, `\`))
+	return backslashes%2 == 1
+}
+
+// unescapeDoubleQuoted processes the escape sequences of a double-quoted scalar.
+// Unknown escape sequences are kept as written
+func unescapeDoubleQuoted(value string) (string, error) {
+	if !strings.Contains(value, `\`) {
+		return value, nil
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' || i == len(value)-1 {
+			b.WriteByte(value[i])
+			continue
+		}
+
+		i++
+		if replacement, ok := doubleQuotedEscapes[value[i]]; ok {
+			b.WriteString(replacement)
+			continue
+		}
+
+		width := hexEscapeWidths[value[i]]
+		if width == 0 {
+			b.WriteByte('\\')
+			b.WriteByte(value[i])
+			continue
+		}
+		if i+width >= len(value) {
+			return "", fmt.Errorf("truncated escape sequence \\%c", value[i])
+		}
+		code, err := strconv.ParseUint(value[i+1:i+1+width], 16, 32)
+		if err != nil {
+			return "", fmt.Errorf("invalid escape sequence \\%s", value[i:i+1+width])
+		}
+		b.WriteRune(rune(code))
+		i += width
+	}
+	return b.String(), nil
+}
+
+// blockScalarValue computes the value of a literal or folded block scalar from its raw content lines.
+//
+// indicators are the chomping (+ or -) and indentation (1-9) indicators of the block scalar header,
+// parentIndentation is the indentation of the node owning the block scalar
+func blockScalarValue(lines []string, folded bool, indicators string, parentIndentation int) (string, error) {
+	chomping := byte(0)
+	indentation := 0
+	for i := 0; i < len(indicators); i++ {
+		switch c := indicators[i]; {
+		case c == '+' || c == '-':
+			chomping = c
+		case c >= '1' && c <= '9':
+			indentation = max(parentIndentation, 0) + int(c-'0')
+		default:
+			return "", fmt.Errorf("invalid block scalar indicator %q", c)
+		}
+	}
+
+	if indentation == 0 {
+		for _, line := range lines {
+			if strings.TrimSpace(line) != "" {
+				indentation = len(line) - len(strings.TrimLeft(line, " "))
+				break
+			}
+		}
+	}
+
+	content := make([]string, 0, len(lines))
+	for _, line := range lines {
+		if len(line)-len(strings.TrimLeft(line, " ")) >= indentation {
+			content = append(content, line[indentation:])
+			continue
+		}
+		if strings.TrimSpace(line) != "" {
+			return "", fmt.Errorf("block scalar line %q is less indented than its first line", line)
+		}
+		content = append(content, "")
+	}
+
+	trailingEmptyLines := 0
+	for len(content) > 0 && strings.TrimSpace(content[len(content)-1]) == "" {
+		content = content[:len(content)-1]
+		trailingEmptyLines++
+	}
+
+	var value string
+	if folded {
+		value = foldBlockLines(content)
+	} else {
+		value = strings.Join(content, "\n")
+	}
+
+	switch chomping {
+	case '-':
+		return value, nil
+	case '+':
+		if len(content) == 0 {
+			return strings.Repeat("\n", trailingEmptyLines), nil
+		}
+		return value + "\n" + strings.Repeat("\n", trailingEmptyLines), nil
+	default:
+		if len(content) == 0 {
+			return "", nil
+		}
+		return value + "\n", nil
+	}
+}
+
+// foldBlockLines joins the lines of a folded block scalar.
+// Adjacent lines are joined by a space unless one of them is more indented, while empty lines become line breaks
+func foldBlockLines(lines []string) string {
+	var b strings.Builder
+	emptyLines := 0
+	first := true
+	previousMoreIndented := false
+	for _, line := range lines {
+		if line == "" {
+			emptyLines++
+			continue
+		}
+
+		moreIndented := line[0] == ' ' || line[0] == '\t'
+		switch {
+		case first:
+			b.WriteString(strings.Repeat("\n", emptyLines))
+		case !moreIndented && !previousMoreIndented && emptyLines == 0:
+			b.WriteByte(' ')
+		case !moreIndented && !previousMoreIndented:
+			b.WriteString(strings.Repeat("\n", emptyLines))
+		default:
+			b.WriteString(strings.Repeat("\n", emptyLines+1))
+		}
+
+		b.WriteString(line)
+		first = false
+		emptyLines = 0
+		previousMoreIndented = moreIndented
+	}
+	return b.String()
+}
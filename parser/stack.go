@@ -13,9 +13,13 @@ func newStack() *stack {
 	}
 }
 
-func (s *stack) push(frame Frame) {
+// push frame onto stack, unless its indentation is inconsistent with the frames already on stack
+func (s *stack) push(frame Frame) error {
+	if err := s.indentationManager.tryPush(frame.IndentationLevel(), frame.NodeType()); err != nil {
+		return err
+	}
 	s.elements = append(s.elements, frame)
-	s.indentationManager.push(frame.IndentationLevel(), frame.NodeType())
+	return nil
 }
 func (s *stack) pop() Frame {
 	if len(s.elements) == 0 {
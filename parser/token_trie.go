@@ -27,13 +27,18 @@ type (
 	}
 
 	// nodeTypeFinder is a tokenTrie traverser capable of finding which yaml.Node
-	// a sequence of token.Token attempts to represent
+	// a sequence of token.Token attempts to represent.
+	//
+	// nodeTypeFinder only reads from its trie, so many finders
+	// can walk the same tokenTrie concurrently
 	nodeTypeFinder struct {
+		trie     *tokenTrie
 		position *tokenTrieNode
 
 		// done indicates that nodeTypeFinder has concluded its finding.
-		// The result of its search can be found in position.nodeType
-		done bool
+		// The result of its search can be found in result
+		done   bool
+		result yaml.NodeType
 	}
 )
 
@@ -58,14 +63,38 @@ type (
 	}
 )
 
-var st *tokenTrie
+// grammar holds the syntax definition of every supported yaml.NodeType.
+//
+// grammar is built once during package initialization and must never be
+// modified afterwards, which makes it safe to share across goroutines
+// and AstBuilder instances
+var grammar = newTokenTrie()
 
-func initTokenTrie() {
-	st = &tokenTrie{
+func newTokenTrie() *tokenTrie {
+	t := &tokenTrie{
 		root: &tokenTrieNode{},
 	}
 
-	st.insertNodeSyntax(scalarNodeSyntax(), yaml.NodeTypeScalar)
+	t.insertNodeSyntax(scalarNodeSyntax(), yaml.NodeTypeScalar)
+	t.insertNodeSyntax(bareScalarNodeSyntax(), yaml.NodeTypeScalar)
+	t.insertNodeSyntax(blockMappingNodeSyntax(), yaml.NodeTypeMappingBlockStyle)
+	t.insertNodeSyntax(blockSequenceNodeSyntax(), yaml.NodeTypeSequenceBlockStyle)
+
+	// nodes introduced by a single token can be either a mapping entry value or a standalone node
+	for _, start := range []struct {
+		tokenType token.Type
+		nodeType  yaml.NodeType
+	}{
+		{token.TypeOpeningSquareBracket, yaml.NodeTypeSequenceFlowStyle},
+		{token.TypeOpeningCurlyBrace, yaml.NodeTypeMappingFlowStyle},
+		{token.TypePipe, yaml.NodeTypeMultilineString},
+		{token.TypeGreaterThan, yaml.NodeTypeFoldedString},
+		{token.TypeAsterisk, yaml.NodeTypeAlias},
+	} {
+		t.insertNodeSyntax(mappingEntryNodeSyntax(start.tokenType), start.nodeType)
+		t.insertNodeSyntax(newNodeSyntax(&nodeSyntaxToken{tokenType: start.tokenType}), start.nodeType)
+	}
+	return t
 }
 
 func (t *tokenTrie) insertNodeSyntax(ts *nodeSyntax, f yaml.NodeType) {
@@ -103,9 +132,10 @@ func containsTokenType(nodes []*tokenTrieNode, t token.Type) int {
 	return -1
 }
 
-func newNodeTypeFinder() *nodeTypeFinder {
+func newNodeTypeFinder(trie *tokenTrie) *nodeTypeFinder {
 	return &nodeTypeFinder{
-		position: st.root,
+		trie:     trie,
+		position: trie.root,
 	}
 }
 
@@ -114,12 +144,12 @@ func (f *nodeTypeFinder) match(tokens []token.Token) {
 
 	for _, next := range tokens {
 
-		// indentation is not part of syntax tree
-		if next.Type == token.TypeIndentation {
+		// indentation, comments and node properties are not part of syntax tree
+		switch next.Type {
+		case token.TypeIndentation, token.TypeComment, token.TypeAmpersand, token.TypeExclamationMark:
 			continue
 		}
-		if f.position.nodeType != yaml.NodeTypeUnknown {
-			f.done = true
+		if f.done {
 			return
 		}
 		if f.position.tokenType == next.Type {
@@ -129,13 +159,11 @@ func (f *nodeTypeFinder) match(tokens []token.Token) {
 		for _, child := range f.position.children {
 			if child.tokenType == next.Type {
 				f.position = child
-				if f.position.nodeType != yaml.NodeTypeUnknown {
+				if leaf := f.position.leaf(); leaf != nil {
+					f.result = leaf.nodeType
 					f.done = true
 					return
 				}
-				if len(f.position.children) == 1 && f.position.children[0].nodeType != yaml.NodeTypeUnknown {
-					f.position.nodeType = f.position.children[0].nodeType
-				}
 				break
 			}
 		}
@@ -143,15 +171,26 @@ func (f *nodeTypeFinder) match(tokens []token.Token) {
 }
 
 func (f *nodeTypeFinder) reset() {
-	f.position = st.root
+	f.position = f.trie.root
 	f.done = false
+	f.result = yaml.NodeTypeUnknown
 }
 
 func (f *nodeTypeFinder) nodeType() yaml.NodeType {
 	if !f.done {
 		panic("finder not done")
 	}
-	return f.position.nodeType
+	return f.result
+}
+
+// leaf returns the child of n that terminates a nodeSyntax, or nil if n has none
+func (n *tokenTrieNode) leaf() *tokenTrieNode {
+	for _, child := range n.children {
+		if child.nodeType != yaml.NodeTypeUnknown {
+			return child
+		}
+	}
+	return nil
 }
 
 func newNodeSyntaxTraverser(start *nodeSyntaxToken) *nodeSyntaxTraverser {
@@ -223,3 +262,28 @@ func scalarNodeSyntax() *nodeSyntax {
 		insert(&nodeSyntaxToken{optional: false, tokenType: token.TypeData}).
 		insert(&nodeSyntaxToken{optional: false, tokenType: token.TypeNewline})
 }
+
+// bareScalarNodeSyntax is a scalar standing on its own line, e.g., a document root or a sequence entry
+func bareScalarNodeSyntax() *nodeSyntax {
+	return newNodeSyntax(&nodeSyntaxToken{optional: false, tokenType: token.TypeData}).
+		insert(&nodeSyntaxToken{optional: false, tokenType: token.TypeNewline})
+}
+
+// blockMappingNodeSyntax is a mapping key whose value is nested on the following lines
+func blockMappingNodeSyntax() *nodeSyntax {
+	return newNodeSyntax(&nodeSyntaxToken{optional: false, tokenType: token.TypeData}).
+		insert(&nodeSyntaxToken{optional: false, tokenType: token.TypeColon}).
+		insert(&nodeSyntaxToken{optional: false, tokenType: token.TypeNewline})
+}
+
+// blockSequenceNodeSyntax is a sequence entry indicator, whatever follows it is a node of its own
+func blockSequenceNodeSyntax() *nodeSyntax {
+	return newNodeSyntax(&nodeSyntaxToken{optional: false, tokenType: token.TypeDash})
+}
+
+// mappingEntryNodeSyntax is a mapping key whose value starts with value token type
+func mappingEntryNodeSyntax(value token.Type) *nodeSyntax {
+	return newNodeSyntax(&nodeSyntaxToken{optional: false, tokenType: token.TypeData}).
+		insert(&nodeSyntaxToken{optional: false, tokenType: token.TypeColon}).
+		insert(&nodeSyntaxToken{optional: false, tokenType: value})
+}
@@ -7,15 +7,18 @@ import (
 )
 
 func TestScalarNodeFinder(t *testing.T) {
-	initTokenTrie()
-
-	finder := newNodeTypeFinder()
+	finder := newNodeTypeFinder(grammar)
 
 	for _, sampleScalar := range testdata.ScalarTokens {
 		finder.match(sampleScalar)
-		if finder.done && finder.nodeType() != yaml.NodeTypeScalar {
+		if !finder.done {
+			t.Errorf("expected finder to be done after matching %v", sampleScalar)
+			continue
+		}
+		if finder.nodeType() != yaml.NodeTypeScalar {
 			t.Errorf("expected node type %d, got %d", yaml.NodeTypeScalar, finder.nodeType())
 		}
+		finder.reset()
 	}
 
 }
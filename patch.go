@@ -0,0 +1,307 @@
+package yaml
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrInvalidPatch is returned when a patch document is malformed, e.g., an operation misses a required member
+	ErrInvalidPatch = errors.New("invalid patch")
+
+	// ErrTestFailed is returned when the value of a JSON Patch test operation differs from the tested node
+	ErrTestFailed = errors.New("test failed")
+)
+
+// PatchError reports the JSON Patch operation that could not be applied
+type PatchError struct {
+	// Index is the index of the operation within the patch, starting at 0
+	Index int
+	Op    string
+	Path  Pointer
+	Err   error
+}
+
+// jsonPatchOperation is an operation of a JSON Patch document
+type jsonPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  *Pointer        `json:"path"`
+	From  *Pointer        `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// ApplyJSONPatch applies the JSON Patch (RFC 6902) patch to document in place.
+//
+// The six operations (add, remove, replace, move, copy and test) are supported, and values are converted
+// to nodes keeping the order of JSON object members. The nodes left untouched by the patch keep their
+// comments, styles and key order, and a replaced value keeps the comments of the node it replaces.
+// A path leading through an alias changes a copy of the node the alias refers to, which replaces the alias.
+// Operations are applied in order, and the first one failing stops the patch with a PatchError:
+// the operations preceding it remain applied
+func ApplyJSONPatch(document *DocumentNode, patch []byte) error {
+	var operations []jsonPatchOperation
+	if err := json.Unmarshal(patch, &operations); err != nil {
+		return fmt.Errorf("json patch must be an array of operations: %w: %w", ErrInvalidPatch, err)
+	}
+
+	for i, operation := range operations {
+		if err := applyOperation(document, operation); err != nil {
+			patchErr := &PatchError{Index: i, Op: operation.Op, Err: err}
+			if operation.Path != nil {
+				patchErr.Path = *operation.Path
+			}
+			return patchErr
+		}
+	}
+	return nil
+}
+
+func applyOperation(document *DocumentNode, operation jsonPatchOperation) error {
+	if operation.Path == nil {
+		return fmt.Errorf("missing path: %w", ErrInvalidPatch)
+	}
+	path := *operation.Path
+
+	var value Node
+	switch operation.Op {
+	case "add", "replace", "test":
+		if operation.Value == nil {
+			return fmt.Errorf("missing value: %w", ErrInvalidPatch)
+		}
+		var err error
+		if value, err = decodeJSON(operation.Value); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidPatch, err)
+		}
+
+	case "move", "copy":
+		if operation.From == nil {
+			return fmt.Errorf("missing from: %w", ErrInvalidPatch)
+		}
+	}
+
+	// changing a node through an alias changes a copy of the anchored node, as mergePatch does
+	switch operation.Op {
+	case "add", "remove", "replace", "move", "copy":
+		if err := unaliasPath(document, path); err != nil {
+			return err
+		}
+	}
+	if operation.Op == "move" {
+		if err := unaliasPath(document, *operation.From); err != nil {
+			return err
+		}
+	}
+
+	switch operation.Op {
+	case "add":
+		return addAt(document, path, value)
+
+	case "remove":
+		return path.Delete(document)
+
+	case "replace":
+		old, err := path.Get(document)
+		if err != nil {
+			return err
+		}
+		keepComments(old, value)
+		return path.Set(document, value, false)
+
+	case "move":
+		from := *operation.From
+		if path != from && strings.HasPrefix(string(path)+"/", string(from)+"/") {
+			return fmt.Errorf("can not move %q into itself: %w", string(from), ErrInvalidPatch)
+		}
+		moved, err := from.Get(document)
+		if err != nil {
+			return err
+		}
+		if path == from {
+			return nil
+		}
+		if err = from.Delete(document); err != nil {
+			return err
+		}
+		return addAt(document, path, moved)
+
+	case "copy":
+		copied, err := operation.From.Get(document)
+		if err != nil {
+			return err
+		}
+		return addAt(document, path, copyNode(copied))
+
+	case "test":
+		actual, err := path.Get(document)
+		if err != nil {
+			return err
+		}
+		if !equalNodes(actual, value) {
+			return fmt.Errorf("expected %s, got %s: %w", diffText(value), diffText(actual), ErrTestFailed)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown operation %q: %w", operation.Op, ErrInvalidPatch)
+}
+
+// addAt adds value at path as the JSON Patch add operation does: a sequence index inserts value before the item at index
+func addAt(document *DocumentNode, path Pointer, value Node) error {
+	segments, err := path.Segments()
+	if err != nil || len(segments) == 0 {
+		return path.Set(document, value, false)
+	}
+
+	parent, err := path.parentOf(document, segments, false)
+	if err != nil {
+		return err
+	}
+	last := segments[len(segments)-1]
+	sequence, ok := resolveAlias(parent).(*SequenceNode)
+	if !ok || last == "-" {
+		return path.Set(document, value, false)
+	}
+
+	i, ok := parseIndex(last)
+	if !ok || i > len(sequence.items) {
+		return path.notFound(last, parent)
+	}
+	sequence.items = insertAt(sequence.items, i, value)
+	return nil
+}
+
+// unaliasPath replaces the aliases leading to the node path refers to with copies of the nodes they refer to,
+// so that changing that node leaves the anchored nodes alone
+func unaliasPath(document *DocumentNode, path Pointer) error {
+	segments, err := path.Segments()
+	if err != nil || len(segments) == 0 {
+		return err
+	}
+
+	current := documentRoot(document)
+	for i, segment := range segments[:len(segments)-1] {
+		child, ok := childAt(current, segment)
+		if !ok {
+			// the operation reports the missing node
+			return nil
+		}
+		if _, alias := child.(*AliasNode); alias {
+			child = copyNode(child)
+			if err := NewPointer(segments[:i+1]...).Set(document, child, false); err != nil {
+				return err
+			}
+		}
+		current = child
+	}
+	return nil
+}
+
+// ApplyMergePatch applies the JSON Merge Patch (RFC 7386) patch to document in place.
+//
+// Members of a patch object replace the values of the same keys, which keep their position in the mapping,
+// null members delete their key, and members missing from the mapping are appended to it.
+// A patch that is not an object replaces the whole document
+func ApplyMergePatch(document *DocumentNode, patch []byte) error {
+	patchNode, err := decodeJSON(patch)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidPatch, err)
+	}
+	document.root = mergePatch(document.root, patchNode)
+	return nil
+}
+
+// mergePatch returns target patched with patch, modifying target in place where possible
+func mergePatch(target Node, patch Node) Node {
+	patchMapping, ok := patch.(*MappingNode)
+	if !ok {
+		keepComments(target, patch)
+		return patch
+	}
+
+	mapping, ok := target.(*MappingNode)
+	if _, alias := target.(*AliasNode); alias {
+		// patching through an alias changes the value of the alias only, leaving the anchored node alone
+		mapping, ok = copyNode(target).(*MappingNode)
+	}
+	if !ok {
+		mapping = NewMappingNode()
+	}
+	for _, member := range patchMapping.pairs {
+		name := member.Key.(*ScalarNode).value
+		i := mapping.indexOf(name)
+		switch {
+		case member.Value.Tag() == TagNull:
+			if i != -1 {
+				mapping.pairs = append(mapping.pairs[:i], mapping.pairs[i+1:]...)
+			}
+		case i != -1:
+			mapping.pairs[i].Value = mergePatch(mapping.pairs[i].Value, member.Value)
+		default:
+			mapping.pairs = append(mapping.pairs, MappingPair{Key: member.Key, Value: mergePatch(nil, member.Value)})
+		}
+	}
+	return mapping
+}
+
+// keepComments gives value the comments of the node it replaces, unless value has comments of its own
+func keepComments(old Node, value Node) {
+	commented, ok := value.(interface {
+		SetHeadComment(comment string)
+		SetLineComment(comment string)
+		SetFootComment(comment string)
+	})
+	if !ok || old == nil || value.HeadComment() != "" || value.LineComment() != "" || value.FootComment() != "" {
+		return
+	}
+	commented.SetHeadComment(old.HeadComment())
+	commented.SetLineComment(old.LineComment())
+	commented.SetFootComment(old.FootComment())
+}
+
+// copyNode returns a deep copy of n. The copy defines no anchor, and its aliases are replaced
+// with copies of the nodes they refer to, except for aliases of an enclosing node, which keep referring to the original node
+func copyNode(n Node) Node {
+	return copyTree(n, make(map[Node]bool))
+}
+
+func copyTree(n Node, enclosing map[Node]bool) Node {
+	target := resolveAlias(n)
+	if enclosing[target] {
+		return n
+	}
+	enclosing[target] = true
+	defer delete(enclosing, target)
+
+	switch target := target.(type) {
+	case *ScalarNode:
+		copied := *target
+		copied.anchor = ""
+		return &copied
+
+	case *MappingNode:
+		copied := &MappingNode{nodeProperties: target.nodeProperties, pairs: make([]MappingPair, len(target.pairs))}
+		copied.anchor = ""
+		for i, pair := range target.pairs {
+			copied.pairs[i] = MappingPair{Key: copyTree(pair.Key, enclosing), Value: copyTree(pair.Value, enclosing)}
+		}
+		return copied
+
+	case *SequenceNode:
+		copied := &SequenceNode{nodeProperties: target.nodeProperties, items: make([]Node, len(target.items))}
+		copied.anchor = ""
+		for i, item := range target.items {
+			copied.items[i] = copyTree(item, enclosing)
+		}
+		return copied
+	}
+	return n
+}
+
+func (e *PatchError) Error() string {
+	return fmt.Sprintf("json patch operation %d (%s %q): %v", e.Index, e.Op, string(e.Path), e.Err)
+}
+
+func (e *PatchError) Unwrap() error {
+	return e.Err
+}
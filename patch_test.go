@@ -0,0 +1,216 @@
+package yaml_test
+
+import (
+	"errors"
+	"github.com/ercross/yaml"
+	"testing"
+)
+
+func TestApplyJSONPatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		patch    string
+		expected string
+	}{
+		{
+			name:     "add",
+			source:   "a: 1 # one\nlist: [x, z]\n",
+			patch:    `[{"op": "add", "path": "/b", "value": {"k": "v", "n": 2}}, {"op": "add", "path": "/list/1", "value": "y"}, {"op": "add", "path": "/list/-", "value": "w"}]`,
+			expected: "a: 1 # one\nlist: [x, \"y\", z, w]\nb:\n  k: v\n  \"n\": 2\n",
+		},
+		{
+			name:     "remove",
+			source:   "a: 1\nb:\n  - x\n  - y\n",
+			patch:    `[{"op": "remove", "path": "/b/0"}, {"op": "remove", "path": "/a"}]`,
+			expected: "b:\n  - y\n",
+		},
+		{
+			name:     "replace keeps comments",
+			source:   "# head\nversion: 1.0 # pinned\nname: app\n",
+			patch:    `[{"op": "replace", "path": "/version", "value": "1.1"}]`,
+			expected: "# head\nversion: \"1.1\" # pinned\nname: app\n",
+		},
+		{
+			name:     "move",
+			source:   "a:\n  x: 1\nb: {}\n",
+			patch:    `[{"op": "move", "from": "/a/x", "path": "/b/y"}]`,
+			expected: "a: {}\nb: {'y': 1}\n",
+		},
+		{
+			name:     "copy",
+			source:   "a: &x\n  k: v\n",
+			patch:    `[{"op": "copy", "from": "/a", "path": "/b"}]`,
+			expected: "a: &x\n  k: v\nb:\n  k: v\n",
+		},
+		{
+			name:     "test",
+			source:   "a: 0x10\nb: [1, 'two']\n",
+			patch:    `[{"op": "test", "path": "/a", "value": 16}, {"op": "test", "path": "/b", "value": [1, "two"]}]`,
+			expected: "a: 0x10\nb: [1, 'two']\n",
+		},
+		{
+			name:     "replace through an alias",
+			source:   "base: &b {x: 1}\nref: *b\n",
+			patch:    `[{"op": "replace", "path": "/ref/x", "value": 2}]`,
+			expected: "base: &b {x: 1}\nref: {x: 2}\n",
+		},
+		{
+			name:     "remove through an alias",
+			source:   "base: &b {x: 1}\nref: *b\n",
+			patch:    `[{"op": "remove", "path": "/ref/x"}]`,
+			expected: "base: &b {x: 1}\nref: {}\n",
+		},
+		{
+			name:     "add through nested aliases",
+			source:   "base: &b {list: &l [1]}\nref: {list: *l, base: *b}\n",
+			patch:    `[{"op": "add", "path": "/ref/list/0", "value": 0}, {"op": "add", "path": "/ref/base/y", "value": 2}]`,
+			expected: "base: &b {list: &l [1]}\nref: {list: [0, 1], base: {list: [1], 'y': 2}}\n",
+		},
+		{
+			name:     "move out of an alias",
+			source:   "base: &b {x: 1}\nref: *b\n",
+			patch:    `[{"op": "move", "from": "/ref/x", "path": "/x"}]`,
+			expected: "base: &b {x: 1}\nref: {}\nx: 1\n",
+		},
+		{
+			name:     "replace root",
+			source:   "a: 1\n",
+			patch:    `[{"op": "replace", "path": "", "value": ["x"]}]`,
+			expected: "- x\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			document := parseDocument(t, test.source)
+			if err := yaml.ApplyJSONPatch(document, []byte(test.patch)); err != nil {
+				t.Fatal(err)
+			}
+			if actual := emit(t, document); actual != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestApplyJSONPatch_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		patch    string
+		index    int
+		path     yaml.Pointer
+		expected error
+	}{
+		{
+			name:     "failed test",
+			patch:    `[{"op": "add", "path": "/b", "value": 2}, {"op": "test", "path": "/a", "value": "1"}]`,
+			index:    1,
+			path:     "/a",
+			expected: yaml.ErrTestFailed,
+		},
+		{
+			name:     "missing target",
+			patch:    `[{"op": "replace", "path": "/missing", "value": 1}]`,
+			path:     "/missing",
+			expected: yaml.ErrPointerNotFound,
+		},
+		{
+			name:     "index out of range",
+			patch:    `[{"op": "add", "path": "/list/5", "value": 1}]`,
+			path:     "/list/5",
+			expected: yaml.ErrPointerNotFound,
+		},
+		{
+			name:     "missing value",
+			patch:    `[{"op": "add", "path": "/b"}]`,
+			path:     "/b",
+			expected: yaml.ErrInvalidPatch,
+		},
+		{
+			name:     "move into itself",
+			patch:    `[{"op": "move", "from": "/list", "path": "/list/0"}]`,
+			path:     "/list/0",
+			expected: yaml.ErrInvalidPatch,
+		},
+		{
+			name:     "unknown operation",
+			patch:    `[{"op": "append", "path": "/list"}]`,
+			path:     "/list",
+			expected: yaml.ErrInvalidPatch,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := yaml.ApplyJSONPatch(parseDocument(t, "a: 1\nlist: [x]\n"), []byte(test.patch))
+			if !errors.Is(err, test.expected) {
+				t.Fatalf("expected %v, got %v", test.expected, err)
+			}
+			var patchErr *yaml.PatchError
+			if !errors.As(err, &patchErr) {
+				t.Fatalf("expected a PatchError, got %T", err)
+			}
+			if patchErr.Index != test.index || patchErr.Path != test.path {
+				t.Errorf("expected operation %d at %q, got %d at %q", test.index, test.path, patchErr.Index, patchErr.Path)
+			}
+		})
+	}
+
+	if err := yaml.ApplyJSONPatch(parseDocument(t, "a: 1\n"), []byte(`{"op": "add"}`)); !errors.Is(err, yaml.ErrInvalidPatch) {
+		t.Errorf("expected %v for a patch that is not an array, got %v", yaml.ErrInvalidPatch, err)
+	}
+}
+
+func TestApplyMergePatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		patch    string
+		expected string
+	}{
+		{
+			name:     "merge",
+			source:   "# config\nname: app # the name\nspec:\n  replicas: 1\n  image: v1\nold: true\n",
+			patch:    `{"spec": {"replicas": 3, "ports": [80]}, "old": null, "name": "web"}`,
+			expected: "# config\nname: web # the name\nspec:\n  replicas: 3\n  image: v1\n  ports:\n    - 80\n",
+		},
+		{
+			name:     "nulls of new members are dropped",
+			source:   "a: 1\n",
+			patch:    `{"b": {"c": null, "d": 1}}`,
+			expected: "a: 1\nb:\n  d: 1\n",
+		},
+		{
+			name:     "scalar replaced by mapping",
+			source:   "a: 1\n",
+			patch:    `{"a": {"b": 2}}`,
+			expected: "a:\n  b: 2\n",
+		},
+		{
+			name:     "merge through an alias",
+			source:   "base: &b {x: 1}\nref: *b\n",
+			patch:    `{"ref": {"y": 2}}`,
+			expected: "base: &b {x: 1}\nref: {x: 1, \"y\": 2}\n",
+		},
+		{
+			name:     "non-object patch replaces the document",
+			source:   "a: 1\n",
+			patch:    `["x"]`,
+			expected: "- x\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			document := parseDocument(t, test.source)
+			if err := yaml.ApplyMergePatch(document, []byte(test.patch)); err != nil {
+				t.Fatal(err)
+			}
+			if actual := emit(t, document); actual != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, actual)
+			}
+		})
+	}
+
+	if err := yaml.ApplyMergePatch(parseDocument(t, "a: 1\n"), []byte(`{"a": `)); !errors.Is(err, yaml.ErrInvalidPatch) {
+		t.Errorf("expected %v, got %v", yaml.ErrInvalidPatch, err)
+	}
+}
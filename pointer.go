@@ -0,0 +1,270 @@
+package yaml
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidPointer  = errors.New("invalid json pointer")
+	ErrPointerNotFound = errors.New("json pointer target not found")
+)
+
+// Pointer is a JSON Pointer (RFC 6901), e.g., /spec/replicas, locating a node within a tree.
+//
+// Each reference token of a Pointer selects either the value of a mapping key
+// or the item at a decimal index of a sequence. Aliases are followed while resolving a Pointer,
+// so modifying the node an alias refers to also modifies its anchored node
+type Pointer string
+
+// PointerError reports the segment of a Pointer that could not be resolved
+type PointerError struct {
+	Pointer Pointer
+
+	// Segment is the unescaped reference token that could not be resolved
+	Segment string
+
+	// Parent is the deepest node Pointer could be resolved to, or nil if the document is empty
+	Parent Node
+
+	Err error
+}
+
+// NewPointer creates the Pointer made of segments, escaping ~ and / in each of them
+func NewPointer(segments ...string) Pointer {
+	var b strings.Builder
+	for _, segment := range segments {
+		b.WriteByte('/')
+		b.WriteString(strings.ReplaceAll(strings.ReplaceAll(segment, "~", "~0"), "/", "~1"))
+	}
+	return Pointer(b.String())
+}
+
+// Segments returns the unescaped reference tokens of p. The empty Pointer, referring to the whole document, has none
+func (p Pointer) Segments() ([]string, error) {
+	if p == "" {
+		return nil, nil
+	}
+	if p[0] != '/' {
+		return nil, fmt.Errorf("json pointer %q must start with /: %w", string(p), ErrInvalidPointer)
+	}
+
+	segments := strings.Split(string(p[1:]), "/")
+	for i, segment := range segments {
+		for j := 0; j < len(segment); j++ {
+			if segment[j] == '~' && (j == len(segment)-1 || (segment[j+1] != '0' && segment[j+1] != '1')) {
+				return nil, fmt.Errorf("json pointer %q has an invalid escape sequence in %q: %w", string(p), segment, ErrInvalidPointer)
+			}
+		}
+		segments[i] = strings.ReplaceAll(strings.ReplaceAll(segment, "~1", "/"), "~0", "~")
+	}
+	return segments, nil
+}
+
+func (p Pointer) String() string {
+	return string(p)
+}
+
+// Get returns the node p refers to within the tree rooted at n.
+// If n is a DocumentNode, p is resolved from the document root
+func (p Pointer) Get(n Node) (Node, error) {
+	segments, err := p.Segments()
+	if err != nil {
+		return nil, err
+	}
+
+	current := documentRoot(n)
+	for _, segment := range segments {
+		child, ok := childAt(current, segment)
+		if !ok {
+			return nil, p.notFound(segment, current)
+		}
+		current = child
+	}
+	if current == nil {
+		return nil, fmt.Errorf("json pointer %q refers to an empty document: %w", string(p), ErrPointerNotFound)
+	}
+	return current, nil
+}
+
+// Set replaces the node p refers to within the tree rooted at n with value,
+// or adds value if p refers to a missing mapping key, to the sequence index following the last item, or to -.
+//
+// If createMissing is true, the missing mapping keys leading to the node p refers to are created,
+// each holding an empty mapping. The empty Pointer replaces the root of a DocumentNode
+func (p Pointer) Set(n Node, value Node, createMissing bool) error {
+	segments, err := p.Segments()
+	if err != nil {
+		return err
+	}
+
+	if len(segments) == 0 {
+		document, ok := n.(*DocumentNode)
+		if !ok {
+			return fmt.Errorf("the empty json pointer can only replace the root of a document: %w", ErrInvalidPointer)
+		}
+		document.root = value
+		return nil
+	}
+
+	parent, err := p.parentOf(n, segments, createMissing)
+	if err != nil {
+		return err
+	}
+
+	last := segments[len(segments)-1]
+	switch collection := resolveAlias(parent).(type) {
+	case *MappingNode:
+		if i := collection.indexOf(last); i != -1 {
+			collection.pairs[i].Value = value
+			return nil
+		}
+		collection.pairs = append(collection.pairs, MappingPair{Key: NewScalarNode(last), Value: value})
+		return nil
+
+	case *SequenceNode:
+		if last == "-" {
+			collection.items = append(collection.items, value)
+			return nil
+		}
+		i, ok := parseIndex(last)
+		if ok && i < len(collection.items) {
+			collection.items[i] = value
+			return nil
+		}
+		if ok && i == len(collection.items) {
+			collection.items = append(collection.items, value)
+			return nil
+		}
+	}
+	return p.notFound(last, parent)
+}
+
+// Delete removes the node p refers to within the tree rooted at n, with its key if it is a mapping value
+func (p Pointer) Delete(n Node) error {
+	segments, err := p.Segments()
+	if err != nil {
+		return err
+	}
+	if len(segments) == 0 {
+		return fmt.Errorf("the empty json pointer refers to the whole document, which can not be deleted: %w", ErrInvalidPointer)
+	}
+
+	parent, err := p.parentOf(n, segments, false)
+	if err != nil {
+		return err
+	}
+
+	last := segments[len(segments)-1]
+	switch collection := resolveAlias(parent).(type) {
+	case *MappingNode:
+		if i := collection.indexOf(last); i != -1 {
+			collection.pairs = append(collection.pairs[:i], collection.pairs[i+1:]...)
+			return nil
+		}
+
+	case *SequenceNode:
+		if i, ok := parseIndex(last); ok && i < len(collection.items) {
+			collection.items = append(collection.items[:i], collection.items[i+1:]...)
+			return nil
+		}
+	}
+	return p.notFound(last, parent)
+}
+
+// parentOf resolves every segment but the last one
+func (p Pointer) parentOf(n Node, segments []string, createMissing bool) (Node, error) {
+	current := documentRoot(n)
+	for _, segment := range segments[:len(segments)-1] {
+		child, ok := childAt(current, segment)
+		if !ok {
+			mapping, isMapping := resolveAlias(current).(*MappingNode)
+			if !createMissing || !isMapping {
+				return nil, p.notFound(segment, current)
+			}
+			child = NewMappingNode()
+			mapping.pairs = append(mapping.pairs, MappingPair{Key: NewScalarNode(segment), Value: child})
+		}
+		current = child
+	}
+	if current == nil {
+		return nil, p.notFound(segments[len(segments)-1], nil)
+	}
+	return current, nil
+}
+
+func (p Pointer) notFound(segment string, parent Node) error {
+	return &PointerError{Pointer: p, Segment: segment, Parent: parent, Err: ErrPointerNotFound}
+}
+
+func (e *PointerError) Error() string {
+	if e.Parent == nil {
+		return fmt.Sprintf("json pointer %q: segment %q not found in empty document: %v", string(e.Pointer), e.Segment, e.Err)
+	}
+	return fmt.Sprintf("json pointer %q: segment %q not found in node at %s: %v",
+		string(e.Pointer), e.Segment, e.Parent.Position(), e.Err)
+}
+
+func (e *PointerError) Unwrap() error {
+	return e.Err
+}
+
+// documentRoot returns the root of n if n is a DocumentNode, or n itself
+func documentRoot(n Node) Node {
+	if document, ok := n.(*DocumentNode); ok {
+		return document.root
+	}
+	return n
+}
+
+// resolveAlias follows n to the node it refers to, if n is an alias
+func resolveAlias(n Node) Node {
+	for {
+		alias, ok := n.(*AliasNode)
+		if !ok || alias.target == nil {
+			return n
+		}
+		n = alias.target
+	}
+}
+
+// childAt returns the mapping value or sequence item of n that segment refers to
+func childAt(n Node, segment string) (Node, bool) {
+	switch collection := resolveAlias(n).(type) {
+	case *MappingNode:
+		if i := collection.indexOf(segment); i != -1 {
+			return collection.pairs[i].Value, true
+		}
+	case *SequenceNode:
+		if i, ok := parseIndex(segment); ok && i < len(collection.items) {
+			return collection.items[i], true
+		}
+	}
+	return nil, false
+}
+
+// indexOf returns the index of the first pair whose key is a scalar holding key, or -1
+func (n *MappingNode) indexOf(key string) int {
+	for i, pair := range n.pairs {
+		if scalar, ok := pair.Key.(*ScalarNode); ok && scalar.value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// parseIndex parses a JSON Pointer array index, which is a decimal number without leading zeros
+func parseIndex(segment string) (int, bool) {
+	if segment == "" || (len(segment) > 1 && segment[0] == '0') {
+		return 0, false
+	}
+	for i := 0; i < len(segment); i++ {
+		if segment[i] < '0' || segment[i] > '9' {
+			return 0, false
+		}
+	}
+	i, err := strconv.Atoi(segment)
+	return i, err == nil
+}
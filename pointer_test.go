@@ -0,0 +1,159 @@
+package yaml_test
+
+import (
+	"errors"
+	"github.com/ercross/yaml"
+	"slices"
+	"testing"
+)
+
+const pointerSource = `spec:
+  replicas: 3
+  a/b: slash
+  m~n: tilde
+  containers:
+    - name: web
+    - name: sidecar
+`
+
+func scalarValue(t *testing.T, n yaml.Node) string {
+	t.Helper()
+	scalar, ok := n.(*yaml.ScalarNode)
+	if !ok {
+		t.Fatalf("expected scalar node, got %T", n)
+	}
+	return scalar.Value()
+}
+
+func TestPointer_Segments(t *testing.T) {
+	segments, err := yaml.Pointer("/a~1b/m~0n/~01").Segments()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"a/b", "m~n", "~1"}; !slices.Equal(expected, segments) {
+		t.Errorf("expected %v, got %v", expected, segments)
+	}
+
+	if p := yaml.NewPointer("a/b", "m~n", "~1"); p != "/a~1b/m~0n/~01" {
+		t.Errorf("unexpected escaped pointer %s", p)
+	}
+
+	for _, invalid := range []yaml.Pointer{"spec", "/a~2", "/a~"} {
+		if _, err = invalid.Segments(); !errors.Is(err, yaml.ErrInvalidPointer) {
+			t.Errorf("expected %q to be invalid, got %v", invalid, err)
+		}
+	}
+}
+
+func TestPointer_Get(t *testing.T) {
+	document := parseDocument(t, pointerSource)
+
+	tests := map[yaml.Pointer]string{
+		"/spec/replicas":          "3",
+		"/spec/a~1b":              "slash",
+		"/spec/m~0n":              "tilde",
+		"/spec/containers/1/name": "sidecar",
+	}
+	for pointer, expected := range tests {
+		n, err := pointer.Get(document)
+		if err != nil {
+			t.Errorf("%s: %v", pointer, err)
+			continue
+		}
+		if actual := scalarValue(t, n); actual != expected {
+			t.Errorf("%s: expected %s, got %s", pointer, expected, actual)
+		}
+	}
+
+	root, err := yaml.Pointer("").Get(document)
+	if err != nil || root != document.Root() {
+		t.Errorf("expected the empty pointer to refer to the document root, got %v", err)
+	}
+}
+
+func TestPointer_GetErrors(t *testing.T) {
+	document := parseDocument(t, pointerSource)
+
+	for _, pointer := range []yaml.Pointer{"/spec/missing/x", "/spec/containers/01", "/spec/containers/2", "/spec/containers/-"} {
+		_, err := pointer.Get(document)
+		var pointerErr *yaml.PointerError
+		if !errors.As(err, &pointerErr) || !errors.Is(err, yaml.ErrPointerNotFound) {
+			t.Errorf("%s: expected PointerError, got %v", pointer, err)
+		}
+	}
+
+	_, err := yaml.Pointer("/spec/containers/0/image").Get(document)
+	var pointerErr *yaml.PointerError
+	if !errors.As(err, &pointerErr) {
+		t.Fatalf("expected PointerError, got %v", err)
+	}
+	if pointerErr.Segment != "image" {
+		t.Errorf("expected missing segment image, got %s", pointerErr.Segment)
+	}
+	if line, column := pointerErr.Parent.Position().Line(), pointerErr.Parent.Position().Column(); line != 6 || column != 7 {
+		t.Errorf("expected parent at 6:7, got %d:%d", line, column)
+	}
+}
+
+func TestPointer_Set(t *testing.T) {
+	document := parseDocument(t, pointerSource)
+
+	set := func(pointer yaml.Pointer, value string, createMissing bool) {
+		t.Helper()
+		if err := pointer.Set(document, yaml.NewScalarNode(value), createMissing); err != nil {
+			t.Fatalf("%s: %v", pointer, err)
+		}
+	}
+	set("/spec/replicas", "5", false)
+	set("/spec/containers/0/image", "nginx", false)
+	set("/spec/containers/-", "debug", false)
+	set("/spec/containers/3", "extra", false)
+	set("/metadata/labels/app", "demo", true)
+
+	expected := map[yaml.Pointer]string{
+		"/spec/replicas":           "5",
+		"/spec/containers/0/image": "nginx",
+		"/spec/containers/2":       "debug",
+		"/spec/containers/3":       "extra",
+		"/metadata/labels/app":     "demo",
+	}
+	for pointer, value := range expected {
+		n, err := pointer.Get(document)
+		if err != nil {
+			t.Errorf("%s: %v", pointer, err)
+			continue
+		}
+		if actual := scalarValue(t, n); actual != value {
+			t.Errorf("%s: expected %s, got %s", pointer, value, actual)
+		}
+	}
+
+	if err := yaml.Pointer("/status/phase").Set(document, yaml.NewScalarNode("x"), false); !errors.Is(err, yaml.ErrPointerNotFound) {
+		t.Errorf("expected missing intermediate mapping to fail without createMissing, got %v", err)
+	}
+}
+
+func TestPointer_Delete(t *testing.T) {
+	document := parseDocument(t, pointerSource)
+
+	if err := yaml.Pointer("/spec/containers/0").Delete(document); err != nil {
+		t.Fatal(err)
+	}
+	if err := yaml.Pointer("/spec/replicas").Delete(document); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := yaml.Pointer("/spec/containers/0/name").Get(document)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual := scalarValue(t, n); actual != "sidecar" {
+		t.Errorf("expected sidecar to become the first container, got %s", actual)
+	}
+	if _, err = yaml.Pointer("/spec/replicas").Get(document); !errors.Is(err, yaml.ErrPointerNotFound) {
+		t.Errorf("expected replicas to be deleted, got %v", err)
+	}
+	if err = yaml.Pointer("/spec/replicas").Delete(document); !errors.Is(err, yaml.ErrPointerNotFound) {
+		t.Errorf("expected deleting a missing key to fail, got %v", err)
+	}
+}
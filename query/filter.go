@@ -0,0 +1,195 @@
+package query
+
+import (
+	"github.com/ercross/yaml"
+	"math"
+	"strconv"
+	"strings"
+)
+
+type (
+	// predicate is the condition a node must satisfy to be selected by a filterSelector
+	predicate interface {
+		test(n yaml.Node) bool
+	}
+
+	andPredicate struct {
+		left, right predicate
+	}
+
+	orPredicate struct {
+		left, right predicate
+	}
+
+	notPredicate struct {
+		operand predicate
+	}
+
+	// existsPredicate checks that path leads to a node
+	existsPredicate struct {
+		path relativePath
+	}
+
+	comparisonPredicate struct {
+		operator    string
+		left, right operand
+	}
+
+	// operand is either side of a comparisonPredicate
+	operand interface {
+		evaluate(n yaml.Node) value
+	}
+
+	// relativePath locates a node from the node being filtered (@)
+	relativePath []selector
+
+	literal value
+
+	// value is the comparable form of a scalar node or a literal
+	value struct {
+		kind   valueKind
+		text   string
+		number float64
+	}
+
+	valueKind int8
+)
+
+const (
+	// kindNothing is the value of a relativePath leading to no node
+	kindNothing valueKind = iota
+	kindString
+	kindNumber
+	kindBool
+	kindNull
+
+	// kindCollection is the value of a sequence or mapping, which can only be compared for existence
+	kindCollection
+)
+
+func (p andPredicate) test(n yaml.Node) bool {
+	return p.left.test(n) && p.right.test(n)
+}
+
+func (p orPredicate) test(n yaml.Node) bool {
+	return p.left.test(n) || p.right.test(n)
+}
+
+func (p notPredicate) test(n yaml.Node) bool {
+	return !p.operand.test(n)
+}
+
+func (p existsPredicate) test(n yaml.Node) bool {
+	return p.path.evaluate(n).kind != kindNothing
+}
+
+func (p comparisonPredicate) test(n yaml.Node) bool {
+	left, right := p.left.evaluate(n), p.right.evaluate(n)
+	switch p.operator {
+	case "==":
+		return left.equal(right)
+	case "!=":
+		return !left.equal(right)
+	}
+
+	if left.kind != right.kind {
+		return false
+	}
+	var order int
+	switch left.kind {
+	case kindNumber:
+		if left.number < right.number {
+			order = -1
+		} else if left.number > right.number {
+			order = 1
+		}
+	case kindString:
+		order = strings.Compare(left.text, right.text)
+	default:
+		return false
+	}
+
+	switch p.operator {
+	case "<":
+		return order < 0
+	case "<=":
+		return order <= 0
+	case ">":
+		return order > 0
+	default:
+		return order >= 0
+	}
+}
+
+func (p relativePath) evaluate(n yaml.Node) value {
+	m := Match{Node: n}
+	for _, s := range p {
+		matches := s.selectFrom(m, nil)
+		if len(matches) == 0 {
+			return value{kind: kindNothing}
+		}
+		m = matches[0]
+	}
+	return valueOf(m.Node)
+}
+
+func (l literal) evaluate(_ yaml.Node) value {
+	return value(l)
+}
+
+// valueOf converts n into a value, according to the tag of n
+func valueOf(n yaml.Node) value {
+	scalar, ok := resolve(n).(*yaml.ScalarNode)
+	if !ok {
+		return value{kind: kindCollection}
+	}
+
+	switch scalar.Tag() {
+	case yaml.TagInt, yaml.TagFloat:
+		if number, ok := parseNumber(scalar.Value()); ok {
+			return value{kind: kindNumber, number: number}
+		}
+	case yaml.TagBool:
+		return value{kind: kindBool, text: strings.ToLower(scalar.Value())}
+	case yaml.TagNull:
+		return value{kind: kindNull}
+	}
+	return value{kind: kindString, text: scalar.Value()}
+}
+
+// parseNumber parses the YAML 1.2 core schema representation of an integer or float
+func parseNumber(s string) (float64, bool) {
+	switch strings.TrimPrefix(strings.TrimPrefix(s, "+"), "-") {
+	case ".inf", ".Inf", ".INF":
+		if strings.HasPrefix(s, "-") {
+			return math.Inf(-1), true
+		}
+		return math.Inf(1), true
+	case ".nan", ".NaN", ".NAN":
+		return math.NaN(), true
+	}
+
+	if i, err := strconv.ParseInt(s, 0, 64); err == nil {
+		return float64(i), true
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	return f, err == nil
+}
+
+// equal checks that v and other are of the same kind and hold the same value.
+// Two missing values are equal, while collections are never equal
+func (v value) equal(other value) bool {
+	if v.kind != other.kind {
+		return false
+	}
+	switch v.kind {
+	case kindNumber:
+		return v.number == other.number
+	case kindString, kindBool:
+		return v.text == other.text
+	case kindCollection:
+		return false
+	default:
+		return true
+	}
+}
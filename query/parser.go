@@ -0,0 +1,390 @@
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// expressionParser compiles a path expression using recursive descent
+type expressionParser struct {
+	expression string
+	position   int
+}
+
+// comparisonOperators are ordered so that two-character operators are matched first
+var comparisonOperators = []string{"==", "!=", "<=", ">=", "<", ">"}
+
+func newExpressionParser(expression string) *expressionParser {
+	return &expressionParser{expression: expression}
+}
+
+func (p *expressionParser) parsePath() ([]segment, error) {
+	// a path can start with a bare name, unless it explicitly starts at the root
+	bare := true
+	if p.consume("$") {
+		bare = false
+	}
+
+	var segments []segment
+	for !p.done() {
+		s, err := p.parseSegment(bare && len(segments) == 0)
+		if err != nil {
+			return nil, err
+		}
+		segments = append(segments, s)
+	}
+	return segments, nil
+}
+
+func (p *expressionParser) parseSegment(bare bool) (segment, error) {
+	switch {
+	case p.consume(".."):
+		s, err := p.parseDescendantSelector()
+		return segment{selector: s, recursive: true}, err
+
+	case p.consume("."):
+		if p.consume("*") {
+			return segment{selector: wildcardSelector{}}, nil
+		}
+		name, err := p.parseName()
+		return segment{selector: nameSelector{name: name}}, err
+
+	case p.consume("["):
+		s, err := p.parseBracketSelector()
+		return segment{selector: s}, err
+
+	case bare:
+		if p.consume("*") {
+			return segment{selector: wildcardSelector{}}, nil
+		}
+		name, err := p.parseName()
+		return segment{selector: nameSelector{name: name}}, err
+
+	default:
+		return segment{}, p.errorf("expected . or [")
+	}
+}
+
+// parseDescendantSelector parses the selector following a recursive descent (..)
+func (p *expressionParser) parseDescendantSelector() (selector, error) {
+	switch {
+	case p.consume("*"):
+		return wildcardSelector{}, nil
+	case p.consume("["):
+		return p.parseBracketSelector()
+	default:
+		name, err := p.parseName()
+		return nameSelector{name: name}, err
+	}
+}
+
+// parseBracketSelector parses the content of a [] selector, following the opening bracket
+func (p *expressionParser) parseBracketSelector() (selector, error) {
+	p.skipSpaces()
+
+	var (
+		s   selector
+		err error
+	)
+	switch r := p.peek(); {
+	case r == '*':
+		p.position++
+		s = wildcardSelector{}
+
+	case r == '\'' || r == '"':
+		var name string
+		name, err = p.parseString()
+		s = nameSelector{name: name}
+
+	case r == '?':
+		p.position++
+		var pr predicate
+		pr, err = p.parseOr()
+		s = filterSelector{predicate: pr}
+
+	case r == '-' || r == ':' || unicode.IsDigit(r):
+		s, err = p.parseIndexOrSlice()
+
+	default:
+		return nil, p.errorf("unexpected selector")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	p.skipSpaces()
+	if !p.consume("]") {
+		return nil, p.errorf("expected ]")
+	}
+	return s, nil
+}
+
+func (p *expressionParser) parseIndexOrSlice() (selector, error) {
+	start, err := p.parseOptionalInt()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpaces()
+	if !p.consume(":") {
+		if start == nil {
+			return nil, p.errorf("expected index")
+		}
+		return indexSelector{index: *start}, nil
+	}
+
+	s := sliceSelector{start: start, step: 1}
+	if s.end, err = p.parseOptionalInt(); err != nil {
+		return nil, err
+	}
+	p.skipSpaces()
+	if p.consume(":") {
+		step, err := p.parseOptionalInt()
+		if err != nil {
+			return nil, err
+		}
+		if step != nil {
+			s.step = *step
+		}
+		if s.step == 0 {
+			return nil, p.errorf("slice step can not be 0")
+		}
+	}
+	return s, nil
+}
+
+func (p *expressionParser) parseOptionalInt() (*int, error) {
+	p.skipSpaces()
+	start := p.position
+	if p.peek() == '-' {
+		p.position++
+	}
+	for unicode.IsDigit(p.peek()) {
+		p.position++
+	}
+	if p.position == start {
+		return nil, nil
+	}
+
+	i, err := strconv.Atoi(p.expression[start:p.position])
+	if err != nil {
+		p.position = start
+		return nil, p.errorf("invalid integer")
+	}
+	return &i, nil
+}
+
+// parseName parses a name made of every character that has no meaning in path expressions
+func (p *expressionParser) parseName() (string, error) {
+	start := p.position
+	for !p.done() && isNameRune(p.peek()) {
+		_, size := utf8.DecodeRuneInString(p.expression[p.position:])
+		p.position += size
+	}
+	if p.position == start {
+		return "", p.errorf("expected name")
+	}
+	return p.expression[start:p.position], nil
+}
+
+// parseString parses a single or double-quoted string, the backslash escaping the next character
+func (p *expressionParser) parseString() (string, error) {
+	quote := p.expression[p.position]
+	start := p.position
+	p.position++
+
+	var b strings.Builder
+	for !p.done() {
+		c := p.expression[p.position]
+		p.position++
+		switch {
+		case c == quote:
+			return b.String(), nil
+		case c == '\\' && !p.done():
+			b.WriteByte(p.expression[p.position])
+			p.position++
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	p.position = start
+	return "", p.errorf("unterminated string")
+}
+
+func (p *expressionParser) parseOr() (predicate, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.skipSpaces(); p.consume("||"); p.skipSpaces() {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orPredicate{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *expressionParser) parseAnd() (predicate, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.skipSpaces(); p.consume("&&"); p.skipSpaces() {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andPredicate{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *expressionParser) parseUnary() (predicate, error) {
+	p.skipSpaces()
+	switch {
+	case p.consume("!"):
+		operand, err := p.parseUnary()
+		return notPredicate{operand: operand}, err
+
+	case p.consume("("):
+		pr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpaces()
+		if !p.consume(")") {
+			return nil, p.errorf("expected )")
+		}
+		return pr, nil
+
+	default:
+		return p.parseComparison()
+	}
+}
+
+func (p *expressionParser) parseComparison() (predicate, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	p.skipSpaces()
+	for _, operator := range comparisonOperators {
+		if !p.consume(operator) {
+			continue
+		}
+		right, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		return comparisonPredicate{operator: operator, left: left, right: right}, nil
+	}
+
+	path, ok := left.(relativePath)
+	if !ok {
+		return nil, p.errorf("expected comparison operator")
+	}
+	return existsPredicate{path: path}, nil
+}
+
+func (p *expressionParser) parseOperand() (operand, error) {
+	p.skipSpaces()
+	switch r := p.peek(); {
+	case r == '@':
+		p.position++
+		return p.parseRelativePath()
+
+	case r == '\'' || r == '"':
+		s, err := p.parseString()
+		return literal{kind: kindString, text: s}, err
+
+	case r == '-' || r == '+' || r == '.' || unicode.IsDigit(r):
+		start := p.position
+		for !p.done() && strings.ContainsRune("+-.eE0123456789", p.peek()) {
+			p.position++
+		}
+		number, err := strconv.ParseFloat(p.expression[start:p.position], 64)
+		if err != nil {
+			p.position = start
+			return nil, p.errorf("invalid number")
+		}
+		return literal{kind: kindNumber, number: number}, nil
+	}
+
+	for _, l := range []literal{{kind: kindBool, text: "true"}, {kind: kindBool, text: "false"}, {kind: kindNull, text: "null"}} {
+		if p.consume(l.text) {
+			return l, nil
+		}
+	}
+	return nil, p.errorf("expected @ or literal")
+}
+
+// parseRelativePath parses the name and index selectors following @
+func (p *expressionParser) parseRelativePath() (relativePath, error) {
+	path := relativePath{}
+	for {
+		switch {
+		case p.consume("."):
+			name, err := p.parseName()
+			if err != nil {
+				return nil, err
+			}
+			path = append(path, nameSelector{name: name})
+
+		case p.consume("["):
+			s, err := p.parseBracketSelector()
+			if err != nil {
+				return nil, err
+			}
+			switch s.(type) {
+			case nameSelector, indexSelector:
+			default:
+				return nil, p.errorf("only names and indexes are allowed in relative paths")
+			}
+			path = append(path, s)
+
+		default:
+			return path, nil
+		}
+	}
+}
+
+func (p *expressionParser) done() bool {
+	return p.position >= len(p.expression)
+}
+
+func (p *expressionParser) peek() rune {
+	if p.done() {
+		return utf8.RuneError
+	}
+	r, _ := utf8.DecodeRuneInString(p.expression[p.position:])
+	return r
+}
+
+// consume moves past prefix if the remaining expression starts with it
+func (p *expressionParser) consume(prefix string) bool {
+	if !strings.HasPrefix(p.expression[p.position:], prefix) {
+		return false
+	}
+	p.position += len(prefix)
+	return true
+}
+
+func (p *expressionParser) skipSpaces() {
+	for !p.done() && (p.expression[p.position] == ' ' || p.expression[p.position] == '\t') {
+		p.position++
+	}
+}
+
+func (p *expressionParser) errorf(message string) error {
+	return fmt.Errorf("%s at offset %d of %q: %w", message, p.position, p.expression, ErrSyntax)
+}
+
+func isNameRune(r rune) bool {
+	return !unicode.IsSpace(r) && !strings.ContainsRune(".[]()'\"=!<>&|,@$*", r)
+}
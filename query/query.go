@@ -0,0 +1,99 @@
+// Package query evaluates JSONPath-like path expressions against yaml.Node trees.
+//
+// A path expression is made of the following segments, optionally preceded by $, which stands for the root node:
+//
+//	.name or name        value of the mapping key name
+//	["name"] or ['name'] value of a mapping key that is not a plain identifier, e.g., ["app.kubernetes.io/name"]
+//	[2] or [-1]          sequence item, counted from the end if negative
+//	.* or [*]            every mapping value or sequence item
+//	[start:end:step]     sequence items within a slice, any bound can be omitted
+//	[?(predicate)]       mapping values or sequence items matching predicate, e.g., [?(@.name == "web")]
+//	..segment            segment applied to every descendant of a node, and the node itself, e.g., ..image or ..[0]
+//
+// Predicates compare nodes relative to the filtered item (@.port, @["a.b"], @[0], or @ itself)
+// with literals (strings, numbers, true, false and null) using ==, !=, <, <=, > and >=.
+// Predicates can be combined with &&, || and !, and a relative path alone checks that the node exists.
+//
+// Aliases are resolved when a segment selects the children of a node, but never followed by recursive descent
+package query
+
+import (
+	"errors"
+	"github.com/ercross/yaml"
+	"github.com/ercross/yaml/token"
+)
+
+// ErrSyntax is returned when a path expression can not be compiled
+var ErrSyntax = errors.New("invalid path expression")
+
+// Query is a compiled path expression. A Query is immutable and can be evaluated concurrently
+type Query struct {
+	expression string
+	segments   []segment
+}
+
+// Match is a node selected by a Query
+type Match struct {
+
+	// Path locates Node from the node the Query was evaluated against
+	Path yaml.Path
+	Node yaml.Node
+}
+
+// Compile parses a path expression into a Query
+func Compile(expression string) (*Query, error) {
+	segments, err := newExpressionParser(expression).parsePath()
+	if err != nil {
+		return nil, err
+	}
+	return &Query{expression: expression, segments: segments}, nil
+}
+
+// MustCompile is like Compile but panics if expression can not be compiled
+func MustCompile(expression string) *Query {
+	q, err := Compile(expression)
+	if err != nil {
+		panic(err)
+	}
+	return q
+}
+
+// Find compiles expression and evaluates it against n
+func Find(n yaml.Node, expression string) ([]Match, error) {
+	q, err := Compile(expression)
+	if err != nil {
+		return nil, err
+	}
+	return q.Evaluate(n), nil
+}
+
+// String returns the expression q was compiled from
+func (q *Query) String() string {
+	return q.expression
+}
+
+// Evaluate returns the nodes selected by q in document order.
+// If n is a yaml.DocumentNode, q is evaluated against its root
+func (q *Query) Evaluate(n yaml.Node) []Match {
+	if document, ok := n.(*yaml.DocumentNode); ok {
+		n = document.Root()
+	}
+	if n == nil {
+		return nil
+	}
+
+	matches := []Match{{Node: n}}
+	for _, s := range q.segments {
+		var next []Match
+		for _, m := range matches {
+			next = s.apply(m, next)
+		}
+		matches = next
+	}
+	return matches
+}
+
+// Position is the location of the matched node in its source
+func (m Match) Position() token.Location {
+	return m.Node.Position()
+}
@@ -0,0 +1,128 @@
+package query
+
+import (
+	"errors"
+	"github.com/ercross/yaml"
+	"github.com/ercross/yaml/parser"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const deployment = `spec:
+  replicas: 3
+  template:
+    spec:
+      containers:
+        - name: web
+          image: nginx:1.25
+          port: 80
+        - name: sidecar
+          image: envoy:1.30
+          port: 9901
+        - name: debug
+          image: busybox
+  "app.kubernetes.io/name": demo
+defaults: &defaults
+  image: base
+copy: *defaults
+`
+
+func parseDocument(t *testing.T, source string) *yaml.DocumentNode {
+	t.Helper()
+	ast, err := parser.Parse(strings.NewReader(source))
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+	return ast.Documents()[0]
+}
+
+// values renders the scalar values of matches, or the type of non-scalar matches
+func values(matches []Match) []string {
+	var result []string
+	for _, m := range matches {
+		if scalar, ok := m.Node.(*yaml.ScalarNode); ok {
+			result = append(result, scalar.Value())
+		} else {
+			result = append(result, "<collection>")
+		}
+	}
+	return result
+}
+
+func TestQuery_Evaluate(t *testing.T) {
+	document := parseDocument(t, deployment)
+
+	tests := []struct {
+		expression string
+		expected   []string
+	}{
+		{"spec.replicas", []string{"3"}},
+		{"$.spec.replicas", []string{"3"}},
+		{"spec.template.spec.containers[*].image", []string{"nginx:1.25", "envoy:1.30", "busybox"}},
+		{"spec.template.spec.containers[1].name", []string{"sidecar"}},
+		{"spec.template.spec.containers[-1].name", []string{"debug"}},
+		{"spec.template.spec.containers[5].name", nil},
+		{"spec.template.spec.containers[0:2].name", []string{"web", "sidecar"}},
+		{"spec.template.spec.containers[::-1].name", []string{"debug", "sidecar", "web"}},
+		{"spec.template.spec.containers[1:].name", []string{"sidecar", "debug"}},
+		{`spec["app.kubernetes.io/name"]`, []string{"demo"}},
+		{"$..image", []string{"nginx:1.25", "envoy:1.30", "busybox", "base"}},
+		{"$..containers[0].port", []string{"80"}},
+		{`spec.template.spec.containers[?(@.name == "web")].image`, []string{"nginx:1.25"}},
+		{`spec.template.spec.containers[?(@.name != 'web')].name`, []string{"sidecar", "debug"}},
+		{`spec.template.spec.containers[?(@.port > 100)].name`, []string{"sidecar"}},
+		{`spec.template.spec.containers[?(@.port)].name`, []string{"web", "sidecar"}},
+		{`spec.template.spec.containers[?(!@.port)].name`, []string{"debug"}},
+		{`spec.template.spec.containers[?(@.port >= 80 && @.name == "web" || @.name == "debug")].name`, []string{"web", "debug"}},
+		{"copy.image", []string{"base"}},
+		{"spec.*", []string{"3", "<collection>", "demo"}},
+		{"$", []string{"<collection>"}},
+		{"missing.key", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			matches, err := Find(document, tt.expression)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if actual := values(matches); !slices.Equal(tt.expected, actual) {
+				t.Errorf("expected %v, got %v", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestQuery_MatchPathAndPosition(t *testing.T) {
+	document := parseDocument(t, deployment)
+
+	matches := MustCompile(`spec.template.spec.containers[?(@.name == "sidecar")].image`).Evaluate(document)
+	if len(matches) != 1 {
+		t.Fatalf("expected 1 match, got %d", len(matches))
+	}
+	if path := matches[0].Path.String(); path != "$.spec.template.spec.containers[1].image" {
+		t.Errorf("unexpected path %s", path)
+	}
+	if line, column := matches[0].Position().Line(), matches[0].Position().Column(); line != 10 || column != 18 {
+		t.Errorf("expected match at 10:18, got %d:%d", line, column)
+	}
+}
+
+func TestCompile_Errors(t *testing.T) {
+	for _, expression := range []string{
+		"spec.",
+		"spec[",
+		"spec[0",
+		"spec['name]",
+		"spec[::0]",
+		"spec[?(@.name ==)]",
+		"spec[?(@.name == 'a']",
+		"spec[?('a')]",
+		"$spec",
+	} {
+		if _, err := Compile(expression); !errors.Is(err, ErrSyntax) {
+			t.Errorf("expected syntax error compiling %q, got %v", expression, err)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package query
+
+import (
+	"github.com/ercross/yaml"
+)
+
+type (
+	// segment is a single step of a Query, selecting nodes from each node matched by the previous step
+	segment struct {
+		selector selector
+
+		// recursive applies selector to every descendant of a node, and to the node itself
+		recursive bool
+	}
+
+	selector interface {
+
+		// selectFrom appends the nodes selected from m to matches
+		selectFrom(m Match, matches []Match) []Match
+	}
+
+	nameSelector struct {
+		name string
+	}
+
+	indexSelector struct {
+		index int
+	}
+
+	wildcardSelector struct{}
+
+	// sliceSelector selects sequence items like a Python slice, nil bounds default to the ends of the sequence
+	sliceSelector struct {
+		start *int
+		end   *int
+		step  int
+	}
+
+	filterSelector struct {
+		predicate predicate
+	}
+)
+
+func (s segment) apply(m Match, matches []Match) []Match {
+	if !s.recursive {
+		return s.selector.selectFrom(m, matches)
+	}
+
+	for path, n := range yaml.All(m.Node) {
+		// aliases are not descended into, as they may refer to an ancestor
+		if (len(path) > 0 && path[len(path)-1].IsKey) || n.Type() == yaml.NodeTypeAlias {
+			continue
+		}
+		matches = s.selector.selectFrom(Match{Path: join(m.Path, path), Node: n}, matches)
+	}
+	return matches
+}
+
+func (s nameSelector) selectFrom(m Match, matches []Match) []Match {
+	mapping, ok := resolve(m.Node).(*yaml.MappingNode)
+	if !ok {
+		return matches
+	}
+	for i, pair := range mapping.Pairs() {
+		if key, ok := pair.Key.(*yaml.ScalarNode); ok && key.Value() == s.name {
+			matches = append(matches, Match{Path: extend(m.Path, yaml.PathSegment{Key: key, Index: i}), Node: pair.Value})
+		}
+	}
+	return matches
+}
+
+func (s indexSelector) selectFrom(m Match, matches []Match) []Match {
+	sequence, ok := resolve(m.Node).(*yaml.SequenceNode)
+	if !ok {
+		return matches
+	}
+	index := s.index
+	if index < 0 {
+		index += sequence.Len()
+	}
+	if index < 0 || index >= sequence.Len() {
+		return matches
+	}
+	return append(matches, Match{Path: extend(m.Path, yaml.PathSegment{Index: index}), Node: sequence.Items()[index]})
+}
+
+func (s wildcardSelector) selectFrom(m Match, matches []Match) []Match {
+	return append(matches, children(m)...)
+}
+
+func (s sliceSelector) selectFrom(m Match, matches []Match) []Match {
+	sequence, ok := resolve(m.Node).(*yaml.SequenceNode)
+	if !ok {
+		return matches
+	}
+
+	length := sequence.Len()
+	bound := func(b *int, fallback int) int {
+		if b == nil {
+			return fallback
+		}
+		i := *b
+		if i < 0 {
+			i += length
+		}
+		if s.step > 0 {
+			return min(max(i, 0), length)
+		}
+		return min(max(i, -1), length-1)
+	}
+
+	if s.step > 0 {
+		for i := bound(s.start, 0); i < bound(s.end, length); i += s.step {
+			matches = append(matches, Match{Path: extend(m.Path, yaml.PathSegment{Index: i}), Node: sequence.Items()[i]})
+		}
+		return matches
+	}
+	for i := bound(s.start, length-1); i > bound(s.end, -1); i += s.step {
+		matches = append(matches, Match{Path: extend(m.Path, yaml.PathSegment{Index: i}), Node: sequence.Items()[i]})
+	}
+	return matches
+}
+
+func (s filterSelector) selectFrom(m Match, matches []Match) []Match {
+	for _, child := range children(m) {
+		if s.predicate.test(child.Node) {
+			matches = append(matches, child)
+		}
+	}
+	return matches
+}
+
+// children returns the mapping values or sequence items of the node matched by m
+func children(m Match) []Match {
+	var matches []Match
+	switch n := resolve(m.Node).(type) {
+	case *yaml.MappingNode:
+		for i, pair := range n.Pairs() {
+			matches = append(matches, Match{Path: extend(m.Path, yaml.PathSegment{Key: pair.Key, Index: i}), Node: pair.Value})
+		}
+	case *yaml.SequenceNode:
+		for i, item := range n.Items() {
+			matches = append(matches, Match{Path: extend(m.Path, yaml.PathSegment{Index: i}), Node: item})
+		}
+	}
+	return matches
+}
+
+// resolve follows n to the node it refers to, if n is an alias
+func resolve(n yaml.Node) yaml.Node {
+	for {
+		alias, ok := n.(*yaml.AliasNode)
+		if !ok || alias.Target() == nil {
+			return n
+		}
+		n = alias.Target()
+	}
+}
+
+// extend returns a new yaml.Path made of path followed by s, leaving path untouched
+func extend(path yaml.Path, s yaml.PathSegment) yaml.Path {
+	return append(path[:len(path):len(path)], s)
+}
+
+// join returns a new yaml.Path made of path followed by relative
+func join(path yaml.Path, relative yaml.Path) yaml.Path {
+	if len(relative) == 0 {
+		return path
+	}
+	return append(path[:len(path):len(path)], relative...)
+}
@@ -0,0 +1,467 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/ercross/yaml"
+	"github.com/ercross/yaml/parser"
+	"io/fs"
+	"maps"
+	"math/big"
+	"net/url"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+type (
+	// schema is a compiled schema object or boolean schema
+	schema struct {
+		// location is the schema file followed by the JSON pointer to the schema within it, e.g., service.json#/$defs/port
+		location string
+
+		// always is the result of a boolean schema, which has no keyword
+		always *bool
+
+		ref   *schema
+		types []string
+
+		enum     []any
+		constant any
+		hasConst bool
+
+		multipleOf, minimum, maximum, exclusiveMinimum, exclusiveMaximum *big.Rat
+
+		minLength, maxLength *int
+		pattern              *regexp.Regexp
+		format               string
+
+		prefixItems              []*schema
+		items, contains          *schema
+		minContains, maxContains *int
+		minItems, maxItems       *int
+		uniqueItems              bool
+
+		properties                            map[string]*schema
+		patternProperties                     []patternSchema
+		additionalProperties, propertyNames   *schema
+		required                              []string
+		dependentRequired                     map[string][]string
+		dependentSchemas                      map[string]*schema
+		minProperties, maxProperties          *int
+		allOf, anyOf, oneOf                   []*schema
+		not, ifSchema, thenSchema, elseSchema *schema
+	}
+
+	// patternSchema is the schema of the properties matching a pattern of patternProperties
+	patternSchema struct {
+		pattern *regexp.Regexp
+		schema  *schema
+	}
+
+	// compiler compiles the schemas of the files of a file system, each schema once, so that references can be recursive
+	compiler struct {
+		fsys fs.FS
+
+		// documents are the decoded schema files by name
+		documents map[string]any
+
+		// compiled are the schemas compiled so far by location
+		compiled map[string]*schema
+	}
+
+	// keywords reads the keywords of a schema object
+	keywords struct {
+		compiler *compiler
+		file     string
+		pointer  string
+		values   map[string]any
+		err      error
+	}
+)
+
+// typeNames are the names of the JSON types the type keyword accepts
+var typeNames = []string{"null", "boolean", "integer", "number", "string", "array", "object"}
+
+func newCompiler(fsys fs.FS) *compiler {
+	return &compiler{fsys: fsys, documents: make(map[string]any), compiled: make(map[string]*schema)}
+}
+
+// compileFile compiles the schema at the root of the file name
+func (c *compiler) compileFile(name string) (*schema, error) {
+	return c.compile(path.Clean(name), "")
+}
+
+// compile compiles the schema found at pointer within file
+func (c *compiler) compile(file, pointer string) (*schema, error) {
+	location := file + "#" + pointer
+	if s, ok := c.compiled[location]; ok {
+		return s, nil
+	}
+
+	document, err := c.load(file)
+	if err != nil {
+		return nil, err
+	}
+	value, ok := resolvePointer(document, pointer)
+	if !ok {
+		return nil, fmt.Errorf("%s: no schema at this location: %w", location, ErrInvalidSchema)
+	}
+
+	s := &schema{location: location}
+	c.compiled[location] = s
+	switch value := value.(type) {
+	case bool:
+		s.always = &value
+		return s, nil
+	case map[string]any:
+		k := &keywords{compiler: c, file: file, pointer: pointer, values: value}
+		k.read(s)
+		return s, k.err
+	}
+	return nil, fmt.Errorf("%s: a schema must be an object or a boolean: %w", location, ErrInvalidSchema)
+}
+
+// load reads and decodes the schema file name, as JSON, or as YAML if it is not valid JSON
+func (c *compiler) load(name string) (any, error) {
+	if document, ok := c.documents[name]; ok {
+		return document, nil
+	}
+	data, err := fs.ReadFile(c.fsys, name)
+	if err != nil {
+		return nil, fmt.Errorf("can not read schema: %w", err)
+	}
+
+	var document any
+	if json.Valid(data) {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		err = decoder.Decode(&document)
+	} else {
+		document, err = decodeYAML(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: %w", name, ErrInvalidSchema, err)
+	}
+	c.documents[name] = document
+	return document, nil
+}
+
+// resolve compiles the schema a reference found in file refers to
+func (c *compiler) resolve(file, ref string) (*schema, error) {
+	refFile, fragment, _ := strings.Cut(ref, "#")
+	if strings.Contains(refFile, "://") || strings.HasPrefix(refFile, "/") {
+		return nil, fmt.Errorf("reference %q is not relative to the local file %s: %w", ref, file, ErrInvalidSchema)
+	}
+	if refFile == "" {
+		refFile = file
+	} else {
+		refFile = path.Join(path.Dir(file), refFile)
+	}
+	fragment, err := url.PathUnescape(fragment)
+	if err != nil {
+		return nil, fmt.Errorf("reference %q: %w: %w", ref, ErrInvalidSchema, err)
+	}
+
+	if fragment == "" || strings.HasPrefix(fragment, "/") {
+		return c.compile(refFile, fragment)
+	}
+	document, err := c.load(refFile)
+	if err != nil {
+		return nil, err
+	}
+	pointer, ok := findAnchor(document, "", fragment)
+	if !ok {
+		return nil, fmt.Errorf("reference %q: no $anchor %q in %s: %w", ref, fragment, refFile, ErrInvalidSchema)
+	}
+	return c.compile(refFile, pointer)
+}
+
+// read reads every supported keyword into s, keeping the first error
+func (k *keywords) read(s *schema) {
+	if ref, ok := k.values["$ref"]; ok {
+		text, isString := ref.(string)
+		if !isString {
+			k.fail("$ref", "must be a string")
+		} else if s.ref, k.err = k.compiler.resolve(k.file, text); k.err != nil {
+			return
+		}
+	}
+
+	switch types := k.values["type"].(type) {
+	case nil:
+	case string:
+		s.types = []string{types}
+	case []any:
+		for _, t := range types {
+			name, _ := t.(string)
+			s.types = append(s.types, name)
+		}
+	default:
+		k.fail("type", "must be a string or an array")
+	}
+	for _, t := range s.types {
+		if !slices.Contains(typeNames, t) {
+			k.fail("type", fmt.Sprintf("unknown type %q", t))
+		}
+	}
+
+	if enum, ok := k.values["enum"]; ok {
+		if s.enum, ok = enum.([]any); !ok {
+			k.fail("enum", "must be an array")
+		}
+	}
+	s.constant, s.hasConst = k.values["const"]
+
+	s.multipleOf = k.number("multipleOf")
+	if s.multipleOf != nil && s.multipleOf.Sign() <= 0 {
+		k.fail("multipleOf", "must be greater than 0")
+	}
+	s.minimum = k.number("minimum")
+	s.maximum = k.number("maximum")
+	s.exclusiveMinimum = k.number("exclusiveMinimum")
+	s.exclusiveMaximum = k.number("exclusiveMaximum")
+
+	s.minLength = k.count("minLength")
+	s.maxLength = k.count("maxLength")
+	if pattern, ok := k.values["pattern"].(string); ok {
+		s.pattern = k.regexp("pattern", pattern)
+	}
+	s.format, _ = k.values["format"].(string)
+
+	s.prefixItems = k.schemas("prefixItems")
+	s.items = k.schema("items")
+	s.contains = k.schema("contains")
+	s.minContains = k.count("minContains")
+	s.maxContains = k.count("maxContains")
+	s.minItems = k.count("minItems")
+	s.maxItems = k.count("maxItems")
+	s.uniqueItems, _ = k.values["uniqueItems"].(bool)
+
+	s.properties = k.schemaMap("properties")
+	patterns := k.schemaMap("patternProperties")
+	for _, pattern := range slices.Sorted(maps.Keys(patterns)) {
+		s.patternProperties = append(s.patternProperties, patternSchema{
+			pattern: k.regexp("patternProperties", pattern),
+			schema:  patterns[pattern],
+		})
+	}
+	s.additionalProperties = k.schema("additionalProperties")
+	s.propertyNames = k.schema("propertyNames")
+	s.required = k.strings("required", k.values["required"])
+	if dependencies, ok := k.values["dependentRequired"].(map[string]any); ok {
+		s.dependentRequired = make(map[string][]string, len(dependencies))
+		for name, required := range dependencies {
+			s.dependentRequired[name] = k.strings("dependentRequired", required)
+		}
+	}
+	s.dependentSchemas = k.schemaMap("dependentSchemas")
+	s.minProperties = k.count("minProperties")
+	s.maxProperties = k.count("maxProperties")
+
+	s.allOf = k.schemas("allOf")
+	s.anyOf = k.schemas("anyOf")
+	s.oneOf = k.schemas("oneOf")
+	s.not = k.schema("not")
+	s.ifSchema = k.schema("if")
+	s.thenSchema = k.schema("then")
+	s.elseSchema = k.schema("else")
+
+	// $defs are compiled even if no reference uses them, so that an invalid definition is reported
+	k.schemaMap("$defs")
+}
+
+// schema compiles the subschema of keyword
+func (k *keywords) schema(keyword string) *schema {
+	if _, ok := k.values[keyword]; !ok || k.err != nil {
+		return nil
+	}
+	s, err := k.compiler.compile(k.file, k.pointer+"/"+escapePointer(keyword))
+	if err != nil {
+		k.err = err
+	}
+	return s
+}
+
+// schemas compiles the array of subschemas of keyword
+func (k *keywords) schemas(keyword string) []*schema {
+	value, ok := k.values[keyword]
+	if !ok || k.err != nil {
+		return nil
+	}
+	items, ok := value.([]any)
+	if !ok || len(items) == 0 {
+		k.fail(keyword, "must be a non-empty array")
+		return nil
+	}
+
+	schemas := make([]*schema, len(items))
+	for i := range items {
+		if schemas[i], k.err = k.compiler.compile(k.file, k.pointer+"/"+keyword+"/"+strconv.Itoa(i)); k.err != nil {
+			return nil
+		}
+	}
+	return schemas
+}
+
+// schemaMap compiles the subschemas of keyword by name
+func (k *keywords) schemaMap(keyword string) map[string]*schema {
+	value, ok := k.values[keyword]
+	if !ok || k.err != nil {
+		return nil
+	}
+	members, ok := value.(map[string]any)
+	if !ok {
+		k.fail(keyword, "must be an object")
+		return nil
+	}
+
+	schemas := make(map[string]*schema, len(members))
+	for name := range members {
+		pointer := k.pointer + "/" + escapePointer(keyword) + "/" + escapePointer(name)
+		if schemas[name], k.err = k.compiler.compile(k.file, pointer); k.err != nil {
+			return nil
+		}
+	}
+	return schemas
+}
+
+// number reads the number of keyword
+func (k *keywords) number(keyword string) *big.Rat {
+	value, ok := k.values[keyword]
+	if !ok {
+		return nil
+	}
+	r, ok := ratOf(value)
+	if !ok {
+		k.fail(keyword, "must be a number")
+	}
+	return r
+}
+
+// count reads the non-negative integer of keyword
+func (k *keywords) count(keyword string) *int {
+	value, ok := k.values[keyword]
+	if !ok {
+		return nil
+	}
+	r, ok := ratOf(value)
+	if !ok || !r.IsInt() || r.Sign() < 0 || !r.Num().IsInt64() {
+		k.fail(keyword, "must be a non-negative integer")
+		return nil
+	}
+	count := int(r.Num().Int64())
+	return &count
+}
+
+// strings reads an array of strings, e.g., the value of required
+func (k *keywords) strings(keyword string, value any) []string {
+	if value == nil {
+		return nil
+	}
+	items, ok := value.([]any)
+	names := make([]string, len(items))
+	for i, item := range items {
+		if names[i], ok = item.(string); !ok {
+			break
+		}
+	}
+	if !ok {
+		k.fail(keyword, "must be an array of strings")
+	}
+	return names
+}
+
+func (k *keywords) regexp(keyword, pattern string) *regexp.Regexp {
+	compiled, err := regexp.Compile(pattern)
+	if err != nil {
+		k.fail(keyword, err.Error())
+	}
+	return compiled
+}
+
+// fail records the first error found reading the keywords
+func (k *keywords) fail(keyword, message string) {
+	if k.err == nil {
+		k.err = fmt.Errorf("%s#%s/%s: %s: %w", k.file, k.pointer, keyword, message, ErrInvalidSchema)
+	}
+}
+
+// decodeYAML decodes a schema written in YAML
+func decodeYAML(data []byte) (any, error) {
+	ast, err := parser.Parse(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	documents := ast.Documents()
+	if len(documents) != 1 {
+		return nil, fmt.Errorf("expected a single document, got %d", len(documents))
+	}
+	var document any
+	err = yaml.Decode(documents[0], &document)
+	return document, err
+}
+
+// resolvePointer returns the value pointer leads to within document
+func resolvePointer(document any, pointer string) (any, bool) {
+	if pointer == "" {
+		return document, true
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, false
+	}
+
+	value := document
+	for _, reference := range strings.Split(pointer[1:], "/") {
+		reference = strings.NewReplacer("~1", "/", "~0", "~").Replace(reference)
+		switch v := value.(type) {
+		case map[string]any:
+			var ok bool
+			if value, ok = v[reference]; !ok {
+				return nil, false
+			}
+		case []any:
+			i, err := strconv.Atoi(reference)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, false
+			}
+			value = v[i]
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// findAnchor returns the pointer to the schema defining the $anchor name within value, found at pointer
+func findAnchor(value any, pointer, name string) (string, bool) {
+	switch v := value.(type) {
+	case map[string]any:
+		if v["$anchor"] == name {
+			return pointer, true
+		}
+		for _, key := range slices.Sorted(maps.Keys(v)) {
+			if key == "enum" || key == "const" {
+				continue
+			}
+			if found, ok := findAnchor(v[key], pointer+"/"+escapePointer(key), name); ok {
+				return found, true
+			}
+		}
+	case []any:
+		for i, item := range v {
+			if found, ok := findAnchor(item, pointer+"/"+strconv.Itoa(i), name); ok {
+				return found, true
+			}
+		}
+	}
+	return "", false
+}
+
+// escapePointer escapes a reference token of a JSON pointer
+func escapePointer(reference string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(reference)
+}
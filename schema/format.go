@@ -0,0 +1,115 @@
+package schema
+
+import (
+	"net/mail"
+	"net/netip"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	// formats check that a string is valid in the format a format keyword asserts
+	formats = map[string]func(value string) bool{
+		"date-time":     isDateTime,
+		"date":          isDate,
+		"time":          isTime,
+		"duration":      isDuration,
+		"email":         isEmail,
+		"hostname":      isHostname,
+		"ipv4":          isIPv4,
+		"ipv6":          isIPv6,
+		"uri":           isURI,
+		"uri-reference": isURIReference,
+		"uuid":          uuidPattern.MatchString,
+		"regex":         isRegex,
+		"json-pointer":  isJSONPointer,
+	}
+
+	hostnameLabelPattern = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?$`)
+	uuidPattern          = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	durationPattern      = regexp.MustCompile(`^P([0-9]+Y)?([0-9]+M)?([0-9]+W)?([0-9]+D)?(T([0-9]+H)?([0-9]+M)?([0-9]+S)?)?$`)
+)
+
+// isDateTime checks an RFC 3339 date-time, e.g., 2024-05-01T10:00:00Z
+func isDateTime(value string) bool {
+	_, err := time.Parse(time.RFC3339Nano, strings.ToUpper(value))
+	return err == nil
+}
+
+// isDate checks an RFC 3339 full-date, e.g., 2024-05-01
+func isDate(value string) bool {
+	_, err := time.Parse(time.DateOnly, value)
+	return err == nil
+}
+
+// isTime checks an RFC 3339 full-time, e.g., 10:00:00+02:00
+func isTime(value string) bool {
+	_, err := time.Parse("15:04:05.999999999Z07:00", strings.ToUpper(value))
+	return err == nil
+}
+
+// isDuration checks an ISO 8601 duration, e.g., P1DT12H
+func isDuration(value string) bool {
+	return durationPattern.MatchString(value) && value != "P" && !strings.HasSuffix(value, "T")
+}
+
+// isEmail checks a bare email address, without a display name
+func isEmail(value string) bool {
+	address, err := mail.ParseAddress(value)
+	return err == nil && address.Address == value
+}
+
+// isHostname checks an RFC 1123 host name
+func isHostname(value string) bool {
+	if value == "" || len(value) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(strings.TrimSuffix(value, "."), ".") {
+		if !hostnameLabelPattern.MatchString(label) {
+			return false
+		}
+	}
+	return true
+}
+
+func isIPv4(value string) bool {
+	address, err := netip.ParseAddr(value)
+	return err == nil && address.Is4()
+}
+
+func isIPv6(value string) bool {
+	address, err := netip.ParseAddr(value)
+	return err == nil && address.Is6() && address.Zone() == ""
+}
+
+// isURI checks an absolute URI, which has a scheme
+func isURI(value string) bool {
+	u, err := url.Parse(value)
+	return err == nil && u.IsAbs()
+}
+
+func isURIReference(value string) bool {
+	_, err := url.Parse(value)
+	return err == nil
+}
+
+func isRegex(value string) bool {
+	_, err := regexp.Compile(value)
+	return err == nil
+}
+
+// isJSONPointer checks an RFC 6901 JSON pointer, where ~ only escapes ~ (~0) and / (~1)
+func isJSONPointer(value string) bool {
+	if value != "" && !strings.HasPrefix(value, "/") {
+		return false
+	}
+	for i := strings.Index(value, "~"); i >= 0; i = strings.Index(value, "~") {
+		if i+1 == len(value) || value[i+1] != '0' && value[i+1] != '1' {
+			return false
+		}
+		value = value[i+2:]
+	}
+	return true
+}
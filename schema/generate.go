@@ -0,0 +1,344 @@
+package schema
+
+import (
+	"encoding"
+	"fmt"
+	"github.com/ercross/yaml"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// draft is the meta-schema of the schemas Generator generates
+const draft = "https://json-schema.org/draft/2020-12/schema"
+
+type (
+	// Generator generates the JSON Schema of Go types, describing the YAML documents yaml.Decode decodes into them
+	Generator struct {
+		// descriptions are the doc comments of types, by type name, and of struct fields, by type and field name,
+		// e.g., Config.Port
+		descriptions map[string]string
+	}
+
+	// generation is the generation of the schema of a type, and of the $defs of the structs it uses
+	generation struct {
+		generator *Generator
+		defs      *yaml.MappingNode
+
+		// names are the names of the $defs of struct types, unique even if types of distinct packages share a name
+		names map[reflect.Type]string
+		used  map[string]bool
+	}
+)
+
+var (
+	timeType            = reflect.TypeFor[time.Time]()
+	durationType        = reflect.TypeFor[time.Duration]()
+	nodeType            = reflect.TypeFor[yaml.Node]()
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+)
+
+// durationTextPattern matches the strings time.ParseDuration parses, e.g., 1h30m
+const durationTextPattern = `^[-+]?(([0-9]+(\.[0-9]*)?|\.[0-9]+)(ns|us|µs|μs|ms|s|m|h))+$|^[-+]?0$`
+
+// NewGenerator creates a Generator without descriptions, see Generator.AddComments
+func NewGenerator() *Generator {
+	return &Generator{descriptions: make(map[string]string)}
+}
+
+// Generate generates the JSON Schema of the type of v, see Generator.Generate
+func Generate(v any) ([]byte, error) {
+	return NewGenerator().Generate(reflect.TypeOf(v))
+}
+
+// AddComments reads the doc comments of the types declared by the Go source files of the directory dir,
+// and of their fields, to be used as descriptions by Generate. Types are matched by name, whatever their package.
+// Test files are ignored
+func (g *Generator) AddComments(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	files := token.NewFileSet()
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".go") || strings.HasSuffix(entry.Name(), "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(files, filepath.Join(dir, entry.Name()), nil, parser.ParseComments)
+		if err != nil {
+			return err
+		}
+		for _, declaration := range file.Decls {
+			if declaration, ok := declaration.(*ast.GenDecl); ok && declaration.Tok == token.TYPE {
+				g.addTypeComments(declaration)
+			}
+		}
+	}
+	return nil
+}
+
+func (g *Generator) addTypeComments(declaration *ast.GenDecl) {
+	for _, spec := range declaration.Specs {
+		spec := spec.(*ast.TypeSpec)
+		doc := spec.Doc
+		if doc == nil && len(declaration.Specs) == 1 {
+			doc = declaration.Doc
+		}
+		g.addComment(spec.Name.Name, doc)
+
+		structure, ok := spec.Type.(*ast.StructType)
+		if !ok {
+			continue
+		}
+		for _, field := range structure.Fields.List {
+			doc := field.Doc
+			if doc == nil {
+				doc = field.Comment
+			}
+			for _, name := range field.Names {
+				g.addComment(spec.Name.Name+"."+name.Name, doc)
+			}
+		}
+	}
+}
+
+func (g *Generator) addComment(name string, doc *ast.CommentGroup) {
+	if text := strings.TrimSpace(doc.Text()); text != "" {
+		g.descriptions[name] = text
+	}
+}
+
+// Generate generates the JSON Schema (draft 2020-12) of t, as indented JSON.
+//
+// The schema describes the documents yaml.Decode decodes into a value of type t: structs are objects whose
+// properties are named by the `yaml` field tag, and are required unless tagged omitempty, slices and arrays
+// are arrays, maps are objects, pointers also allow null, a time.Time is a date-time string, a time.Duration
+// either a string such as 1m30s or an integer of nanoseconds, and other encoding.TextUnmarshaler types are strings.
+// Interfaces and yaml.Node fields allow any value. Named struct types are defined in $defs, so that they can be recursive
+func (g *Generator) Generate(t reflect.Type) ([]byte, error) {
+	if t == nil {
+		return nil, fmt.Errorf("can not generate the schema of a nil type: %w", yaml.ErrUnsupportedValue)
+	}
+
+	generation := &generation{
+		generator: g,
+		defs:      yaml.NewMappingNode(),
+		names:     make(map[reflect.Type]string),
+		used:      make(map[string]bool),
+	}
+	root, err := generation.schemaOf(t)
+	if err != nil {
+		return nil, err
+	}
+
+	document := yaml.NewMappingNode()
+	document.Set("$schema", stringNode(draft))
+	for _, pair := range root.Pairs() {
+		document.Set(pair.Key.(*yaml.ScalarNode).Value(), pair.Value)
+	}
+	if generation.defs.Len() > 0 {
+		document.Set("$defs", generation.defs)
+	}
+	return yaml.ToJSON(document, yaml.JSONOptions{StringifyKeys: true, Indent: "  "})
+}
+
+// schemaOf returns the schema of t, or a reference to its definition in $defs
+func (g *generation) schemaOf(t reflect.Type) (*yaml.MappingNode, error) {
+	switch {
+	case t == nodeType || t.Implements(nodeType) || t.Kind() == reflect.Interface && t.NumMethod() == 0:
+		return yaml.NewMappingNode(), nil
+	case t == timeType:
+		return schemaNode("type", "string", "format", "date-time"), nil
+	case t == durationType:
+		s := yaml.NewMappingNode()
+		s.Set("type", sequenceNode("string", "integer"))
+		s.Set("pattern", stringNode(durationTextPattern))
+		return s, nil
+	case t.Kind() != reflect.Pointer && reflect.PointerTo(t).Implements(textUnmarshalerType):
+		return schemaNode("type", "string"), nil
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		s, err := g.schemaOf(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return allowNull(s), nil
+
+	case reflect.Bool:
+		return schemaNode("type", "boolean"), nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		s := schemaNode("type", "integer")
+		if bits := t.Bits(); bits < 64 {
+			s.Set("minimum", yaml.NewScalarNode(strconv.FormatInt(math.MinInt64>>(64-bits), 10)))
+			s.Set("maximum", yaml.NewScalarNode(strconv.FormatInt(math.MaxInt64>>(64-bits), 10)))
+		}
+		return s, nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		s := schemaNode("type", "integer")
+		s.Set("minimum", yaml.NewScalarNode("0"))
+		if bits := t.Bits(); bits < 64 {
+			s.Set("maximum", yaml.NewScalarNode(strconv.FormatUint(math.MaxUint64>>(64-bits), 10)))
+		}
+		return s, nil
+
+	case reflect.Float32, reflect.Float64:
+		return schemaNode("type", "number"), nil
+
+	case reflect.String:
+		return schemaNode("type", "string"), nil
+
+	case reflect.Slice, reflect.Array:
+		items, err := g.schemaOf(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		s := schemaNode("type", "array")
+		s.Set("items", items)
+		if t.Kind() == reflect.Array {
+			s.Set("maxItems", yaml.NewScalarNode(strconv.Itoa(t.Len())))
+		}
+		return s, nil
+
+	case reflect.Map:
+		values, err := g.schemaOf(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		s := schemaNode("type", "object")
+		s.Set("additionalProperties", values)
+		return s, nil
+
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structure(t)
+		}
+		return g.definition(t)
+	}
+	return nil, fmt.Errorf("can not generate the schema of %s: %w", t, yaml.ErrUnsupportedValue)
+}
+
+// definition defines the named struct type t in $defs, and returns a reference to its definition
+func (g *generation) definition(t reflect.Type) (*yaml.MappingNode, error) {
+	name, defined := g.names[t]
+	if !defined {
+		name = t.Name()
+		for i := 2; g.used[name]; i++ {
+			name = t.Name() + strconv.Itoa(i)
+		}
+		g.names[t] = name
+		g.used[name] = true
+
+		s, err := g.structure(t)
+		if err != nil {
+			return nil, err
+		}
+		if description, ok := g.generator.descriptions[t.Name()]; ok {
+			s.Insert(0, "description", stringNode(description))
+		}
+		g.defs.Set(name, s)
+	}
+	return schemaNode("$ref", "#/$defs/"+escapePointer(name)), nil
+}
+
+// structure returns the schema of the exported fields of the struct type t
+func (g *generation) structure(t reflect.Type) (*yaml.MappingNode, error) {
+	properties := yaml.NewMappingNode()
+	required := yaml.NewSequenceNode()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, options, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		s, err := g.schemaOf(field.Type)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", field.Name, err)
+		}
+		if description, ok := g.generator.descriptions[t.Name()+"."+field.Name]; ok && t.Name() != "" {
+			s.Insert(0, "description", stringNode(description))
+		}
+		properties.AddChild(stringNode(name))
+		properties.AddChild(s)
+		if !slices.Contains(strings.Split(options, ","), "omitempty") {
+			required.Append(stringNode(name))
+		}
+	}
+
+	s := schemaNode("type", "object")
+	s.Set("properties", properties)
+	if required.Len() > 0 {
+		s.Set("required", required)
+	}
+	return s, nil
+}
+
+// allowNull makes s, the schema of the value a pointer points to, also allow null
+func allowNull(s *yaml.MappingNode) *yaml.MappingNode {
+	t, typed := s.Get("type")
+	if !typed {
+		if s.Len() == 0 {
+			return s
+		}
+		// a reference to a definition, which must be left unchanged
+		anyOf := yaml.NewSequenceNode()
+		anyOf.Append(s, schemaNode("type", "null"))
+		allowed := yaml.NewMappingNode()
+		allowed.Set("anyOf", anyOf)
+		return allowed
+	}
+
+	types := yaml.NewSequenceNode()
+	switch t := t.(type) {
+	case *yaml.ScalarNode:
+		types.Append(t)
+	case *yaml.SequenceNode:
+		types.Append(t.Items()...)
+	}
+	types.Append(stringNode("null"))
+	s.Set("type", types)
+	return s
+}
+
+// schemaNode creates a schema holding keywords with string values, given as keyword and value pairs
+func schemaNode(keywordValues ...string) *yaml.MappingNode {
+	s := yaml.NewMappingNode()
+	for i := 0; i+1 < len(keywordValues); i += 2 {
+		s.Set(keywordValues[i], stringNode(keywordValues[i+1]))
+	}
+	return s
+}
+
+// sequenceNode creates a sequence of strings
+func sequenceNode(values ...string) *yaml.SequenceNode {
+	sequence := yaml.NewSequenceNode()
+	for _, value := range values {
+		sequence.Append(stringNode(value))
+	}
+	return sequence
+}
+
+// stringNode creates a scalar read back as the string value, e.g., "null" rather than null
+func stringNode(value string) yaml.Node {
+	n, _ := yaml.NewNode(value)
+	return n
+}
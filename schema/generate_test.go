@@ -0,0 +1,117 @@
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/ercross/yaml"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+type (
+	Service struct {
+		Name     string            `yaml:"name"`
+		Replicas *int32            `yaml:"replicas,omitempty"`
+		Timeout  time.Duration     `yaml:"timeout,omitempty"`
+		Ports    []Port            `yaml:"ports"`
+		Labels   map[string]string `yaml:"labels,omitempty,flow"`
+		Backup   *Service          `yaml:"backup,omitempty"`
+		Created  time.Time         `yaml:"created,omitempty"`
+		Extra    yaml.Node         `yaml:"extra,flow,omitempty"`
+		Ignored  string            `yaml:"-"`
+		internal string
+	}
+
+	Port struct {
+		Number   uint16 `yaml:"number"`
+		Protocol string `yaml:"protocol,omitempty"`
+	}
+)
+
+// serviceSource declares the types of the test with their doc comments, as read by Generator.AddComments
+const serviceSource = `package config
+
+// Service is a deployed service
+type Service struct {
+	// Name identifies the service
+	Name string
+	Ports []Port // Ports are the exposed ports
+}
+
+type Port struct{}
+`
+
+func TestGenerator_Generate(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "service.go"), []byte(serviceSource), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	generator := NewGenerator()
+	if err := generator.AddComments(dir); err != nil {
+		t.Fatal(err)
+	}
+	generated, err := generator.Generate(reflect.TypeFor[Service]())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var document map[string]any
+	if err = json.Unmarshal(generated, &document); err != nil {
+		t.Fatal(err)
+	}
+	definitions := document["$defs"].(map[string]any)
+	service := definitions["Service"].(map[string]any)
+	properties := service["properties"].(map[string]any)
+	expected := map[string]any{
+		"description": "Service is a deployed service",
+		"required":    []any{"name", "ports"},
+	}
+	for keyword, value := range expected {
+		if !reflect.DeepEqual(service[keyword], value) {
+			t.Errorf("expected %s to be %v, got %v", keyword, value, service[keyword])
+		}
+	}
+	if description := properties["ports"].(map[string]any)["description"]; description != "Ports are the exposed ports" {
+		t.Errorf("expected the line comment of Ports as description, got %v", description)
+	}
+	if _, ok := properties["Ignored"]; ok {
+		t.Error("expected the field tagged - to be skipped")
+	}
+
+	s := MustCompile(fstest.MapFS{"service.json": {Data: generated}}, "service.json")
+	tests := []struct {
+		source string
+		valid  bool
+	}{
+		{source: "name: web\nports: [{number: 80}]\ntimeout: 1m30s\nreplicas: ~\nbackup: {name: b, ports: []}\n", valid: true},
+		{source: "name: web\nports: []\ncreated: 2024-05-01T10:00:00Z\nextra: [anything]\n", valid: true},
+		{source: "name: web\nports: [{number: 70000}]\n"},
+		{source: "name: web\nports: []\ntimeout: soon\n"},
+		{source: "name: web\nports: []\nreplicas: 3000000000\n"},
+		{source: "name: web\nports: []\nbackup: {name: b}\n"},
+		{source: "ports: []\n"},
+	}
+	for _, test := range tests {
+		document := mustParse(t, test.source)
+		err := s.Validate(document)
+		if valid := err == nil; valid != test.valid {
+			t.Errorf("expected %q to be valid: %t, got %v", test.source, test.valid, err)
+		}
+
+		// the schema describes what yaml.Decode decodes
+		var decoded Service
+		if decodeErr := yaml.Decode(document, &decoded); test.valid && decodeErr != nil {
+			t.Errorf("expected %q to decode, got %v", test.source, decodeErr)
+		}
+	}
+}
+
+func TestGenerate_Unsupported(t *testing.T) {
+	if _, err := Generate(struct{ C chan int }{}); !errors.Is(err, yaml.ErrUnsupportedValue) {
+		t.Errorf("expected %v, got %v", yaml.ErrUnsupportedValue, err)
+	}
+}
@@ -0,0 +1,291 @@
+package schema
+
+import (
+	"encoding/json"
+	"github.com/ercross/yaml"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// member is a property of an object, read from a mapping pair
+type member struct {
+	name       string
+	key, value yaml.Node
+
+	// index is the index of the pair within the mapping defining it, which is another mapping for merged pairs
+	index int
+}
+
+// resolve follows the aliases leading to n
+func resolve(n yaml.Node) yaml.Node {
+	for {
+		alias, ok := n.(*yaml.AliasNode)
+		if !ok || alias.Target() == nil {
+			return n
+		}
+		n = alias.Target()
+	}
+}
+
+// checkAliasExpansion counts the nodes the aliases of the tree rooted at n expand to, as yaml.DecodeOptions does,
+// and fails with a *yaml.LimitError beyond max, so that validating the tree does not expand them further.
+// 0 is yaml.DefaultMaxAliasExpansion, and a negative max is no limit
+func checkAliasExpansion(n yaml.Node, max int) error {
+	if max == 0 {
+		max = yaml.DefaultMaxAliasExpansion
+	}
+	if max < 0 {
+		return nil
+	}
+
+	// enclosing are the nodes being expanded, which an alias within them can not expand again
+	nodes, enclosing := 0, make(map[yaml.Node]bool)
+	var expand func(n yaml.Node, alias yaml.Node) error
+	expand = func(n yaml.Node, alias yaml.Node) error {
+		for _, child := range yaml.All(n) {
+			if alias != nil {
+				nodes++
+				if nodes > max {
+					return &yaml.LimitError{Err: yaml.ErrMaxAliasExpansion, Max: max, Position: alias.Position()}
+				}
+			}
+			target := resolve(child)
+			if _, ok := child.(*yaml.AliasNode); !ok || target == child || enclosing[target] {
+				continue
+			}
+			enclosing[target] = true
+			err := expand(target, child)
+			delete(enclosing, target)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return expand(n, nil)
+}
+
+// kindOf returns the JSON type of the resolved node n: null, boolean, integer, number, string, array or object
+func kindOf(n yaml.Node) string {
+	switch n := n.(type) {
+	case *yaml.ScalarNode:
+		switch n.Tag() {
+		case yaml.TagNull:
+			return "null"
+		case yaml.TagBool:
+			return "boolean"
+		case yaml.TagInt:
+			return "integer"
+		case yaml.TagFloat:
+			return "number"
+		}
+		return "string"
+	case *yaml.SequenceNode:
+		return "array"
+	case *yaml.MappingNode:
+		return "object"
+	}
+	return "null"
+}
+
+// numberOf returns the value of a scalar of kind integer or number, exactly if it is finite
+func numberOf(n *yaml.ScalarNode) (exact *big.Rat, f float64) {
+	value := strings.TrimPrefix(n.Value(), "+")
+	if n.Tag() == yaml.TagInt {
+		base := 10
+		if digits := strings.TrimPrefix(value, "-"); strings.HasPrefix(digits, "0x") || strings.HasPrefix(digits, "0o") {
+			base = 0
+		}
+		if i, ok := new(big.Int).SetString(value, base); ok {
+			exact = new(big.Rat).SetInt(i)
+		}
+	} else {
+		switch strings.ToLower(value) {
+		case ".inf":
+			return nil, math.Inf(1)
+		case "-.inf":
+			return nil, math.Inf(-1)
+		case ".nan":
+			return nil, math.NaN()
+		}
+		exact, _ = new(big.Rat).SetString(value)
+	}
+
+	if exact == nil {
+		return nil, math.NaN()
+	}
+	f, _ = exact.Float64()
+	return exact, f
+}
+
+// membersOf returns the properties of a mapping, merge keys replaced with the pairs of the mappings they merge,
+// unless the mapping defines the same keys itself. Keys that are not scalars have no property name and are skipped
+func membersOf(n *yaml.MappingNode, enclosing map[yaml.Node]bool) []member {
+	var members []member
+	defined := make(map[string]bool)
+	var merged []member
+	for i, pair := range n.Pairs() {
+		key, ok := resolve(pair.Key).(*yaml.ScalarNode)
+		if !ok {
+			continue
+		}
+		if key.Tag() != yaml.TagMerge {
+			defined[key.Value()] = true
+			members = append(members, member{name: key.Value(), key: pair.Key, value: pair.Value, index: i})
+			continue
+		}
+
+		sources := []yaml.Node{pair.Value}
+		if sequence, ok := resolve(pair.Value).(*yaml.SequenceNode); ok {
+			sources = sequence.Items()
+		}
+		for _, source := range sources {
+			mapping, ok := resolve(source).(*yaml.MappingNode)
+			if !ok || enclosing[mapping] {
+				continue
+			}
+			enclosing[mapping] = true
+			merged = append(merged, membersOf(mapping, enclosing)...)
+			delete(enclosing, mapping)
+		}
+	}
+
+	for _, m := range merged {
+		if !defined[m.name] {
+			defined[m.name] = true
+			members = append(members, m)
+		}
+	}
+	return members
+}
+
+// valueOf returns the JSON value of the resolved node n, numbers being *big.Rat when finite, or float64
+func valueOf(n yaml.Node, enclosing map[yaml.Node]bool) any {
+	switch n := n.(type) {
+	case *yaml.ScalarNode:
+		switch kindOf(n) {
+		case "null":
+			return nil
+		case "boolean":
+			return strings.EqualFold(n.Value(), "true")
+		case "integer", "number":
+			exact, f := numberOf(n)
+			if exact == nil {
+				return f
+			}
+			return exact
+		}
+		return n.Value()
+
+	case *yaml.SequenceNode:
+		if enclosing[n] {
+			return nil
+		}
+		enclosing[n] = true
+		defer delete(enclosing, n)
+
+		items := make([]any, n.Len())
+		for i, item := range n.Items() {
+			items[i] = valueOf(resolve(item), enclosing)
+		}
+		return items
+
+	case *yaml.MappingNode:
+		if enclosing[n] {
+			return nil
+		}
+		enclosing[n] = true
+		defer delete(enclosing, n)
+
+		members := make(map[string]any)
+		for _, m := range membersOf(n, enclosing) {
+			members[m.name] = valueOf(resolve(m.value), enclosing)
+		}
+		return members
+	}
+	return nil
+}
+
+// equal compares JSON values, numbers being equal if they have the same value whatever their representation
+func equal(a, b any) bool {
+	if ra, ok := ratOf(a); ok {
+		rb, ok := ratOf(b)
+		return ok && ra.Cmp(rb) == 0
+	}
+	if fa, ok := a.(float64); ok {
+		fb, ok := b.(float64)
+		return ok && fa == fb
+	}
+
+	switch a := a.(type) {
+	case []any:
+		b, ok := b.([]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !equal(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+
+	case map[string]any:
+		b, ok := b.(map[string]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for name, value := range a {
+			other, ok := b[name]
+			if !ok || !equal(value, other) {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
+
+// ratOf returns the exact value of a finite number, as decoded from a schema or read from an instance
+func ratOf(v any) (*big.Rat, bool) {
+	switch v := v.(type) {
+	case *big.Rat:
+		return v, true
+	case json.Number:
+		return new(big.Rat).SetString(v.String())
+	case int:
+		return new(big.Rat).SetInt64(int64(v)), true
+	case uint64:
+		return new(big.Rat).SetInt(new(big.Int).SetUint64(v)), true
+	case float64:
+		if math.IsInf(v, 0) || math.IsNaN(v) {
+			return nil, false
+		}
+		// the shortest representation of v is the number it was decoded from, e.g., 0.1 rather than its binary approximation
+		return new(big.Rat).SetString(strconv.FormatFloat(v, 'g', -1, 64))
+	}
+	return nil, false
+}
+
+// formatRat formats a number of a schema for a violation message
+func formatRat(r *big.Rat) string {
+	if r.IsInt() {
+		return r.Num().String()
+	}
+	f, _ := r.Float64()
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// formatValue formats a value of a schema, e.g., of const, for a violation message
+func formatValue(v any) string {
+	if r, ok := ratOf(v); ok {
+		return formatRat(r)
+	}
+	text, err := json.Marshal(v)
+	if err != nil {
+		return "?"
+	}
+	return string(text)
+}
@@ -0,0 +1,148 @@
+// Package schema validates yaml.Node trees against JSON Schema (draft 2020-12), reporting the position
+// of every node violating the schema in its YAML source.
+//
+// Schemas are read from JSON or YAML files, and can refer to each other with $ref, e.g., "#/$defs/port",
+// "common.json#/$defs/port", or "#name" for a subschema defining the $anchor name.
+// References are resolved relatively to the file holding them: $id does not change the base of references.
+//
+// The following keywords are supported:
+//
+//	$ref $defs allOf anyOf oneOf not if then else
+//	type enum const multipleOf minimum maximum exclusiveMinimum exclusiveMaximum
+//	minLength maxLength pattern format
+//	items prefixItems contains minContains maxContains minItems maxItems uniqueItems
+//	properties patternProperties additionalProperties propertyNames required dependentRequired dependentSchemas
+//	minProperties maxProperties
+//
+// Other keywords, e.g., unevaluatedProperties, are ignored. Patterns are Go regular expressions (RE2 syntax).
+// The format keyword is an assertion for date-time, date, time, duration, email, hostname, ipv4, ipv6,
+// uri, uri-reference, uuid, regex and json-pointer, and is ignored for other formats.
+//
+// Instances are read as JSON values: scalars by their tag (explicit, or resolved with the core schema),
+// aliases expanded and merge keys (<<) replaced with the pairs they merge.
+//
+// Generate produces the schema of the documents yaml.Decode decodes into a Go type, so that the schema
+// of a configuration can not drift from the types it is decoded into
+package schema
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"github.com/ercross/yaml"
+	"github.com/ercross/yaml/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ErrInvalidSchema is returned when a schema can not be compiled
+var ErrInvalidSchema = errors.New("invalid schema")
+
+type (
+	// Schema is a compiled JSON Schema. A Schema is immutable and can validate documents concurrently
+	Schema struct {
+		root *schema
+	}
+
+	// Violation is a node that does not satisfy a keyword of a Schema
+	Violation struct {
+		// Path locates the node from the root of the validated document, e.g., $.spec.ports[0]
+		Path     yaml.Path
+		Position token.Location
+
+		// Keyword locates the keyword the node violates, as the schema file followed by a JSON pointer,
+		// e.g., service.json#/properties/ports/items/type
+		Keyword string
+		Message string
+	}
+
+	// ValidationError is returned when a document does not match a Schema, and holds every Violation in document order
+	ValidationError struct {
+		Violations []Violation
+	}
+
+	// ValidateOptions control the validation of a document
+	ValidateOptions struct {
+		// MaxAliasExpansion is the number of nodes the aliases of the document may expand to, see yaml.DecodeOptions
+		MaxAliasExpansion int
+	}
+)
+
+// Compile compiles the schema held in the file name of fsys, and the schemas it refers to
+func Compile(fsys fs.FS, name string) (*Schema, error) {
+	root, err := newCompiler(fsys).compileFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return &Schema{root: root}, nil
+}
+
+// CompileFile compiles the schema held in the local file name, and the schemas it refers to
+func CompileFile(name string) (*Schema, error) {
+	abs, err := filepath.Abs(name)
+	if err != nil {
+		return nil, err
+	}
+	root := filepath.VolumeName(abs) + string(filepath.Separator)
+	rel, err := filepath.Rel(root, abs)
+	if err != nil {
+		return nil, err
+	}
+	return Compile(os.DirFS(root), filepath.ToSlash(rel))
+}
+
+// MustCompile is like Compile but panics if the schema can not be compiled
+func MustCompile(fsys fs.FS, name string) *Schema {
+	s, err := Compile(fsys, name)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// Validate validates the tree rooted at n against s, and returns a *ValidationError if it does not match.
+// If n is a yaml.DocumentNode, its root is validated, and an empty document is null.
+// A tree whose aliases expand to more than yaml.DefaultMaxAliasExpansion nodes is not validated:
+// Validate returns a *yaml.LimitError
+func (s *Schema) Validate(n yaml.Node) error {
+	return s.ValidateWithOptions(n, ValidateOptions{})
+}
+
+// ValidateWithOptions is like Validate, with opts
+func (s *Schema) ValidateWithOptions(n yaml.Node, opts ValidateOptions) error {
+	if document, ok := n.(*yaml.DocumentNode); ok {
+		n = document.Root()
+	}
+	if n == nil {
+		n = yaml.NewScalarNode("null")
+	}
+	if err := checkAliasExpansion(n, opts.MaxAliasExpansion); err != nil {
+		return err
+	}
+
+	v := newValidator()
+	v.validate(s.root, nil, n)
+	if len(v.violations) > 0 {
+		// violations are reported in document order, rather than in the order of the keywords of the schema
+		slices.SortStableFunc(v.violations, func(a, b Violation) int {
+			return cmp.Or(cmp.Compare(a.Position.Line(), b.Position.Line()), cmp.Compare(a.Position.Column(), b.Position.Column()))
+		})
+		return &ValidationError{Violations: v.violations}
+	}
+	return nil
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s: %s: %s", v.Position, v.Path, v.Message)
+}
+
+func (e *ValidationError) Error() string {
+	lines := make([]string, len(e.Violations))
+	for i, violation := range e.Violations {
+		lines[i] = violation.String()
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,277 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ercross/yaml"
+	"github.com/ercross/yaml/parser"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+var files = fstest.MapFS{
+	"service.json": {Data: []byte(`{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"type": "object",
+		"required": ["name", "ports"],
+		"additionalProperties": false,
+		"properties": {
+			"name": {"type": "string", "pattern": "^[a-z-]+$"},
+			"ports": {"type": "array", "minItems": 1, "items": {"$ref": "common/port.json"}},
+			"owner": {"$ref": "#owner"},
+			"labels": {"type": "object", "additionalProperties": {"type": "string"}},
+			"defaults": true
+		},
+		"$defs": {
+			"owner": {"$anchor": "owner", "type": "string", "format": "email"}
+		}
+	}`)},
+	"common/port.json": {Data: []byte(`{
+		"type": "object",
+		"required": ["number"],
+		"properties": {
+			"number": {"$ref": "#/$defs/number"},
+			"protocol": {"enum": ["tcp", "udp"]}
+		},
+		"$defs": {
+			"number": {"type": "integer", "minimum": 1, "maximum": 65535}
+		}
+	}`)},
+	"yaml-schema.yaml": {Data: []byte("type: array\nitems:\n  type: number\n  multipleOf: 0.1\n")},
+}
+
+func mustParse(t *testing.T, source string) *yaml.DocumentNode {
+	t.Helper()
+	ast, err := parser.Parse(strings.NewReader(source))
+	if err != nil {
+		t.Fatalf("failed to parse %q: %v", source, err)
+	}
+	return ast.Documents()[0]
+}
+
+func TestSchema_Validate(t *testing.T) {
+	s := MustCompile(files, "service.json")
+	source := "defaults: &d\n  protocol: tcp\nname: Web\nports:\n  - <<: *d\n    number: 80\n  - number: 70000\n    protocol: sctp\n" +
+		"owner: nobody\nlabels: {tier: 1}\nextra: x\n"
+
+	err := s.Validate(mustParse(t, source))
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected a ValidationError, got %v", err)
+	}
+
+	expected := []string{
+		`line(3): column(7): $.name: "Web" does not match the pattern "^[a-z-]+$" (service.json#/properties/name/pattern)`,
+		`line(7): column(13): $.ports[1].number: expected at most 65535 (common/port.json#/$defs/number/maximum)`,
+		`line(8): column(15): $.ports[1].protocol: expected one of "tcp", "udp" (common/port.json#/properties/protocol/enum)`,
+		`line(9): column(8): $.owner: "nobody" is not a valid email (service.json#/$defs/owner/format)`,
+		`line(10): column(16): $.labels.tier: expected string, got integer (service.json#/properties/labels/additionalProperties/type)`,
+		`line(11): column(1): $.extra: property "extra" is not allowed (service.json#/additionalProperties)`,
+	}
+	actual := make([]string, len(validationErr.Violations))
+	for i, violation := range validationErr.Violations {
+		actual[i] = violation.String() + " (" + violation.Keyword + ")"
+	}
+	if strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected violations\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(actual, "\n"))
+	}
+
+	valid := "name: web\nports:\n  - number: 443\nowner: team@example.com\n"
+	if err = s.Validate(mustParse(t, valid)); err != nil {
+		t.Errorf("expected %q to be valid, got %v", valid, err)
+	}
+}
+
+func TestSchema_Validate_AliasExpansion(t *testing.T) {
+	s := MustCompile(files, "yaml-schema.yaml")
+	n := mustParse(t, "- &a 1\n- *a\n- *a\n")
+	if err := s.ValidateWithOptions(n, ValidateOptions{MaxAliasExpansion: 2}); err != nil {
+		t.Fatalf("expected the document to be valid, got %v", err)
+	}
+
+	var limitErr *yaml.LimitError
+	err := s.ValidateWithOptions(n, ValidateOptions{MaxAliasExpansion: 1})
+	if !errors.As(err, &limitErr) || !errors.Is(err, yaml.ErrMaxAliasExpansion) {
+		t.Fatalf("expected a *yaml.LimitError of %v, got %v", yaml.ErrMaxAliasExpansion, err)
+	}
+	if limitErr.Position.Line() != 3 {
+		t.Errorf("expected the limit to be exceeded by the alias on line 3, got %s", limitErr.Position)
+	}
+
+	// every alias expands to 9 aliases of the preceding sequence, up to a billion scalars
+	laughs := "a: &a [lol, lol, lol, lol, lol, lol, lol, lol, lol]\n"
+	for name := 'b'; name <= 'i'; name++ {
+		alias := "*" + string(name-1)
+		laughs += fmt.Sprintf("%c: &%c [%s%s]\n", name, name, strings.Repeat(alias+", ", 8), alias)
+	}
+	if err := s.Validate(mustParse(t, laughs)); !errors.Is(err, yaml.ErrMaxAliasExpansion) {
+		t.Errorf("expected %v, got %v", yaml.ErrMaxAliasExpansion, err)
+	}
+}
+
+func TestSchema_Validate_Keywords(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		valid  []string
+		errors []string
+	}{
+		{
+			name:   "integer",
+			schema: `{"type": "integer"}`,
+			valid:  []string{"1", "0x1F", "2.0"},
+			errors: []string{"1.5", "'1'", "~"},
+		},
+		{
+			name:   "multipleOf",
+			schema: `{"multipleOf": 0.1}`,
+			valid:  []string{"0.3", "2", "text"},
+			errors: []string{"0.35", ".inf"},
+		},
+		{
+			name:   "exclusive bounds",
+			schema: `{"exclusiveMinimum": 0, "exclusiveMaximum": 10}`,
+			valid:  []string{"0.5", "9"},
+			errors: []string{"0", "10", "-.inf"},
+		},
+		{
+			name:   "const",
+			schema: `{"const": {"a": [1, "x"]}}`,
+			valid:  []string{"{a: [1.0, x]}", "a: [0x1, 'x']"},
+			errors: []string{"{a: [1, x, 2]}", "{a: ['1', x]}"},
+		},
+		{
+			name:   "string length in characters",
+			schema: `{"minLength": 2, "maxLength": 3}`,
+			valid:  []string{"éé", "abc", "1"},
+			errors: []string{"é", "abcd"},
+		},
+		{
+			name:   "uniqueItems",
+			schema: `{"uniqueItems": true}`,
+			valid:  []string{"[1, '1', true]", "[{a: 1}, {a: 2}]"},
+			errors: []string{"[1, 1.0]", "[{a: 1}, {a: 1}]"},
+		},
+		{
+			name:   "contains",
+			schema: `{"contains": {"type": "string"}, "minContains": 2, "maxContains": 3}`,
+			valid:  []string{"[a, b, 1]"},
+			errors: []string{"[a, 1]", "[a, b, c, d]"},
+		},
+		{
+			name:   "prefixItems",
+			schema: `{"prefixItems": [{"type": "string"}, {"type": "integer"}], "items": false}`,
+			valid:  []string{"[a, 1]", "[a]"},
+			errors: []string{"[1, 1]", "[a, 1, x]"},
+		},
+		{
+			name:   "combinations",
+			schema: `{"anyOf": [{"type": "string"}, {"type": "integer"}], "oneOf": [{"minimum": 5}, {"maximum": 10}], "not": {"const": "x"}}`,
+			valid:  []string{"1", "11"},
+			errors: []string{"7", "x", "1.5", "text"},
+		},
+		{
+			name:   "if then else",
+			schema: `{"if": {"properties": {"kind": {"const": "tcp"}}}, "then": {"required": ["port"]}, "else": {"required": ["path"]}}`,
+			valid:  []string{"{kind: tcp, port: 80}", "{kind: unix, path: /run}"},
+			errors: []string{"{kind: tcp, path: /run}", "{kind: unix}"},
+		},
+		{
+			name:   "dependencies",
+			schema: `{"dependentRequired": {"tls": ["cert"]}, "dependentSchemas": {"cert": {"required": ["key"]}}}`,
+			valid:  []string{"{tls: true, cert: c, key: k}", "{}"},
+			errors: []string{"{tls: true}", "{cert: c}"},
+		},
+		{
+			name:   "property names and counts",
+			schema: `{"propertyNames": {"pattern": "^[a-z0-9]+$"}, "patternProperties": {"^x": {"type": "string"}}, "maxProperties": 2}`,
+			valid:  []string{"{a: 1, 1: b}", "{x1: s}"},
+			errors: []string{"{A: 1}", "{x1: 1}", "{a: 1, b: 2, c: 3}"},
+		},
+		{
+			name:   "formats",
+			schema: `{"anyOf": [{"format": "date-time"}, {"format": "ipv6"}, {"format": "duration"}], "type": "string"}`,
+			valid:  []string{"2024-05-01T10:00:00Z", "'::1'", "P1DT12H"},
+			errors: []string{"2024-02-30T10:00:00Z", "P", "PT"},
+		},
+		{
+			name:   "boolean schema",
+			schema: `false`,
+			errors: []string{"~", "a"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := MustCompile(fstest.MapFS{"schema.json": {Data: []byte(test.schema)}}, "schema.json")
+			for _, source := range test.valid {
+				if err := s.Validate(mustParse(t, source)); err != nil {
+					t.Errorf("expected %q to be valid, got %v", source, err)
+				}
+			}
+			for _, source := range test.errors {
+				if err := s.Validate(mustParse(t, source)); err == nil {
+					t.Errorf("expected %q to be invalid", source)
+				}
+			}
+		})
+	}
+}
+
+func TestCompile_YAML(t *testing.T) {
+	s := MustCompile(files, "yaml-schema.yaml")
+	if err := s.Validate(mustParse(t, "[0.1, 0.7, 3]")); err != nil {
+		t.Errorf("expected the items to be valid, got %v", err)
+	}
+	if err := s.Validate(mustParse(t, "[0.15]")); err == nil {
+		t.Error("expected 0.15 not to be a multiple of 0.1")
+	}
+}
+
+func TestCompile_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		schema   string
+		expected error
+	}{
+		{name: "invalid pattern", schema: `{"pattern": "("}`, expected: ErrInvalidSchema},
+		{name: "unknown type", schema: `{"type": "text"}`, expected: ErrInvalidSchema},
+		{name: "unresolved pointer", schema: `{"$ref": "#/$defs/missing"}`, expected: ErrInvalidSchema},
+		{name: "unknown anchor", schema: `{"$ref": "#missing"}`, expected: ErrInvalidSchema},
+		{name: "remote reference", schema: `{"$ref": "https://example.com/schema.json"}`, expected: ErrInvalidSchema},
+		{name: "invalid definition", schema: `{"$defs": {"a": {"minLength": -1}}}`, expected: ErrInvalidSchema},
+		{name: "missing file", schema: `{"items": {"$ref": "missing.json"}}`, expected: fs.ErrNotExist},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Compile(fstest.MapFS{"schema.json": {Data: []byte(test.schema)}}, "schema.json")
+			if !errors.Is(err, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, err)
+			}
+		})
+	}
+}
+
+func TestCompileFile(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "defs"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "defs", "name.json"), []byte(`{"type": "string"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "schema.json"), []byte(`{"items": {"$ref": "defs/name.json"}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := CompileFile(filepath.Join(dir, "schema.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Validate(mustParse(t, "[a, 1]")); err == nil || !strings.Contains(err.Error(), "$[1]: expected string, got integer") {
+		t.Errorf("expected the second item to be reported, got %v", err)
+	}
+}
@@ -0,0 +1,324 @@
+package schema
+
+import (
+	"cmp"
+	"fmt"
+	"github.com/ercross/yaml"
+	"math/big"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// validator validates a node tree against a schema, collecting the violations found
+type validator struct {
+	violations []Violation
+
+	// enclosing are the collections being validated, which an alias can not refer to
+	enclosing map[yaml.Node]bool
+}
+
+func newValidator() *validator {
+	return &validator{enclosing: make(map[yaml.Node]bool)}
+}
+
+// validate records the violations of s by n, found at path, and reports whether n matches s
+func (v *validator) validate(s *schema, path yaml.Path, n yaml.Node) bool {
+	count := len(v.violations)
+	if s.always != nil {
+		if !*s.always {
+			v.report(s, "", path, n, "no value is allowed")
+		}
+		return *s.always
+	}
+
+	target := resolve(n)
+	if _, unresolved := target.(*yaml.AliasNode); unresolved || v.enclosing[target] {
+		v.report(s, "", path, n, "recursive alias can not be validated")
+		return false
+	}
+
+	if s.ref != nil {
+		v.validate(s.ref, path, n)
+	}
+	v.value(s, path, n, target)
+	switch target := target.(type) {
+	case *yaml.ScalarNode:
+		v.scalar(s, path, n, target)
+	case *yaml.SequenceNode:
+		v.enclosing[target] = true
+		v.array(s, path, n, target)
+		delete(v.enclosing, target)
+	case *yaml.MappingNode:
+		v.enclosing[target] = true
+		v.object(s, path, n, target)
+		delete(v.enclosing, target)
+	}
+	v.combined(s, path, n)
+	return len(v.violations) == count
+}
+
+// matches reports whether n matches s, without recording any violation
+func (v *validator) matches(s *schema, path yaml.Path, n yaml.Node) bool {
+	return (&validator{enclosing: v.enclosing}).validate(s, path, n)
+}
+
+// value checks the keywords applying to every type: type, enum and const
+func (v *validator) value(s *schema, path yaml.Path, n, target yaml.Node) {
+	kind := kindOf(target)
+	if len(s.types) > 0 && !slices.ContainsFunc(s.types, func(t string) bool { return isKind(t, kind, target) }) {
+		v.report(s, "type", path, n, "expected %s, got %s", strings.Join(s.types, " or "), kind)
+	}
+	if s.enum == nil && !s.hasConst {
+		return
+	}
+
+	value := valueOf(target, make(map[yaml.Node]bool))
+	if s.enum != nil && !slices.ContainsFunc(s.enum, func(allowed any) bool { return equal(value, allowed) }) {
+		allowed := make([]string, len(s.enum))
+		for i, a := range s.enum {
+			allowed[i] = formatValue(a)
+		}
+		v.report(s, "enum", path, n, "expected one of %s", strings.Join(allowed, ", "))
+	}
+	if s.hasConst && !equal(value, s.constant) {
+		v.report(s, "const", path, n, "expected %s", formatValue(s.constant))
+	}
+}
+
+// scalar checks the keywords applying to numbers and strings
+func (v *validator) scalar(s *schema, path yaml.Path, n yaml.Node, target *yaml.ScalarNode) {
+	switch kindOf(target) {
+	case "integer", "number":
+		v.number(s, path, n, target)
+
+	case "string":
+		value := target.Value()
+		length := utf8.RuneCountInString(value)
+		if s.minLength != nil && length < *s.minLength {
+			v.report(s, "minLength", path, n, "expected at least %d characters, got %d", *s.minLength, length)
+		}
+		if s.maxLength != nil && length > *s.maxLength {
+			v.report(s, "maxLength", path, n, "expected at most %d characters, got %d", *s.maxLength, length)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(value) {
+			v.report(s, "pattern", path, n, "%q does not match the pattern %q", value, s.pattern)
+		}
+		if valid, known := formats[s.format]; known && !valid(value) {
+			v.report(s, "format", path, n, "%q is not a valid %s", value, s.format)
+		}
+	}
+}
+
+func (v *validator) number(s *schema, path yaml.Path, n yaml.Node, target *yaml.ScalarNode) {
+	exact, f := numberOf(target)
+
+	// compare returns the sign of the difference between the number and the bound of a keyword
+	compare := func(bound *big.Rat) int {
+		if exact != nil {
+			return exact.Cmp(bound)
+		}
+		limit, _ := bound.Float64()
+		return cmp.Compare(f, limit)
+	}
+
+	if s.multipleOf != nil && (exact == nil || !new(big.Rat).Quo(exact, s.multipleOf).IsInt()) {
+		v.report(s, "multipleOf", path, n, "expected a multiple of %s", formatRat(s.multipleOf))
+	}
+	if s.minimum != nil && compare(s.minimum) < 0 {
+		v.report(s, "minimum", path, n, "expected at least %s", formatRat(s.minimum))
+	}
+	if s.maximum != nil && compare(s.maximum) > 0 {
+		v.report(s, "maximum", path, n, "expected at most %s", formatRat(s.maximum))
+	}
+	if s.exclusiveMinimum != nil && compare(s.exclusiveMinimum) <= 0 {
+		v.report(s, "exclusiveMinimum", path, n, "expected more than %s", formatRat(s.exclusiveMinimum))
+	}
+	if s.exclusiveMaximum != nil && compare(s.exclusiveMaximum) >= 0 {
+		v.report(s, "exclusiveMaximum", path, n, "expected less than %s", formatRat(s.exclusiveMaximum))
+	}
+}
+
+// array checks the keywords applying to arrays
+func (v *validator) array(s *schema, path yaml.Path, n yaml.Node, target *yaml.SequenceNode) {
+	items := target.Items()
+	if s.minItems != nil && len(items) < *s.minItems {
+		v.report(s, "minItems", path, n, "expected at least %d items, got %d", *s.minItems, len(items))
+	}
+	if s.maxItems != nil && len(items) > *s.maxItems {
+		v.report(s, "maxItems", path, n, "expected at most %d items, got %d", *s.maxItems, len(items))
+	}
+
+	for i, item := range items {
+		itemPath := appendPath(path, yaml.PathSegment{Index: i})
+		switch {
+		case i < len(s.prefixItems):
+			v.validate(s.prefixItems[i], itemPath, item)
+		case s.items != nil:
+			v.validate(s.items, itemPath, item)
+		}
+	}
+
+	if s.contains != nil {
+		contained := 0
+		for i, item := range items {
+			if v.matches(s.contains, appendPath(path, yaml.PathSegment{Index: i}), item) {
+				contained++
+			}
+		}
+		minContains := 1
+		if s.minContains != nil {
+			minContains = *s.minContains
+		}
+		if contained < minContains {
+			v.report(s, "contains", path, n, "expected at least %d items matching contains, got %d", minContains, contained)
+		}
+		if s.maxContains != nil && contained > *s.maxContains {
+			v.report(s, "maxContains", path, n, "expected at most %d items matching contains, got %d", *s.maxContains, contained)
+		}
+	}
+
+	if s.uniqueItems {
+		values := make([]any, len(items))
+		for i, item := range items {
+			values[i] = valueOf(resolve(item), make(map[yaml.Node]bool))
+			for j := range i {
+				if equal(values[i], values[j]) {
+					v.report(s, "uniqueItems", appendPath(path, yaml.PathSegment{Index: i}), item, "duplicates item %d", j)
+					break
+				}
+			}
+		}
+	}
+}
+
+// object checks the keywords applying to objects
+func (v *validator) object(s *schema, path yaml.Path, n yaml.Node, target *yaml.MappingNode) {
+	members := membersOf(target, v.enclosing)
+	defined := make(map[string]bool, len(members))
+	for _, m := range members {
+		defined[m.name] = true
+	}
+
+	if s.minProperties != nil && len(members) < *s.minProperties {
+		v.report(s, "minProperties", path, n, "expected at least %d properties, got %d", *s.minProperties, len(members))
+	}
+	if s.maxProperties != nil && len(members) > *s.maxProperties {
+		v.report(s, "maxProperties", path, n, "expected at most %d properties, got %d", *s.maxProperties, len(members))
+	}
+	for _, name := range s.required {
+		if !defined[name] {
+			v.report(s, "required", path, n, "missing required property %q", name)
+		}
+	}
+
+	for _, m := range members {
+		memberPath := appendPath(path, yaml.PathSegment{Key: m.key, Index: m.index})
+		if s.propertyNames != nil {
+			// the name of a property is a string, whatever the tag of its key
+			name := yaml.NewScalarNode(m.name)
+			name.SetTag(yaml.TagString)
+			name.SetPosition(m.key.Position())
+			v.validate(s.propertyNames, appendPath(path, yaml.PathSegment{Key: m.key, Index: m.index, IsKey: true}), name)
+		}
+		for _, required := range s.dependentRequired[m.name] {
+			if !defined[required] {
+				v.report(s, "dependentRequired", path, n, "missing property %q, required by %q", required, m.name)
+			}
+		}
+
+		evaluated := false
+		if property, ok := s.properties[m.name]; ok {
+			evaluated = true
+			v.validate(property, memberPath, m.value)
+		}
+		for _, p := range s.patternProperties {
+			if p.pattern.MatchString(m.name) {
+				evaluated = true
+				v.validate(p.schema, memberPath, m.value)
+			}
+		}
+		switch additional := s.additionalProperties; {
+		case evaluated || additional == nil:
+		case additional.always != nil && !*additional.always:
+			v.report(s, "additionalProperties", memberPath, m.key, "property %q is not allowed", m.name)
+		default:
+			v.validate(additional, memberPath, m.value)
+		}
+	}
+}
+
+// combined checks the keywords applying subschemas to n itself: allOf, anyOf, oneOf, not, if with then and else,
+// and dependentSchemas
+func (v *validator) combined(s *schema, path yaml.Path, n yaml.Node) {
+	if mapping, ok := resolve(n).(*yaml.MappingNode); ok && s.dependentSchemas != nil {
+		for _, m := range membersOf(mapping, v.enclosing) {
+			if dependent, ok := s.dependentSchemas[m.name]; ok {
+				v.validate(dependent, path, n)
+			}
+		}
+	}
+	for _, sub := range s.allOf {
+		v.validate(sub, path, n)
+	}
+	if s.anyOf != nil && !slices.ContainsFunc(s.anyOf, func(sub *schema) bool { return v.matches(sub, path, n) }) {
+		v.report(s, "anyOf", path, n, "expected to match at least one of %d schemas", len(s.anyOf))
+	}
+	if s.oneOf != nil {
+		matched := 0
+		for _, sub := range s.oneOf {
+			if v.matches(sub, path, n) {
+				matched++
+			}
+		}
+		if matched != 1 {
+			v.report(s, "oneOf", path, n, "expected to match exactly one of %d schemas, matches %d", len(s.oneOf), matched)
+		}
+	}
+	if s.not != nil && v.matches(s.not, path, n) {
+		v.report(s, "not", path, n, "expected not to match the schema of not")
+	}
+
+	if s.ifSchema != nil {
+		then := s.elseSchema
+		if v.matches(s.ifSchema, path, n) {
+			then = s.thenSchema
+		}
+		if then != nil {
+			v.validate(then, path, n)
+		}
+	}
+}
+
+// report records that n, found at path, violates keyword of s
+func (v *validator) report(s *schema, keyword string, path yaml.Path, n yaml.Node, format string, args ...any) {
+	location := s.location
+	if keyword != "" {
+		location += "/" + keyword
+	}
+	v.violations = append(v.violations, Violation{
+		Path:     path,
+		Position: n.Position(),
+		Keyword:  location,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// isKind checks that a node of kind matches the type name, an integral number matching integer
+func isKind(name, kind string, n yaml.Node) bool {
+	switch {
+	case name == kind:
+		return true
+	case name == "number":
+		return kind == "integer"
+	case name == "integer" && kind == "number":
+		exact, _ := numberOf(n.(*yaml.ScalarNode))
+		return exact != nil && exact.IsInt()
+	}
+	return false
+}
+
+// appendPath returns a new yaml.Path made of path followed by segment, leaving path untouched
+func appendPath(path yaml.Path, segment yaml.PathSegment) yaml.Path {
+	return append(path[:len(path):len(path)], segment)
+}
@@ -0,0 +1,73 @@
+package yaml
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Tags of the YAML 1.2 failsafe, JSON and core schemas, in their shorthand form
+const (
+	TagString    = "!!str"
+	TagInt       = "!!int"
+	TagFloat     = "!!float"
+	TagBool      = "!!bool"
+	TagNull      = "!!null"
+	TagMap       = "!!map"
+	TagSeq       = "!!seq"
+	TagBinary    = "!!binary"
+	TagTimestamp = "!!timestamp"
+	TagMerge     = "!!merge"
+
+	// TagNonSpecific is the `!` tag, forcing a scalar to be a string
+	TagNonSpecific = "!"
+
+	// tagPrefix is the prefix the `!!` shorthand stands for
+	tagPrefix = "tag:yaml.org,2002:"
+)
+
+var (
+	intPattern   = regexp.MustCompile(`^([-+]?[0-9]+|0o[0-7]+|0x[0-9a-fA-F]+)$`)
+	floatPattern = regexp.MustCompile(`^([-+]?(\.[0-9]+|[0-9]+(\.[0-9]*)?)([eE][-+]?[0-9]+)?|[-+]?\.(inf|Inf|INF)|\.(nan|NaN|NAN))$`)
+
+	// yaml11Pattern matches the plain scalars YAML 1.1 reads as booleans or numbers, while the core schema may read
+	// them as strings: y, n, yes, no, on and off in any case, binary numbers, numbers with underscores,
+	// and base 60 numbers such as 12:30
+	yaml11Pattern = regexp.MustCompile(`^((?i)y|n|yes|no|on|off)$|` +
+		`^[-+]?(0b[01_]+|0x[0-9a-fA-F_]+|[0-9][0-9_]*|[0-9][0-9_]*(:[0-5]?[0-9])+(\.[0-9_]*)?|([0-9][0-9_]*)?\.[0-9_]+([eE][-+]?[0-9]+)?)$`)
+)
+
+// resolveTag finds the tag of a plain scalar value using the YAML 1.2 core schema
+func resolveTag(value string) string {
+	switch value {
+	case "", "~", "null", "Null", "NULL":
+		return TagNull
+	case "true", "True", "TRUE", "false", "False", "FALSE":
+		return TagBool
+	case "<<":
+		return TagMerge
+	}
+
+	if intPattern.MatchString(value) {
+		return TagInt
+	}
+	if floatPattern.MatchString(value) {
+		return TagFloat
+	}
+	return TagString
+}
+
+// IsYAML11Scalar checks that YAML 1.1 reads the plain scalar value as a boolean or a number, e.g., yes, 0755 or 1_000,
+// so that a string holding value is quoted for YAML 1.1 readers
+func IsYAML11Scalar(value string) bool {
+	return yaml11Pattern.MatchString(value)
+}
+
+// normalizeTag turns the verbatim form of a yaml.org tag (e.g., !<tag:yaml.org,2002:str>)
+// into its shorthand form (e.g., !!str)
+func normalizeTag(tag string) string {
+	verbatim := strings.TrimSuffix(strings.TrimPrefix(tag, "!<"), ">")
+	if strings.HasPrefix(verbatim, tagPrefix) {
+		return "!!" + strings.TrimPrefix(verbatim, tagPrefix)
+	}
+	return tag
+}
@@ -0,0 +1,55 @@
+package yaml
+
+import "testing"
+
+func TestResolveTag(t *testing.T) {
+	tests := map[string]string{
+		"":           TagNull,
+		"~":          TagNull,
+		"Null":       TagNull,
+		"true":       TagBool,
+		"FALSE":      TagBool,
+		"12345":      TagInt,
+		"-7":         TagInt,
+		"0o17":       TagInt,
+		"0xFF":       TagInt,
+		"3.14159":    TagFloat,
+		"1.23e4":     TagFloat,
+		"-.inf":      TagFloat,
+		".NaN":       TagFloat,
+		"<<":         TagMerge,
+		"yes":        TagString,
+		"0x":         TagString,
+		"1.2.3":      TagString,
+		"John Doe":   TagString,
+		"2024-09-08": TagString,
+	}
+
+	for value, expected := range tests {
+		if actual := resolveTag(value); actual != expected {
+			t.Errorf("resolveTag(%q): expected %s, got %s", value, expected, actual)
+		}
+	}
+}
+
+func TestScalarNode_Tag(t *testing.T) {
+	n := NewScalarNode("12")
+	if n.Tag() != TagInt {
+		t.Errorf("expected %s, got %s", TagInt, n.Tag())
+	}
+
+	n.SetStyle(StyleDoubleQuoted)
+	if n.Tag() != TagString {
+		t.Errorf("quoted scalar: expected %s, got %s", TagString, n.Tag())
+	}
+
+	n.SetTag("!<tag:yaml.org,2002:float>")
+	if n.Tag() != TagFloat {
+		t.Errorf("verbatim tag: expected %s, got %s", TagFloat, n.Tag())
+	}
+
+	n.SetTag("!<tag:example.com,2024:thing>")
+	if n.Tag() != "!<tag:example.com,2024:thing>" {
+		t.Errorf("expected verbatim tag to be kept, got %s", n.Tag())
+	}
+}
@@ -13,6 +13,7 @@ var scalarLines = []string{
 	"single_quote_string: 'This is YAML!'\n",
 	`escaped_chars: "Line with a "quote" inside"` + "\n",
 	"scientific: 1.23e4\n",
+	"commented: value # note\n",
 }
 
 var ScalarTokens = scalarTokens
@@ -21,8 +22,8 @@ var scalarTokens = [][]token.Token{
 	{
 		token.New(token.TypeData, "string", 1, 1),
 		token.New(token.TypeColon, "", 1, 7),
-		token.New(token.TypeData, `Hello\, World`, 1, 9),
-		token.New(token.TypeNewline, "", 1, 23),
+		token.NewQuoted(`Hello\, World`, '"', 1, 9),
+		token.New(token.TypeNewline, "", 1, 24),
 	},
 
 	{
@@ -68,16 +69,16 @@ var scalarTokens = [][]token.Token{
 		// single_quote_string: 'This is YAML!'
 		token.New(token.TypeData, "single_quote_string", 7, 1),
 		token.New(token.TypeColon, "", 7, 20),
-		token.New(token.TypeData, "This is YAML!", 7, 22),
-		token.New(token.TypeNewline, "", 7, 36),
+		token.NewQuoted("This is YAML!", '\'', 7, 22),
+		token.New(token.TypeNewline, "", 7, 37),
 	},
 
 	{
 		// escaped_chars: "Line with a \"quote\" inside"
 		token.New(token.TypeData, "escaped_chars", 8, 1),
 		token.New(token.TypeColon, "", 8, 14),
-		token.New(token.TypeData, `Line with a "quote" inside`, 8, 16),
-		token.New(token.TypeNewline, "", 8, 41),
+		token.NewQuoted(`Line with a "quote" inside`, '"', 8, 16),
+		token.New(token.TypeNewline, "", 8, 44),
 	},
 
 	{
@@ -87,6 +88,15 @@ var scalarTokens = [][]token.Token{
 		token.New(token.TypeData, "1.23e4", 9, 13),
 		token.New(token.TypeNewline, "", 9, 19),
 	},
+
+	{
+		// commented: value # note
+		token.New(token.TypeData, "commented", 10, 1),
+		token.New(token.TypeColon, "", 10, 10),
+		token.New(token.TypeData, "value", 10, 12),
+		token.New(token.TypeComment, " note", 10, 18),
+		token.New(token.TypeNewline, "", 10, 24),
+	},
 }
 
 var AllNodes = []string{
@@ -30,6 +30,16 @@ const (
 	TypeClosingSquareBracket
 	TypeOpeningCurlyBrace
 	TypeClosingCurlyBrace
+
+	// TypeDash is a block sequence entry indicator, i.e., a dash followed by a whitespace or a newline
+	TypeDash
+
+	// TypeBlockScalarLine is a raw line of literal or folded block scalar content.
+	// Its Value holds the whole line, including indentation but excluding the newline
+	TypeBlockScalarLine
+
+	// TypeDirective is a directive line such as %YAML 1.2, its Value holds the whole directive
+	TypeDirective
 )
 
 const (
@@ -53,12 +63,17 @@ const (
 	CharClosingSquareBracket      = ']'
 	CharOpeningCurlyBrace         = '{'
 	CharClosingCurlyBrace         = '}'
+	CharPercent                   = '%'
 )
 
 type Token struct {
 	Type     Type
 	Value    string
 	Position Location
+
+	// Quote is the quote character enclosing a TypeData Token,
+	// or zero if the data was not quoted
+	Quote rune
 }
 
 // Location represent the Location of the Token within the document
@@ -67,6 +82,21 @@ type Location struct {
 	column int
 }
 
+// NewLocation creates a Location pointing at line and column, both counted from 1
+func NewLocation(line, column int) Location {
+	return Location{line: line, column: column}
+}
+
+// Line is the 1-based line number of Location
+func (l Location) Line() int {
+	return l.line
+}
+
+// Column is the 1-based column number of Location
+func (l Location) Column() int {
+	return l.column
+}
+
 func (l Location) String() string {
 	return fmt.Sprintf("line(%d): column(%d)", l.line, l.column)
 }
@@ -85,3 +115,10 @@ func New(typ Type, value string, line, column int) Token {
 		},
 	}
 }
+
+// NewQuoted creates a TypeData Token whose value was enclosed in quote
+func NewQuoted(value string, quote rune, line, column int) Token {
+	t := New(TypeData, value, line, column)
+	t.Quote = quote
+	return t
+}
@@ -1,18 +1,22 @@
 package tokenizer
 
 import (
+	"context"
 	"fmt"
 	"github.com/ercross/yaml/token"
 	"strings"
-	"unicode"
 	"unicode/utf8"
 )
 
 type Tokenizer struct {
 	complexTokenBuilder *complexTokenBuilder
 
-	// indentationCharacter must be consistent once set
-	indentationCharacter rune
+	// flowLevel is the nesting depth of flow collections ([] and {}) at the current position.
+	// Flow indicators such as comma only carry a meaning while flowLevel > 0
+	flowLevel int
+
+	// blockScalar is set while the next lines might be content of a literal or folded block scalar
+	blockScalar *blockScalar
 }
 
 // manages build process for complex tokens (e.g., quoted strings)
@@ -24,6 +28,17 @@ type complexTokenBuilder struct {
 	startCharacterOccurrenceCount int
 }
 
+// blockScalar tracks the content lines of a literal (|) or folded (>) block scalar
+type blockScalar struct {
+
+	// parentIndentation is the indentation of the node owning the block scalar.
+	// A content line must be indented deeper than parentIndentation
+	parentIndentation int
+}
+
+// contextCheckInterval is the number of runes of a line tokenized between two checks of the context of TokenizeContext
+const contextCheckInterval = 1 << 16
+
 var symbolToTokenType map[rune]token.Type = map[rune]token.Type{
 	token.CharNewline:              token.TypeNewline,
 	token.CharColon:                token.TypeColon,
@@ -51,6 +66,7 @@ func (t *complexTokenBuilder) endBuild() {
 	t.endBuildOnNext = 0
 	t.startLine = 0
 	t.startColumn = 0
+	t.startCharacterOccurrenceCount = 0
 }
 
 func (t *complexTokenBuilder) startBuilding(breakOn rune, lineNumber int, column int) {
@@ -59,54 +75,96 @@ func (t *complexTokenBuilder) startBuilding(breakOn rune, lineNumber int, column
 	t.startColumn = column
 }
 
+// Tokenize breaks line into tokens.
+//
+// Tokenizer keeps state between invocations (e.g., an unterminated quoted string or block scalar content),
+// so lines must be tokenized in document order
 func (t *Tokenizer) Tokenize(line string, lineNumber int) (tokens []token.Token, err error) {
+	return t.TokenizeContext(context.Background(), line, lineNumber)
+}
+
+// TokenizeContext is like Tokenize, but stops once ctx is done, checking it every contextCheckInterval runes
+// so that a single long line can be stopped, and fails with the error of ctx and the position it stopped at
+func (t *Tokenizer) TokenizeContext(ctx context.Context, line string, lineNumber int) (tokens []token.Token, err error) {
 	if len(line) == 0 {
 		return tokens, nil
 	}
 
+	if t.blockScalar != nil {
+		if t.isBlockScalarContent(line) {
+			return tokenizeBlockScalarLine(line, lineNumber), nil
+		}
+		t.blockScalar = nil
+	}
+
 	column := 1
 	rawLine := []byte(line)
 
+	// entryIndentation is the indentation of the latest mapping key or sequence entry found on line
+	entryIndentation := -1
+
+	// nextCheck is the column from which ctx is checked next
+	nextCheck := contextCheckInterval
+
 	for len(rawLine) > 0 {
+		if column >= nextCheck {
+			if err := ctx.Err(); err != nil {
+				return nil, fmt.Errorf("tokenizing stopped on %d:%d: %w", lineNumber, column, err)
+			}
+			nextCheck = column + contextCheckInterval
+		}
 
 		r, runeSize := utf8.DecodeRune(rawLine)
 
-		if column == 1 {
+		if column == 1 && !t.complexTokenBuilder.isBuilding() {
 			if isWhiteSpaceCharacter(r) {
-				if err = t.handleWhitespace(tokens, rawLine, &column, lineNumber); err != nil {
+				if tokens, rawLine, err = t.handleWhitespace(tokens, rawLine, &column, lineNumber); err != nil {
 					return tokens, err
 				}
 				continue
 			}
 
-			if (r == token.CharPeriod || r == token.CharDash) && len(tokens) == 0 {
-				return t.handleDocumentStarters(rawLine, lineNumber)
+			if isDocumentMarker(rawLine) {
+				tokens = append(tokens, t.handleDocumentStarters(rawLine, lineNumber))
+				rawLine = rawLine[3:]
+				column += 3
+				continue
+			}
+
+			if r == token.CharPercent {
+				directive := strings.TrimRight(line, "\n")
+				tokens = append(tokens, token.New(token.TypeDirective, directive, lineNumber, column))
+				if strings.HasSuffix(line, "\n") {
+					tokens = append(tokens, token.New(token.TypeNewline, "", lineNumber, column+utf8.RuneCountInString(directive)))
+				}
+				return tokens, nil
 			}
 		}
 
-		if (r == token.CharDoubleQuote || r == token.CharSingleQuote) && !t.complexTokenBuilder.isEscapeSequence(r) {
+		if isQuote(r) && t.complexTokenBuilder.canHandleQuote(r) {
 			t.complexTokenBuilder.startCharacterOccurrenceCount++
 			rawLine = rawLine[runeSize:]
-			if t.complexTokenBuilder.isBuilding() && t.complexTokenBuilder.endBuildOnNext == r {
+			column++
+			if t.complexTokenBuilder.isBuilding() {
 				if t.complexTokenBuilder.canEndBuilding(rawLine) {
-					tokens = append(tokens, token.New(token.TypeData, t.complexTokenBuilder.builder.String(), t.complexTokenBuilder.startLine, t.complexTokenBuilder.startColumn))
+					tokens = append(tokens, token.NewQuoted(t.complexTokenBuilder.builder.String(), r,
+						t.complexTokenBuilder.startLine, t.complexTokenBuilder.startColumn))
 					t.complexTokenBuilder.endBuild()
-					column++
 					continue
 				}
 
-				t.complexTokenBuilder.builder.WriteString(string(r))
+				t.complexTokenBuilder.builder.WriteRune(r)
 				continue
 			}
 
-			t.complexTokenBuilder.startBuilding(r, lineNumber, column)
+			t.complexTokenBuilder.startBuilding(r, lineNumber, column-1)
 			continue
 
 		}
 
 		if t.complexTokenBuilder.isBuilding() {
 			// build data Token
-			t.complexTokenBuilder.builder.WriteString(string(r))
+			t.complexTokenBuilder.builder.WriteRune(r)
 			rawLine = rawLine[runeSize:]
 			column++
 			continue
@@ -121,33 +179,100 @@ func (t *Tokenizer) Tokenize(line string, lineNumber int) (tokens []token.Token,
 		}
 
 		if r == token.CharCommentStarter {
-			tokens = append(tokens, token.New(token.TypeComment, extractComment(line), lineNumber, column))
-			return
+			comment := extractComment(string(rawLine))
+			tokens = append(tokens, token.New(token.TypeComment, comment, lineNumber, column))
+			if strings.HasSuffix(line, "\n") {
+				tokens = append(tokens, token.New(token.TypeNewline, "", lineNumber, column+1+utf8.RuneCountInString(comment)))
+			}
+			return tokens, nil
 		}
 
-		// check for YAML-meaningful symbol
-		if tt, ok := symbolToTokenType[r]; ok {
-			rawLine = rawLine[runeSize:]
-			tokens = append(tokens, token.New(tt, "", lineNumber, column))
+		next := rawLine[runeSize:]
+
+		switch {
+		case r == token.CharDash && t.flowLevel == 0 && isBlankOrEnd(next):
+			tokens = append(tokens, token.New(token.TypeDash, "", lineNumber, column))
+			entryIndentation = column - 1
+			rawLine = next
+			column++
+			continue
+
+		case r == token.CharColon && t.isMappingValueIndicator(next, tokens):
+			if t.flowLevel == 0 && len(tokens) > 0 && tokens[len(tokens)-1].Type == token.TypeData {
+				entryIndentation = tokens[len(tokens)-1].Position.Column() - 1
+			}
+			tokens = append(tokens, token.New(token.TypeColon, "", lineNumber, column))
+			rawLine = next
+			column++
+			continue
+
+		case r == token.CharQuestionMark && isBlankOrEnd(next):
+			tokens = append(tokens, token.New(token.TypeQuestionMark, "", lineNumber, column))
+			entryIndentation = column - 1
+			rawLine = next
+			column++
+			continue
+
+		case r == token.CharAmpersand || r == token.CharAsterisk || r == token.CharExclamationMark:
+			property, size := scanProperty(rawLine)
+			if (r != token.CharExclamationMark && len(property) == 1) || property == "" {
+				return nil, fmt.Errorf("missing %s name on %d:%d", string(r), lineNumber, column)
+			}
+			value := property
+			if r != token.CharExclamationMark {
+				// anchor and alias tokens only hold the name
+				value = property[1:]
+			}
+			tokens = append(tokens, token.New(symbolToTokenType[r], value, lineNumber, column))
+			rawLine = rawLine[size:]
+			column += utf8.RuneCountInString(property)
+			continue
+
+		case (r == token.CharPipe || r == token.CharGreaterThan) && t.flowLevel == 0:
+			indicators, size := scanBlockScalarIndicators(next)
+			rest := strings.TrimLeft(string(next[size:]), " \t")
+			if rest != "" && rest[0] != token.CharNewline && rest[0] != token.CharCommentStarter {
+				return nil, fmt.Errorf("invalid block scalar header on %d:%d", lineNumber, column)
+			}
+			tokens = append(tokens, token.New(symbolToTokenType[r], indicators, lineNumber, column))
+			t.blockScalar = &blockScalar{parentIndentation: entryIndentation}
+			rawLine = next[size:]
+			column += 1 + size
+			continue
+
+		case r == token.CharOpeningSquareBracket || r == token.CharOpeningCurlyBrace:
+			t.flowLevel++
+			tokens = append(tokens, token.New(symbolToTokenType[r], "", lineNumber, column))
+			rawLine = next
+			column++
+			continue
+
+		case r == token.CharClosingSquareBracket || r == token.CharClosingCurlyBrace:
+			if t.flowLevel > 0 {
+				t.flowLevel--
+			}
+			tokens = append(tokens, token.New(symbolToTokenType[r], "", lineNumber, column))
+			rawLine = next
+			column++
+			continue
+
+		case r == token.CharComma || r == token.CharNewline:
+			tokens = append(tokens, token.New(symbolToTokenType[r], "", lineNumber, column))
+			rawLine = next
 			column++
 			continue
 		}
 
 		// check for data
 		if isData(r) {
-			startColumn := column
-			var b strings.Builder
-			for !isYAMLValidSymbol(r) {
-				b.WriteRune(r)
-				rawLine = rawLine[runeSize:]
-				column++
-				r, runeSize = utf8.DecodeRune(rawLine)
-				if r == utf8.RuneError && runeSize == 1 {
-					return nil, fmt.Errorf("invalid character on line %d; column %d", lineNumber, column)
-				}
+			value, size, runeCount := t.scanPlainScalar(rawLine)
+			if value == "" && size == 0 {
+				return nil, fmt.Errorf("invalid character on line %d; column %d", lineNumber, column)
 			}
 
-			tokens = append(tokens, token.New(token.TypeData, b.String(), lineNumber, startColumn))
+			tokens = append(tokens, token.New(token.TypeData, value, lineNumber, column))
+			rawLine = rawLine[size:]
+			column += runeCount
 			continue
 		}
 
@@ -157,17 +282,45 @@ func (t *Tokenizer) Tokenize(line string, lineNumber int) (tokens []token.Token,
 	return tokens, nil
 }
 
+// Finish reports an error if Tokenizer stopped in the middle of a multi-line token
+func (t *Tokenizer) Finish() error {
+	if t.complexTokenBuilder.isBuilding() {
+		return fmt.Errorf("unterminated quoted string starting on %d:%d",
+			t.complexTokenBuilder.startLine, t.complexTokenBuilder.startColumn)
+	}
+	return nil
+}
+
 func (t complexTokenBuilder) isBuilding() bool {
 	return t.startColumn > 0 && t.endBuildOnNext != 0
 }
 
-func extractComment(line string) string {
-	parts := strings.Split("#", line)
-	return parts[1]
+// canHandleQuote reports whether quote starts or possibly ends the complex token being built.
+// Quotes of the other kind are ordinary characters inside a quoted string
+func (t complexTokenBuilder) canHandleQuote(quote rune) bool {
+	if !t.isBuilding() {
+		return true
+	}
+	return quote == t.endBuildOnNext && !t.isEscapeSequence(quote)
+}
+
+// extractComment returns the text of the comment starting rawLine, following its # indicator
+func extractComment(rawLine string) string {
+	comment := strings.TrimPrefix(rawLine, string(token.CharCommentStarter))
+	return strings.TrimRight(comment, "\r\n")
 }
 
+// isData checks that r can start a plain (unquoted) scalar
 func isData(r rune) bool {
-	return unicode.IsLetter(r) || unicode.IsDigit(r)
+	if r == utf8.RuneError {
+		return false
+	}
+	switch r {
+	case '@', '`':
+		// reserved indicators
+		return false
+	}
+	return !isYAMLValidSymbol(r)
 }
 
 func isYAMLValidSymbol(r rune) bool {
@@ -175,12 +328,49 @@ func isYAMLValidSymbol(r rune) bool {
 	return ok
 }
 
+func isQuote(r rune) bool {
+	return r == token.CharDoubleQuote || r == token.CharSingleQuote
+}
+
+func isFlowIndicator(r rune) bool {
+	switch r {
+	case token.CharComma, token.CharOpeningSquareBracket, token.CharClosingSquareBracket,
+		token.CharOpeningCurlyBrace, token.CharClosingCurlyBrace:
+		return true
+	default:
+		return false
+	}
+}
+
+// isBlankOrEnd checks that rawLine is empty or starts with a whitespace or newline
+func isBlankOrEnd(rawLine []byte) bool {
+	if len(rawLine) == 0 {
+		return true
+	}
+	r, _ := utf8.DecodeRune(rawLine)
+	return isWhiteSpaceCharacter(r) || r == token.CharNewline
+}
+
+// isDocumentMarker checks that rawLine starts with a document start (---) or end (...) marker
+func isDocumentMarker(rawLine []byte) bool {
+	if len(rawLine) < 3 {
+		return false
+	}
+	marker := string(rawLine[:3])
+	if marker != "---" && marker != "..." {
+		return false
+	}
+	return isBlankOrEnd(rawLine[3:])
+}
+
 func (t complexTokenBuilder) isEscapeSequence(r rune) bool {
-	if !t.isBuilding() {
+	if !t.isBuilding() || r != token.CharDoubleQuote {
 		return false
 	}
 
-	return r == token.CharDoubleQuote && strings.HasSuffix(t.builder.String(), `\`)
+	built := t.builder.String()
+	backslashes := len(built) - len(strings.TrimRight(built, `\`))
+	return backslashes%2 == 1
 }
 
 func isWhiteSpaceCharacter(r rune) bool {
@@ -189,65 +379,168 @@ func isWhiteSpaceCharacter(r rune) bool {
 
 func (t complexTokenBuilder) canEndBuilding(rawLine []byte) bool {
 
-	nextCharacter, size := utf8.DecodeRune(rawLine)
-	if size == utf8.RuneError {
+	if t.startCharacterOccurrenceCount%2 != 0 {
 		return false
 	}
 
-	if (nextCharacter == token.CharWhitespace || nextCharacter == token.CharNewline) && t.startCharacterOccurrenceCount%2 == 0 {
+	if len(rawLine) == 0 {
 		return true
 	}
-	return false
+
+	nextCharacter, _ := utf8.DecodeRune(rawLine)
+	return isWhiteSpaceCharacter(nextCharacter) || nextCharacter == token.CharNewline ||
+		nextCharacter == token.CharColon || isFlowIndicator(nextCharacter)
 }
 
-func (t *Tokenizer) handleDocumentStarters(rawLine []byte, lineNumber int) ([]token.Token, error) {
-	column := 1
-	allowedLength := 3
-	if len(rawLine) != allowedLength {
-		return nil, fmt.Errorf("document start [---] or end [...] tokens must be alone on a separate line")
+// isMappingValueIndicator checks that a colon followed by rawLine separates a mapping key from its value
+func (t *Tokenizer) isMappingValueIndicator(rawLine []byte, tokens []token.Token) bool {
+	if isBlankOrEnd(rawLine) {
+		return true
 	}
-	r, runeSize := utf8.DecodeRune(rawLine)
 
-	t.complexTokenBuilder.startBuilding(r, lineNumber, column)
-	for len(rawLine) > 0 {
-		if r != t.complexTokenBuilder.endBuildOnNext {
-			return nil, fmt.Errorf("unexpected rune %v", string(r))
+	if t.flowLevel == 0 {
+		return false
+	}
+
+	next, _ := utf8.DecodeRune(rawLine)
+	if isFlowIndicator(next) {
+		return true
+	}
+
+	// JSON-like keys (e.g., {"key":value}) may be directly followed by the colon
+	if len(tokens) == 0 {
+		return false
+	}
+	previous := tokens[len(tokens)-1]
+	return previous.Quote != 0 ||
+		previous.Type == token.TypeClosingSquareBracket || previous.Type == token.TypeClosingCurlyBrace
+}
+
+// scanPlainScalar reads a plain (unquoted) scalar from the start of rawLine.
+//
+// It returns the scalar value stripped of trailing whitespaces,
+// the number of bytes and the number of runes consumed from rawLine
+func (t *Tokenizer) scanPlainScalar(rawLine []byte) (value string, size int, runeCount int) {
+	var previous rune
+	for size < len(rawLine) {
+		r, runeSize := utf8.DecodeRune(rawLine[size:])
+		if r == utf8.RuneError && runeSize == 1 {
+			return "", 0, 0
+		}
+
+		if r == token.CharNewline {
+			break
+		}
+		if r == token.CharColon {
+			next := rawLine[size+runeSize:]
+			if isBlankOrEnd(next) {
+				break
+			}
+			if n, _ := utf8.DecodeRune(next); t.flowLevel > 0 && isFlowIndicator(n) {
+				break
+			}
 		}
-		t.complexTokenBuilder.builder.WriteString(string(r))
-		rawLine = rawLine[runeSize:]
-		r, runeSize = utf8.DecodeRune(rawLine)
-		column++
+		if r == token.CharCommentStarter && isWhiteSpaceCharacter(previous) {
+			break
+		}
+		if t.flowLevel > 0 && isFlowIndicator(r) {
+			break
+		}
+
+		previous = r
+		size += runeSize
+		runeCount++
 	}
 
-	tt := token.TypeDocumentEnd
-	if r == token.CharDash {
-		tt = token.TypeDocumentStart
+	return strings.TrimRight(string(rawLine[:size]), " \t"), size, runeCount
+}
+
+// scanProperty reads an anchor (&name), alias (*name) or tag (!tag) from the start of rawLine.
+// It returns the property including its indicator and the number of bytes read
+func scanProperty(rawLine []byte) (value string, size int) {
+	if strings.HasPrefix(string(rawLine), "!<") {
+		end := strings.IndexRune(string(rawLine), '>')
+		if end == -1 {
+			return "", 0
+		}
+		return string(rawLine[:end+1]), end + 1
 	}
-	tokens := []token.Token{token.New(tt, t.complexTokenBuilder.builder.String(), lineNumber, column)}
-	t.complexTokenBuilder.endBuild()
-	return tokens, nil
+
+	for size < len(rawLine) {
+		r, runeSize := utf8.DecodeRune(rawLine[size:])
+		if isWhiteSpaceCharacter(r) || r == token.CharNewline || (size > 0 && isFlowIndicator(r)) {
+			break
+		}
+		size += runeSize
+	}
+	return string(rawLine[:size]), size
+}
+
+// scanBlockScalarIndicators reads the chomping and indentation indicators following a block scalar indicator
+func scanBlockScalarIndicators(rawLine []byte) (indicators string, size int) {
+	for size < len(rawLine) && strings.IndexByte("+-0123456789", rawLine[size]) != -1 {
+		size++
+	}
+	return string(rawLine[:size]), size
+}
+
+func (t *Tokenizer) isBlockScalarContent(line string) bool {
+	if isDocumentMarker([]byte(line)) {
+		return false
+	}
+
+	content := strings.TrimRight(line, "\n")
+	if strings.TrimSpace(content) == "" {
+		// blank lines belong to the block scalar until a less indented line ends it
+		return true
+	}
+
+	indentation := len(content) - len(strings.TrimLeft(content, " "))
+	return indentation > t.blockScalar.parentIndentation
 }
 
-func (t *Tokenizer) handleWhitespace(tokens []token.Token, rawLine []byte, column *int, lineNumber int) error {
-	r, runeSize := utf8.DecodeRune(rawLine)
-	if t.indentationCharacter == 0 {
-		t.indentationCharacter = r
+func tokenizeBlockScalarLine(line string, lineNumber int) []token.Token {
+	content := strings.TrimSuffix(line, "\n")
+	tokens := []token.Token{token.New(token.TypeBlockScalarLine, content, lineNumber, 1)}
+	if len(content) < len(line) {
+		tokens = append(tokens, token.New(token.TypeNewline, "", lineNumber, utf8.RuneCountInString(content)+1))
 	}
-	if t.indentationCharacter != r {
-		return fmt.Errorf("inconsistent indentation character found at %d:%d", lineNumber, *column)
+	return tokens
+}
+
+// handleDocumentStarters creates the document start [---] or end [...] token rawLine starts with
+func (t *Tokenizer) handleDocumentStarters(rawLine []byte, lineNumber int) token.Token {
+	tt := token.TypeDocumentEnd
+	if rune(rawLine[0]) == token.CharDash {
+		tt = token.TypeDocumentStart
 	}
+	return token.New(tt, string(rawLine[:3]), lineNumber, 1)
+}
+
+// handleWhitespace creates the indentation token of the spaces rawLine starts with.
+// YAML forbids tabs in indentation: tabs may only follow the indentation of a line holding no node,
+// or of a line within a flow collection
+func (t *Tokenizer) handleWhitespace(tokens []token.Token, rawLine []byte, column *int, lineNumber int) ([]token.Token, []byte, error) {
 
 	// build indentation
-	var b strings.Builder
-	for r == token.CharWhitespace {
-		rawLine = rawLine[runeSize:]
-		b.WriteString(" ")
-		*column++
-		r, runeSize = utf8.DecodeRune(rawLine)
+	startColumn := *column
+	spaces := 0
+	for spaces < len(rawLine) && rawLine[spaces] == token.CharWhitespace {
+		spaces++
 	}
-	if b.Len() > 0 {
-		tokens = append(tokens, token.New(token.TypeIndentation, b.String(), lineNumber, *column))
+	if spaces > 0 {
+		tokens = append(tokens, token.New(token.TypeIndentation, string(rawLine[:spaces]), lineNumber, startColumn))
 	}
+	rawLine = rawLine[spaces:]
+	*column += spaces
 
-	return nil
+	if len(rawLine) == 0 || rawLine[0] != token.CharTab {
+		return tokens, rawLine, nil
+	}
+	rest := strings.TrimLeft(string(rawLine), " \t")
+	if t.flowLevel == 0 && rest != "" && rest[0] != token.CharNewline && rest[0] != token.CharCommentStarter {
+		return tokens, rawLine, fmt.Errorf("tab character used for indentation at %d:%d", lineNumber, *column)
+	}
+	*column += len(rawLine) - len(rest)
+	return tokens, []byte(rest), nil
 }
@@ -1,8 +1,13 @@
 package tokenizer
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"github.com/ercross/yaml/test/data"
 	"github.com/ercross/yaml/token"
+	"slices"
+	"strings"
 	"testing"
 )
 
@@ -31,3 +36,212 @@ func TestTokenizeLine(t *testing.T) {
 		}
 	}
 }
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name     string
+		lines    []string
+		expected []token.Token
+	}{
+		{
+			name:  "comment",
+			lines: []string{"key: value # note\n"},
+			expected: []token.Token{
+				token.New(token.TypeData, "key", 1, 1),
+				token.New(token.TypeColon, "", 1, 4),
+				token.New(token.TypeData, "value", 1, 6),
+				token.New(token.TypeComment, " note", 1, 12),
+				token.New(token.TypeNewline, "", 1, 18),
+			},
+		},
+		{
+			name:  "document marker and comment",
+			lines: []string{"--- # c\n"},
+			expected: []token.Token{
+				token.New(token.TypeDocumentStart, "---", 1, 1),
+				token.New(token.TypeComment, " c", 1, 5),
+				token.New(token.TypeNewline, "", 1, 8),
+			},
+		},
+		{
+			name:  "quoted scalars",
+			lines: []string{`"a b": 'c''d' # "e"` + "\n"},
+			expected: []token.Token{
+				token.NewQuoted("a b", '"', 1, 1),
+				token.New(token.TypeColon, "", 1, 6),
+				token.NewQuoted("c''d", '\'', 1, 8),
+				token.New(token.TypeComment, ` "e"`, 1, 15),
+				token.New(token.TypeNewline, "", 1, 20),
+			},
+		},
+		{
+			name:  "escaped double quote",
+			lines: []string{`a: "x \"y\""` + "\n"},
+			expected: []token.Token{
+				token.New(token.TypeData, "a", 1, 1),
+				token.New(token.TypeColon, "", 1, 2),
+				token.NewQuoted(`x \"y\"`, '"', 1, 4),
+				token.New(token.TypeNewline, "", 1, 13),
+			},
+		},
+		{
+			name:  "quoted scalar spanning lines",
+			lines: []string{"a: \"x\n", "  y\"\n"},
+			expected: []token.Token{
+				token.New(token.TypeData, "a", 1, 1),
+				token.New(token.TypeColon, "", 1, 2),
+				token.NewQuoted("x\n  y", '"', 1, 4),
+				token.New(token.TypeNewline, "", 2, 5),
+			},
+		},
+		{
+			name:  "flow indicators",
+			lines: []string{"{a: [1, 2], b: c}\n"},
+			expected: []token.Token{
+				token.New(token.TypeOpeningCurlyBrace, "", 1, 1),
+				token.New(token.TypeData, "a", 1, 2),
+				token.New(token.TypeColon, "", 1, 3),
+				token.New(token.TypeOpeningSquareBracket, "", 1, 5),
+				token.New(token.TypeData, "1", 1, 6),
+				token.New(token.TypeComma, "", 1, 7),
+				token.New(token.TypeData, "2", 1, 9),
+				token.New(token.TypeClosingSquareBracket, "", 1, 10),
+				token.New(token.TypeComma, "", 1, 11),
+				token.New(token.TypeData, "b", 1, 13),
+				token.New(token.TypeColon, "", 1, 14),
+				token.New(token.TypeData, "c", 1, 16),
+				token.New(token.TypeClosingCurlyBrace, "", 1, 17),
+				token.New(token.TypeNewline, "", 1, 18),
+			},
+		},
+		{
+			name:  "colons within flow collections",
+			lines: []string{`[a:b, {"c":d}]` + "\n"},
+			expected: []token.Token{
+				token.New(token.TypeOpeningSquareBracket, "", 1, 1),
+				token.New(token.TypeData, "a:b", 1, 2),
+				token.New(token.TypeComma, "", 1, 5),
+				token.New(token.TypeOpeningCurlyBrace, "", 1, 7),
+				token.NewQuoted("c", '"', 1, 8),
+				token.New(token.TypeColon, "", 1, 11),
+				token.New(token.TypeData, "d", 1, 12),
+				token.New(token.TypeClosingCurlyBrace, "", 1, 13),
+				token.New(token.TypeClosingSquareBracket, "", 1, 14),
+				token.New(token.TypeNewline, "", 1, 15),
+			},
+		},
+		{
+			name:  "indentation, dash and properties",
+			lines: []string{"  - &x !!str a\n"},
+			expected: []token.Token{
+				token.New(token.TypeIndentation, "  ", 1, 1),
+				token.New(token.TypeDash, "", 1, 3),
+				token.New(token.TypeAmpersand, "x", 1, 5),
+				token.New(token.TypeExclamationMark, "!!str", 1, 8),
+				token.New(token.TypeData, "a", 1, 14),
+				token.New(token.TypeNewline, "", 1, 15),
+			},
+		},
+		{
+			name:  "columns count runes",
+			lines: []string{"ключ: значение\n"},
+			expected: []token.Token{
+				token.New(token.TypeData, "ключ", 1, 1),
+				token.New(token.TypeColon, "", 1, 5),
+				token.New(token.TypeData, "значение", 1, 7),
+				token.New(token.TypeNewline, "", 1, 15),
+			},
+		},
+		{
+			name:  "tabs following the indentation of a blank line",
+			lines: []string{"  \t# c\n"},
+			expected: []token.Token{
+				token.New(token.TypeIndentation, "  ", 1, 1),
+				token.New(token.TypeComment, " c", 1, 4),
+				token.New(token.TypeNewline, "", 1, 7),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tkn := New()
+			var tokens []token.Token
+			for i, line := range tt.lines {
+				lineTokens, err := tkn.Tokenize(line, i+1)
+				if err != nil {
+					t.Fatal(err)
+				}
+				tokens = append(tokens, lineTokens...)
+			}
+			if err := tkn.Finish(); err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(tokens, tt.expected) {
+				t.Errorf("expected tokens:\n%v\ngot:\n%v", tt.expected, tokens)
+			}
+		})
+	}
+}
+
+func TestTokenize_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+	}{
+		{"tab indentation", []string{"a:\n", "\tb: 1\n"}},
+		{"tab following spaces in indentation", []string{"a:\n", "  \tb: 1\n"}},
+		{"missing alias name", []string{"a: *\n"}},
+		{"unterminated quoted scalar", []string{"a: 'b\n", "c\n"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tkn := New()
+			for i, line := range tt.lines {
+				if _, err := tkn.Tokenize(line, i+1); err != nil {
+					return
+				}
+			}
+			if err := tkn.Finish(); err == nil {
+				t.Errorf("expected an error tokenizing %q", tt.lines)
+			}
+		})
+	}
+}
+
+// cancelledContext is done once checked more than checks times
+type cancelledContext struct {
+	context.Context
+	checks int
+}
+
+func (ctx *cancelledContext) Err() error {
+	if ctx.checks == 0 {
+		return context.Canceled
+	}
+	ctx.checks--
+	return nil
+}
+
+func TestTokenizeContext(t *testing.T) {
+	// a single line holding a long flow sequence, then a long quoted scalar
+	lines := []string{
+		"[" + strings.Repeat("1, ", 4*contextCheckInterval) + "1]\n",
+		"'" + strings.Repeat("a", 4*contextCheckInterval) + "'\n",
+	}
+	for _, line := range lines {
+		ctx := &cancelledContext{Context: context.Background(), checks: 1}
+		_, err := New().TokenizeContext(ctx, line, 1)
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected context.Canceled, got %v", err)
+		}
+		if !strings.Contains(err.Error(), fmt.Sprintf("on 1:%d", 2*contextCheckInterval)) {
+			t.Errorf("expected the position tokenizing stopped at, got %v", err)
+		}
+	}
+
+	if _, err := New().TokenizeContext(context.Background(), lines[0], 1); err != nil {
+		t.Errorf("expected the line to be tokenized, got %v", err)
+	}
+}
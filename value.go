@@ -0,0 +1,177 @@
+package yaml
+
+import (
+	"cmp"
+	"encoding"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ErrUnsupportedValue is returned when a Go value has no YAML representation, e.g., a channel or a function
+var ErrUnsupportedValue = errors.New("unsupported value")
+
+// NewNode creates the node tree representing the Go value v.
+//
+// Nodes are returned as is, nil and nil pointers become null, and maps become mappings sorted by key.
+// Structs become mappings of their exported fields, named by the `yaml` field tag when present, e.g.,
+// `yaml:"name"`, `yaml:"name,omitempty"`, or `yaml:"-"` to skip a field.
+// A string that would otherwise be read back as another type (e.g., "true" or "1.5") is double-quoted
+func NewNode(v any) (Node, error) {
+	return newNode(reflect.ValueOf(v))
+}
+
+func newNode(v reflect.Value) (Node, error) {
+	if !v.IsValid() {
+		return NewScalarNode("null"), nil
+	}
+	if v.CanInterface() && !(v.Kind() == reflect.Pointer && v.IsNil()) {
+		switch value := v.Interface().(type) {
+		case Node:
+			return value, nil
+		case encoding.TextMarshaler:
+			text, err := value.MarshalText()
+			if err != nil {
+				return nil, err
+			}
+			return newStringNode(string(text)), nil
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return NewScalarNode("null"), nil
+		}
+		return newNode(v.Elem())
+
+	case reflect.Bool:
+		return NewScalarNode(strconv.FormatBool(v.Bool())), nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return NewScalarNode(strconv.FormatInt(v.Int(), 10)), nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return NewScalarNode(strconv.FormatUint(v.Uint(), 10)), nil
+
+	case reflect.Float32, reflect.Float64:
+		return NewScalarNode(formatFloat(v.Float(), v.Type().Bits())), nil
+
+	case reflect.String:
+		return newStringNode(v.String()), nil
+
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return NewScalarNode("null"), nil
+		}
+		sequence := NewSequenceNode()
+		for i := 0; i < v.Len(); i++ {
+			item, err := newNode(v.Index(i))
+			if err != nil {
+				return nil, fmt.Errorf("item %d: %w", i, err)
+			}
+			sequence.Append(item)
+		}
+		return sequence, nil
+
+	case reflect.Map:
+		if v.IsNil() {
+			return NewScalarNode("null"), nil
+		}
+		return newMappingNode(v)
+
+	case reflect.Struct:
+		return newStructNode(v)
+	}
+	return nil, fmt.Errorf("can not represent %s: %w", v.Type(), ErrUnsupportedValue)
+}
+
+// newStringNode creates a scalar read back as a string, quoting value if it would resolve to another tag
+func newStringNode(value string) *ScalarNode {
+	n := NewScalarNode(value)
+	if resolveTag(value) != TagString || IsYAML11Scalar(value) {
+		n.SetStyle(StyleDoubleQuoted)
+	}
+	return n
+}
+
+func newMappingNode(v reflect.Value) (Node, error) {
+	type entry struct {
+		key   Node
+		text  string
+		value reflect.Value
+	}
+
+	entries := make([]entry, 0, v.Len())
+	for iter := v.MapRange(); iter.Next(); {
+		key, err := newNode(iter.Key())
+		if err != nil {
+			return nil, fmt.Errorf("key %v: %w", iter.Key(), err)
+		}
+		entries = append(entries, entry{key: key, text: fmt.Sprint(iter.Key().Interface()), value: iter.Value()})
+	}
+	slices.SortFunc(entries, func(a, b entry) int {
+		return cmp.Compare(a.text, b.text)
+	})
+
+	mapping := NewMappingNode()
+	for _, e := range entries {
+		value, err := newNode(e.value)
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %w", e.text, err)
+		}
+		mapping.pairs = append(mapping.pairs, MappingPair{Key: e.key, Value: value})
+	}
+	return mapping, nil
+}
+
+func newStructNode(v reflect.Value) (Node, error) {
+	mapping := NewMappingNode()
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, options, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if slices.Contains(strings.Split(options, ","), "omitempty") && v.Field(i).IsZero() {
+			continue
+		}
+
+		value, err := newNode(v.Field(i))
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", field.Name, err)
+		}
+		mapping.pairs = append(mapping.pairs, MappingPair{Key: NewScalarNode(name), Value: value})
+	}
+	return mapping, nil
+}
+
+// formatFloat formats f as a float of the YAML core schema
+func formatFloat(f float64, bits int) string {
+	switch {
+	case math.IsInf(f, 1):
+		return ".inf"
+	case math.IsInf(f, -1):
+		return "-.inf"
+	case math.IsNaN(f):
+		return ".nan"
+	}
+
+	text := strconv.FormatFloat(f, 'g', -1, bits)
+	if !strings.ContainsAny(text, ".eEn") {
+		// keep the value a float when read back, e.g., 2.0 rather than 2
+		text += ".0"
+	}
+	return text
+}
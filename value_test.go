@@ -0,0 +1,50 @@
+package yaml_test
+
+import (
+	"errors"
+	"github.com/ercross/yaml"
+	"math"
+	"testing"
+)
+
+func TestNewNode(t *testing.T) {
+	type container struct {
+		Name    string            `yaml:"name"`
+		Port    int               `yaml:"port,omitempty"`
+		Labels  map[string]string `yaml:"labels,omitempty,flow"`
+		Ignored string            `yaml:"-"`
+		Ratio   float64
+	}
+
+	tests := []struct {
+		value    any
+		expected string
+	}{
+		{value: nil, expected: "null\n"},
+		{value: true, expected: "true\n"},
+		{value: uint8(7), expected: "7\n"},
+		{value: 2.0, expected: "2.0\n"},
+		{value: math.Inf(-1), expected: "-.inf\n"},
+		{value: "plain", expected: "plain\n"},
+		{value: "1.5", expected: "\"1.5\"\n"},
+		{value: []any{"a", 1, nil}, expected: "- a\n- 1\n- null\n"},
+		{value: map[string]int{"b": 2, "a": 1}, expected: "a: 1\nb: 2\n"},
+		{value: container{Name: "web", Ignored: "x", Ratio: 0.5}, expected: "name: web\nRatio: 0.5\n"},
+		{value: &container{Name: "api", Port: 80}, expected: "name: api\nport: 80\nRatio: 0.0\n"},
+		{value: yaml.NewScalarNode("node"), expected: "node\n"},
+	}
+	for _, test := range tests {
+		n, err := yaml.NewNode(test.value)
+		if err != nil {
+			t.Errorf("%v: %v", test.value, err)
+			continue
+		}
+		if actual := emit(t, n); actual != test.expected {
+			t.Errorf("%v: expected %q, got %q", test.value, test.expected, actual)
+		}
+	}
+
+	if _, err := yaml.NewNode(func() {}); !errors.Is(err, yaml.ErrUnsupportedValue) {
+		t.Errorf("expected ErrUnsupportedValue, got %v", err)
+	}
+}
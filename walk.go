@@ -0,0 +1,143 @@
+package yaml
+
+import (
+	"iter"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type (
+	// Visitor is called by Walk on every node of a tree, in depth-first order
+	Visitor interface {
+
+		// Enter is called when n is reached, before its children.
+		// The children of n are skipped if Enter returns false
+		Enter(path Path, n Node) (walkChildren bool)
+
+		// Leave is called once the children of n have been walked, or skipped
+		Leave(path Path, n Node)
+	}
+
+	// Path locates a Node from the node a traversal started at.
+	// The Path of the starting node is empty, and so is the Path of the root of a DocumentNode
+	Path []PathSegment
+
+	// PathSegment is a single step from a sequence or mapping to one of its children
+	PathSegment struct {
+
+		// Key is the key of the mapping pair the step leads to, or nil if the step leads to a sequence item
+		Key Node
+
+		// Index is the index of the sequence item, or of the mapping pair, the step leads to
+		Index int
+
+		// IsKey indicates that the step leads to the key of the mapping pair rather than its value
+		IsKey bool
+	}
+)
+
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+// Walk traverses the tree rooted at n in depth-first order, calling v.Enter and v.Leave on every node.
+// The key of a mapping pair is walked right before its value.
+//
+// Aliases are not followed, so Walk terminates on recursive aliases
+func Walk(n Node, v Visitor) {
+	walk(nil, n, v)
+}
+
+func walk(path Path, n Node, v Visitor) {
+	if v.Enter(path, n) {
+		eachChild(path, n, func(childPath Path, child Node) bool {
+			walk(childPath, child, v)
+			return true
+		})
+	}
+	v.Leave(path, n)
+}
+
+// All returns an iterator over every node of the tree rooted at n, with its Path from n,
+// in the same order as Walk.
+//
+// Paths yielded by the iterator are never modified afterwards and can be retained
+func All(n Node) iter.Seq2[Path, Node] {
+	return func(yield func(Path, Node) bool) {
+		all(nil, n, yield)
+	}
+}
+
+func all(path Path, n Node, yield func(Path, Node) bool) bool {
+	if !yield(path, n) {
+		return false
+	}
+	return eachChild(path, n, func(childPath Path, child Node) bool {
+		return all(childPath, child, yield)
+	})
+}
+
+// eachChild calls fn on every child of n, until fn returns false.
+// eachChild reports whether all children were handed to fn
+func eachChild(path Path, n Node, fn func(Path, Node) bool) bool {
+	switch n := n.(type) {
+	case *DocumentNode:
+		if n.root != nil {
+			return fn(path, n.root)
+		}
+
+	case *MappingNode:
+		for i, pair := range n.pairs {
+			if !fn(path.append(PathSegment{Key: pair.Key, Index: i, IsKey: true}), pair.Key) ||
+				!fn(path.append(PathSegment{Key: pair.Key, Index: i}), pair.Value) {
+				return false
+			}
+		}
+
+	case *SequenceNode:
+		for i, item := range n.items {
+			if !fn(path.append(PathSegment{Index: i}), item) {
+				return false
+			}
+		}
+
+	default:
+		for i, child := range n.Children() {
+			if !fn(path.append(PathSegment{Index: i}), child) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// append returns a new Path made of p followed by segment, leaving p untouched
+func (p Path) append(segment PathSegment) Path {
+	return append(p[:len(p):len(p)], segment)
+}
+
+// String renders p in a JSONPath-like notation, e.g., $.spec.containers[0].image.
+//
+// Scalar keys that are not plain identifiers are quoted (e.g., $["a.b"]),
+// other keys are rendered by the index of their pair (e.g., $[#2]).
+// A path leading to a mapping key rather than its value ends with ~ (e.g., $.spec~)
+func (p Path) String() string {
+	var b strings.Builder
+	b.WriteByte('$')
+	for _, segment := range p {
+		scalar, isScalar := segment.Key.(*ScalarNode)
+		switch {
+		case segment.Key == nil:
+			b.WriteString("[" + strconv.Itoa(segment.Index) + "]")
+		case isScalar && identifierPattern.MatchString(scalar.value):
+			b.WriteString("." + scalar.value)
+		case isScalar:
+			b.WriteString("[" + strconv.Quote(scalar.value) + "]")
+		default:
+			b.WriteString("[#" + strconv.Itoa(segment.Index) + "]")
+		}
+		if segment.IsKey {
+			b.WriteByte('~')
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,102 @@
+package yaml_test
+
+import (
+	"github.com/ercross/yaml"
+	"github.com/ercross/yaml/parser"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func parseDocument(t *testing.T, source string) *yaml.DocumentNode {
+	t.Helper()
+	ast, err := parser.Parse(strings.NewReader(source))
+	if err != nil {
+		t.Fatalf("failed to parse %q: %v", source, err)
+	}
+	return ast.Documents()[0]
+}
+
+type recordingVisitor struct {
+	events []string
+	skip   string
+}
+
+func (v *recordingVisitor) Enter(path yaml.Path, n yaml.Node) bool {
+	v.events = append(v.events, "enter "+path.String())
+	return path.String() != v.skip
+}
+
+func (v *recordingVisitor) Leave(path yaml.Path, _ yaml.Node) {
+	v.events = append(v.events, "leave "+path.String())
+}
+
+func TestWalk(t *testing.T) {
+	document := parseDocument(t, "a: [x]\nb: y\n")
+
+	v := &recordingVisitor{skip: "$.a"}
+	yaml.Walk(document, v)
+
+	expected := []string{
+		"enter $", // document
+		"enter $", // root mapping
+		"enter $.a~",
+		"leave $.a~",
+		"enter $.a",
+		"leave $.a",
+		"enter $.b~",
+		"leave $.b~",
+		"enter $.b",
+		"leave $.b",
+		"leave $",
+		"leave $",
+	}
+	if !slices.Equal(expected, v.events) {
+		t.Errorf("expected events %v, got %v", expected, v.events)
+	}
+}
+
+func TestAll(t *testing.T) {
+	document := parseDocument(t, "spec:\n  containers:\n    - image: web\n  \"a.b\": 1\n")
+
+	var paths []string
+	for path, n := range yaml.All(document.Root()) {
+		if _, isScalar := n.(*yaml.ScalarNode); isScalar && !path[len(path)-1].IsKey {
+			paths = append(paths, path.String())
+		}
+	}
+
+	expected := []string{`$.spec.containers[0].image`, `$.spec["a.b"]`}
+	if !slices.Equal(expected, paths) {
+		t.Errorf("expected paths %v, got %v", expected, paths)
+	}
+}
+
+func TestAll_Break(t *testing.T) {
+	document := parseDocument(t, "- a\n- b\n- c\n")
+
+	count := 0
+	for _, n := range yaml.All(document) {
+		if scalar, ok := n.(*yaml.ScalarNode); ok && scalar.Value() == "b" {
+			break
+		}
+		count++
+	}
+	if count != 3 {
+		t.Errorf("expected 3 nodes before b, got %d", count)
+	}
+}
+
+func TestAll_AliasNotFollowed(t *testing.T) {
+	document := parseDocument(t, "a: &x [1]\nb: *x\n")
+
+	aliases := 0
+	for _, n := range yaml.All(document) {
+		if n.Type() == yaml.NodeTypeAlias {
+			aliases++
+		}
+	}
+	if aliases != 1 {
+		t.Errorf("expected the alias to be yielded once without being followed, got %d", aliases)
+	}
+}